@@ -16,6 +16,18 @@ const (
 	ActionDelete        ActionType = "delete"
 
 	ActionRollbackCanary ActionType = "rollback-canary"
+	ActionRollbackStable ActionType = "rollback-stable"
+	ActionRollbackSimple ActionType = "rollback-simple"
+
+	ActionDeployProgressive ActionType = "deploy-progressive"
+
+	ActionSwitchBlueGreen ActionType = "switch-bluegreen"
+
+	ActionHistory ActionType = "history"
+
+	ActionSuspendCronJob ActionType = "suspend-cronjob"
+	ActionResumeCronJob  ActionType = "resume-cronjob"
+	ActionTriggerCronJob ActionType = "trigger-cronjob"
 
 	ActionUnknown ActionType = ""
 )
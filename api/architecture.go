@@ -0,0 +1,10 @@
+package api
+
+type Architecture string
+
+const (
+	ArchitectureAMD64 Architecture = "amd64"
+	ArchitectureARM64 Architecture = "arm64"
+
+	ArchitectureUnknown Architecture = ""
+)
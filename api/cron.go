@@ -0,0 +1,251 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CronSchedule is a parsed cron expression, accepting the same syntax as the schedule of a kubernetes cronjob
+type CronSchedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+
+	// if either day field is a wildcard both have to match, otherwise matching one of them is enough
+	dayOfMonthWildcard, dayOfWeekWildcard bool
+
+	every time.Duration
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute     = cronField{name: "minute", min: 0, max: 59}
+	cronHour       = cronField{name: "hour", min: 0, max: 23}
+	cronDayOfMonth = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth      = cronField{name: "month", min: 1, max: 12, names: map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}}
+	cronDayOfWeek  = cronField{name: "day of week", min: 0, max: 6, names: map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}}
+
+	cronDescriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// ParseCronSchedule parses a cron expression with minute, hour, day of month, month and day of week fields, or one of the descriptors like @daily
+// and @every 1h
+func ParseCronSchedule(expression string) (schedule *CronSchedule, err error) {
+	expression = strings.TrimSpace(expression)
+	if strings.HasPrefix(expression, "TZ=") || strings.HasPrefix(expression, "CRON_TZ=") {
+		return nil, fmt.Errorf("a time zone in the schedule isn't supported, use the timeZone parameter instead")
+	}
+
+	if strings.HasPrefix(expression, "@every ") {
+		every, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expression, "@every ")))
+		if err != nil || every <= 0 {
+			return nil, fmt.Errorf("%v has an invalid duration", expression)
+		}
+		return &CronSchedule{every: every}, nil
+	}
+	if descriptor, ok := cronDescriptors[strings.ToLower(expression)]; ok {
+		expression = descriptor
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields - minute, hour, day of month, month and day of week - but found %v", len(fields))
+	}
+
+	schedule = &CronSchedule{}
+	if schedule.minute, _, err = parseCronField(fields[0], cronMinute); err != nil {
+		return nil, err
+	}
+	if schedule.hour, _, err = parseCronField(fields[1], cronHour); err != nil {
+		return nil, err
+	}
+	if schedule.dayOfMonth, schedule.dayOfMonthWildcard, err = parseCronField(fields[2], cronDayOfMonth); err != nil {
+		return nil, err
+	}
+	if schedule.month, _, err = parseCronField(fields[3], cronMonth); err != nil {
+		return nil, err
+	}
+	if schedule.dayOfWeek, schedule.dayOfWeekWildcard, err = parseCronField(fields[4], cronDayOfWeek); err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+// parseCronField returns the bits of the values a comma-separated list of values, ranges and steps allows
+func parseCronField(value string, field cronField) (bits uint64, wildcard bool, err error) {
+	for _, term := range strings.Split(value, ",") {
+		rangeAndStep := strings.Split(term, "/")
+		if len(rangeAndStep) > 2 {
+			return 0, false, fmt.Errorf("%v %v has more than one step", field.name, term)
+		}
+
+		start, end := field.min, field.max
+		termWildcard := false
+		switch {
+		case rangeAndStep[0] == "*" || rangeAndStep[0] == "?":
+			termWildcard = true
+		case strings.Contains(rangeAndStep[0], "-"):
+			bounds := strings.SplitN(rangeAndStep[0], "-", 2)
+			if start, err = parseCronValue(bounds[0], field); err != nil {
+				return 0, false, err
+			}
+			if end, err = parseCronValue(bounds[1], field); err != nil {
+				return 0, false, err
+			}
+			if start > end {
+				return 0, false, fmt.Errorf("%v range %v starts after it ends", field.name, rangeAndStep[0])
+			}
+		default:
+			if start, err = parseCronValue(rangeAndStep[0], field); err != nil {
+				return 0, false, err
+			}
+			// a single value with a step runs up to the end of the field
+			if len(rangeAndStep) == 1 {
+				end = start
+			}
+		}
+
+		step := 1
+		if len(rangeAndStep) == 2 {
+			step, err = strconv.Atoi(rangeAndStep[1])
+			if err != nil || step <= 0 {
+				return 0, false, fmt.Errorf("%v step %v isn't a positive number", field.name, rangeAndStep[1])
+			}
+		}
+
+		// like kubernetes, a wildcard with a step doesn't count as a wildcard when combining the day fields
+		if termWildcard && step == 1 {
+			wildcard = true
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, wildcard, nil
+}
+
+func parseCronValue(value string, field cronField) (int, error) {
+	if number, ok := field.names[strings.ToLower(value)]; ok {
+		return number, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%v %v isn't a number", field.name, value)
+	}
+	if number < field.min || number > field.max {
+		return 0, fmt.Errorf("%v %v is outside of %v-%v", field.name, value, field.min, field.max)
+	}
+
+	return number, nil
+}
+
+// Next returns the first time after t the schedule runs at, in the location of t, or the zero time if it never runs
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Truncate(time.Second).Add(s.every)
+	}
+
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location()).Add(time.Minute)
+
+	// a schedule like 30 february never runs, so give up after a few years
+	yearLimit := t.Year() + 5
+	for t.Year() <= yearLimit {
+		var next time.Time
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchesDay(t):
+			next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			next = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+
+		// skipping to the next hour or day can end up at the same time when the clock changes for daylight saving time
+		if !next.After(t) {
+			next = t.Add(time.Hour)
+		}
+		t = next
+	}
+
+	return time.Time{}
+}
+
+// NextRuns returns up to n times after t the schedule runs at
+func (s *CronSchedule) NextRuns(t time.Time, n int) (runs []time.Time) {
+	for i := 0; i < n; i++ {
+		t = s.Next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+
+	return runs
+}
+
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	dayOfMonth := s.dayOfMonth&(1<<uint(t.Day())) > 0
+	dayOfWeek := s.dayOfWeek&(1<<uint(t.Weekday())) > 0
+	if s.dayOfMonthWildcard || s.dayOfWeekWildcard {
+		return dayOfMonth && dayOfWeek
+	}
+
+	return dayOfMonth || dayOfWeek
+}
+
+// validateSchedule checks that the schedule and time zone of a cronjob are valid and that the schedule runs at all, and logs its next runs
+func (p *Params) validateSchedule() (errors []error) {
+	schedule, err := ParseCronSchedule(p.Schedule)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("Schedule %v is invalid: %v; set schedule to a cron expression like '0 3 * * *'", p.Schedule, err))
+	}
+
+	location := time.UTC
+	if p.TimeZone != "" {
+		location, err = time.LoadLocation(p.TimeZone)
+		if err != nil || p.TimeZone == "Local" {
+			errors = append(errors, fmt.Errorf("Time zone %v is invalid; set timeZone to a name from the tz database like Europe/Amsterdam", p.TimeZone))
+			location = time.UTC
+		}
+	}
+
+	if schedule == nil {
+		return errors
+	}
+
+	runs := schedule.NextRuns(time.Now().In(location), 3)
+	if len(runs) == 0 {
+		return append(errors, fmt.Errorf("Schedule %v never runs; set schedule to a cron expression matching an existing date", p.Schedule))
+	}
+
+	formatted := []string{}
+	for _, run := range runs {
+		formatted = append(formatted, run.Format("2006-01-02 15:04 MST"))
+	}
+	log.Info().Msgf("Next runs of schedule %v: %v", p.Schedule, strings.Join(formatted, ", "))
+
+	return errors
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+
+	t.Run("ReturnsErrorForInvalidExpressions", func(t *testing.T) {
+
+		expressions := map[string]string{
+			"* * * *":            "expected 5 fields - minute, hour, day of month, month and day of week - but found 4",
+			"60 * * * *":         "minute 60 is outside of 0-59",
+			"* 24 * * *":         "hour 24 is outside of 0-23",
+			"* * 0 * *":          "day of month 0 is outside of 1-31",
+			"* * * foo * ":       "month foo isn't a number",
+			"* * * * 7":          "day of week 7 is outside of 0-6",
+			"5-1 * * * *":        "minute range 5-1 starts after it ends",
+			"*/0 * * * *":        "minute step 0 isn't a positive number",
+			"@every forever":     "@every forever has an invalid duration",
+			"TZ=UTC 0 3 * * *":   "a time zone in the schedule isn't supported, use the timeZone parameter instead",
+			"CRON_TZ=UTC @daily": "a time zone in the schedule isn't supported, use the timeZone parameter instead",
+		}
+
+		for expression, expectedError := range expressions {
+
+			// act
+			_, err := ParseCronSchedule(expression)
+
+			if assert.NotNil(t, err, expression) {
+				assert.Equal(t, expectedError, err.Error(), expression)
+			}
+		}
+	})
+
+	t.Run("AcceptsDescriptorsNamesRangesAndSteps", func(t *testing.T) {
+
+		for _, expression := range []string{"@daily", "@hourly", "@every 1h30m", "0 3 * * *", "*/15 9-17 * * mon-fri", "0 0 1,15 jan,jul ?"} {
+
+			// act
+			_, err := ParseCronSchedule(expression)
+
+			assert.Nil(t, err, expression)
+		}
+	})
+}
+
+func TestCronScheduleNext(t *testing.T) {
+
+	from := time.Date(2026, 10, 17, 12, 34, 56, 0, time.UTC)
+
+	t.Run("ReturnsNextRunsOfSchedule", func(t *testing.T) {
+
+		schedule, err := ParseCronSchedule("*/15 9-17 * * mon-fri")
+		assert.Nil(t, err)
+
+		// act
+		runs := schedule.NextRuns(from, 3)
+
+		// 17 october 2026 is a saturday
+		assert.Equal(t, []time.Time{
+			time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 19, 9, 15, 0, 0, time.UTC),
+			time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC),
+		}, runs)
+	})
+
+	t.Run("RunsOnEitherDayIfBothDayFieldsAreRestricted", func(t *testing.T) {
+
+		schedule, err := ParseCronSchedule("0 0 20 * mon")
+		assert.Nil(t, err)
+
+		// act
+		runs := schedule.NextRuns(from, 2)
+
+		assert.Equal(t, []time.Time{
+			time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC),
+		}, runs)
+	})
+
+	t.Run("RunsOnlyOnMatchingDaysIfDayOfMonthIsWildcard", func(t *testing.T) {
+
+		schedule, err := ParseCronSchedule("0 0 * * tue")
+		assert.Nil(t, err)
+
+		// act
+		next := schedule.Next(from)
+
+		assert.Equal(t, time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), next)
+	})
+
+	t.Run("RunsEveryDurationForEveryDescriptor", func(t *testing.T) {
+
+		schedule, err := ParseCronSchedule("@every 1h30m")
+		assert.Nil(t, err)
+
+		// act
+		next := schedule.Next(from)
+
+		assert.Equal(t, time.Date(2026, 10, 17, 14, 4, 56, 0, time.UTC), next)
+	})
+
+	t.Run("ReturnsZeroTimeIfScheduleNeverRuns", func(t *testing.T) {
+
+		schedule, err := ParseCronSchedule("0 0 30 2 *")
+		assert.Nil(t, err)
+
+		// act
+		runs := schedule.NextRuns(from, 3)
+
+		assert.Equal(t, 0, len(runs))
+	})
+
+	t.Run("ReturnsRunsInTimeZoneOfTime", func(t *testing.T) {
+
+		location, err := time.LoadLocation("Europe/Amsterdam")
+		assert.Nil(t, err)
+		schedule, err := ParseCronSchedule("@daily")
+		assert.Nil(t, err)
+
+		// act
+		next := schedule.Next(from.In(location))
+
+		assert.Equal(t, time.Date(2026, 10, 18, 0, 0, 0, 0, location), next)
+		assert.Equal(t, time.Date(2026, 10, 17, 22, 0, 0, 0, time.UTC), next.UTC())
+	})
+
+	t.Run("SkipsTimeThatDoesNotExistWhenClockMovesForward", func(t *testing.T) {
+
+		location, err := time.LoadLocation("Europe/Amsterdam")
+		assert.Nil(t, err)
+		schedule, err := ParseCronSchedule("30 2 * * *")
+		assert.Nil(t, err)
+
+		// act
+		next := schedule.Next(time.Date(2027, 3, 28, 1, 0, 0, 0, location))
+
+		assert.Equal(t, time.Date(2027, 3, 29, 2, 30, 0, 0, location), next)
+	})
+}
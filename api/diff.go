@@ -0,0 +1,34 @@
+package api
+
+import "fmt"
+
+// DiffSummary is the structured result of the diff actions: the resources a release would add, change or remove
+type DiffSummary struct {
+	Added   []DiffResource `json:"added"`
+	Changed []DiffResource `json:"changed"`
+	Removed []DiffResource `json:"removed"`
+}
+
+// DiffResource is a resource that would be added, changed or removed, with the fields that would change
+type DiffResource struct {
+	Kind      string      `json:"kind"`
+	Namespace string      `json:"namespace,omitempty"`
+	Name      string      `json:"name"`
+	Fields    []DiffField `json:"fields,omitempty"`
+}
+
+// DiffField is a field that differs between the live and rendered state of a resource; the values of secrets are redacted
+type DiffField struct {
+	Path     string `json:"path"`
+	Live     string `json:"live,omitempty"`
+	Rendered string `json:"rendered,omitempty"`
+}
+
+// HasDrift tells whether the live state differs from the rendered manifests
+func (s DiffSummary) HasDrift() bool {
+	return len(s.Added) > 0 || len(s.Changed) > 0 || len(s.Removed) > 0
+}
+
+func (s DiffSummary) String() string {
+	return fmt.Sprintf("%v to add, %v to change, %v to remove", len(s.Added), len(s.Changed), len(s.Removed))
+}
@@ -15,6 +15,9 @@ type GKECredentialAdditionalProperties struct {
 	Zone                  string  `json:"zone,omitempty"`
 	ServiceAccountKeyfile string  `json:"serviceAccountKeyfile,omitempty"`
 	Defaults              *Params `json:"defaults,omitempty"`
+
+	// Policies are evaluated against the rendered manifests of every release using this credential
+	Policies []PolicyRule `json:"policies,omitempty"`
 }
 
 func (c *GKECredentials) GetLocation() string {
@@ -54,36 +54,6 @@ func SanitizeLabels(labels map[string]string) (sanitizedLabels map[string]string
 	return
 }
 
-func httpRequestHeader(method, url string, headers map[string]string, responseHeader string) string {
-	client := pester.New()
-	client.MaxRetries = 3
-	client.Backoff = pester.ExponentialJitterBackoff
-	client.KeepLog = true
-	client.Timeout = time.Second * 5
-	request, err := http.NewRequest(method, url, nil)
-	if err != nil {
-		return ""
-	}
-
-	for k, v := range headers {
-		request.Header.Add(k, v)
-	}
-
-	// perform actual request
-	response, err := client.Do(request)
-	if err != nil {
-		return ""
-	}
-
-	defer response.Body.Close()
-
-	if response.StatusCode != http.StatusOK {
-		return ""
-	}
-
-	return response.Header.Get(responseHeader)
-}
-
 func httpRequestBody(method, url string, headers map[string]string) string {
 	client := pester.New()
 	client.MaxRetries = 3
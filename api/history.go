@@ -0,0 +1,51 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ReleaseRecord is an entry in the release history of an application, stored in the cluster for every release that changes it
+type ReleaseRecord struct {
+	ReleaseID       string         `json:"releaseID,omitempty"`
+	Action          ActionType     `json:"action"`
+	Kind            Kind           `json:"kind"`
+	BuildVersion    string         `json:"buildVersion,omitempty"`
+	Image           string         `json:"image,omitempty"`
+	ImageDigest     string         `json:"imageDigest,omitempty"`
+	ParamsHash      string         `json:"paramsHash"`
+	GitBranch       string         `json:"gitBranch,omitempty"`
+	GitRevision     string         `json:"gitRevision,omitempty"`
+	BuilderImageSHA string         `json:"builderImageSHA,omitempty"`
+	TriggeredBy     string         `json:"triggeredBy,omitempty"`
+	Succeeded       bool           `json:"succeeded"`
+	Rollout         RolloutOutcome `json:"rollout,omitempty"`
+	Error           string         `json:"error,omitempty"`
+	StartedAt       time.Time      `json:"startedAt"`
+	FinishedAt      time.Time      `json:"finishedAt"`
+}
+
+func (r ReleaseRecord) String() string {
+	result := "succeeded"
+	if !r.Succeeded {
+		result = "failed"
+	}
+	paramsHash := r.ParamsHash
+	if len(paramsHash) > 12 {
+		paramsHash = paramsHash[:12]
+	}
+
+	return fmt.Sprintf("%v %v %v %v version %v image %v params %v triggered by %v", r.FinishedAt.UTC().Format(time.RFC3339), r.ReleaseID, r.Action, result, r.BuildVersion, r.Image, paramsHash, r.TriggeredBy)
+}
+
+// ParamsHash returns a hash of the effective params of a release, so releases with identical params can be recognized in the release history
+func ParamsHash(params Params) string {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParamsHash(t *testing.T) {
+
+	t.Run("ReturnsSameHashForSameParams", func(t *testing.T) {
+
+		params := Params{App: "myapp", Replicas: 3}
+
+		// act
+		hash := ParamsHash(params)
+
+		assert.Equal(t, 64, len(hash))
+		assert.Equal(t, hash, ParamsHash(Params{App: "myapp", Replicas: 3}))
+	})
+
+	t.Run("ReturnsDifferentHashIfAnyParamChanges", func(t *testing.T) {
+
+		// act
+		hash := ParamsHash(Params{App: "myapp", Replicas: 3})
+
+		assert.NotEqual(t, hash, ParamsHash(Params{App: "myapp", Replicas: 4}))
+	})
+}
+
+func TestReleaseRecordString(t *testing.T) {
+
+	t.Run("ReturnsSingleLineSummary", func(t *testing.T) {
+
+		record := ReleaseRecord{
+			ReleaseID:    "123",
+			Action:       ActionDeploySimple,
+			BuildVersion: "1.0.3",
+			Image:        "estafette/myapp:1.0.3",
+			ParamsHash:   "0123456789abcdef0123",
+			TriggeredBy:  "me@server.com",
+			Succeeded:    true,
+			FinishedAt:   time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		}
+
+		// act
+		summary := record.String()
+
+		assert.Equal(t, "2024-03-01T10:00:00Z 123 deploy-simple succeeded version 1.0.3 image estafette/myapp:1.0.3 params 0123456789ab triggered by me@server.com", summary)
+	})
+}
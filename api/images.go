@@ -0,0 +1,170 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ImageLookup returns the platforms an image supports, like linux/amd64, and whether it exists; an error means the registry couldn't tell
+type ImageLookup func(image string) (platforms []string, exists bool, err error)
+
+// DigestResolver returns the digest of the manifest an image reference points at in its registry
+type DigestResolver func(image string) (digest string, err error)
+
+// ParamsImage is one of the images the pod of the application runs, with the parameter setting it
+type ParamsImage struct {
+	Description string
+	Path        string
+	Image       string
+
+	pin func(digest string)
+}
+
+// Images returns the images of the main container, the sidecars, the custom sidecars and the init containers
+func (p *Params) Images() (images []ParamsImage) {
+	if p.Container.ImageRepository != "" && p.Container.ImageName != "" {
+		images = append(images, ParamsImage{
+			Description: "container",
+			Path:        "container.tag",
+			Image:       fmt.Sprintf("%v/%v:%v", p.Container.ImageRepository, p.Container.ImageName, p.Container.ImageTag),
+			// the digest gets rendered after the tag, so the tag stays visible in the manifests
+			pin: func(digest string) { p.Container.ImageDigest = digest },
+		})
+	}
+
+	for i, sidecar := range p.Sidecars {
+		sidecar := sidecar
+		images = append(images, ParamsImage{
+			Description: fmt.Sprintf("sidecar %v", sidecar.Type),
+			Path:        fmt.Sprintf("sidecars[%v].image", i),
+			Image:       sidecar.Image,
+			pin:         func(digest string) { sidecar.Image = pinnedImage(sidecar.Image, digest) },
+		})
+	}
+	for i, container := range p.CustomSidecars {
+		images = append(images, rawContainerImage(fmt.Sprintf("custom sidecar %v", rawContainerName(container)), fmt.Sprintf("customsidecars[%v].image", i), container))
+	}
+	for i, container := range p.InitContainers {
+		images = append(images, rawContainerImage(fmt.Sprintf("init container %v", rawContainerName(container)), fmt.Sprintf("initcontainers[%v].image", i), container))
+	}
+
+	return images
+}
+
+// rawContainerImage returns the image of a container spec that's passed as is into the manifests
+func rawContainerImage(description, path string, container *map[string]interface{}) ParamsImage {
+	image := ParamsImage{Description: description, Path: path}
+	if container == nil {
+		return image
+	}
+	if value, ok := (*container)["image"].(string); ok {
+		image.Image = value
+	}
+	image.pin = func(digest string) { (*container)["image"] = pinnedImage(image.Image, digest) }
+
+	return image
+}
+
+// PinImageDigests replaces the tags of all images with the digest they point at, so every pod of a release runs exactly the same image even if
+// the tag gets pushed again; images that already have a digest are left as they are, and images that can't be resolved keep their tag
+func (p *Params) PinImageDigests(resolve DigestResolver) (warnings []string) {
+	digests := map[string]string{}
+	for _, image := range p.Images() {
+		if image.Image == "" || strings.Contains(image.Image, "@") {
+			continue
+		}
+
+		digest, ok := digests[image.Image]
+		if !ok {
+			var err error
+			digest, err = resolve(image.Image)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("Image %v of %v can't be pinned to a digest, it keeps using the tag: %v", image.Image, image.Description, err))
+				continue
+			}
+			digests[image.Image] = digest
+		}
+
+		image.pin(digest)
+		log.Info().Msgf("Pinned image %v of %v to digest %v", image.Image, image.Description, digest)
+	}
+
+	return warnings
+}
+
+// pinnedImage returns the image with the digest added after its tag; the tag is ignored when pulling, but shows which version the digest is
+func pinnedImage(image, digest string) string {
+	return fmt.Sprintf("%v@%v", image, digest)
+}
+
+// TargetPlatform returns the os and architecture of the nodes the pods get scheduled on, like linux/amd64
+func (p *Params) TargetPlatform() string {
+	operatingSystem, architecture := p.OperatingSystem, p.Architecture
+	if operatingSystem == OperatingSystemUnknown {
+		operatingSystem = OperatingSystemLinux
+	}
+	if architecture == ArchitectureUnknown {
+		architecture = ArchitectureAMD64
+	}
+
+	return fmt.Sprintf("%v/%v", operatingSystem, architecture)
+}
+
+// DeploysImages returns whether the action renders the workload with its images, so the images need to exist for it to succeed
+func (p *Params) DeploysImages() bool {
+	switch p.Kind {
+	case KindDeployment, KindHeadlessDeployment, KindStatefulset, KindJob, KindCronJob:
+	default:
+		return false
+	}
+
+	switch p.Action {
+	case ActionDeploySimple, ActionDeployCanary, ActionDeployStable, ActionDeployProgressive, ActionDiffSimple, ActionDiffCanary, ActionDiffStable:
+		return true
+	}
+
+	return false
+}
+
+// CheckImages checks that every image exists in its registry and supports the platform of the nodes, so a tag that was never pushed fails the
+// release instead of leaving pods in ImagePullBackOff; images the registry can't tell anything about only get a warning
+func (p *Params) CheckImages(lookup ImageLookup) (errors []error, warnings []string) {
+	target := p.TargetPlatform()
+	checked := map[string]bool{}
+	for _, image := range p.Images() {
+		if image.Image == "" || checked[image.Image] {
+			continue
+		}
+		checked[image.Image] = true
+
+		platforms, exists, err := lookup(image.Image)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Image %v of %v can't be checked: %v", image.Image, image.Description, err))
+			continue
+		}
+		if !exists {
+			errors = append(errors, fmt.Errorf("Image %v of %v doesn't exist in its registry; check whether the build pushed it or set %v to an existing image", image.Image, image.Description, image.Path))
+			continue
+		}
+		if len(platforms) > 0 && !supportsPlatform(platforms, target) {
+			errors = append(errors, fmt.Errorf("Image %v of %v doesn't support platform %v, only %v; build it for %v or set os and architecture to match the image", image.Image, image.Description, target, strings.Join(platforms, ", "), target))
+		}
+	}
+
+	return errors, warnings
+}
+
+// supportsPlatform returns whether any of the platforms matches the os and architecture of the target, ignoring the variant like v8 in
+// linux/arm64/v8
+func supportsPlatform(platforms []string, target string) bool {
+	for _, platform := range platforms {
+		parts := strings.Split(platform, "/")
+		if len(parts) >= 2 && parts[0]+"/"+parts[1] == target {
+			return true
+		}
+	}
+
+	return false
+}
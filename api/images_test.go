@@ -0,0 +1,173 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPinImageDigests(t *testing.T) {
+
+	t.Run("PinsContainerSidecarsCustomSidecarsAndInitContainers", func(t *testing.T) {
+
+		params := Params{
+			Container: ContainerParams{ImageRepository: "europe-docker.pkg.dev/myproject/images", ImageName: "myapp", ImageTag: "1.0.0"},
+			Sidecars: []*SidecarParams{
+				{Type: SidecarTypeOpenresty, Image: "estafette/openresty-sidecar:1.0"},
+				{Type: SidecarTypeCloudSQLProxy, Image: "gcr.io/cloudsql-docker/gce-proxy:1.33"},
+			},
+			CustomSidecars: []*map[string]interface{}{{"name": "custom", "image": "busybox:1.36"}},
+			InitContainers: []*map[string]interface{}{{"name": "migrate", "image": "europe-docker.pkg.dev/myproject/images/myapp:1.0.0"}},
+		}
+		resolved := []string{}
+
+		// act
+		warnings := params.PinImageDigests(func(image string) (string, error) {
+			resolved = append(resolved, image)
+			return "sha256:" + fmt.Sprint(len(image)), nil
+		})
+
+		assert.Equal(t, 0, len(warnings))
+		assert.Equal(t, "sha256:50", params.Container.ImageDigest)
+		assert.Equal(t, "1.0.0", params.Container.ImageTag)
+		assert.Equal(t, "estafette/openresty-sidecar:1.0@sha256:31", params.Sidecars[0].Image)
+		assert.Equal(t, "gcr.io/cloudsql-docker/gce-proxy:1.33@sha256:37", params.Sidecars[1].Image)
+		assert.Equal(t, "busybox:1.36@sha256:12", (*params.CustomSidecars[0])["image"])
+		assert.Equal(t, "europe-docker.pkg.dev/myproject/images/myapp:1.0.0@sha256:50", (*params.InitContainers[0])["image"])
+		// the init container uses the same image as the main container, so it's only resolved once
+		assert.Equal(t, 4, len(resolved))
+	})
+
+	t.Run("KeepsImagesThatAlreadyHaveADigest", func(t *testing.T) {
+
+		params := Params{
+			Sidecars: []*SidecarParams{
+				{Type: SidecarTypeOpenresty, Image: "estafette/openresty-sidecar@sha256:4300dc7d45600c428f4196009ee842c1c3bdd51aaa4f55361479f6fa60e78faf"},
+			},
+		}
+
+		// act
+		warnings := params.PinImageDigests(func(image string) (string, error) {
+			t.Errorf("image %v shouldn't be resolved", image)
+			return "", nil
+		})
+
+		assert.Equal(t, 0, len(warnings))
+		assert.Equal(t, "estafette/openresty-sidecar@sha256:4300dc7d45600c428f4196009ee842c1c3bdd51aaa4f55361479f6fa60e78faf", params.Sidecars[0].Image)
+	})
+
+	t.Run("KeepsTagAndReturnsWarningIfImageCannotBeResolved", func(t *testing.T) {
+
+		params := Params{
+			Container: ContainerParams{ImageRepository: "estafette", ImageName: "myapp", ImageTag: "1.0.0"},
+		}
+
+		// act
+		warnings := params.PinImageDigests(func(image string) (string, error) {
+			return "", fmt.Errorf("registry is unavailable")
+		})
+
+		assert.Equal(t, []string{"Image estafette/myapp:1.0.0 of container can't be pinned to a digest, it keeps using the tag: registry is unavailable"}, warnings)
+		assert.Equal(t, "", params.Container.ImageDigest)
+	})
+}
+
+func TestCheckImages(t *testing.T) {
+
+	t.Run("ReturnsNoErrorsIfAllImagesExistForTargetPlatform", func(t *testing.T) {
+
+		params := Params{
+			Architecture: ArchitectureARM64,
+			Container:    ContainerParams{ImageRepository: "estafette", ImageName: "myapp", ImageTag: "1.0.0"},
+			Sidecars:     []*SidecarParams{{Type: SidecarTypeOpenresty, Image: "estafette/openresty-sidecar:1.0"}},
+		}
+
+		// act
+		errors, warnings := params.CheckImages(func(image string) ([]string, bool, error) {
+			return []string{"linux/amd64", "linux/arm64/v8"}, true, nil
+		})
+
+		assert.Equal(t, 0, len(errors))
+		assert.Equal(t, 0, len(warnings))
+	})
+
+	t.Run("ReturnsErrorIfImageDoesNotExist", func(t *testing.T) {
+
+		params := Params{
+			Container: ContainerParams{ImageRepository: "estafette", ImageName: "myapp", ImageTag: "1.0.0"},
+		}
+
+		// act
+		errors, _ := params.CheckImages(func(image string) ([]string, bool, error) {
+			return nil, false, nil
+		})
+
+		if assert.Equal(t, 1, len(errors)) {
+			assert.Equal(t, "Image estafette/myapp:1.0.0 of container doesn't exist in its registry; check whether the build pushed it or set container.tag to an existing image", errors[0].Error())
+		}
+	})
+
+	t.Run("ReturnsErrorIfImageDoesNotSupportTargetPlatform", func(t *testing.T) {
+
+		params := Params{
+			OperatingSystem: OperatingSystemWindows,
+			Sidecars:        []*SidecarParams{{Type: SidecarTypeOpenresty, Image: "estafette/openresty-sidecar:1.0"}},
+		}
+
+		// act
+		errors, _ := params.CheckImages(func(image string) ([]string, bool, error) {
+			return []string{"linux/amd64", "linux/arm64"}, true, nil
+		})
+
+		if assert.Equal(t, 1, len(errors)) {
+			assert.Equal(t, "Image estafette/openresty-sidecar:1.0 of sidecar openresty doesn't support platform windows/amd64, only linux/amd64, linux/arm64; build it for windows/amd64 or set os and architecture to match the image", errors[0].Error())
+		}
+	})
+
+	t.Run("ReturnsWarningIfImageCannotBeChecked", func(t *testing.T) {
+
+		params := Params{
+			Container: ContainerParams{ImageRepository: "estafette", ImageName: "myapp", ImageTag: "1.0.0"},
+		}
+
+		// act
+		errors, warnings := params.CheckImages(func(image string) ([]string, bool, error) {
+			return nil, true, fmt.Errorf("registry is unavailable")
+		})
+
+		assert.Equal(t, 0, len(errors))
+		assert.Equal(t, []string{"Image estafette/myapp:1.0.0 of container can't be checked: registry is unavailable"}, warnings)
+	})
+}
+
+func TestDeploysImages(t *testing.T) {
+
+	t.Run("ReturnsTrueForDeployAndDiffActionsOfWorkloads", func(t *testing.T) {
+
+		for _, params := range []Params{
+			{Kind: KindDeployment, Action: ActionDeployCanary},
+			{Kind: KindJob, Action: ActionDeploySimple},
+			{Kind: KindCronJob, Action: ActionDiffSimple},
+		} {
+			// act
+			deploysImages := params.DeploysImages()
+
+			assert.True(t, deploysImages, params.Kind, params.Action)
+		}
+	})
+
+	t.Run("ReturnsFalseForOtherActionsOrKinds", func(t *testing.T) {
+
+		for _, params := range []Params{
+			{Kind: KindDeployment, Action: ActionRollbackCanary},
+			{Kind: KindCronJob, Action: ActionTriggerCronJob},
+			{Kind: KindConfig, Action: ActionDeploySimple},
+		} {
+			// act
+			deploysImages := params.DeploysImages()
+
+			assert.False(t, deploysImages, params.Kind, params.Action)
+		}
+	})
+}
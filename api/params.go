@@ -2,37 +2,70 @@ package api
 
 import (
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
+// defaultTrustedIPRanges holds cloudflare's ips from https://www.cloudflare.com/ips-v4, used when they can't be retrieved from cloudflare's api
+var defaultTrustedIPRanges = []string{
+	"173.245.48.0/20",
+	"103.21.244.0/22",
+	"103.22.200.0/22",
+	"103.31.4.0/22",
+	"141.101.64.0/18",
+	"108.162.192.0/18",
+	"190.93.240.0/20",
+	"188.114.96.0/20",
+	"197.234.240.0/22",
+	"198.41.128.0/17",
+	"162.158.0.0/15",
+	"104.16.0.0/13",
+	"104.24.0.0/14",
+	"172.64.0.0/13",
+	"131.0.72.0/22",
+}
+
 // Params is used to parameterize the deployment, set from custom properties in the manifest
 type Params struct {
 	// control params
-	Action                  ActionType      `json:"action,omitempty" yaml:"action,omitempty"`
-	Kind                    Kind            `json:"kind,omitempty" yaml:"kind,omitempty"`
-	DryRun                  bool            `json:"dryrun,omitempty" yaml:"dryrun,omitempty"`
-	ProgressDeadlineSeconds int             `json:"progressDeadlineSeconds,omitempty" yaml:"progressDeadlineSeconds,omitempty"`
-	BuildVersion            string          `json:"-" yaml:"-"`
-	ChaosProof              bool            `json:"chaosproof,omitempty" yaml:"chaosproof,omitempty"`
-	OperatingSystem         OperatingSystem `json:"os,omitempty" yaml:"os,omitempty"`
-	Manifests               ManifestsParams `json:"manifests,omitempty" yaml:"manifests,omitempty"`
-	TrustedIPRanges         []string        `json:"trustedips,omitempty" yaml:"trustedips,omitempty"`
-	Canary                  CanaryParams    `json:"canary,omitempty" yaml:"canary,omitempty"`
+	Action                  ActionType       `json:"action,omitempty" yaml:"action,omitempty"`
+	Kind                    Kind             `json:"kind,omitempty" yaml:"kind,omitempty"`
+	DryRun                  bool             `json:"dryrun,omitempty" yaml:"dryrun,omitempty"`
+	ProgressDeadlineSeconds int              `json:"progressDeadlineSeconds,omitempty" yaml:"progressDeadlineSeconds,omitempty"`
+	BuildVersion            string           `json:"-" yaml:"-"`
+	ChaosProof              bool             `json:"chaosproof,omitempty" yaml:"chaosproof,omitempty"`
+	OperatingSystem         OperatingSystem  `json:"os,omitempty" yaml:"os,omitempty"`
+	Architecture            Architecture     `json:"architecture,omitempty" yaml:"architecture,omitempty"`
+	Manifests               ManifestsParams  `json:"manifests,omitempty" yaml:"manifests,omitempty"`
+	TrustedIPRanges         []string         `json:"trustedips,omitempty" yaml:"trustedips,omitempty"`
+	Canary                  CanaryParams     `json:"canary,omitempty" yaml:"canary,omitempty"`
+	ReportPath              string           `json:"reportPath,omitempty" yaml:"reportPath,omitempty"`
+	Hooks                   HooksParams      `json:"hooks,omitempty" yaml:"hooks,omitempty"`
+	Smoketests              SmoketestsParams `json:"smoketests,omitempty" yaml:"smoketests,omitempty"`
+	BlueGreen               BlueGreenParams  `json:"bluegreen,omitempty" yaml:"bluegreen,omitempty"`
+	AutoRollback            bool             `json:"autoRollback,omitempty" yaml:"autoRollback,omitempty"`
+	Lock                    LockParams       `json:"lock,omitempty" yaml:"lock,omitempty"`
+	Diff                    DiffParams       `json:"diff,omitempty" yaml:"diff,omitempty"`
+	KubernetesVersion       string           `json:"kubernetesVersion,omitempty" yaml:"kubernetesVersion,omitempty"`
+	Strict                  bool             `json:"strict,omitempty" yaml:"strict,omitempty"`
 
 	// app params
 	App                             string                 `json:"app,omitempty" yaml:"app,omitempty"`
 	Namespace                       string                 `json:"namespace,omitempty" yaml:"namespace,omitempty"`
 	Schedule                        string                 `json:"schedule,omitempty" yaml:"schedule,omitempty"`
+	TimeZone                        string                 `json:"timeZone,omitempty" yaml:"timeZone,omitempty"`
+	Suspend                         *bool                  `json:"suspend,omitempty" yaml:"suspend,omitempty"`
 	RestartPolicy                   string                 `json:"restartPolicy,omitempty" yaml:"restartPolicy,omitempty"`
 	Completions                     int                    `json:"completions,omitempty" yaml:"completions,omitempty"`
 	Parallelism                     int                    `json:"parallelism,omitempty" yaml:"parallelism,omitempty"`
 	BackoffLimit                    *int                   `json:"backoffLimit,omitempty" yaml:"backoffLimit,omitempty"`
+	JobTimeout                      string                 `json:"jobTimeout,omitempty" yaml:"jobTimeout,omitempty"`
+	KeepFinishedJob                 bool                   `json:"keepFinishedJob,omitempty" yaml:"keepFinishedJob,omitempty"`
 	ConcurrencyPolicy               string                 `json:"concurrencypolicy,omitempty" yaml:"concurrencypolicy,omitempty"`
 	PodManagementPolicy             string                 `json:"podManagementpolicy,omitempty" yaml:"podManagementpolicy,omitempty"`
 	Replicas                        int                    `json:"replicas,omitempty" yaml:"replicas,omitempty"`
@@ -87,6 +120,8 @@ type Params struct {
 	CustomSidecars         []*map[string]interface{} `json:"customsidecars,omitempty" yaml:"customsidecars,omitempty"`
 	StrategyType           StrategyType              `json:"strategytype,omitempty" yaml:"strategytype,omitempty"`
 	AtomicID               string                    `json:"-" yaml:"-"`
+	BlueGreenColor         string                    `json:"-" yaml:"-"`
+	BlueGreenLiveColor     string                    `json:"-" yaml:"-"`
 	RollingUpdate          RollingUpdateParams       `json:"rollingupdate,omitempty" yaml:"rollingupdate,omitempty"`
 
 	// set default image for sidecars
@@ -105,6 +140,7 @@ type ContainerParams struct {
 	ImageRepository            string                 `json:"repository,omitempty" yaml:"repository,omitempty"`
 	ImageName                  string                 `json:"name,omitempty" yaml:"name,omitempty"`
 	ImageTag                   string                 `json:"tag,omitempty" yaml:"tag,omitempty"`
+	ImageDigest                string                 `json:"-" yaml:"-"`
 	ImagePullPolicy            string                 `json:"imagePullPolicy,omitempty" yaml:"imagePullPolicy,omitempty"`
 	Port                       int                    `json:"port,omitempty" yaml:"port,omitempty"`
 	PortGrpc                   int                    `json:"portGrpc,omitempty" yaml:"portGrpc,omitempty"`
@@ -231,7 +267,74 @@ type CanaryParams struct {
 	HeaderValue string `json:"headervalue,omitempty" yaml:"headervalue,omitempty"`
 	Weight      string `json:"weight,omitempty" yaml:"weight,omitempty"`
 	MinReplicas string `json:"minreplicas,omitempty" yaml:"minreplicas,omitempty"`
-	MaxReplicas string `json:"minreplicas,omitempty" yaml:"maxreplicas,omitempty"`
+	MaxReplicas string `json:"maxreplicas,omitempty" yaml:"maxreplicas,omitempty"`
+
+	// progressive canary params
+	Steps        []int                `json:"steps,omitempty" yaml:"steps,omitempty"`
+	StepInterval string               `json:"stepinterval,omitempty" yaml:"stepinterval,omitempty"`
+	Analysis     CanaryAnalysisParams `json:"analysis,omitempty" yaml:"analysis,omitempty"`
+}
+
+// CanaryAnalysisParams configures the prometheus checks evaluated at every step of a progressive canary release
+type CanaryAnalysisParams struct {
+	PrometheusURL string              `json:"prometheus,omitempty" yaml:"prometheus,omitempty"`
+	AllowNoData   bool                `json:"allownodata,omitempty" yaml:"allownodata,omitempty"`
+	Checks        []CanaryCheckParams `json:"checks,omitempty" yaml:"checks,omitempty"`
+}
+
+// CanaryCheckParams defines a promql query and the range its result has to stay within for the canary to be healthy
+type CanaryCheckParams struct {
+	Name  string   `json:"name,omitempty" yaml:"name,omitempty"`
+	Query string   `json:"query,omitempty" yaml:"query,omitempty"`
+	Min   *float64 `json:"min,omitempty" yaml:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty" yaml:"max,omitempty"`
+}
+
+// HooksParams sets jobs that run at specific moments of a release
+type HooksParams struct {
+	PreDeploy []HookParams `json:"preDeploy,omitempty" yaml:"preDeploy,omitempty"`
+}
+
+// HookParams sets a job that runs the application container with its env, secrets and configs, but a different command
+type HookParams struct {
+	Name         string   `json:"name,omitempty" yaml:"name,omitempty"`
+	Command      []string `json:"command,omitempty" yaml:"command,omitempty"`
+	Args         []string `json:"args,omitempty" yaml:"args,omitempty"`
+	BackoffLimit *int     `json:"backoffLimit,omitempty" yaml:"backoffLimit,omitempty"`
+	Timeout      string   `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// SmoketestsParams sets http checks that run inside the cluster once the rollout has finished
+type SmoketestsParams struct {
+	Checks            []SmoketestCheckParams `json:"checks,omitempty" yaml:"checks,omitempty"`
+	Image             string                 `json:"image,omitempty" yaml:"image,omitempty"`
+	Timeout           string                 `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	RollbackOnFailure bool                   `json:"rollbackOnFailure,omitempty" yaml:"rollbackOnFailure,omitempty"`
+}
+
+// SmoketestCheckParams defines a request sent to the service and each host and the status it has to respond with
+type SmoketestCheckParams struct {
+	Path           string            `json:"path,omitempty" yaml:"path,omitempty"`
+	ExpectedStatus int               `json:"expectedStatus,omitempty" yaml:"expectedStatus,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	CanaryHeader   bool              `json:"canaryHeader,omitempty" yaml:"canaryHeader,omitempty"`
+}
+
+// BlueGreenParams sets params for the BlueGreen strategy type, which deploys to the idle of two colours and only sends traffic to it with the switch-bluegreen action
+type BlueGreenParams struct {
+	ScaleDownDelay string `json:"scaleDownDelay,omitempty" yaml:"scaleDownDelay,omitempty"`
+}
+
+// LockParams sets how a release behaves when another release of the same application holds the release lock
+type LockParams struct {
+	OnConflict string `json:"onConflict,omitempty" yaml:"onConflict,omitempty"`
+	Timeout    string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// DiffParams sets where the diff actions write their summary and how they exit when the cluster has drifted from the rendered manifests
+type DiffParams struct {
+	OutputPath      string `json:"outputPath,omitempty" yaml:"outputPath,omitempty"`
+	ExitCodeOnDrift int    `json:"exitCodeOnDrift,omitempty" yaml:"exitCodeOnDrift,omitempty"`
 }
 
 // RollingUpdateParams sets params for controlling rolling update speed
@@ -239,6 +342,7 @@ type RollingUpdateParams struct {
 	MaxSurge       string `json:"maxsurge,omitempty" yaml:"maxsurge,omitempty"`
 	MaxUnavailable string `json:"maxunavailable,omitempty" yaml:"maxunavailable,omitempty"`
 	Timeout        string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	OnTimeout      string `json:"onTimeout,omitempty" yaml:"onTimeout,omitempty"`
 }
 
 // ManifestsParams can be used to override or add additional manifests located in the application repository
@@ -301,6 +405,11 @@ func (p *Params) SetDefaults(gitSource, gitOwner, gitName, appLabel, buildVersio
 		p.OperatingSystem = OperatingSystemLinux
 	}
 
+	// default architecture to the one of most node pools
+	if p.Architecture == ArchitectureUnknown {
+		p.Architecture = ArchitectureAMD64
+	}
+
 	// default app to estafette app label if no override in stage params
 	if p.App == "" && appLabel == "" && gitName != "" {
 		p.App = gitName
@@ -313,6 +422,19 @@ func (p *Params) SetDefaults(gitSource, gitOwner, gitName, appLabel, buildVersio
 		p.ProgressDeadlineSeconds = 600
 	}
 
+	// the rendered manifests get validated against the schemas of this version
+	if p.KubernetesVersion == "" {
+		p.KubernetesVersion = "1.29"
+	}
+
+	// defaults for the release lock
+	if p.Lock.OnConflict == "" {
+		p.Lock.OnConflict = "wait"
+	}
+	if p.Lock.Timeout == "" {
+		p.Lock.Timeout = "30m"
+	}
+
 	// default DisableServiceAccountKeyRotation to true for avoiding unintended side-effects of key rotation
 	if p.DisableServiceAccountKeyRotation == nil {
 		p.DisableServiceAccountKeyRotation = &trueValue
@@ -342,6 +464,55 @@ func (p *Params) SetDefaults(gitSource, gitOwner, gitName, appLabel, buildVersio
 	if p.Canary.HeaderValue == "" {
 		p.Canary.HeaderValue = "canary"
 	}
+
+	// set defaults for progressive canary deployments
+	if len(p.Canary.Steps) == 0 {
+		p.Canary.Steps = []int{5, 25, 50, 100}
+	}
+	if p.Canary.StepInterval == "" {
+		p.Canary.StepInterval = "5m"
+	}
+	if p.Canary.Analysis.PrometheusURL == "" {
+		p.Canary.Analysis.PrometheusURL = "http://prometheus-server.monitoring.svc"
+	}
+	if len(p.Canary.Analysis.Checks) == 0 {
+		maxErrorRate := 0.05
+		p.Canary.Analysis.Checks = []CanaryCheckParams{
+			{
+				Name:  "error-rate",
+				Query: fmt.Sprintf("sum(rate(nginx_http_requests_total{app='%v',track='canary',status=~'5..'}[5m])) / sum(rate(nginx_http_requests_total{app='%v',track='canary'}[5m]))", p.App, p.App),
+				Max:   &maxErrorRate,
+			},
+		}
+	}
+	for i := range p.Hooks.PreDeploy {
+		// a failing migration shouldn't be retried by default, it might have been partially applied
+		if p.Hooks.PreDeploy[i].BackoffLimit == nil {
+			defaultBackoffLimit := 0
+			p.Hooks.PreDeploy[i].BackoffLimit = &defaultBackoffLimit
+		}
+		if p.Hooks.PreDeploy[i].Timeout == "" {
+			p.Hooks.PreDeploy[i].Timeout = "10m"
+		}
+	}
+
+	if len(p.Smoketests.Checks) > 0 {
+		if p.Smoketests.Image == "" {
+			p.Smoketests.Image = "curlimages/curl:8.6.0"
+		}
+		if p.Smoketests.Timeout == "" {
+			p.Smoketests.Timeout = "2m"
+		}
+		for i := range p.Smoketests.Checks {
+			if p.Smoketests.Checks[i].Path == "" {
+				p.Smoketests.Checks[i].Path = "/"
+			}
+			if p.Smoketests.Checks[i].ExpectedStatus == 0 {
+				p.Smoketests.Checks[i].ExpectedStatus = 200
+			}
+		}
+	}
+
 	// default image name to estafette app label if no override in stage params
 	if p.Container.ImageName == "" && p.App != "" {
 		p.Container.ImageName = p.App
@@ -700,6 +871,10 @@ func (p *Params) SetDefaults(gitSource, gitOwner, gitName, appLabel, buildVersio
 		}
 	}
 
+	if p.StrategyType == StrategyTypeBlueGreen && p.BlueGreen.ScaleDownDelay == "" {
+		p.BlueGreen.ScaleDownDelay = "15m"
+	}
+
 	if p.RollingUpdate.MaxSurge == "" {
 		p.RollingUpdate.MaxSurge = "25%"
 	}
@@ -709,6 +884,9 @@ func (p *Params) SetDefaults(gitSource, gitOwner, gitName, appLabel, buildVersio
 	if p.RollingUpdate.Timeout == "" {
 		p.RollingUpdate.Timeout = "5m"
 	}
+	if p.RollingUpdate.OnTimeout == "" {
+		p.RollingUpdate.OnTimeout = "fail"
+	}
 
 	if p.Replicas == 0 && p.StrategyType == StrategyTypeRecreate {
 		p.Replicas = 1
@@ -726,23 +904,7 @@ func (p *Params) SetDefaults(gitSource, gitOwner, gitName, appLabel, buildVersio
 
 	if len(p.TrustedIPRanges) == 0 {
 		// Fallback to the previous behavior of utilizing the Cloudflare IPs if the GetIPList function encounters an error.
-		p.TrustedIPRanges = []string{
-			"173.245.48.0/20",
-			"103.21.244.0/22",
-			"103.22.200.0/22",
-			"103.31.4.0/22",
-			"141.101.64.0/18",
-			"108.162.192.0/18",
-			"190.93.240.0/20",
-			"188.114.96.0/20",
-			"197.234.240.0/22",
-			"198.41.128.0/17",
-			"162.158.0.0/15",
-			"104.16.0.0/13",
-			"104.24.0.0/14",
-			"172.64.0.0/13",
-			"131.0.72.0/22",
-		}
+		p.TrustedIPRanges = append([]string{}, defaultTrustedIPRanges...)
 
 		ipList, err := getCloudFlareIps("https://api.cloudflare.com/client/v4/ips")
 		if err != nil {
@@ -772,6 +934,9 @@ func (p *Params) SetDefaults(gitSource, gitOwner, gitName, appLabel, buildVersio
 		defaultBackoffLimit := 6
 		p.BackoffLimit = &defaultBackoffLimit
 	}
+	if (p.Kind == KindJob || p.Kind == KindCronJob) && p.JobTimeout == "" {
+		p.JobTimeout = "30m"
+	}
 
 	if p.Kind == KindStatefulset {
 		if p.PodManagementPolicy == "" {
@@ -889,7 +1054,51 @@ func (p *Params) ValidateRequiredProperties() (bool, []error, []string) {
 		errors = append(errors, fmt.Errorf("Namespace is required; either use credentials with a defaultNamespace or set it via namespace property on this stage"))
 	}
 
-	if p.Action == ActionRollbackCanary || p.Kind == KindConfig || p.Kind == KindConfigToFile {
+	if (p.Action == ActionRollbackStable || p.Action == ActionRollbackSimple) && p.Kind != KindDeployment && p.Kind != KindHeadlessDeployment {
+		errors = append(errors, fmt.Errorf("Action %v can only be used with kind deployment or headless-deployment", p.Action))
+	}
+
+	if p.Architecture != ArchitectureUnknown && p.Architecture != ArchitectureAMD64 && p.Architecture != ArchitectureARM64 {
+		errors = append(errors, fmt.Errorf("Architecture %v is invalid; allowed values for architecture are amd64 or arm64", p.Architecture))
+	}
+	if p.OperatingSystem == OperatingSystemWindows && p.Architecture == ArchitectureARM64 {
+		errors = append(errors, fmt.Errorf("Architecture arm64 isn't available for os windows; windows node pools only run amd64"))
+	}
+
+	if p.Lock.OnConflict != "" && p.Lock.OnConflict != "wait" && p.Lock.OnConflict != "fail" {
+		errors = append(errors, fmt.Errorf("Lock on conflict %v is invalid; allowed values for lock.onConflict are wait or fail", p.Lock.OnConflict))
+	}
+	if p.Lock.Timeout != "" {
+		if timeout, err := time.ParseDuration(p.Lock.Timeout); err != nil || timeout <= 0 {
+			errors = append(errors, fmt.Errorf("Lock timeout %v is invalid; set lock.timeout to a duration like 30m", p.Lock.Timeout))
+		}
+	}
+	if p.Diff.ExitCodeOnDrift < 0 || p.Diff.ExitCodeOnDrift == 1 || p.Diff.ExitCodeOnDrift > 255 {
+		errors = append(errors, fmt.Errorf("Diff exit code on drift %v is invalid; set diff.exitCodeOnDrift to a value between 2 and 255, exit code 1 is used for failures", p.Diff.ExitCodeOnDrift))
+	}
+
+	if p.Action == ActionSwitchBlueGreen && (p.Kind != KindDeployment || p.StrategyType != StrategyTypeBlueGreen) {
+		errors = append(errors, fmt.Errorf("Action %v can only be used with kind deployment and strategytype BlueGreen", p.Action))
+	}
+	if p.StrategyType == StrategyTypeBlueGreen {
+		if scaleDownDelay, err := time.ParseDuration(p.BlueGreen.ScaleDownDelay); err != nil || scaleDownDelay < 0 {
+			errors = append(errors, fmt.Errorf("BlueGreen scale down delay %v is invalid; set bluegreen.scaleDownDelay to a duration like 15m", p.BlueGreen.ScaleDownDelay))
+		}
+		if p.Action == ActionRollbackSimple {
+			errors = append(errors, fmt.Errorf("StrategyType: BlueGreen doesn't support action %v; use action switch-bluegreen to send traffic back to the previous colour", p.Action))
+		}
+	}
+
+	if (p.Action == ActionSuspendCronJob || p.Action == ActionResumeCronJob || p.Action == ActionTriggerCronJob) && p.Kind != KindCronJob {
+		errors = append(errors, fmt.Errorf("Action %v can only be used with kind cronjob", p.Action))
+	}
+	if p.Action == ActionTriggerCronJob {
+		if timeout, err := time.ParseDuration(p.JobTimeout); err != nil || timeout <= 0 {
+			errors = append(errors, fmt.Errorf("Job timeout %v is invalid; set jobTimeout to a duration like 30m", p.JobTimeout))
+		}
+	}
+
+	if p.Action == ActionRollbackCanary || p.Action == ActionRollbackStable || p.Action == ActionRollbackSimple || p.Action == ActionSwitchBlueGreen || p.Action == ActionHistory || p.Action == ActionSuspendCronJob || p.Action == ActionResumeCronJob || p.Action == ActionTriggerCronJob || p.Kind == KindConfig || p.Kind == KindConfigToFile {
 		// the above properties are all you need for a rollback
 		return len(errors) == 0, errors, warnings
 	}
@@ -921,13 +1130,29 @@ func (p *Params) ValidateRequiredProperties() (bool, []error, []string) {
 		errors = append(errors, fmt.Errorf("Memory limit is required; set it via container.memory.limit property on this stage"))
 	}
 
+	// validate cpu and memory quantities of all containers, since kubernetes only rejects them when applying
+	resourceErrors, resourceWarnings := p.validateResources()
+	errors = append(errors, resourceErrors...)
+	warnings = append(warnings, resourceWarnings...)
+	if len(resourceErrors) == 0 {
+		log.Info().Msgf("Pod resources: %v", p.PodResources())
+	}
+
 	// validate params for rollingupdate
 	if p.StrategyType == StrategyTypeUnknown {
-		errors = append(errors, fmt.Errorf("StrategyType is required; set it via strategytype property on this stage; valid values are RollingUpdate, Recreate or AtomicUpdate"))
+		errors = append(errors, fmt.Errorf("StrategyType is required; set it via strategytype property on this stage; valid values are RollingUpdate, Recreate, AtomicUpdate or BlueGreen"))
 	}
 	if p.StrategyType == StrategyTypeAtomicUpdate && p.Action != ActionDeploySimple {
 		errors = append(errors, fmt.Errorf("StrategyType: AtomicUpdate can't be used in combination with other actions than deploy-simple as this would allow multiple versions to be served. Please use action: deploy-simple"))
 	}
+	if p.StrategyType == StrategyTypeBlueGreen {
+		if p.Kind != KindDeployment {
+			errors = append(errors, fmt.Errorf("StrategyType: BlueGreen can only be used with kind deployment"))
+		}
+		if p.Action != ActionDeploySimple && p.Action != ActionDiffSimple {
+			errors = append(errors, fmt.Errorf("StrategyType: BlueGreen can't be used in combination with other actions than deploy-simple, diff-simple or switch-bluegreen, the colours replace the canary and stable tracks. Please use action: deploy-simple"))
+		}
+	}
 	if p.RollingUpdate.MaxSurge == "" {
 		errors = append(errors, fmt.Errorf("Rollingupdate max surge is required; set it via rollingupdate.maxsurge property on this stage"))
 	}
@@ -937,17 +1162,86 @@ func (p *Params) ValidateRequiredProperties() (bool, []error, []string) {
 	if p.RollingUpdate.MaxUnavailable == "" {
 		errors = append(errors, fmt.Errorf("Rollingupdate max unavailable is required; set it via rollingupdate.maxunavailable property on this stage"))
 	}
+	if timeout, err := time.ParseDuration(p.RollingUpdate.Timeout); err != nil || timeout <= 0 {
+		errors = append(errors, fmt.Errorf("Rollingupdate timeout %v is invalid; set rollingupdate.timeout to a duration like 5m", p.RollingUpdate.Timeout))
+	}
+	if p.RollingUpdate.OnTimeout != "fail" && p.RollingUpdate.OnTimeout != "undo" && p.RollingUpdate.OnTimeout != "pause" {
+		errors = append(errors, fmt.Errorf("Rollingupdate on timeout %v is invalid; allowed values for rollingupdate.onTimeout are fail, undo or pause", p.RollingUpdate.OnTimeout))
+	} else if p.RollingUpdate.OnTimeout == "pause" && p.Kind != KindDeployment && p.Kind != KindHeadlessDeployment {
+		errors = append(errors, fmt.Errorf("Rollingupdate on timeout pause can only be used with kind deployment or headless-deployment; statefulsets can't be paused, use undo instead"))
+	} else if p.RollingUpdate.OnTimeout == "undo" && p.Kind != KindDeployment && p.Kind != KindHeadlessDeployment && p.Kind != KindStatefulset {
+		errors = append(errors, fmt.Errorf("Rollingupdate on timeout undo can only be used with kind deployment, headless-deployment or statefulset"))
+	}
+	if p.AutoRollback {
+		if p.Kind != KindDeployment && p.Kind != KindHeadlessDeployment && p.Kind != KindStatefulset {
+			errors = append(errors, fmt.Errorf("Auto rollback can only be used with kind deployment, headless-deployment or statefulset"))
+		}
+		if p.RollingUpdate.OnTimeout != "fail" {
+			errors = append(errors, fmt.Errorf("Auto rollback already rolls back rollouts that time out; remove rollingupdate.onTimeout or set it to fail"))
+		}
+	}
+
+	// validate pre-deploy hooks
+	if len(p.Hooks.PreDeploy) > 0 && p.Kind != KindDeployment && p.Kind != KindHeadlessDeployment && p.Kind != KindStatefulset {
+		errors = append(errors, fmt.Errorf("Pre-deploy hooks can only be used with kind deployment, headless-deployment or statefulset"))
+	}
+	hookNames := map[string]bool{}
+	for _, hook := range p.Hooks.PreDeploy {
+		if matches, _ := regexp.MatchString("^[a-z0-9]([-a-z0-9]*[a-z0-9])?$", hook.Name); !matches {
+			errors = append(errors, fmt.Errorf("Pre-deploy hook name %v is invalid; set hooks.preDeploy[].name to lowercase characters, digits and dashes", hook.Name))
+		} else if len(p.App)+len("-canary-")+len(hook.Name) > 63 {
+			errors = append(errors, fmt.Errorf("Pre-deploy hook name %v is too long; the job name, consisting of the app name with track and hook name, can't exceed 63 characters", hook.Name))
+		}
+		if hookNames[hook.Name] {
+			errors = append(errors, fmt.Errorf("Pre-deploy hook name %v is used more than once; set hooks.preDeploy[].name to unique names", hook.Name))
+		}
+		hookNames[hook.Name] = true
+		if len(hook.Command) == 0 && len(hook.Args) == 0 {
+			errors = append(errors, fmt.Errorf("Pre-deploy hook %v has no command or args; set at least one of them to run something else than the application", hook.Name))
+		}
+		if timeout, err := time.ParseDuration(hook.Timeout); err != nil || timeout <= 0 {
+			errors = append(errors, fmt.Errorf("Pre-deploy hook %v timeout %v is invalid; set hooks.preDeploy[].timeout to a duration like 10m", hook.Name, hook.Timeout))
+		}
+	}
+
+	// validate smoke tests
+	if len(p.Smoketests.Checks) > 0 {
+		if p.Kind != KindDeployment && p.Kind != KindHeadlessDeployment && p.Kind != KindStatefulset {
+			errors = append(errors, fmt.Errorf("Smoke tests can only be used with kind deployment, headless-deployment or statefulset"))
+		}
+		if p.Smoketests.RollbackOnFailure && p.Kind == KindStatefulset {
+			errors = append(errors, fmt.Errorf("Smoke tests can't roll back a statefulset; remove smoketests.rollbackOnFailure"))
+		}
+		if timeout, err := time.ParseDuration(p.Smoketests.Timeout); err != nil || timeout <= 0 {
+			errors = append(errors, fmt.Errorf("Smoke tests timeout %v is invalid; set smoketests.timeout to a duration like 2m", p.Smoketests.Timeout))
+		}
+		for _, check := range p.Smoketests.Checks {
+			if !strings.HasPrefix(check.Path, "/") {
+				errors = append(errors, fmt.Errorf("Smoke test path %v is invalid; set smoketests.checks[].path to a path starting with /", check.Path))
+			}
+			if check.ExpectedStatus < 100 || check.ExpectedStatus > 599 {
+				errors = append(errors, fmt.Errorf("Smoke test expected status %v for path %v is invalid; set smoketests.checks[].expectedStatus to an http status code", check.ExpectedStatus, check.Path))
+			}
+		}
+	}
 
 	if p.Kind == KindJob || p.Kind == KindCronJob {
 		if p.Kind == KindCronJob {
 			if p.Schedule == "" {
 				errors = append(errors, fmt.Errorf("Schedule is required for a cronjob; set it via schedule property on this stage"))
+			} else {
+				errors = append(errors, p.validateSchedule()...)
 			}
 
 			if p.ConcurrencyPolicy != "Allow" && p.ConcurrencyPolicy != "Forbid" && p.ConcurrencyPolicy != "Replace" {
 				errors = append(errors, fmt.Errorf("ConcurrencyPolicy is invalid; allowed values for concurrencypolicy property are Allow, Forbid or Replace"))
 			}
 		}
+		if p.Kind == KindJob {
+			if timeout, err := time.ParseDuration(p.JobTimeout); err != nil || timeout <= 0 {
+				errors = append(errors, fmt.Errorf("Job timeout %v is invalid; set jobTimeout to a duration like 30m", p.JobTimeout))
+			}
+		}
 
 		// the above properties are all you need for a worker
 		return len(errors) == 0, errors, warnings
@@ -1047,6 +1341,40 @@ func (p *Params) ValidateRequiredProperties() (bool, []error, []string) {
 		}
 	}
 
+	// validate params for progressive canary releases
+	if p.Action == ActionDeployProgressive {
+		if p.Kind != KindDeployment {
+			errors = append(errors, fmt.Errorf("Action deploy-progressive can only be used with kind deployment"))
+		}
+		if p.Visibility != VisibilityPrivate && p.Visibility != VisibilityPublicWhitelist && p.Visibility != VisibilityApigee && !((p.Visibility == VisibilityESP || p.Visibility == VisibilityESPv2) && p.EspServiceTypeClusterIP) {
+			errors = append(errors, fmt.Errorf("Action deploy-progressive shifts traffic with nginx ingress canary weights; use visibility private, public-whitelist, apigee or esp with espServiceTypeClusterIP: true"))
+		}
+		for i, weight := range p.Canary.Steps {
+			if weight <= 0 || weight > 100 {
+				errors = append(errors, fmt.Errorf("Canary step weight %v is invalid; set canary.steps to increasing weights between 1 and 100", weight))
+			} else if i > 0 && weight <= p.Canary.Steps[i-1] {
+				errors = append(errors, fmt.Errorf("Canary steps %v are not increasing; set canary.steps to increasing weights between 1 and 100", p.Canary.Steps))
+			}
+		}
+		if len(p.Canary.Steps) > 0 && p.Canary.Steps[len(p.Canary.Steps)-1] != 100 {
+			errors = append(errors, fmt.Errorf("The last canary step needs to have weight 100 before the canary is promoted; set canary.steps to end with 100"))
+		}
+		if stepInterval, err := time.ParseDuration(p.Canary.StepInterval); err != nil || stepInterval < 0 {
+			errors = append(errors, fmt.Errorf("Canary step interval %v is invalid; set canary.stepinterval to a duration like 5m", p.Canary.StepInterval))
+		}
+		if p.Canary.Analysis.PrometheusURL == "" {
+			errors = append(errors, fmt.Errorf("Canary analysis prometheus url is required; set it via canary.analysis.prometheus property on this stage"))
+		}
+		for _, check := range p.Canary.Analysis.Checks {
+			if check.Query == "" {
+				errors = append(errors, fmt.Errorf("Canary analysis check %v has no query; set it via canary.analysis.checks[].query property on this stage", check.Name))
+			}
+			if check.Min == nil && check.Max == nil {
+				errors = append(errors, fmt.Errorf("Canary analysis check %v has no min or max; set at least one of them on this stage", check.Name))
+			}
+		}
+	}
+
 	if p.Basepath == "" {
 		errors = append(errors, fmt.Errorf("Basepath property is required; set it via basepath property on this stage"))
 	}
@@ -1182,61 +1510,3 @@ func (p *Params) validateSidecar(sidecar *SidecarParams, errors []error) []error
 
 	return errors
 }
-
-// ReplaceSidecarTagsWithDigest replaces image tags for sidecars with a digest
-func (p *Params) ReplaceSidecarTagsWithDigest() {
-
-	// see if there's a sidecar of type openresty
-	for _, s := range p.Sidecars {
-		log.Info().Msgf("Replacing sidecar %v image tag with digest...", s.Type)
-
-		if s.Image == "" {
-			continue
-		}
-
-		imageDigestParts := strings.Split(s.Image, "@")
-		if len(imageDigestParts) > 1 {
-			// already uses a digest, skip replacement
-			continue
-		}
-
-		imageParts := strings.Split(s.Image, ":")
-		repository := imageParts[0]
-		tag := "latest"
-		if len(imageParts) > 1 {
-			tag = imageParts[1]
-		}
-
-		// get docker hub api token
-		tokenJSON := httpRequestBody("GET", fmt.Sprintf("https://auth.docker.io/token?scope=repository:%v:pull&service=registry.docker.io", repository), map[string]string{})
-		if tokenJSON == "" {
-			continue
-		}
-
-		type TokenObject struct {
-			Token string `json:"token"`
-		}
-
-		tokenObject := TokenObject{}
-		err := json.Unmarshal([]byte(tokenJSON), &tokenObject)
-		if err != nil {
-			continue
-		}
-		if tokenObject.Token == "" {
-			continue
-		}
-
-		digest := httpRequestHeader("HEAD", fmt.Sprintf("https://index.docker.io/v2/%v/manifests/%v", repository, tag), map[string]string{
-			"Accept":        "application/vnd.docker.distribution.manifest.v2+json",
-			"Authorization": fmt.Sprintf("Bearer %v", tokenObject.Token),
-		}, "Docker-Content-Digest")
-
-		if len(digest) == 0 {
-			continue
-		}
-
-		s.Image = fmt.Sprintf("%v@%v", repository, digest)
-
-		log.Info().Msgf("Successfully replaced tag %v with digest %v...", tag, digest)
-	}
-}
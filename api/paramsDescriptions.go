@@ -0,0 +1,227 @@
+package api
+
+// paramDescriptions describes every parameter by its path in the stage, with [] for the items of a list
+var paramDescriptions = map[string]string{
+	"action":                                       "Controls what action is taken; can take values from Estafette release actions",
+	"affinity":                                     "Map of pod and node (anti)affinity config to configure Kubernetes affinity",
+	"allowhttp":                                    "If the application needs to be available on http, besides the default https",
+	"apigeesuffix":                                 "Suffix for the hostnames when using `visibility: apigee`",
+	"app":                                          "The name used to deploy the application",
+	"architecture":                                 "The cpu architecture of the nodes to deploy to; arm64 schedules the pods on arm node pools",
+	"autoRollback":                                 "Restores the previous version after a failed rollout of a deployment, headless-deployment or statefulset",
+	"autoscale":                                    "Configures the Horizontal Pod Autoscaler",
+	"autoscale.behavior":                           "https://kubernetes.io/docs/tasks/run-application/horizontal-pod-autoscale/#configurable-scaling-behavior",
+	"autoscale.cpu":                                "Target CPU percentage set in the HPA",
+	"autoscale.enabled":                            "Enables Horizontal Pod Autoscaler",
+	"autoscale.max":                                "The maximum replicas set in the HPA",
+	"autoscale.min":                                "The minimum replicas set in the HPA",
+	"autoscale.safety":                             "Configures estafette-k8s-hpa-scaler to keep the minimum replicas in line with the request rate",
+	"autoscale.safety.delta":                       "A constant to increase or lower the function `minReplicas = Ceiling ( delta + ( promquery / ratio ) )`",
+	"autoscale.safety.enabled":                     "Enabled use of [estafette-k8s-hpa-scaler](https://github.com/estafette/estafette-k8s-hpa-scaler) as a safety net",
+	"autoscale.safety.promquery":                   "The Prometheus query to get the request rate to this or a downstream application in the call stack",
+	"autoscale.safety.ratio":                       "A divider to get from request rate to number of pods; equals the desired requests per pod",
+	"autoscale.safety.scaledownratio":              "Sets the fraction the min replicas is allowed to scale down compared to the last value in order to ease scaling",
+	"backoffLimit":                                 "After how many failures to stop retrying",
+	"basepath":                                     "Base path in the ingresses to route to this application",
+	"bluegreen":                                    "Configures the BlueGreen strategy type",
+	"bluegreen.scaleDownDelay":                     "Time `switch-bluegreen` keeps the previous colour running before scaling it down, see [Blue/green releases](#bluegreen-releases)",
+	"canary":                                       "Configures canary releases",
+	"canary.analysis":                              "Configures the Prometheus checks run at every step of `deploy-progressive`",
+	"canary.analysis.allownodata":                  "Pass a check if its query returns no data, for example when the canary doesn't receive any traffic yet",
+	"canary.analysis.checks":                       "The checks that all have to pass for the canary to be healthy",
+	"canary.analysis.checks[].max":                 "The maximum value the query result is allowed to have",
+	"canary.analysis.checks[].min":                 "The minimum value the query result is allowed to have",
+	"canary.analysis.checks[].name":                "The name of the check, used for logging",
+	"canary.analysis.checks[].query":               "The PromQL query to run at every step; it should aggregate to a single value",
+	"canary.analysis.prometheus":                   "The url of the Prometheus server queried by `deploy-progressive`",
+	"canary.header":                                "The header name to be used for routing traffic to canary pods",
+	"canary.headervalue":                           "The header value to be used for routing traffic to canary pods",
+	"canary.maxreplicas":                           "Maximum number of canary pods of the canary deployment",
+	"canary.minreplicas":                           "Minimum number of canary pods of the canary deployment",
+	"canary.stepinterval":                          "How long `deploy-progressive` waits at every step before analyzing the canary",
+	"canary.steps":                                 "The canary weights `deploy-progressive` steps through; the last step has to be 100",
+	"canary.weight":                                "The weight of the canary deployment",
+	"certificatesecret":                            "If set use a pre-existing secret with TLS certificate instead of automatically creating one from the `host` and `internalhosts` using a secret with [estafette-letsencrypt-certificate](https://github.com/estafette/estafette-letsencrypt-certificate) annotations",
+	"chaosproof":                                   "Determines whether it's okay to run the application on preemptibles",
+	"completions":                                  "The amount of times the job needs to complete",
+	"concurrencypolicy":                            "Indicates whether concurrent jobs are allowed or forbidden",
+	"configs":                                      "Configures the configmap mounted into the application container",
+	"configs.data":                                 "Key/value map to replace any gotemplate placeholders in the config files set with `configs.files`",
+	"configs.files":                                "Files in the repository to include in a configmap, mounted into the application container",
+	"configs.inline":                               "Key/value map to set config files for the configmap without using templates on disk",
+	"configs.mountpath":                            "Path to where the configmap is mounted",
+	"container":                                    "Configures the application container",
+	"container.additionalports":                    "Additional ports exposed by the application container and its service",
+	"container.additionalports[].name":             "To configure any other ports than the usual http/https ports the application can communicate through",
+	"container.additionalports[].port":             "The port number for an additional port",
+	"container.additionalports[].protocol":         "Can be any of the [Kubernetes supported protocols](https://kubernetes.io/docs/concepts/services-networking/service/#protocol-support)",
+	"container.additionalports[].visibility":       "Can be set differently from the main `visibility` if it needs to be different (more restrictive for example)",
+	"container.containerLifecycle":                 "To set custom lifecycle as yaml if this set it will disable the container.lifecycle.prestopsleep, so make sure to set the sleep with this custom hook",
+	"container.cpu":                                "Sets the cpu request and limit of the application container",
+	"container.cpu.limit":                          "The cpu limit; no need to set, it can lead to cpu throttling, but in case your application turns out to be a noisy neighbour can be set",
+	"container.cpu.request":                        "The cpu request value; this ensures the application has at least the cpu required to operate under normal circumstances and is used for calculating the autoscaling cpu load",
+	"container.env":                                "A map of environment variable keys and values, passed on to the container",
+	"container.imagePullPolicy":                    "The image pull policy for the main container image",
+	"container.lifecycle":                          "Configures the prestop sleep of the application container",
+	"container.lifecycle.prestopsleep":             "To reduce the risk of failing requests for terminating pods a prestop sleep is used; disable if the container has no sleep command because there's no os (scratch image)",
+	"container.lifecycle.prestopsleepseconds":      "Number of seconds to sleep; 15 to 20 should be enough in the majority of cases",
+	"container.liveness":                           "Configures the liveness probe of the application container",
+	"container.liveness.delay":                     "Sets the number of seconds to wait before running the liveness probe first; increase if it takes longer than 30 seconds for the application to be up and running",
+	"container.liveness.enabled":                   "Toggles the liveness probe on the application container, which determines whether the application is still healthy",
+	"container.liveness.failureThreshold":          "Number of failures before liveness probe is considered to have failed and the container is killed",
+	"container.liveness.path":                      "Sets the path for the liveness probe",
+	"container.liveness.period":                    "Interval between liveness probes",
+	"container.liveness.port":                      "Sets the port for the liveness probe",
+	"container.liveness.successThreshold":          "Number of consecutive successes for liveness probe to be considered successful",
+	"container.liveness.timeout":                   "Time to wait for a response from the liveness path",
+	"container.memory":                             "Sets the memory request and limit of the application container",
+	"container.memory.limit":                       "The memory limit; when a container hits this limit it's killed with an _out of memory kill_; set equal to request for guaranteed Quality of Service",
+	"container.memory.request":                     "The requested memory; setting it lower than the limit can lead to _out of memory kill_ before hitting the limit if the node it runs on is short on memory",
+	"container.metrics":                            "Configures the scraping of Prometheus metrics of the application container",
+	"container.metrics.path":                       "The path to the Prometheus metrics endpoint",
+	"container.metrics.port":                       "The port at which the Prometheus metrics are exposed",
+	"container.metrics.scrape":                     "Toggles whether Prometheus metrics are exposed and need to be scraped",
+	"container.name":                               "The name of the container image, usually `${ESTAFETTE_LABEL_APP}` or `${ESTAFETTE_GIT_NAME}`",
+	"container.port":                               "The port the main container listens on; preferably port 5000 so you don't have to explicitly set it",
+	"container.portGrpc":                           "The gRPC port the main container listens on; gRPC is only exposed if set",
+	"container.readiness":                          "Configures the readiness probe of the application container",
+	"container.readiness.delay":                    "Sets the number of seconds to wait before running the readiness probe first",
+	"container.readiness.enabled":                  "Toggles the readiness probe on the application container, which determines whether the application is ready to receive requests",
+	"container.readiness.failureThreshold":         "Number of failures before readiness probe is considered to have failed and removed as an endpoint from the service, so it no longer receives requests",
+	"container.readiness.path":                     "Sets the path for the readiness probe",
+	"container.readiness.period":                   "Interval between readiness probes",
+	"container.readiness.port":                     "Sets the port for the readiness probe",
+	"container.readiness.successThreshold":         "Number of consecutive successes for readiness probe to be considered successful",
+	"container.readiness.timeout":                  "Time to wait for a response from the readiness path",
+	"container.repository":                         "Path for the image repository minus the last part, for example `extensions` for `extensions/gke` image, or `gcr.io/<project id>`",
+	"container.secretEnv":                          "Same as `env` but the values are stored in a secret instead and referenced with `secretKeyRef` automatically; no need to base64 encode",
+	"container.securityContext":                    "Yaml snippet for the security context of the application container",
+	"container.tag":                                "The container image tag, usually the build version",
+	"containerNativeLoadBalancing":                 "To use Google Cloud container-native load balancing",
+	"customsidecars":                               "Yaml snippets to pass in additional sidecars",
+	"defaultCloudSQLProxySidecarImage":             "Allows the default Cloud SQL proxy sidecar image to be overridden via defaults in `kubernetes-engine` credentials",
+	"defaultESPSidecarImage":                       "Allows the default ESP sidecar image to be overridden via defaults in `kubernetes-engine` credentials",
+	"defaultESPv2SidecarImage":                     "Allows the default ESP v2 sidecar image to be overridden via defaults in `kubernetes-engine` credentials",
+	"defaultOpenrestySidecarImage":                 "Allows the default OpenResty sidecar image to be overridden via defaults in `kubernetes-engine` credentials",
+	"diff":                                         "Configures the summary of the `diff-*` actions",
+	"diff.exitCodeOnDrift":                         "Exit code of a `diff-*` action when the live state differs from the manifests",
+	"diff.outputPath":                              "Path to write the json diff summary of a `diff-*` action to",
+	"disableHTTPPort":                              "Removes the http port from the service in front of the openresty sidecar, leaving https only",
+	"disableServiceAccountKeyRotation":             "Keeps the retrieved service account keyfile without rotating it regularly",
+	"dns":                                          "Configures the annotations for dns management",
+	"dns.useCloudflareEstafetteExtension":          "Add annotations used by [estafette-cloudflare-dns-extension](https://github.com/estafette/estafette-cloudflare-dns)",
+	"dns.useExternalDNS":                           "Add annotations used by [external-dns](https://github.com/kubernetes-sigs/external-dns)",
+	"dryrun":                                       "Controls whether the changes generated by this extension will be applied",
+	"enablePayloadLogging":                         "Mounts a host path into the container that's used for an internal Travix payload log shipper",
+	"espConfigID":                                  "When you want to pin the version of the openapi spec uploaded as a Google Cloud Endpoint config it can be set",
+	"espEndpointsProjectID":                        "When Google Cloud Endpoints are set up in a centralized project set it's ID with this parameter",
+	"espOpenapiYamlPath":                           "Path to `openapi.yaml` file to use for creating the endpoint config; use separate ones per environment",
+	"espServiceTypeClusterIP":                      "Creates ClusterIP service instead of LoadBalancer for ESP. Doesn't destroy already existing LB (if exists), but will point DNS record to new IP",
+	"googleCloudCredentialsApp":                    "When a shared service account needs to be used set the name of the app the service account is generated for",
+	"hooks":                                        "Configures jobs that run at specific moments of a release",
+	"hooks.preDeploy":                              "Jobs running the application container with a different command before the manifests are applied",
+	"hooks.preDeploy[].args":                       "Overrides the arguments of the application container",
+	"hooks.preDeploy[].backoffLimit":               "Number of retries before the hook is considered failed",
+	"hooks.preDeploy[].command":                    "Overrides the entrypoint of the application container",
+	"hooks.preDeploy[].name":                       "Name of the hook, appended to the job name; must be unique",
+	"hooks.preDeploy[].timeout":                    "Maximum time to wait for the hook to complete",
+	"hosts":                                        "The public hostnames associated with this application",
+	"hostsrouteonly":                               "Additional hostnames listened to by the app and its ingresses, but not set in DNS records",
+	"iapOauthClientID":                             "Needs a Google OAuth Client ID encoded in base64 when using `visibility: iap`; has to be created in advance",
+	"iapOauthClientSecret":                         "Needs a Google OAuth Client Secret encoded in base64 when using `visibility: iap`; has to be created in advance",
+	"imagePullSecretPassword":                      "Password for the private registry",
+	"imagePullSecretUser":                          "When the application image is stored in a private registry not accessible for the GKE cluster set a username",
+	"initcontainers":                               "Yaml snippets to configure Kubernetes init containers",
+	"injecthttpproxysidecar":                       "Indicates whether the openresty sidecar should be injected",
+	"internalhosts":                                "The internal hostnames associated with this application",
+	"internalhostsrouteonly":                       "Additional internal hostnames listened to by the app and its ingresses, but not set in DNS records",
+	"jobTimeout":                                   "Maximum time to wait for the job to complete",
+	"keepFinishedJob":                              "Keeps the job after it completed successfully",
+	"kind":                                         "Determines the type of Kubernetes resource to get created",
+	"kubernetesVersion":                            "Kubernetes version the rendered manifests get validated against, see [Validating manifests](#validating-manifests)",
+	"labels":                                       "To set labels that are use on all kubernetes resources",
+	"legacyGoogleCloudServiceAccountKeyFile":       "Base64 encoded keyfile stored in the application secret",
+	"lock":                                         "Configures the lock that prevents concurrent releases of the application",
+	"lock.onConflict":                              "Whether to wait for another release holding the lock to finish, or to fail the release right away",
+	"lock.timeout":                                 "Maximum time to wait for the lock before failing the release",
+	"manifests":                                    "Configures additional templates from the application repository",
+	"manifests.data":                               "To provide extra data to the additional templates beyond what's already set by the extension",
+	"manifests.files":                              "To set additional template files to apply",
+	"namespace":                                    "Sets the kubernetes namespace to deploy to",
+	"os":                                           "The operating system to deploy to",
+	"parallelism":                                  "How many jobs to run in parallel",
+	"podManagementpolicy":                          "Controls whether the statefulset creates and deletes its pods one by one or in parallel",
+	"probeService":                                 "Configures a prometheus probe on the service using blackbox-exporter to check for availability",
+	"progressDeadlineSeconds":                      "Sets the number of seconds for Kubernetes to wait for a deployment to lack progress before treating it as a failure",
+	"replicas":                                     "The number of pods to run",
+	"reportPath":                                   "Path to write a json report of the run to, see [Deployment report](#deployment-report)",
+	"request":                                      "Configures how requests are handled by the ingresses and openresty sidecar",
+	"request.authsecret":                           "Secret name in the form of `namespace/secret` used for client certificate authentication",
+	"request.clientbodybuffersize":                 "Buffer size for request body, set at the ingresses and openresty sidecar",
+	"request.configurationSnippet":                 "Configuration snippet for all nginx ingresses",
+	"request.ingressbackendprotocol":               "The backend protocol (HTTPS or GRPCS) to be used by the Ingress",
+	"request.keepaliveTimeout":                     "Maximum time a keepalive connection stays open, set at the openresty sidecar",
+	"request.loadbalance":                          "Loadbalancing algorithm used by the ingress controller",
+	"request.maxbodysize":                          "Maximum body size for a request, set at the ingresses and openresty sidecar",
+	"request.proxybuffersize":                      "Buffer size for proxying, set at the ingresses and openresty sidecar",
+	"request.proxybuffersnumber":                   "Number of buffers for proxying, set at the ingresses and openresty sidecar",
+	"request.timeout":                              "Maximum time for a response, set at the ingresses and openresty sidecar",
+	"request.verifydepth":                          "The validation depth between the provided client certificate and the Certification Authority chain",
+	"restartPolicy":                                "Controls whether a container should be restarted when it stops",
+	"rollingupdate":                                "Configures rolling updates",
+	"rollingupdate.maxsurge":                       "Maximum percentage of pods to surge during a rolling update",
+	"rollingupdate.maxunavailable":                 "Maximum number of unavailable pods during a rolling update",
+	"rollingupdate.onTimeout":                      "What to do with a rollout that doesn't finish within `rollingupdate.timeout` or exceeds `progressDeadlineSeconds`: `fail` leaves it as is, `undo` rolls a deployment back to its previous revision or removes a canary, `pause` pauses the deployment until the next release",
+	"rollingupdate.timeout":                        "Maximum time to wait for the rollout of a deployment or statefulset before considering it as failed",
+	"schedule":                                     "Sets the schedule at which the cronjob spawns a new job",
+	"suspend":                                      "Deploys the cronjob suspended, so it doesn't spawn any jobs until it's resumed",
+	"secrets":                                      "Configures the secret mounted into the application container",
+	"secrets.keys":                                 "Map of filenames and base64 encoded values stored in a secret, mounted into the application container",
+	"secrets.mountpath":                            "Path to where the secret is mounted",
+	"securityContext":                              "Yaml snippet for the security context of the pods",
+	"sidecar":                                      "_deprecated_, use `sidecars` parameter instead",
+	"sidecars":                                     "Sidecars added to the pods",
+	"sidecars[].cpu":                               "Sets the cpu request and limit of the sidecar",
+	"sidecars[].cpu.limit":                         "The cpu limit value",
+	"sidecars[].cpu.request":                       "The cpu request value",
+	"sidecars[].dbinstanceconnectionname":          "A Cloud SQL connection name to be used in the Cloud SQL proxy sidecar",
+	"sidecars[].env":                               "Environment variables passed into the sidecar",
+	"sidecars[].healthcheckpath":                   "Can be set for the health check through the openresty sidecar towards the main application",
+	"sidecars[].image":                             "The full container image path for the sidecar",
+	"sidecars[].memory":                            "Sets the memory request and limit of the sidecar",
+	"sidecars[].memory.limit":                      "The memory limit value",
+	"sidecars[].memory.request":                    "The memory request value",
+	"sidecars[].secretEnv":                         "Secret values passed into the sidecar as environment variables by storing them in a secret and using secretKeyRef",
+	"sidecars[].sqlproxyport":                      "The port the cloud sql proxy listens on",
+	"sidecars[].sqlproxyterminationtimeoutseconds": "The cloud sql proxy termination timeout",
+	"sidecars[].type":                              "Can be used to configure a couple of sidecars known by this extension",
+	"smoketests":                                   "Configures http checks run inside the cluster once the rollout has finished",
+	"smoketests.checks":                            "The requests sent to the service and each host",
+	"smoketests.checks[].canaryHeader":             "Sends `canary.header` with `canary.headervalue` on canary releases, so the hosts route the request to the canary",
+	"smoketests.checks[].expectedStatus":           "Status code the response has to have",
+	"smoketests.checks[].headers":                  "Headers to send with the request",
+	"smoketests.checks[].path":                     "Path to request",
+	"smoketests.image":                             "Image with curl used to run the checks",
+	"smoketests.rollbackOnFailure":                 "Rolls back the release if a check fails; not supported for statefulsets",
+	"smoketests.timeout":                           "Maximum time for all checks to finish",
+	"strict":                                       "Fails the release on parameters that aren't known, instead of only warning about them",
+	"storageclass":                                 "Determines the type of persistent disk created",
+	"storagemountpath":                             "The path where the persistent disk is mounted",
+	"storagesize":                                  "The size of the persistent disk",
+	"strategytype":                                 "Configures the upgrade strategy for `kind: deployment`; augments the Kubernetes strategyType with `AtomicUpdate` and `BlueGreen`",
+	"timeZone":                                     "Time zone of the schedule, a name from the tz database like `Europe/Amsterdam`; requires kubernetes 1.27 or newer",
+	"tolerations":                                  "Yaml snippets to configure Kubernetes tolerations",
+	"topologyAwareHints":                           "Enables Topology Aware Hints, which provides a mechanism to help keep traffic within the zone it originated fromand reduce the extra costs generated from egress traffic",
+	"trustedips":                                   "To set `loadBalancerSourceRanges` on the service of type `LoadBalancer` for `visibility: public` or `visibility: esp` or `visibility: espv2`",
+	"useGoogleCloudCredentials":                    "Uses a [estafette-gcp-service-account](https://github.com/estafette/estafette-gcp-service-account) annotated secret to get a service account keyfile and mount it into the application container",
+	"visibility":                                   "Determines how the application can be reached",
+	"volumemounts":                                 "Additional volumes mounted into the application container",
+	"volumemounts[].mountpath":                     "Path to where the volume is mounted",
+	"volumemounts[].name":                          "Additional volumes to mount into the application container",
+	"volumemounts[].volume":                        "Yaml snippet for the volume spec; can be used to mount secrets, configmaps, persistentvolumeclaims, etc",
+	"vpa":                                          "Configures the Vertical Pod Autoscaler",
+	"vpa.enabled":                                  "Enables Vertical Pod Autoscaler",
+	"vpa.updateMode":                               "The update mode for VPA",
+	"whitelist":                                    "A list of [CIDRs][https://en.wikipedia.org/wiki/Classless_Inter-Domain_Routing] to allow access to the application",
+	"workloadIdentity":                             "Enable workload identity to access Google Cloud services from applications running within GKE due to its improved security properties and manageability.",
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// the layers parameters get merged from, from lowest to highest precedence
+const (
+	ParamsLayerBuiltIn    = "built-in"
+	ParamsLayerCredential = "credential"
+	ParamsLayerNamespace  = "namespace"
+	ParamsLayerStage      = "stage"
+)
+
+// explainMaskedKeys lists the keys of parameters holding secret values, which get masked wherever they appear when explaining the parameters
+var explainMaskedKeys = map[string]bool{
+	"secretEnv":                              true,
+	"iapOauthClientSecret":                   true,
+	"imagePullSecretPassword":                true,
+	"legacyGoogleCloudServiceAccountKeyFile": true,
+}
+
+var paramPathIndexRegex = regexp.MustCompile(`\[\d+\]`)
+
+// ParamsLayer holds the parameters set by one of the layers the parameters of a stage get merged from
+type ParamsLayer struct {
+	Name   string
+	Values map[string]interface{}
+}
+
+// ParamExplanation holds the effective value of a parameter and the layer it comes from
+type ParamExplanation struct {
+	Path   string `json:"path"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// NewParamsLayer returns a layer with the parameters in yaml; an explicit null removes the value set by a lower layer
+func NewParamsLayer(name string, paramsYAML []byte) (layer ParamsLayer, err error) {
+	var values map[string]interface{}
+	err = yaml.Unmarshal(paramsYAML, &values)
+	if err != nil {
+		return layer, fmt.Errorf("Failed unmarshalling %v parameters: %w", name, err)
+	}
+
+	return ParamsLayer{Name: name, Values: normalizeParamsValue(values).(map[string]interface{})}, nil
+}
+
+// NewParamsLayerFromParams returns a layer with the parameters that are set in params
+func NewParamsLayerFromParams(name string, params *Params) (layer ParamsLayer, err error) {
+	if params == nil {
+		return ParamsLayer{Name: name, Values: map[string]interface{}{}}, nil
+	}
+
+	paramsYAML, err := yaml.Marshal(params)
+	if err != nil {
+		return layer, fmt.Errorf("Failed marshalling %v parameters: %w", name, err)
+	}
+
+	return NewParamsLayer(name, paramsYAML)
+}
+
+// MergeParamsLayers deep merges the layers in order, so maps get merged and any other value of a higher layer replaces the value of a lower
+// layer; sources holds the layer that set each of the values
+func MergeParamsLayers(layers ...ParamsLayer) (params Params, sources map[string]string, err error) {
+	merged := map[string]interface{}{}
+	sources = map[string]string{}
+	for _, layer := range layers {
+		mergeParamsValues(merged, layer.Values, "", layer.Name, sources)
+	}
+
+	mergedYAML, err := yaml.Marshal(merged)
+	if err != nil {
+		return params, sources, fmt.Errorf("Failed marshalling merged parameters: %w", err)
+	}
+
+	err = yaml.Unmarshal(mergedYAML, &params)
+	if err != nil {
+		return params, sources, fmt.Errorf("Failed unmarshalling merged parameters: %w", err)
+	}
+
+	return params, sources, nil
+}
+
+func mergeParamsValues(merged, values map[string]interface{}, path, layer string, sources map[string]string) {
+	for key, value := range values {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+
+		if value == nil {
+			delete(merged, key)
+			removeParamSources(sources, keyPath)
+			continue
+		}
+
+		valueMap, isMap := value.(map[string]interface{})
+		if !isMap {
+			removeParamSources(sources, keyPath)
+			merged[key] = value
+			sources[keyPath] = layer
+			continue
+		}
+
+		mergedMap, mergedIsMap := merged[key].(map[string]interface{})
+		if !mergedIsMap {
+			removeParamSources(sources, keyPath)
+			mergedMap = map[string]interface{}{}
+			merged[key] = mergedMap
+		}
+		if len(valueMap) == 0 && len(mergedMap) == 0 {
+			sources[keyPath] = layer
+		}
+		mergeParamsValues(mergedMap, valueMap, keyPath, layer, sources)
+	}
+}
+
+// removeParamSources removes the source of a parameter and of everything it holds
+func removeParamSources(sources map[string]string, path string) {
+	for p := range sources {
+		if p == path || strings.HasPrefix(p, path+".") || strings.HasPrefix(p, path+"[") {
+			delete(sources, p)
+		}
+	}
+}
+
+// ExplainParams returns the effective value of every parameter that's set, sorted by path; values that aren't set by any of the layers are set
+// by the extension itself
+func ExplainParams(params Params, sources map[string]string) (explanations []ParamExplanation, err error) {
+	paramsYAML, err := yaml.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("Failed marshalling parameters: %w", err)
+	}
+
+	var values map[string]interface{}
+	err = yaml.Unmarshal(paramsYAML, &values)
+	if err != nil {
+		return nil, fmt.Errorf("Failed unmarshalling parameters: %w", err)
+	}
+
+	explanations = []ParamExplanation{}
+	err = explainParamsValue(normalizeParamsValue(values), "", sources, &explanations)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(explanations, func(i, j int) bool {
+		return explanations[i].Path < explanations[j].Path
+	})
+
+	return explanations, nil
+}
+
+func explainParamsValue(value interface{}, path string, sources map[string]string, explanations *[]ParamExplanation) error {
+	if maskedParam(path) {
+		*explanations = append(*explanations, ParamExplanation{Path: path, Value: "***", Source: paramSource(path, sources)})
+		return nil
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) > 0 {
+			for key, item := range v {
+				keyPath := key
+				if path != "" {
+					keyPath = path + "." + key
+				}
+				err := explainParamsValue(item, keyPath, sources, explanations)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+	case []interface{}:
+		// lists of objects are explained per item, other lists as a whole
+		if len(v) > 0 {
+			if _, ok := v[0].(map[string]interface{}); ok {
+				for i, item := range v {
+					err := explainParamsValue(item, fmt.Sprintf("%v[%v]", path, i), sources, explanations)
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+		}
+	}
+
+	formatted := fmt.Sprint(value)
+	if _, isScalar := value.(string); !isScalar {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("Failed marshalling value of parameter %v: %w", path, err)
+		}
+		formatted = string(data)
+	}
+	*explanations = append(*explanations, ParamExplanation{Path: path, Value: formatted, Source: paramSource(path, sources)})
+
+	return nil
+}
+
+// paramSource returns the layer that set a parameter or any of the parameters holding it
+func paramSource(path string, sources map[string]string) string {
+	for p := path; p != ""; {
+		if source, ok := sources[p]; ok {
+			return source
+		}
+		if strings.HasSuffix(p, "]") {
+			p = p[:strings.LastIndex(p, "[")]
+			continue
+		}
+		p = parentPath(p)
+	}
+
+	return ParamsLayerBuiltIn
+}
+
+// maskedParam returns whether a parameter holds secret values, going by its key so secrets nested anywhere, like in the deprecated sidecar
+// parameter, get masked as well
+func maskedParam(path string) bool {
+	keys := strings.Split(paramPathIndexRegex.ReplaceAllString(path, ""), ".")
+	key := keys[len(keys)-1]
+	if explainMaskedKeys[key] {
+		return true
+	}
+
+	return key == "keys" && len(keys) > 1 && keys[len(keys)-2] == "secrets"
+}
+
+// normalizeParamsValue converts the maps yaml unmarshals into maps with string keys, so they can be merged and marshalled to json
+func normalizeParamsValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		normalized := map[string]interface{}{}
+		for key, item := range v {
+			normalized[fmt.Sprint(key)] = normalizeParamsValue(item)
+		}
+		return normalized
+
+	case map[string]interface{}:
+		normalized := map[string]interface{}{}
+		for key, item := range v {
+			normalized[key] = normalizeParamsValue(item)
+		}
+		return normalized
+
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, item := range v {
+			normalized[i] = normalizeParamsValue(item)
+		}
+		return normalized
+	}
+
+	return value
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeParamsLayers(t *testing.T) {
+
+	t.Run("MergesMapsOfAllLayers", func(t *testing.T) {
+
+		credentialLayer, _ := NewParamsLayerFromParams(ParamsLayerCredential, &Params{
+			Namespace: "mynamespace",
+			Labels:    map[string]string{"team": "myteam"},
+			Container: ContainerParams{
+				EnvironmentVariables: map[string]interface{}{"LOG_LEVEL": "info", "REGION": "europe-west1"},
+			},
+		})
+		namespaceLayer, _ := NewParamsLayer(ParamsLayerNamespace, []byte("labels:\n  costcenter: cc123\n"))
+		stageLayer, _ := NewParamsLayer(ParamsLayerStage, []byte("container:\n  env:\n    LOG_LEVEL: debug\n"))
+
+		// act
+		params, sources, err := MergeParamsLayers(credentialLayer, namespaceLayer, stageLayer)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "mynamespace", params.Namespace)
+		assert.Equal(t, map[string]string{"team": "myteam", "costcenter": "cc123"}, params.Labels)
+		assert.Equal(t, map[string]interface{}{"LOG_LEVEL": "debug", "REGION": "europe-west1"}, params.Container.EnvironmentVariables)
+		assert.Equal(t, ParamsLayerCredential, sources["labels.team"])
+		assert.Equal(t, ParamsLayerNamespace, sources["labels.costcenter"])
+		assert.Equal(t, ParamsLayerStage, sources["container.env.LOG_LEVEL"])
+		assert.Equal(t, ParamsLayerCredential, sources["container.env.REGION"])
+	})
+
+	t.Run("RemovesValuesOfLowerLayersSetToNull", func(t *testing.T) {
+
+		credentialLayer, _ := NewParamsLayerFromParams(ParamsLayerCredential, &Params{
+			Labels:     map[string]string{"team": "myteam", "costcenter": "cc123"},
+			Visibility: VisibilityPublic,
+		})
+		stageLayer, _ := NewParamsLayer(ParamsLayerStage, []byte("labels:\n  costcenter: null\nvisibility: null\n"))
+
+		// act
+		params, sources, err := MergeParamsLayers(credentialLayer, stageLayer)
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"team": "myteam"}, params.Labels)
+		assert.Equal(t, VisibilityUnknown, params.Visibility)
+		_, hasSource := sources["visibility"]
+		assert.False(t, hasSource)
+	})
+
+	t.Run("ReplacesListsOfLowerLayers", func(t *testing.T) {
+
+		credentialLayer, _ := NewParamsLayerFromParams(ParamsLayerCredential, &Params{
+			Hosts: []string{"a.example.com", "b.example.com"},
+		})
+		stageLayer, _ := NewParamsLayer(ParamsLayerStage, []byte("hosts:\n- c.example.com\n"))
+
+		// act
+		params, sources, err := MergeParamsLayers(credentialLayer, stageLayer)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"c.example.com"}, params.Hosts)
+		assert.Equal(t, ParamsLayerStage, sources["hosts"])
+	})
+
+	t.Run("ReplacesMapOfLowerLayerSetByHigherLayerAsScalar", func(t *testing.T) {
+
+		namespaceLayer, _ := NewParamsLayer(ParamsLayerNamespace, []byte("autoscale:\n  min: 2\n  max: 10\n"))
+		stageLayer, _ := NewParamsLayer(ParamsLayerStage, []byte("autoscale:\n  max: 5\n"))
+
+		// act
+		params, sources, err := MergeParamsLayers(namespaceLayer, stageLayer)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, params.Autoscale.MinReplicas)
+		assert.Equal(t, 5, params.Autoscale.MaxReplicas)
+		assert.Equal(t, ParamsLayerNamespace, sources["autoscale.min"])
+		assert.Equal(t, ParamsLayerStage, sources["autoscale.max"])
+	})
+
+	t.Run("ReturnsErrorForInvalidYAML", func(t *testing.T) {
+
+		// act
+		_, err := NewParamsLayer(ParamsLayerStage, []byte("kind: [deployment"))
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestExplainParams(t *testing.T) {
+
+	t.Run("ReturnsValuesWithTheirSourceAndBuiltInForValuesNotSetByALayer", func(t *testing.T) {
+
+		params := Params{
+			App:        "myapp",
+			Namespace:  "mynamespace",
+			Hosts:      []string{"a.example.com"},
+			Sidecars:   []*SidecarParams{{Type: SidecarTypeOpenresty, Image: "openresty:1.0"}},
+			Autoscale:  AutoscaleParams{MinReplicas: 3},
+			Visibility: VisibilityPrivate,
+		}
+		sources := map[string]string{
+			"namespace":     ParamsLayerCredential,
+			"hosts":         ParamsLayerStage,
+			"sidecars":      ParamsLayerStage,
+			"autoscale.min": ParamsLayerNamespace,
+		}
+
+		// act
+		explanations, err := ExplainParams(params, sources)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []ParamExplanation{
+			{Path: "app", Value: "myapp", Source: ParamsLayerBuiltIn},
+			{Path: "autoscale.min", Value: "3", Source: ParamsLayerNamespace},
+			{Path: "hosts", Value: `["a.example.com"]`, Source: ParamsLayerStage},
+			{Path: "namespace", Value: "mynamespace", Source: ParamsLayerCredential},
+			{Path: "sidecars[0].image", Value: "openresty:1.0", Source: ParamsLayerStage},
+			{Path: "sidecars[0].type", Value: "openresty", Source: ParamsLayerStage},
+			{Path: "visibility", Value: "private", Source: ParamsLayerBuiltIn},
+		}, explanations)
+	})
+
+	t.Run("MasksSecretValues", func(t *testing.T) {
+
+		params := Params{
+			Secrets:                 SecretsParams{Keys: map[string]interface{}{"secret.json": "c2VjcmV0"}},
+			ImagePullSecretPassword: "password",
+			Sidecar:                 SidecarParams{SecretEnvironmentVariables: map[string]interface{}{"TOKEN": "supersecret"}},
+			Sidecars:                []*SidecarParams{{Type: SidecarTypeOpenresty, SecretEnvironmentVariables: map[string]interface{}{"TOKEN": "secret"}}},
+		}
+
+		// act
+		explanations, err := ExplainParams(params, map[string]string{"secrets.keys": ParamsLayerStage})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []ParamExplanation{
+			{Path: "imagePullSecretPassword", Value: "***", Source: ParamsLayerBuiltIn},
+			{Path: "secrets.keys", Value: "***", Source: ParamsLayerStage},
+			{Path: "sidecar.secretEnv", Value: "***", Source: ParamsLayerBuiltIn},
+			{Path: "sidecars[0].secretEnv", Value: "***", Source: ParamsLayerBuiltIn},
+			{Path: "sidecars[0].type", Value: "openresty", Source: ParamsLayerBuiltIn},
+		}, explanations)
+	})
+}
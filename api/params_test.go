@@ -2,7 +2,6 @@ package api
 
 import (
 	"encoding/base64"
-	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -25,6 +24,12 @@ var (
 		RollingUpdate: RollingUpdateParams{
 			MaxSurge:       "25%",
 			MaxUnavailable: "25%",
+			Timeout:        "5m",
+			OnTimeout:      "fail",
+		},
+		Lock: LockParams{
+			OnConflict: "wait",
+			Timeout:    "30m",
 		},
 		Container: ContainerParams{
 			ImageRepository: "estafette",
@@ -2183,6 +2188,47 @@ func TestSetDefaults(t *testing.T) {
 		assert.Equal(t, "/api", params.Basepath)
 	})
 
+	t.Run("DefaultsRollingUpdateOnTimeoutToFailIfEmpty", func(t *testing.T) {
+
+		params := Params{}
+
+		// act
+		params.SetDefaults("", "", "", "", "", "", "", "", map[string]string{})
+
+		assert.Equal(t, "fail", params.RollingUpdate.OnTimeout)
+	})
+
+	t.Run("DefaultsLockToWaitUpTo30MinutesIfEmpty", func(t *testing.T) {
+
+		params := Params{}
+
+		// act
+		params.SetDefaults("", "", "", "", "", "", "", "", map[string]string{})
+
+		assert.Equal(t, "wait", params.Lock.OnConflict)
+		assert.Equal(t, "30m", params.Lock.Timeout)
+	})
+
+	t.Run("DefaultsKubernetesVersionTo1Dot29IfEmpty", func(t *testing.T) {
+
+		params := Params{}
+
+		// act
+		params.SetDefaults("", "", "", "", "", "", "", "", map[string]string{})
+
+		assert.Equal(t, "1.29", params.KubernetesVersion)
+	})
+
+	t.Run("KeepsKubernetesVersionIfNotEmpty", func(t *testing.T) {
+
+		params := Params{KubernetesVersion: "1.27"}
+
+		// act
+		params.SetDefaults("", "", "", "", "", "", "", "", map[string]string{})
+
+		assert.Equal(t, "1.27", params.KubernetesVersion)
+	})
+
 	t.Run("DefaultsRollingUpdateMaxSurgeTo25PercentIfEmpty", func(t *testing.T) {
 
 		params := Params{
@@ -2409,6 +2455,22 @@ func TestSetDefaults(t *testing.T) {
 		assert.Equal(t, ActionRollbackCanary, params.Action)
 	})
 
+	t.Run("DefaultsProgressiveCanaryStepsIfEmpty", func(t *testing.T) {
+
+		params := Params{
+			App: "myapp",
+		}
+
+		// act
+		params.SetDefaults("", "", "", "", "", "", "", "", map[string]string{})
+
+		assert.Equal(t, []int{5, 25, 50, 100}, params.Canary.Steps)
+		assert.Equal(t, "5m", params.Canary.StepInterval)
+		assert.Equal(t, "http://prometheus-server.monitoring.svc", params.Canary.Analysis.PrometheusURL)
+		assert.Equal(t, 1, len(params.Canary.Analysis.Checks))
+		assert.Equal(t, 0.05, *params.Canary.Analysis.Checks[0].Max)
+	})
+
 	t.Run("DefaultsKindToDeploymentIfEmpty", func(t *testing.T) {
 
 		params := Params{
@@ -2607,6 +2669,60 @@ func TestSetDefaults(t *testing.T) {
 
 		assert.Equal(t, 3, params.Request.VerifyDepth)
 	})
+
+	t.Run("DefaultsSmoketestsImageTimeoutPathAndExpectedStatus", func(t *testing.T) {
+
+		params := Params{
+			Kind:       KindDeployment,
+			Smoketests: SmoketestsParams{Checks: []SmoketestCheckParams{{}}},
+		}
+
+		// act
+		params.SetDefaults("", "", "", "", "", "", "", "", map[string]string{})
+
+		assert.Equal(t, "curlimages/curl:8.6.0", params.Smoketests.Image)
+		assert.Equal(t, "2m", params.Smoketests.Timeout)
+		assert.Equal(t, "/", params.Smoketests.Checks[0].Path)
+		assert.Equal(t, 200, params.Smoketests.Checks[0].ExpectedStatus)
+	})
+
+	t.Run("DefaultsBlueGreenScaleDownDelayTo15mIfStrategyTypeIsBlueGreen", func(t *testing.T) {
+
+		params := Params{
+			StrategyType: StrategyTypeBlueGreen,
+		}
+
+		// act
+		params.SetDefaults("", "", "", "", "", "", "", "", map[string]string{})
+
+		assert.Equal(t, "15m", params.BlueGreen.ScaleDownDelay)
+	})
+
+	t.Run("DefaultsJobTimeoutTo30mIfKindIsJob", func(t *testing.T) {
+
+		params := Params{
+			Kind: KindJob,
+		}
+
+		// act
+		params.SetDefaults("", "", "", "", "", "", "", "", map[string]string{})
+
+		assert.Equal(t, "30m", params.JobTimeout)
+	})
+
+	t.Run("DefaultsPreDeployHookBackoffLimitAndTimeout", func(t *testing.T) {
+
+		params := Params{
+			Kind:  KindDeployment,
+			Hooks: HooksParams{PreDeploy: []HookParams{{Name: "migrate"}}},
+		}
+
+		// act
+		params.SetDefaults("", "", "", "", "", "", "", "", map[string]string{})
+
+		assert.Equal(t, 0, *params.Hooks.PreDeploy[0].BackoffLimit)
+		assert.Equal(t, "10m", params.Hooks.PreDeploy[0].Timeout)
+	})
 }
 
 func TestValidateRequiredProperties(t *testing.T) {
@@ -2895,10 +3011,36 @@ func TestValidateRequiredProperties(t *testing.T) {
 		assert.True(t, len(errors) > 0)
 	})
 
+	t.Run("ReturnsFalseIfMemoryLimitIsNotAQuantity", func(t *testing.T) {
+
+		params := validParams
+		params.Container.Memory.Limit = "1024mb"
+		error_string := "Memory limit 1024mb of container is invalid; set container.memory.limit to a quantity like 128Mi or 1Gi"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsFalseIfMemoryLimitIsLowerThanRequest", func(t *testing.T) {
+
+		params := validParams
+		params.Container.Memory.Limit = "512Mi"
+		error_string := "Memory request 768Mi of container exceeds its limit 512Mi; set container.memory.request to at most the limit"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
 	t.Run("ReturnsTrueIfMemoryLimitIsSet", func(t *testing.T) {
 
 		params := validParams
-		params.Container.Memory.Limit = "100m"
+		params.Container.Memory.Limit = "1024Mi"
 
 		// act
 		valid, errors, _ := params.ValidateRequiredProperties()
@@ -3588,7 +3730,7 @@ func TestValidateRequiredProperties(t *testing.T) {
 	t.Run("ReturnsTrueIfSidecarCpuRequestIsSet", func(t *testing.T) {
 
 		params := validParams
-		params.Sidecar.CPU.Request = "100m"
+		params.Sidecar.CPU.Request = "40m"
 
 		// act
 		valid, errors, _ := params.ValidateRequiredProperties()
@@ -3660,7 +3802,7 @@ func TestValidateRequiredProperties(t *testing.T) {
 	t.Run("ReturnsTrueIfSidecarMemoryLimitIsSet", func(t *testing.T) {
 
 		params := validParams
-		params.Sidecar.Memory.Limit = "100m"
+		params.Sidecar.Memory.Limit = "100Mi"
 
 		// act
 		valid, errors, _ := params.ValidateRequiredProperties()
@@ -3879,6 +4021,134 @@ func TestValidateRequiredProperties(t *testing.T) {
 		assert.True(t, len(errors) == 0)
 	})
 
+	t.Run("ReturnsFalseIfScheduleIsInvalidAndKindIsCronjob", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindCronJob
+		params.Schedule = "*/5 * * *"
+		params.ConcurrencyPolicy = "Allow"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, 1, len(errors))
+		assert.Equal(t, "Schedule */5 * * * is invalid: expected 5 fields - minute, hour, day of month, month and day of week - but found 4; set schedule to a cron expression like '0 3 * * *'", errors[0].Error())
+	})
+
+	t.Run("ReturnsFalseIfScheduleNeverRunsAndKindIsCronjob", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindCronJob
+		params.Schedule = "0 0 30 2 *"
+		params.ConcurrencyPolicy = "Allow"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, 1, len(errors))
+		assert.Equal(t, "Schedule 0 0 30 2 * never runs; set schedule to a cron expression matching an existing date", errors[0].Error())
+	})
+
+	t.Run("ReturnsFalseIfTimeZoneIsInvalidAndKindIsCronjob", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindCronJob
+		params.Schedule = "0 3 * * *"
+		params.TimeZone = "Europe/Amsterdan"
+		params.ConcurrencyPolicy = "Allow"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, 1, len(errors))
+		assert.Equal(t, "Time zone Europe/Amsterdan is invalid; set timeZone to a name from the tz database like Europe/Amsterdam", errors[0].Error())
+	})
+
+	t.Run("ReturnsTrueIfTimeZoneIsValidAndKindIsCronjob", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindCronJob
+		params.Schedule = "0 3 * * *"
+		params.TimeZone = "Europe/Amsterdam"
+		params.ConcurrencyPolicy = "Allow"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.True(t, valid)
+		assert.True(t, len(errors) == 0)
+	})
+
+	t.Run("ReturnsFalseIfCronJobActionIsUsedWithOtherKind", func(t *testing.T) {
+
+		params := validParams
+		params.Action = ActionSuspendCronJob
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, 1, len(errors))
+		assert.Equal(t, "Action suspend-cronjob can only be used with kind cronjob", errors[0].Error())
+	})
+
+	t.Run("ReturnsFalseIfJobTimeoutIsInvalidAndActionIsTriggerCronjob", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindCronJob
+		params.Action = ActionTriggerCronJob
+		params.JobTimeout = "forever"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, 1, len(errors))
+	})
+
+	t.Run("ReturnsTrueIfActionIsResumeCronjobAndKindIsCronjob", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindCronJob
+		params.Action = ActionResumeCronJob
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.True(t, valid)
+		assert.True(t, len(errors) == 0)
+	})
+
+	t.Run("ReturnsTrueIfJobTimeoutIsValidAndKindIsJob", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindJob
+		params.JobTimeout = "30m"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.True(t, valid)
+		assert.True(t, len(errors) == 0)
+	})
+
+	t.Run("ReturnsFalseIfJobTimeoutIsInvalidAndKindIsJob", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindJob
+		params.JobTimeout = "half an hour"
+		error_string := "Job timeout half an hour is invalid; set jobTimeout to a duration like 30m"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
 	t.Run("ReturnsFalseIfPodManagementPolicyIsInvalidAndKindIsStatefulset", func(t *testing.T) {
 
 		params := validParams
@@ -4043,56 +4313,595 @@ func TestValidateRequiredProperties(t *testing.T) {
 		assert.False(t, valid)
 		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
 	})
-}
+	t.Run("ReturnsTrueIfActionIsRollbackSimpleWithoutContainerParams", func(t *testing.T) {
 
-func TestReplaceSidecarTagsWithDigest(t *testing.T) {
+		params := Params{
+			Action:    ActionRollbackSimple,
+			Kind:      KindDeployment,
+			App:       "myapp",
+			Namespace: "mynamespace",
+		}
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.True(t, valid)
+		assert.Equal(t, 0, len(errors))
+	})
 
-	t.Run("ReplacesFirstSidecarImageTagWithDigest", func(t *testing.T) {
+	t.Run("ReturnsFalseIfActionIsRollbackStableForKindStatefulset", func(t *testing.T) {
 
 		params := validParams
+		params.Action = ActionRollbackStable
+		params.Kind = KindStatefulset
+		error_string := "Action rollback-stable can only be used with kind deployment or headless-deployment"
 
 		// act
-		params.ReplaceSidecarTagsWithDigest()
+		valid, errors, _ := params.ValidateRequiredProperties()
 
-		assert.Equal(t, SidecarTypeOpenresty, params.Sidecars[0].Type)
-		assert.True(t, strings.HasPrefix(params.Sidecars[0].Image, "estafette/openresty-sidecar@sha256:"))
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
 	})
 
-	t.Run("KeepsFirstSidecarImageTagWithDigest", func(t *testing.T) {
+	t.Run("ReturnsTrueIfActionIsDeployProgressiveWithValidCanarySteps", func(t *testing.T) {
 
+		maxErrorRate := 0.05
 		params := validParams
-		params.Sidecars[0].Image = "estafette/openresty-sidecar@sha256:4300dc7d45600c428f4196009ee842c1c3bdd51aaa4f55361479f6fa60e78faf"
+		params.Kind = KindDeployment
+		params.Action = ActionDeployProgressive
+		params.Canary = CanaryParams{
+			Steps:        []int{5, 25, 50, 100},
+			StepInterval: "5m",
+			Analysis: CanaryAnalysisParams{
+				PrometheusURL: "http://prometheus-server.monitoring.svc",
+				Checks: []CanaryCheckParams{
+					{Name: "error-rate", Query: "vector(0)", Max: &maxErrorRate},
+				},
+			},
+		}
 
 		// act
-		params.ReplaceSidecarTagsWithDigest()
+		valid, errors, _ := params.ValidateRequiredProperties()
 
-		assert.Equal(t, SidecarTypeOpenresty, params.Sidecars[0].Type)
-		assert.True(t, strings.HasPrefix(params.Sidecars[0].Image, "estafette/openresty-sidecar@sha256:"))
+		assert.True(t, valid)
+		assert.Equal(t, 0, len(errors))
+	})
+
+	t.Run("ReturnsFalseIfActionIsDeployProgressiveAndLastCanaryStepIsNot100", func(t *testing.T) {
+
+		maxErrorRate := 0.05
+		params := validParams
+		params.Kind = KindDeployment
+		params.Action = ActionDeployProgressive
+		params.Canary = CanaryParams{
+			Steps:        []int{5, 25, 50},
+			StepInterval: "5m",
+			Analysis: CanaryAnalysisParams{
+				PrometheusURL: "http://prometheus-server.monitoring.svc",
+				Checks: []CanaryCheckParams{
+					{Name: "error-rate", Query: "vector(0)", Max: &maxErrorRate},
+				},
+			},
+		}
+		error_string := "The last canary step needs to have weight 100 before the canary is promoted; set canary.steps to end with 100"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
 	})
 
-	t.Run("ReplacesLastSidecarImageTagWithDigest", func(t *testing.T) {
+	t.Run("ReturnsFalseIfActionIsDeployProgressiveAndCanaryStepsAreNotIncreasing", func(t *testing.T) {
 
+		maxErrorRate := 0.05
 		params := validParams
+		params.Kind = KindDeployment
+		params.Action = ActionDeployProgressive
+		params.Canary = CanaryParams{
+			Steps:        []int{50, 25, 100},
+			StepInterval: "5m",
+			Analysis: CanaryAnalysisParams{
+				PrometheusURL: "http://prometheus-server.monitoring.svc",
+				Checks: []CanaryCheckParams{
+					{Name: "error-rate", Query: "vector(0)", Max: &maxErrorRate},
+				},
+			},
+		}
 
 		// act
-		params.ReplaceSidecarTagsWithDigest()
+		valid, _, _ := params.ValidateRequiredProperties()
 
-		assert.Equal(t, SidecarTypeESP, params.Sidecars[1].Type)
-		assert.True(t, strings.HasPrefix(params.Sidecars[1].Image, "estafette/estafette-docker-cache-heater@sha256:"))
+		assert.False(t, valid)
 	})
 
-	t.Run("KeepsLastSidecarImageTagWithDigest", func(t *testing.T) {
+	t.Run("ReturnsFalseIfActionIsDeployProgressiveAndCanaryCheckHasNoMinOrMax", func(t *testing.T) {
 
 		params := validParams
-		params.Sidecars[1].Image = "estafette/estafette-docker-cache-heater@sha256:4300dc7d45600c428f4196009ee842c1c3bdd51aaa4f55361479f6fa60e78faf"
+		params.Kind = KindDeployment
+		params.Action = ActionDeployProgressive
+		params.Canary = CanaryParams{
+			Steps:        []int{5, 25, 50, 100},
+			StepInterval: "5m",
+			Analysis: CanaryAnalysisParams{
+				PrometheusURL: "http://prometheus-server.monitoring.svc",
+				Checks: []CanaryCheckParams{
+					{Name: "error-rate", Query: "vector(0)"},
+				},
+			},
+		}
+		error_string := "Canary analysis check error-rate has no min or max; set at least one of them on this stage"
 
 		// act
-		params.ReplaceSidecarTagsWithDigest()
+		valid, errors, _ := params.ValidateRequiredProperties()
 
-		assert.Equal(t, SidecarTypeESP, params.Sidecars[1].Type)
-		assert.True(t, strings.HasPrefix(params.Sidecars[1].Image, "estafette/estafette-docker-cache-heater@sha256:"))
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
 	})
 
+	t.Run("ReturnsFalseIfActionIsDeployProgressiveAndVisibilityIsIAP", func(t *testing.T) {
+
+		maxErrorRate := 0.05
+		params := validParams
+		params.Kind = KindDeployment
+		params.Action = ActionDeployProgressive
+		params.Visibility = VisibilityIAP
+		params.IapOauthCredentialsClientID = "abc"
+		params.IapOauthCredentialsClientSecret = "def"
+		params.Canary = CanaryParams{
+			Steps:        []int{5, 25, 50, 100},
+			StepInterval: "5m",
+			Analysis: CanaryAnalysisParams{
+				PrometheusURL: "http://prometheus-server.monitoring.svc",
+				Checks: []CanaryCheckParams{
+					{Name: "error-rate", Query: "vector(0)", Max: &maxErrorRate},
+				},
+			},
+		}
+		error_string := "Action deploy-progressive shifts traffic with nginx ingress canary weights; use visibility private, public-whitelist, apigee or esp with espServiceTypeClusterIP: true"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsFalseIfSmoketestsAreUsedWithKindCronJob", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindCronJob
+		params.Smoketests = SmoketestsParams{Timeout: "2m", Checks: []SmoketestCheckParams{{Path: "/", ExpectedStatus: 200}}}
+		error_string := "Smoke tests can only be used with kind deployment, headless-deployment or statefulset"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsFalseIfSmoketestPathDoesNotStartWithSlash", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindDeployment
+		params.Smoketests = SmoketestsParams{Timeout: "2m", Checks: []SmoketestCheckParams{{Path: "liveness", ExpectedStatus: 200}}}
+		error_string := "Smoke test path liveness is invalid; set smoketests.checks[].path to a path starting with /"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsFalseIfSmoketestsRollBackStatefulset", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindStatefulset
+		params.Smoketests = SmoketestsParams{Timeout: "2m", RollbackOnFailure: true, Checks: []SmoketestCheckParams{{Path: "/", ExpectedStatus: 200}}}
+		error_string := "Smoke tests can't roll back a statefulset; remove smoketests.rollbackOnFailure"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsTrueIfPreDeployHooksAreValid", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindDeployment
+		params.Hooks.PreDeploy = []HookParams{{Name: "migrate", Command: []string{"./migrate"}, Timeout: "10m"}}
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.True(t, valid)
+		assert.True(t, len(errors) == 0)
+	})
+
+	t.Run("ReturnsFalseIfPreDeployHooksAreUsedWithKindJob", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindJob
+		params.Hooks.PreDeploy = []HookParams{{Name: "migrate", Command: []string{"./migrate"}, Timeout: "10m"}}
+		error_string := "Pre-deploy hooks can only be used with kind deployment, headless-deployment or statefulset"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsFalseIfPreDeployHookNamesAreNotUnique", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindDeployment
+		params.Hooks.PreDeploy = []HookParams{
+			{Name: "migrate", Command: []string{"./migrate"}, Timeout: "10m"},
+			{Name: "migrate", Command: []string{"./seed"}, Timeout: "10m"},
+		}
+		error_string := "Pre-deploy hook name migrate is used more than once; set hooks.preDeploy[].name to unique names"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsFalseIfPreDeployHookNameIsInvalid", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindDeployment
+		params.Hooks.PreDeploy = []HookParams{{Name: "Migrate_DB", Command: []string{"./migrate"}, Timeout: "10m"}}
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.True(t, len(errors) > 0)
+	})
+
+	t.Run("ReturnsFalseIfPreDeployHookTimeoutIsInvalid", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindDeployment
+		params.Hooks.PreDeploy = []HookParams{{Name: "migrate", Command: []string{"./migrate"}, Timeout: "ten minutes"}}
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.True(t, len(errors) > 0)
+	})
+
+	t.Run("ReturnsFalseIfRollingUpdateTimeoutIsInvalid", func(t *testing.T) {
+
+		params := validParams
+		params.RollingUpdate.Timeout = "five minutes"
+		error_string := "Rollingupdate timeout five minutes is invalid; set rollingupdate.timeout to a duration like 5m"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsFalseIfRollingUpdateOnTimeoutIsInvalid", func(t *testing.T) {
+
+		params := validParams
+		params.RollingUpdate.OnTimeout = "retry"
+		error_string := "Rollingupdate on timeout retry is invalid; allowed values for rollingupdate.onTimeout are fail, undo or pause"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsTrueIfRollingUpdateOnTimeoutIsUndoAndKindIsStatefulset", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindStatefulset
+		params.RollingUpdate.OnTimeout = "undo"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.True(t, valid)
+		assert.True(t, len(errors) == 0)
+	})
+
+	t.Run("ReturnsFalseIfRollingUpdateOnTimeoutIsUndoAndKindIsJob", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindJob
+		params.RollingUpdate.OnTimeout = "undo"
+		error_string := "Rollingupdate on timeout undo can only be used with kind deployment, headless-deployment or statefulset"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsFalseIfRollingUpdateOnTimeoutIsPauseAndKindIsStatefulset", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindStatefulset
+		params.RollingUpdate.OnTimeout = "pause"
+		error_string := "Rollingupdate on timeout pause can only be used with kind deployment or headless-deployment; statefulsets can't be paused, use undo instead"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsTrueIfRollingUpdateOnTimeoutIsPauseAndKindIsDeployment", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindDeployment
+		params.RollingUpdate.OnTimeout = "pause"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.True(t, valid)
+		assert.True(t, len(errors) == 0)
+	})
+
+	t.Run("ReturnsFalseIfArchitectureIsInvalid", func(t *testing.T) {
+
+		params := validParams
+		params.Architecture = "x86"
+		error_string := "Architecture x86 is invalid; allowed values for architecture are amd64 or arm64"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsFalseIfArchitectureIsArm64AndOperatingSystemIsWindows", func(t *testing.T) {
+
+		params := validParams
+		params.OperatingSystem = OperatingSystemWindows
+		params.Architecture = ArchitectureARM64
+		error_string := "Architecture arm64 isn't available for os windows; windows node pools only run amd64"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsTrueIfArchitectureIsArm64", func(t *testing.T) {
+
+		params := validParams
+		params.Architecture = ArchitectureARM64
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.True(t, valid)
+		assert.True(t, len(errors) == 0)
+	})
+
+	t.Run("ReturnsFalseIfLockOnConflictIsInvalid", func(t *testing.T) {
+
+		params := validParams
+		params.Lock.OnConflict = "skip"
+		error_string := "Lock on conflict skip is invalid; allowed values for lock.onConflict are wait or fail"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsFalseIfLockTimeoutIsInvalid", func(t *testing.T) {
+
+		params := validParams
+		params.Lock.Timeout = "forever"
+		error_string := "Lock timeout forever is invalid; set lock.timeout to a duration like 30m"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsFalseIfDiffExitCodeOnDriftIsOne", func(t *testing.T) {
+
+		params := validParams
+		params.Diff.ExitCodeOnDrift = 1
+		error_string := "Diff exit code on drift 1 is invalid; set diff.exitCodeOnDrift to a value between 2 and 255, exit code 1 is used for failures"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsTrueIfDiffExitCodeOnDriftIsTwo", func(t *testing.T) {
+
+		params := validParams
+		params.Diff.ExitCodeOnDrift = 2
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.True(t, valid)
+		assert.True(t, len(errors) == 0)
+	})
+
+	t.Run("ReturnsTrueIfAutoRollbackIsEnabledForKindDeployment", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindDeployment
+		params.AutoRollback = true
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.True(t, valid)
+		assert.True(t, len(errors) == 0)
+	})
+
+	t.Run("ReturnsFalseIfAutoRollbackIsEnabledForKindJob", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindJob
+		params.AutoRollback = true
+		error_string := "Auto rollback can only be used with kind deployment, headless-deployment or statefulset"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsFalseIfAutoRollbackIsCombinedWithRollingUpdateOnTimeoutUndo", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindDeployment
+		params.AutoRollback = true
+		params.RollingUpdate.OnTimeout = "undo"
+		error_string := "Auto rollback already rolls back rollouts that time out; remove rollingupdate.onTimeout or set it to fail"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsTrueIfBlueGreenIsUsedWithActionDeploySimple", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindDeployment
+		params.StrategyType = StrategyTypeBlueGreen
+		params.BlueGreen.ScaleDownDelay = "1h"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.True(t, valid)
+		assert.True(t, len(errors) == 0)
+	})
+
+	t.Run("ReturnsFalseIfBlueGreenIsUsedWithActionDeployCanary", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindDeployment
+		params.Action = ActionDeployCanary
+		params.StrategyType = StrategyTypeBlueGreen
+		params.BlueGreen.ScaleDownDelay = "1h"
+		error_string := "StrategyType: BlueGreen can't be used in combination with other actions than deploy-simple, diff-simple or switch-bluegreen, the colours replace the canary and stable tracks. Please use action: deploy-simple"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsFalseIfBlueGreenIsUsedWithKindStatefulset", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindStatefulset
+		params.StrategyType = StrategyTypeBlueGreen
+		params.BlueGreen.ScaleDownDelay = "1h"
+		error_string := "StrategyType: BlueGreen can only be used with kind deployment"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsTrueIfActionSwitchBlueGreenOnlyHasAppAndNamespace", func(t *testing.T) {
+
+		params := Params{
+			Action:       ActionSwitchBlueGreen,
+			Kind:         KindDeployment,
+			App:          "myapp",
+			Namespace:    "mynamespace",
+			StrategyType: StrategyTypeBlueGreen,
+			BlueGreen:    BlueGreenParams{ScaleDownDelay: "30m"},
+		}
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.True(t, valid)
+		assert.True(t, len(errors) == 0)
+	})
+
+	t.Run("ReturnsTrueIfActionHistoryOnlyHasAppAndNamespace", func(t *testing.T) {
+
+		params := Params{
+			Action:    ActionHistory,
+			Kind:      KindDeployment,
+			App:       "myapp",
+			Namespace: "mynamespace",
+		}
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.True(t, valid)
+		assert.True(t, len(errors) == 0)
+	})
+
+	t.Run("ReturnsFalseIfActionSwitchBlueGreenIsUsedWithoutStrategyTypeBlueGreen", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindDeployment
+		params.Action = ActionSwitchBlueGreen
+		error_string := "Action switch-bluegreen can only be used with kind deployment and strategytype BlueGreen"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+
+	t.Run("ReturnsFalseIfBlueGreenScaleDownDelayIsInvalid", func(t *testing.T) {
+
+		params := validParams
+		params.Kind = KindDeployment
+		params.StrategyType = StrategyTypeBlueGreen
+		params.BlueGreen.ScaleDownDelay = "an hour"
+		error_string := "BlueGreen scale down delay an hour is invalid; set bluegreen.scaleDownDelay to a duration like 15m"
+
+		// act
+		valid, errors, _ := params.ValidateRequiredProperties()
+
+		assert.False(t, valid)
+		assert.Equal(t, error_string, stringInErrorSlice(error_string, errors))
+	})
+}
+
+func TestSetDefaultsForSidecarsAndDNS(t *testing.T) {
+
 	t.Run("DefaultsSQLProxyPortTo5432IfNotSet", func(t *testing.T) {
 
 		params := Params{
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"path"
+)
+
+type PolicyRuleType string
+
+const (
+	PolicyRuleMaxReplicas            PolicyRuleType = "max-replicas"
+	PolicyRuleRequireResourceLimits  PolicyRuleType = "require-resource-limits"
+	PolicyRuleDisallowLatestTag      PolicyRuleType = "disallow-latest-tag"
+	PolicyRuleAllowedVisibilities    PolicyRuleType = "allowed-visibilities"
+	PolicyRuleRequireSecurityContext PolicyRuleType = "require-security-context"
+
+	PolicyRuleUnknown PolicyRuleType = ""
+)
+
+type PolicySeverity string
+
+const (
+	PolicySeverityError   PolicySeverity = "error"
+	PolicySeverityWarning PolicySeverity = "warning"
+
+	PolicySeverityUnknown PolicySeverity = ""
+)
+
+// PolicyRules holds the rules of a policy file
+type PolicyRules struct {
+	Rules []PolicyRule `json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// PolicyRule is a guardrail set by a platform team that the rendered manifests have to comply with
+type PolicyRule struct {
+	Name     string         `json:"name,omitempty" yaml:"name,omitempty"`
+	Rule     PolicyRuleType `json:"rule,omitempty" yaml:"rule,omitempty"`
+	Severity PolicySeverity `json:"severity,omitempty" yaml:"severity,omitempty"`
+
+	// Namespaces limits the rule to namespaces matching any of these patterns, like team-*; the rule applies to all namespaces if empty
+	Namespaces []string `json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
+
+	MaxReplicas  int          `json:"maxReplicas,omitempty" yaml:"maxReplicas,omitempty"`
+	Resources    []string     `json:"resources,omitempty" yaml:"resources,omitempty"`
+	Visibilities []Visibility `json:"visibilities,omitempty" yaml:"visibilities,omitempty"`
+}
+
+// SetDefaults names a rule after its type and makes it fail the release unless its severity is set
+func (r *PolicyRule) SetDefaults() {
+	if r.Name == "" {
+		r.Name = string(r.Rule)
+	}
+	if r.Severity == PolicySeverityUnknown {
+		r.Severity = PolicySeverityError
+	}
+	if r.Rule == PolicyRuleRequireResourceLimits && len(r.Resources) == 0 {
+		r.Resources = []string{"cpu", "memory"}
+	}
+}
+
+// Validate returns an error if the rule can't be evaluated
+func (r *PolicyRule) Validate() error {
+	switch r.Rule {
+	case PolicyRuleMaxReplicas:
+		if r.MaxReplicas <= 0 {
+			return fmt.Errorf("Policy rule %v of type %v needs maxReplicas larger than 0", r.Name, r.Rule)
+		}
+	case PolicyRuleAllowedVisibilities:
+		if len(r.Visibilities) == 0 {
+			return fmt.Errorf("Policy rule %v of type %v needs at least one of visibilities", r.Name, r.Rule)
+		}
+	case PolicyRuleRequireResourceLimits, PolicyRuleDisallowLatestTag, PolicyRuleRequireSecurityContext:
+	default:
+		return fmt.Errorf("Policy rule %v has unknown type %v; use %v, %v, %v, %v or %v", r.Name, r.Rule, PolicyRuleMaxReplicas, PolicyRuleRequireResourceLimits, PolicyRuleDisallowLatestTag, PolicyRuleAllowedVisibilities, PolicyRuleRequireSecurityContext)
+	}
+
+	if r.Severity != PolicySeverityError && r.Severity != PolicySeverityWarning {
+		return fmt.Errorf("Policy rule %v has unknown severity %v; use %v or %v", r.Name, r.Severity, PolicySeverityError, PolicySeverityWarning)
+	}
+	for _, pattern := range r.Namespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Policy rule %v has invalid namespace pattern %v: %w", r.Name, pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// AppliesToNamespace returns true if the rule isn't limited to particular namespaces or the namespace matches one of its patterns
+func (r *PolicyRule) AppliesToNamespace(namespace string) bool {
+	if len(r.Namespaces) == 0 {
+		return true
+	}
+	for _, pattern := range r.Namespaces {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return true
+		}
+	}
+
+	return false
+}
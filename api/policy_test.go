@@ -0,0 +1,105 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyRuleSetDefaults(t *testing.T) {
+
+	t.Run("DefaultsNameToRuleAndSeverityToError", func(t *testing.T) {
+
+		rule := PolicyRule{Rule: PolicyRuleRequireResourceLimits}
+
+		// act
+		rule.SetDefaults()
+
+		assert.Equal(t, "require-resource-limits", rule.Name)
+		assert.Equal(t, PolicySeverityError, rule.Severity)
+		assert.Equal(t, []string{"cpu", "memory"}, rule.Resources)
+	})
+
+	t.Run("KeepsNameAndSeverityIfSet", func(t *testing.T) {
+
+		rule := PolicyRule{Name: "limits", Rule: PolicyRuleRequireResourceLimits, Severity: PolicySeverityWarning, Resources: []string{"memory"}}
+
+		// act
+		rule.SetDefaults()
+
+		assert.Equal(t, "limits", rule.Name)
+		assert.Equal(t, PolicySeverityWarning, rule.Severity)
+		assert.Equal(t, []string{"memory"}, rule.Resources)
+	})
+}
+
+func TestPolicyRuleValidate(t *testing.T) {
+
+	t.Run("ReturnsNilForValidRule", func(t *testing.T) {
+
+		rule := PolicyRule{Name: "max-replicas", Rule: PolicyRuleMaxReplicas, Severity: PolicySeverityError, MaxReplicas: 10, Namespaces: []string{"team-*"}}
+
+		// act
+		err := rule.Validate()
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrorForUnknownRule", func(t *testing.T) {
+
+		rule := PolicyRule{Name: "no-root", Rule: "no-root", Severity: PolicySeverityError}
+
+		// act
+		err := rule.Validate()
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("ReturnsErrorForMaxReplicasRuleWithoutMaximum", func(t *testing.T) {
+
+		rule := PolicyRule{Name: "max-replicas", Rule: PolicyRuleMaxReplicas, Severity: PolicySeverityError}
+
+		// act
+		err := rule.Validate()
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("ReturnsErrorForUnknownSeverity", func(t *testing.T) {
+
+		rule := PolicyRule{Name: "latest", Rule: PolicyRuleDisallowLatestTag, Severity: "fatal"}
+
+		// act
+		err := rule.Validate()
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("ReturnsErrorForInvalidNamespacePattern", func(t *testing.T) {
+
+		rule := PolicyRule{Name: "latest", Rule: PolicyRuleDisallowLatestTag, Severity: PolicySeverityError, Namespaces: []string{"team-["}}
+
+		// act
+		err := rule.Validate()
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestPolicyRuleAppliesToNamespace(t *testing.T) {
+
+	t.Run("ReturnsTrueWithoutNamespaces", func(t *testing.T) {
+
+		rule := PolicyRule{}
+
+		assert.True(t, rule.AppliesToNamespace("mynamespace"))
+	})
+
+	t.Run("ReturnsTrueIfNamespaceMatchesPattern", func(t *testing.T) {
+
+		rule := PolicyRule{Namespaces: []string{"production", "team-*"}}
+
+		assert.True(t, rule.AppliesToNamespace("team-a"))
+		assert.False(t, rule.AppliesToNamespace("staging"))
+	})
+}
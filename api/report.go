@@ -0,0 +1,85 @@
+package api
+
+import "time"
+
+// RolloutOutcome is the result of waiting for the applied workload to become ready
+type RolloutOutcome string
+
+const (
+	RolloutOutcomeSucceeded RolloutOutcome = "succeeded"
+	RolloutOutcomeFailed    RolloutOutcome = "failed"
+	RolloutOutcomeSkipped   RolloutOutcome = "skipped"
+
+	// the outcomes of a rollout that didn't finish within rollingupdate.timeout or its progress deadline, by rollingupdate.onTimeout policy;
+	// a failed rollout rolled back by autoRollback is undone as well
+	RolloutOutcomeTimedOut RolloutOutcome = "timed-out"
+	RolloutOutcomeUndone   RolloutOutcome = "undone"
+	RolloutOutcomePaused   RolloutOutcome = "paused"
+)
+
+// Report is the machine-readable result of a run, written to the path set by the reportPath parameter
+type Report struct {
+	App             string                 `json:"app"`
+	Namespace       string                 `json:"namespace"`
+	Kind            Kind                   `json:"kind"`
+	Action          ActionType             `json:"action"`
+	BuildVersion    string                 `json:"buildVersion,omitempty"`
+	ReleaseID       string                 `json:"releaseID,omitempty"`
+	StartedAt       time.Time              `json:"startedAt"`
+	FinishedAt      time.Time              `json:"finishedAt"`
+	Succeeded       bool                   `json:"succeeded"`
+	Error           string                 `json:"error,omitempty"`
+	Image           string                 `json:"image,omitempty"`
+	ImageDigest     string                 `json:"imageDigest,omitempty"`
+	Replicas        *ReportReplicas        `json:"replicas,omitempty"`
+	Rollout         RolloutOutcome         `json:"rollout"`
+	Resources       []ReportResource       `json:"resources"`
+	Steps           []ReportStep           `json:"steps"`
+	Troubleshooting *ReportTroubleshooting `json:"troubleshooting,omitempty"`
+	Diff            *DiffSummary           `json:"diff,omitempty"`
+}
+
+// ReportReplicas holds the replica counts of a deployment once its rollout has finished
+type ReportReplicas struct {
+	Desired   int32 `json:"desired"`
+	Updated   int32 `json:"updated"`
+	Ready     int32 `json:"ready"`
+	Available int32 `json:"available"`
+}
+
+// ReportResource is a resource that got applied, deleted or patched
+type ReportResource struct {
+	Operation string `json:"operation"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// ReportStep holds how long a step of the run took and whether it failed
+type ReportStep struct {
+	Name            string  `json:"name"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// ReportTroubleshooting holds the data gathered to help troubleshoot a release
+type ReportTroubleshooting struct {
+	Resources    []string `json:"resources,omitempty"`
+	Logs         string   `json:"logs,omitempty"`
+	RootCause    string   `json:"rootCause,omitempty"`
+	Summary      string   `json:"summary,omitempty"`
+	Events       []string `json:"events,omitempty"`
+	PreviousLogs string   `json:"previousLogs,omitempty"`
+}
+
+// AddStep records a step that started at start and ended now
+func (r *Report) AddStep(name string, start time.Time, err error) {
+	step := ReportStep{
+		Name:            name,
+		DurationSeconds: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	r.Steps = append(r.Steps, step)
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddStep(t *testing.T) {
+
+	t.Run("RecordsDurationSinceStart", func(t *testing.T) {
+
+		report := &Report{}
+
+		// act
+		report.AddStep("apply", time.Now().Add(-2*time.Second), nil)
+
+		assert.Equal(t, "apply", report.Steps[0].Name)
+		assert.True(t, report.Steps[0].DurationSeconds >= 2)
+		assert.Equal(t, "", report.Steps[0].Error)
+	})
+
+	t.Run("RecordsError", func(t *testing.T) {
+
+		report := &Report{}
+
+		// act
+		report.AddStep("rollout", time.Now(), errors.New("progress deadline exceeded"))
+
+		assert.Equal(t, "progress deadline exceeded", report.Steps[0].Error)
+	})
+}
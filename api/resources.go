@@ -0,0 +1,221 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// PodResources holds the requests of a pod and the quality of service class kubernetes assigns to it
+type PodResources struct {
+	QOSClass      corev1.PodQOSClass
+	CPURequest    resource.Quantity
+	MemoryRequest resource.Quantity
+}
+
+func (r PodResources) String() string {
+	return fmt.Sprintf("QoS class %v, requests cpu %v and memory %v", r.QOSClass, r.CPURequest.String(), r.MemoryRequest.String())
+}
+
+// containerResources holds the cpu and memory of one of the containers of the pod, as set in the parameters
+type containerResources struct {
+	description string
+	path        string
+	init        bool
+	raw         bool
+	cpu         CPUParams
+	memory      MemoryParams
+}
+
+// podContainerResources returns the resources of every container in the pod, including the injected sidecars and init containers
+func (p *Params) podContainerResources() (containers []containerResources) {
+	containers = append(containers, containerResources{description: "container", path: "container", cpu: p.Container.CPU, memory: p.Container.Memory})
+
+	// the deprecated sidecar is only added to the sidecars list by SetDefaults
+	if p.Sidecar.Type != "" && p.Sidecar.Type != "none" && (len(p.Sidecars) == 0 || p.Sidecars[0] != &p.Sidecar) {
+		containers = append(containers, containerResources{description: fmt.Sprintf("sidecar %v", p.Sidecar.Type), path: "sidecar", cpu: p.Sidecar.CPU, memory: p.Sidecar.Memory})
+	}
+	for i, sidecar := range p.Sidecars {
+		containers = append(containers, containerResources{description: fmt.Sprintf("sidecar %v", sidecar.Type), path: fmt.Sprintf("sidecars[%v]", i), cpu: sidecar.CPU, memory: sidecar.Memory})
+	}
+	for i, sidecar := range p.CustomSidecars {
+		containers = append(containers, rawContainerResources(fmt.Sprintf("custom sidecar %v", rawContainerName(sidecar)), fmt.Sprintf("customsidecars[%v]", i), false, sidecar))
+	}
+
+	// the init container waiting for workload identity doesn't set any resources
+	if p.WorkloadIdentity != nil && *p.WorkloadIdentity {
+		containers = append(containers, containerResources{description: "init container workload-identity", init: true})
+	}
+	for i, initContainer := range p.InitContainers {
+		containers = append(containers, rawContainerResources(fmt.Sprintf("init container %v", rawContainerName(initContainer)), fmt.Sprintf("initcontainers[%v]", i), true, initContainer))
+	}
+
+	return containers
+}
+
+// quantityPath returns the path of the parameter setting a request or limit of the container
+func (c containerResources) quantityPath(resourceName, kind string) string {
+	if c.raw {
+		return fmt.Sprintf("%v.resources.%vs.%v", c.path, kind, resourceName)
+	}
+
+	return fmt.Sprintf("%v.%v.%v", c.path, resourceName, kind)
+}
+
+// rawContainerResources returns the resources of a container spec that's passed as is into the manifests
+func rawContainerResources(description, path string, init bool, container *map[string]interface{}) containerResources {
+	c := containerResources{description: description, path: path, init: init, raw: true}
+	if container == nil {
+		return c
+	}
+
+	resources := toStringKeyedMap((*container)["resources"])
+	requests := toStringKeyedMap(resources["requests"])
+	limits := toStringKeyedMap(resources["limits"])
+
+	c.cpu = CPUParams{Request: rawQuantity(requests["cpu"]), Limit: rawQuantity(limits["cpu"])}
+	c.memory = MemoryParams{Request: rawQuantity(requests["memory"]), Limit: rawQuantity(limits["memory"])}
+
+	return c
+}
+
+func rawContainerName(container *map[string]interface{}) string {
+	if container == nil {
+		return ""
+	}
+
+	return fmt.Sprint((*container)["name"])
+}
+
+func toStringKeyedMap(value interface{}) map[string]interface{} {
+	if m, ok := normalizeParamsValue(value).(map[string]interface{}); ok {
+		return m
+	}
+
+	return map[string]interface{}{}
+}
+
+func rawQuantity(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
+
+// validateResources checks that all cpu and memory quantities can be parsed by kubernetes and that no request exceeds its limit
+func (p *Params) validateResources() (errors []error, warnings []string) {
+	for _, c := range p.podContainerResources() {
+		quantities := []struct {
+			name, path, value, example string
+		}{
+			{"Cpu request", c.quantityPath("cpu", "request"), c.cpu.Request, "100m or 0.5"},
+			{"Cpu limit", c.quantityPath("cpu", "limit"), c.cpu.Limit, "100m or 0.5"},
+			{"Memory request", c.quantityPath("memory", "request"), c.memory.Request, "128Mi or 1Gi"},
+			{"Memory limit", c.quantityPath("memory", "limit"), c.memory.Limit, "128Mi or 1Gi"},
+		}
+
+		parsed := map[string]*resource.Quantity{}
+		for _, q := range quantities {
+			if q.value == "" {
+				continue
+			}
+			quantity, err := resource.ParseQuantity(q.value)
+			if err != nil {
+				errors = append(errors, fmt.Errorf("%v %v of %v is invalid; set %v to a quantity like %v", q.name, q.value, c.description, q.path, q.example))
+				continue
+			}
+			if strings.HasPrefix(q.name, "Memory") && strings.HasSuffix(q.value, "m") {
+				warnings = append(warnings, fmt.Sprintf("%v %v of %v is in millibytes; did you mean %vMi?", q.name, q.value, c.description, strings.TrimSuffix(q.value, "m")))
+			}
+			parsed[q.name] = &quantity
+		}
+
+		if request, limit := parsed["Cpu request"], parsed["Cpu limit"]; request != nil && limit != nil && request.Cmp(*limit) > 0 {
+			errors = append(errors, fmt.Errorf("Cpu request %v of %v exceeds its limit %v; set %v to at most the limit", c.cpu.Request, c.description, c.cpu.Limit, quantities[0].path))
+		}
+		if request, limit := parsed["Memory request"], parsed["Memory limit"]; request != nil && limit != nil && request.Cmp(*limit) > 0 {
+			errors = append(errors, fmt.Errorf("Memory request %v of %v exceeds its limit %v; set %v to at most the limit", c.memory.Request, c.description, c.memory.Limit, quantities[2].path))
+		}
+	}
+
+	return errors, warnings
+}
+
+// PodResources returns the requests of the pod and its quality of service class, following the rules of kubernetes; quantities that can't be
+// parsed are ignored, validateResources reports those
+func (p *Params) PodResources() PodResources {
+	podResources := PodResources{}
+	initCPU, initMemory := resource.Quantity{}, resource.Quantity{}
+
+	anySet := false
+	guaranteed := true
+	for _, c := range p.podContainerResources() {
+		cpuRequest, cpuLimit := parseQuantity(c.cpu.Request), parseQuantity(c.cpu.Limit)
+		memoryRequest, memoryLimit := parseQuantity(c.memory.Request), parseQuantity(c.memory.Limit)
+
+		// kubernetes defaults a request that isn't set to its limit
+		if cpuRequest == nil {
+			cpuRequest = cpuLimit
+		}
+		if memoryRequest == nil {
+			memoryRequest = memoryLimit
+		}
+
+		if cpuRequest != nil || memoryRequest != nil {
+			anySet = true
+		}
+		if cpuLimit == nil || memoryLimit == nil || cpuRequest.Cmp(*cpuLimit) != 0 || memoryRequest.Cmp(*memoryLimit) != 0 {
+			guaranteed = false
+		}
+
+		// init containers run one after the other before the other containers, so only the largest of them counts
+		if c.init {
+			if cpuRequest != nil && cpuRequest.Cmp(initCPU) > 0 {
+				initCPU = *cpuRequest
+			}
+			if memoryRequest != nil && memoryRequest.Cmp(initMemory) > 0 {
+				initMemory = *memoryRequest
+			}
+			continue
+		}
+		if cpuRequest != nil {
+			podResources.CPURequest.Add(*cpuRequest)
+		}
+		if memoryRequest != nil {
+			podResources.MemoryRequest.Add(*memoryRequest)
+		}
+	}
+
+	if initCPU.Cmp(podResources.CPURequest) > 0 {
+		podResources.CPURequest = initCPU
+	}
+	if initMemory.Cmp(podResources.MemoryRequest) > 0 {
+		podResources.MemoryRequest = initMemory
+	}
+
+	switch {
+	case !anySet:
+		podResources.QOSClass = corev1.PodQOSBestEffort
+	case guaranteed:
+		podResources.QOSClass = corev1.PodQOSGuaranteed
+	default:
+		podResources.QOSClass = corev1.PodQOSBurstable
+	}
+
+	return podResources
+}
+
+func parseQuantity(value string) *resource.Quantity {
+	if value == "" {
+		return nil
+	}
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return nil
+	}
+
+	return &quantity
+}
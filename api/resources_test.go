@@ -0,0 +1,164 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidateResources(t *testing.T) {
+
+	t.Run("ReturnsNoErrorsForValidQuantities", func(t *testing.T) {
+
+		params := validParams
+
+		// act
+		errors, warnings := params.validateResources()
+
+		assert.Equal(t, 0, len(errors))
+		assert.Equal(t, 0, len(warnings))
+	})
+
+	t.Run("ReturnsErrorsForInvalidQuantities", func(t *testing.T) {
+
+		params := validParams
+		params.Container.CPU = CPUParams{Request: "0.5 cpu", Limit: "1"}
+		params.Container.Memory = MemoryParams{Request: "128mb", Limit: "256Mi"}
+
+		// act
+		errors, _ := params.validateResources()
+
+		assert.Equal(t, []string{
+			"Cpu request 0.5 cpu of container is invalid; set container.cpu.request to a quantity like 100m or 0.5",
+			"Memory request 128mb of container is invalid; set container.memory.request to a quantity like 128Mi or 1Gi",
+		}, errorMessages(errors))
+	})
+
+	t.Run("ReturnsErrorsForRequestsLargerThanLimits", func(t *testing.T) {
+
+		params := validParams
+		params.Container.CPU = CPUParams{Request: "1", Limit: "500m"}
+		params.Sidecars = []*SidecarParams{{Type: SidecarTypeOpenresty, CPU: CPUParams{Request: "10m"}, Memory: MemoryParams{Request: "1Gi", Limit: "50Mi"}}}
+
+		// act
+		errors, _ := params.validateResources()
+
+		assert.Equal(t, []string{
+			"Cpu request 1 of container exceeds its limit 500m; set container.cpu.request to at most the limit",
+			"Memory request 1Gi of sidecar openresty exceeds its limit 50Mi; set sidecars[0].memory.request to at most the limit",
+		}, errorMessages(errors))
+	})
+
+	t.Run("ValidatesResourcesOfInitContainersAndCustomSidecars", func(t *testing.T) {
+
+		params := validParams
+		params.InitContainers = []*map[string]interface{}{
+			{"name": "migrate", "resources": map[interface{}]interface{}{"requests": map[interface{}]interface{}{"cpu": "2"}, "limits": map[interface{}]interface{}{"cpu": "1"}}},
+		}
+		params.CustomSidecars = []*map[string]interface{}{
+			{"name": "proxy", "resources": map[string]interface{}{"limits": map[string]interface{}{"memory": "64 Mi"}}},
+		}
+
+		// act
+		errors, _ := params.validateResources()
+
+		assert.Equal(t, []string{
+			"Memory limit 64 Mi of custom sidecar proxy is invalid; set customsidecars[0].resources.limits.memory to a quantity like 128Mi or 1Gi",
+			"Cpu request 2 of init container migrate exceeds its limit 1; set initcontainers[0].resources.requests.cpu to at most the limit",
+		}, errorMessages(errors))
+	})
+
+	t.Run("ReturnsWarningForMemoryInMillibytes", func(t *testing.T) {
+
+		params := validParams
+		params.Container.Memory = MemoryParams{Request: "128m", Limit: "1Gi"}
+
+		// act
+		errors, warnings := params.validateResources()
+
+		assert.Equal(t, 0, len(errors))
+		assert.Equal(t, []string{"Memory request 128m of container is in millibytes; did you mean 128Mi?"}, warnings)
+	})
+}
+
+func TestPodResources(t *testing.T) {
+
+	t.Run("ReturnsBurstableAndSumOfRequestsIncludingSidecars", func(t *testing.T) {
+
+		params := validParams
+		params.Sidecar = SidecarParams{}
+
+		// act
+		podResources := params.PodResources()
+
+		assert.Equal(t, corev1.PodQOSBurstable, podResources.QOSClass)
+		assert.Equal(t, "120m", podResources.CPURequest.String())
+		assert.Equal(t, "788Mi", podResources.MemoryRequest.String())
+	})
+
+	t.Run("ReturnsGuaranteedIfAllRequestsEqualLimits", func(t *testing.T) {
+
+		params := validParams
+		params.Sidecar = SidecarParams{}
+		params.Container.CPU = CPUParams{Limit: "500m"}
+		params.Container.Memory = MemoryParams{Request: "1Gi", Limit: "1Gi"}
+		params.Sidecars = []*SidecarParams{{Type: SidecarTypeOpenresty, CPU: CPUParams{Request: "50m", Limit: "50m"}, Memory: MemoryParams{Request: "50Mi", Limit: "50Mi"}}}
+
+		// act
+		podResources := params.PodResources()
+
+		assert.Equal(t, corev1.PodQOSGuaranteed, podResources.QOSClass)
+		assert.Equal(t, "550m", podResources.CPURequest.String())
+		assert.Equal(t, "1074Mi", podResources.MemoryRequest.String())
+	})
+
+	t.Run("ReturnsBurstableIfWorkloadIdentityInitContainerIsInjected", func(t *testing.T) {
+
+		params := validParams
+		params.Sidecar = SidecarParams{}
+		params.Sidecars = nil
+		params.Container.CPU = CPUParams{Request: "500m", Limit: "500m"}
+		params.Container.Memory = MemoryParams{Request: "1Gi", Limit: "1Gi"}
+		params.WorkloadIdentity = &trueValue
+
+		// act
+		podResources := params.PodResources()
+
+		assert.Equal(t, corev1.PodQOSBurstable, podResources.QOSClass)
+	})
+
+	t.Run("ReturnsLargestInitContainerRequestIfLargerThanSumOfContainers", func(t *testing.T) {
+
+		params := validParams
+		params.Sidecar = SidecarParams{}
+		params.Sidecars = nil
+		params.InitContainers = []*map[string]interface{}{
+			{"name": "migrate", "resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": "2", "memory": "64Mi"}}},
+		}
+
+		// act
+		podResources := params.PodResources()
+
+		assert.Equal(t, "2", podResources.CPURequest.String())
+		assert.Equal(t, "768Mi", podResources.MemoryRequest.String())
+	})
+
+	t.Run("ReturnsBestEffortIfNoContainerSetsResources", func(t *testing.T) {
+
+		params := Params{}
+
+		// act
+		podResources := params.PodResources()
+
+		assert.Equal(t, corev1.PodQOSBestEffort, podResources.QOSClass)
+	})
+}
+
+func errorMessages(errors []error) (messages []string) {
+	for _, err := range errors {
+		messages = append(messages, err.Error())
+	}
+
+	return messages
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+)
+
+// JSONSchema is the subset of json schema draft-07 needed to describe the parameters
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+}
+
+// schemaEnums holds the allowed values of the string types of the parameters, without their unknown value
+var schemaEnums = map[reflect.Type][]string{
+	reflect.TypeOf(ActionUnknown):          {"deploy-simple", "deploy-canary", "deploy-stable", "deploy-progressive", "restart-simple", "restart-canary", "restart-stable", "diff-simple", "diff-canary", "diff-stable", "diff-delete", "rollback-canary", "rollback-stable", "rollback-simple", "switch-bluegreen", "delete", "history", "suspend-cronjob", "resume-cronjob", "trigger-cronjob"},
+	reflect.TypeOf(KindUnknown):            {"deployment", "headless-deployment", "statefulset", "job", "cronjob", "config", "config-to-file"},
+	reflect.TypeOf(VisibilityUnknown):      {"private", "public", "public-whitelist", "esp", "espv2", "iap", "apigee"},
+	reflect.TypeOf(SidecarTypeUnknown):     {"openresty", "esp", "espv2", "cloudsqlproxy", "istio"},
+	reflect.TypeOf(UpdateModeUnknown):      {"Off", "Initial", "Recreate", "Auto"},
+	reflect.TypeOf(StrategyTypeUnknown):    {"RollingUpdate", "Recreate", "AtomicUpdate", "BlueGreen"},
+	reflect.TypeOf(OperatingSystemUnknown): {"linux", "windows"},
+	reflect.TypeOf(ArchitectureUnknown):    {"amd64", "arm64"},
+}
+
+// schemaDefaultsExcluded lists the parameters whose defaults depend on the application or on other parameters, so they don't show up as default
+var schemaDefaultsExcluded = map[string]bool{
+	"labels":                                 true,
+	"sidecar":                                true,
+	"sidecars":                               true,
+	"canary.analysis.checks":                 true,
+	"autoscale.safety.promquery":             true,
+	"container.additionalports[].visibility": true,
+}
+
+// GenerateParamsSchema returns the json schema of the parameters of a stage, with the defaults set by SetDefaults
+func GenerateParamsSchema() *JSONSchema {
+
+	// apply the defaults to every kind, since some of them only apply to particular kinds; the first kind is the default one, so its defaults win
+	defaults := []reflect.Value{}
+	for _, kind := range []Kind{KindUnknown, KindHeadlessDeployment, KindStatefulset, KindJob, KindCronJob} {
+		params := Params{
+			Kind: kind,
+			// avoid retrieving cloudflare's ips
+			TrustedIPRanges: append([]string{}, defaultTrustedIPRanges...),
+			// a single item gets the defaults of the items of a list
+			Container:  ContainerParams{AdditionalPorts: []*AdditionalPortParams{{}}},
+			Hooks:      HooksParams{PreDeploy: []HookParams{{}}},
+			Smoketests: SmoketestsParams{Checks: []SmoketestCheckParams{{}}},
+		}
+		params.SetDefaults("", "", "", "", "", "", ActionUnknown, "", map[string]string{})
+		defaults = append(defaults, reflect.ValueOf(params))
+	}
+
+	schema := generateSchema(reflect.TypeOf(Params{}), defaults, "")
+	schema.Schema = "http://json-schema.org/draft-07/schema#"
+	schema.Title = "estafette-extension-gke"
+	schema.Description = "Parameters of a stage using the estafette-extension-gke image"
+	// a stage holds estafette's own properties, like image and when, next to the parameters
+	schema.AdditionalProperties = true
+
+	return schema
+}
+
+func generateSchema(t reflect.Type, defaults []reflect.Value, path string) *JSONSchema {
+	schema := &JSONSchema{
+		Description: schemaDescription(path),
+	}
+
+	// a pointer that's set holds an explicit default, even if it's false or 0
+	explicit := t.Kind() == reflect.Ptr
+	if explicit {
+		t = t.Elem()
+		for i := range defaults {
+			defaults[i] = defaults[i].Elem()
+		}
+	}
+
+	if enum, ok := schemaEnums[t]; ok {
+		schema.Type = "string"
+		schema.Enum = enum
+		schema.Default = schemaDefault(defaults, path, explicit)
+		return schema
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		schema.Type = "string"
+		schema.Default = schemaDefault(defaults, path, explicit)
+
+	case reflect.Bool:
+		schema.Type = "boolean"
+		schema.Default = schemaDefault(defaults, path, explicit)
+
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		schema.Type = "integer"
+		schema.Default = schemaDefault(defaults, path, explicit)
+
+	case reflect.Float32, reflect.Float64:
+		schema.Type = "number"
+		schema.Default = schemaDefault(defaults, path, explicit)
+
+	case reflect.Slice:
+		schema.Type = "array"
+		if t.Elem().Kind() == reflect.Struct || t.Elem().Kind() == reflect.Ptr && t.Elem().Elem().Kind() == reflect.Struct {
+			// the defaults of a list of objects apply to each of its items
+			schema.Items = generateSchema(t.Elem(), firstItems(defaults), path+"[]")
+		} else {
+			schema.Default = schemaDefault(defaults, path, explicit)
+			schema.Items = generateSchema(t.Elem(), nil, path+"[]")
+		}
+
+	case reflect.Map:
+		schema.Type = "object"
+		if t.Elem().Kind() == reflect.Interface {
+			schema.AdditionalProperties = true
+		} else {
+			schema.AdditionalProperties = generateSchema(t.Elem(), nil, "")
+		}
+
+	case reflect.Struct:
+		schema.Type = "object"
+		schema.Properties = map[string]*JSONSchema{}
+		schema.AdditionalProperties = false
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, inline := yamlFieldName(field)
+			if inline {
+				// inlined maps take any property besides the fields of the struct
+				schema.AdditionalProperties = true
+				continue
+			}
+			if name == "" {
+				continue
+			}
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+			fieldDefaults := []reflect.Value{}
+			for _, d := range defaults {
+				if d.IsValid() {
+					fieldDefaults = append(fieldDefaults, d.Field(i))
+				}
+			}
+			schema.Properties[name] = generateSchema(field.Type, fieldDefaults, fieldPath)
+		}
+
+	case reflect.Interface:
+		// any value
+	}
+
+	return schema
+}
+
+// yamlFieldName returns the name of a field in the stage's yaml, or an empty name if it can't be set from yaml
+func yamlFieldName(field reflect.StructField) (name string, inline bool) {
+	tag := field.Tag.Get("yaml")
+	if tag == "-" {
+		return "", false
+	}
+	parts := strings.Split(tag, ",")
+	for _, option := range parts[1:] {
+		if option == "inline" {
+			return "", true
+		}
+	}
+	if parts[0] == "" {
+		return strings.ToLower(field.Name), false
+	}
+
+	return parts[0], false
+}
+
+// schemaDefault returns the first default value that's set, skipping nil pointers and, unless explicit, zero values
+func schemaDefault(defaults []reflect.Value, path string, explicit bool) interface{} {
+	for p := path; p != ""; p = parentPath(p) {
+		if schemaDefaultsExcluded[strings.TrimSuffix(p, "[]")] {
+			return nil
+		}
+	}
+	for _, d := range defaults {
+		if !d.IsValid() {
+			continue
+		}
+		if d.Kind() == reflect.Slice && d.Len() == 0 {
+			continue
+		}
+		if d.IsZero() && !explicit {
+			continue
+		}
+		if d.Kind() == reflect.String {
+			return d.String()
+		}
+		return d.Interface()
+	}
+
+	return nil
+}
+
+// parentPath returns the path of the parameter holding a parameter, or an empty path for a top-level one
+func parentPath(path string) string {
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[:i]
+	}
+
+	return ""
+}
+
+// firstItems returns the first item of each of the default lists, to get the defaults of list items
+func firstItems(defaults []reflect.Value) (items []reflect.Value) {
+	for _, d := range defaults {
+		if d.IsValid() && d.Len() > 0 {
+			items = append(items, d.Index(0))
+		}
+	}
+
+	return items
+}
+
+// schemaDescription returns the description of a parameter; the deprecated sidecar parameter shares the descriptions of the sidecars list
+func schemaDescription(path string) string {
+	if description, ok := paramDescriptions[path]; ok {
+		return description
+	}
+	if strings.HasPrefix(path, "sidecar.") {
+		return paramDescriptions["sidecars[]."+strings.TrimPrefix(path, "sidecar.")]
+	}
+
+	return ""
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateParamsSchema(t *testing.T) {
+
+	t.Run("DescribesEveryParameter", func(t *testing.T) {
+
+		// act
+		schema := GenerateParamsSchema()
+
+		var assertDescribed func(schema *JSONSchema, path string)
+		assertDescribed = func(schema *JSONSchema, path string) {
+			if path != "" && !strings.HasSuffix(path, "[]") {
+				assert.NotEmpty(t, schema.Description, "parameter %v has no description", path)
+			}
+			for name, property := range schema.Properties {
+				if path == "" {
+					assertDescribed(property, name)
+				} else {
+					assertDescribed(property, path+"."+name)
+				}
+			}
+			if schema.Items != nil {
+				assertDescribed(schema.Items, path+"[]")
+			}
+		}
+		assertDescribed(schema, "")
+	})
+
+	t.Run("HasEnumsForStringTypes", func(t *testing.T) {
+
+		// act
+		schema := GenerateParamsSchema()
+
+		assert.Equal(t, []string{"deployment", "headless-deployment", "statefulset", "job", "cronjob", "config", "config-to-file"}, schema.Properties["kind"].Enum)
+		assert.Contains(t, schema.Properties["action"].Enum, "deploy-progressive")
+		assert.Contains(t, schema.Properties["visibility"].Enum, "iap")
+		assert.Contains(t, schema.Properties["sidecars"].Items.Properties["type"].Enum, "cloudsqlproxy")
+		assert.Equal(t, []string{"Off", "Initial", "Recreate", "Auto"}, schema.Properties["vpa"].Properties["updateMode"].Enum)
+		assert.Contains(t, schema.Properties["container"].Properties["additionalports"].Items.Properties["visibility"].Enum, "private")
+	})
+
+	t.Run("TakesDefaultsFromSetDefaults", func(t *testing.T) {
+
+		// act
+		schema := GenerateParamsSchema()
+
+		assert.Equal(t, "deployment", schema.Properties["kind"].Default)
+		assert.Equal(t, "deploy-simple", schema.Properties["action"].Default)
+		assert.Equal(t, 5000, schema.Properties["container"].Properties["port"].Default)
+		assert.Equal(t, "10", schema.Properties["canary"].Properties["maxreplicas"].Default)
+		assert.Equal(t, []int{5, 25, 50, 100}, schema.Properties["canary"].Properties["steps"].Default)
+		assert.Equal(t, false, schema.Properties["vpa"].Properties["enabled"].Default)
+		assert.Equal(t, "10m", schema.Properties["hooks"].Properties["preDeploy"].Items.Properties["timeout"].Default)
+	})
+
+	t.Run("TakesDefaultsThatOnlyApplyToOtherKinds", func(t *testing.T) {
+
+		// act
+		schema := GenerateParamsSchema()
+
+		assert.Equal(t, "standard", schema.Properties["storageclass"].Default)
+		assert.Equal(t, "Allow", schema.Properties["concurrencypolicy"].Default)
+		assert.Equal(t, "30m", schema.Properties["jobTimeout"].Default)
+	})
+
+	t.Run("OmitsDefaultsThatDependOnApplication", func(t *testing.T) {
+
+		// act
+		schema := GenerateParamsSchema()
+
+		assert.Nil(t, schema.Properties["labels"].Default)
+		assert.Nil(t, schema.Properties["sidecars"].Items.Properties["image"].Default)
+		assert.Nil(t, schema.Properties["autoscale"].Properties["safety"].Properties["promquery"].Default)
+		assert.Nil(t, schema.Properties["canary"].Properties["analysis"].Properties["checks"].Items.Properties["query"].Default)
+	})
+
+	t.Run("AllowsAnyPropertyForMapsAndInlinedSidecarProperties", func(t *testing.T) {
+
+		// act
+		schema := GenerateParamsSchema()
+
+		assert.Equal(t, true, schema.AdditionalProperties)
+		assert.Equal(t, false, schema.Properties["container"].AdditionalProperties)
+		assert.Equal(t, true, schema.Properties["container"].Properties["env"].AdditionalProperties)
+		assert.Equal(t, &JSONSchema{Type: "string"}, schema.Properties["labels"].AdditionalProperties)
+		assert.Equal(t, true, schema.Properties["sidecars"].Items.AdditionalProperties)
+	})
+
+	t.Run("MarshalsToJSON", func(t *testing.T) {
+
+		// act
+		data, err := json.Marshal(GenerateParamsSchema())
+
+		assert.Nil(t, err)
+		assert.Contains(t, string(data), `"$schema":"http://json-schema.org/draft-07/schema#"`)
+	})
+}
+
+func TestParamsTags(t *testing.T) {
+
+	t.Run("UsesSameNameForJSONAndYAML", func(t *testing.T) {
+
+		var assertTags func(t *testing.T, typ reflect.Type, path string)
+		assertTags = func(t *testing.T, typ reflect.Type, path string) {
+			for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+				typ = typ.Elem()
+			}
+			if typ.Kind() != reflect.Struct {
+				return
+			}
+			for i := 0; i < typ.NumField(); i++ {
+				field := typ.Field(i)
+				if _, inline := yamlFieldName(field); inline {
+					continue
+				}
+				yamlName := strings.Split(field.Tag.Get("yaml"), ",")[0]
+				jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+				assert.Equal(t, jsonName, yamlName, "field %v.%v", path, field.Name)
+				assertTags(t, field.Type, path+"."+field.Name)
+			}
+		}
+
+		assertTags(t, reflect.TypeOf(Params{}), "Params")
+	})
+
+	t.Run("UnmarshalsCanaryMaxReplicasFromJSON", func(t *testing.T) {
+
+		var params Params
+
+		// act
+		err := json.Unmarshal([]byte(`{"canary":{"minreplicas":"3","maxreplicas":"20"}}`), &params)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "3", params.Canary.MinReplicas)
+		assert.Equal(t, "20", params.Canary.MaxReplicas)
+	})
+}
@@ -6,6 +6,7 @@ const (
 	StrategyTypeRollingUpdate StrategyType = "RollingUpdate"
 	StrategyTypeRecreate      StrategyType = "Recreate"
 	StrategyTypeAtomicUpdate  StrategyType = "AtomicUpdate"
+	StrategyTypeBlueGreen     StrategyType = "BlueGreen"
 
 	StrategyTypeUnknown StrategyType = ""
 )
@@ -4,8 +4,11 @@ package api
 type TemplateData struct {
 	Name                            string
 	NameWithTrack                   string
+	JobName                         string
 	Namespace                       string
 	Schedule                        string
+	TimeZone                        string
+	Suspend                         *bool
 	RestartPolicy                   string
 	Completions                     int
 	Parallelism                     int
@@ -50,6 +53,7 @@ type TemplateData struct {
 	VpaUpdateMode                        string
 	PreferPreemptibles                   bool
 	UseWindowsNodes                      bool
+	UseArm64Nodes                        bool
 	Container                            ContainerData
 	Sidecars                             []SidecarData
 	HasCustomSidecars                    bool
@@ -134,6 +138,9 @@ type TemplateData struct {
 
 	IncludeAtomicIDSelector bool
 	AtomicID                string
+	UseBlueGreen            bool
+	BlueGreenColor          string
+	BlueGreenLiveColor      string
 	Canary                  CanaryParams
 }
 
@@ -151,6 +158,7 @@ type ContainerData struct {
 	Repository                      string
 	Name                            string
 	Tag                             string
+	Digest                          string
 	ImagePullPolicy                 string
 	CPURequest                      string
 	MemoryRequest                   string
@@ -167,6 +175,8 @@ type ContainerData struct {
 	PreStopSleepSeconds             int
 	ContainerSecurityContext        map[string]interface{}
 	ContainerLifeCycle              map[string]interface{}
+	Command                         []string
+	Args                            []string
 }
 
 // ProbeData has data specific to liveness and readiness probes
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// FindUnknownParams returns a message for every key in the parameters of a stage that doesn't match any parameter, with a suggestion if a known
+// parameter is spelled similarly
+func FindUnknownParams(paramsYAML []byte) (unknownParams []string, err error) {
+	var root yaml.MapSlice
+	err = yaml.Unmarshal(paramsYAML, &root)
+	if err != nil {
+		return nil, fmt.Errorf("Failed unmarshalling parameters: %w", err)
+	}
+
+	// the credentials parameter is resolved before the other parameters, see CredentialsParam
+	known := map[string]bool{"credentials": true}
+
+	return findUnknownKeys(reflect.TypeOf(Params{}), root, "", known), nil
+}
+
+func findUnknownKeys(t reflect.Type, value interface{}, path string, extraKeys map[string]bool) (unknownParams []string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, item := range items {
+			unknownParams = append(unknownParams, findUnknownKeys(t.Elem(), item, fmt.Sprintf("%v[%v]", path, i), nil)...)
+		}
+
+	case reflect.Struct:
+		mapSlice, ok := value.(yaml.MapSlice)
+		if !ok {
+			return nil
+		}
+
+		fields := map[string]reflect.StructField{}
+		inline := false
+		for i := 0; i < t.NumField(); i++ {
+			name, isInline := yamlFieldName(t.Field(i))
+			if isInline {
+				inline = true
+			} else if name != "" {
+				fields[name] = t.Field(i)
+			}
+		}
+
+		// the properties of a sidecar that aren't parameters end up in its container spec, so they have to be fields of a container
+		if inline && t == reflect.TypeOf(SidecarParams{}) {
+			extraKeys = containerFieldNames()
+		}
+
+		for _, item := range mapSlice {
+			key := fmt.Sprint(item.Key)
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			if field, ok := fields[key]; ok {
+				unknownParams = append(unknownParams, findUnknownKeys(field.Type, item.Value, keyPath, nil)...)
+				continue
+			}
+			if extraKeys[key] || inline && extraKeys == nil {
+				continue
+			}
+
+			candidates := []string{}
+			for name := range fields {
+				candidates = append(candidates, name)
+			}
+			for name := range extraKeys {
+				candidates = append(candidates, name)
+			}
+			if suggestion := suggestKey(key, candidates); suggestion != "" {
+				suggestionPath := suggestion
+				if path != "" {
+					suggestionPath = path + "." + suggestion
+				}
+				unknownParams = append(unknownParams, fmt.Sprintf("Unknown parameter %v; did you mean %v?", keyPath, suggestionPath))
+			} else {
+				unknownParams = append(unknownParams, fmt.Sprintf("Unknown parameter %v", keyPath))
+			}
+		}
+	}
+
+	// maps and free-form values take any key
+	return unknownParams
+}
+
+// containerFieldNames returns the fields of a kubernetes container spec
+func containerFieldNames() map[string]bool {
+	names := map[string]bool{}
+	t := reflect.TypeOf(corev1.Container{})
+	for i := 0; i < t.NumField(); i++ {
+		names[strings.Split(t.Field(i).Tag.Get("json"), ",")[0]] = true
+	}
+
+	return names
+}
+
+// suggestKey returns the candidate closest to key, ignoring case, or an empty string if none of them is close enough to be a likely misspelling
+func suggestKey(key string, candidates []string) (suggestion string) {
+	sort.Strings(candidates)
+
+	// allow more typos in longer keys
+	maxDistance := len(key) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	bestDistance := maxDistance + 1
+	for _, candidate := range candidates {
+		distance := editDistance(strings.ToLower(key), strings.ToLower(candidate))
+		if distance < bestDistance {
+			bestDistance = distance
+			suggestion = candidate
+		}
+	}
+
+	return suggestion
+}
+
+// editDistance returns the levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindUnknownParams(t *testing.T) {
+
+	t.Run("ReturnsNothingForKnownParams", func(t *testing.T) {
+
+		paramsYAML := `
+credentials: gke-production
+kind: deployment
+visibility: private
+container:
+  repository: extensions
+  secretEnv:
+    ANY_KEY: value
+autoscale:
+  min: 3
+  max: 10
+labels:
+  team: myteam
+hooks:
+  preDeploy:
+  - name: migrate
+    command: ["/migrate"]
+`
+
+		// act
+		unknownParams, err := FindUnknownParams([]byte(paramsYAML))
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(unknownParams))
+	})
+
+	t.Run("ReturnsMisspelledParamsWithFullPathAndSuggestion", func(t *testing.T) {
+
+		paramsYAML := `
+visiblity: private
+autoscale:
+  maxx: 10
+container:
+  secretenv:
+    KEY: value
+`
+
+		// act
+		unknownParams, err := FindUnknownParams([]byte(paramsYAML))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{
+			"Unknown parameter visiblity; did you mean visibility?",
+			"Unknown parameter autoscale.maxx; did you mean autoscale.max?",
+			"Unknown parameter container.secretenv; did you mean container.secretEnv?",
+		}, unknownParams)
+	})
+
+	t.Run("ReturnsUnknownParamWithoutSuggestionIfNothingIsSimilar", func(t *testing.T) {
+
+		paramsYAML := `
+container:
+  somethingelse: true
+`
+
+		// act
+		unknownParams, err := FindUnknownParams([]byte(paramsYAML))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"Unknown parameter container.somethingelse"}, unknownParams)
+	})
+
+	t.Run("ReturnsIndexOfListItemsInPath", func(t *testing.T) {
+
+		paramsYAML := `
+smoketests:
+  checks:
+  - path: /
+  - path: /readiness
+    expectedstatuss: 200
+`
+
+		// act
+		unknownParams, err := FindUnknownParams([]byte(paramsYAML))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"Unknown parameter smoketests.checks[1].expectedstatuss; did you mean smoketests.checks[1].expectedStatus?"}, unknownParams)
+	})
+
+	t.Run("AllowsContainerFieldsAsSidecarCustomProperties", func(t *testing.T) {
+
+		paramsYAML := `
+sidecars:
+- type: cloudsqlproxy
+  dbinstanceconnectionname: project:region:instance
+  command: ["/cloud_sql_proxy"]
+  volumeMounts:
+  - name: data
+    mountPath: /data
+sidecar:
+  type: openresty
+  args: ["--verbose"]
+`
+
+		// act
+		unknownParams, err := FindUnknownParams([]byte(paramsYAML))
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(unknownParams))
+	})
+
+	t.Run("ReturnsSidecarPropertiesThatAreNeitherParamsNorContainerFields", func(t *testing.T) {
+
+		paramsYAML := `
+sidecars:
+- type: openresty
+  healtcheckpath: /readiness
+  workindir: /app
+`
+
+		// act
+		unknownParams, err := FindUnknownParams([]byte(paramsYAML))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{
+			"Unknown parameter sidecars[0].healtcheckpath; did you mean sidecars[0].healthcheckpath?",
+			"Unknown parameter sidecars[0].workindir; did you mean sidecars[0].workingDir?",
+		}, unknownParams)
+	})
+
+	t.Run("ReturnsErrorForInvalidYAML", func(t *testing.T) {
+
+		// act
+		_, err := FindUnknownParams([]byte("kind: [deployment"))
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestEditDistance(t *testing.T) {
+
+	t.Run("ReturnsNumberOfInsertionsDeletionsAndSubstitutions", func(t *testing.T) {
+
+		assert.Equal(t, 0, editDistance("visibility", "visibility"))
+		assert.Equal(t, 1, editDistance("visiblity", "visibility"))
+		assert.Equal(t, 1, editDistance("maxx", "max"))
+		assert.Equal(t, 3, editDistance("kitten", "sitting"))
+		assert.Equal(t, 4, editDistance("", "kind"))
+	})
+}
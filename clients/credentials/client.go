@@ -16,6 +16,7 @@ import (
 //go:generate mockgen -package=credentials -destination ./mock.go -source=client.go
 type Client interface {
 	Init(ctx context.Context, paramsJSON, releaseName, credentialsPath string) (credential *api.GKECredentials, err error)
+	GetCredential(ctx context.Context, paramsJSON, releaseName, credentialsPath string) (credential *api.GKECredentials, err error)
 	GetCredentialsByName(c []api.GKECredentials, credentialName string) *api.GKECredentials
 }
 
@@ -28,6 +29,33 @@ type client struct {
 }
 
 func (c *client) Init(ctx context.Context, paramsJSON, releaseName, credentialsPath string) (credential *api.GKECredentials, err error) {
+	credential, err = c.GetCredential(ctx, paramsJSON, releaseName, credentialsPath)
+	if err != nil {
+		return
+	}
+
+	log.Info().Msgf("Storing gke credential %v on disk at path %v...", credential.Name, os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
+	err = ioutil.WriteFile(os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"), []byte(credential.AdditionalProperties.ServiceAccountKeyfile), 0666)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed writing service account keyfile")
+	}
+
+	err = c.authWithGcloud(ctx, os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to authenticate with credentials %v", os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
+	}
+
+	var serviceAccountKeyFile struct {
+		ClientEmail string `json:"client_email"`
+	}
+	json.Unmarshal([]byte(credential.AdditionalProperties.ServiceAccountKeyfile), &serviceAccountKeyFile)
+	log.Info().Msgf("Using service account %v...", serviceAccountKeyFile.ClientEmail)
+
+	return
+}
+
+// GetCredential resolves the credential from the injected credentials without authenticating with it, so it's usable without gcp access
+func (c *client) GetCredential(ctx context.Context, paramsJSON, releaseName, credentialsPath string) (credential *api.GKECredentials, err error) {
 	log.Info().Msg("Unmarshalling credentials parameter...")
 	var credentialsParam api.CredentialsParam
 	err = json.Unmarshal([]byte(paramsJSON), &credentialsParam)
@@ -74,23 +102,6 @@ func (c *client) Init(ctx context.Context, paramsJSON, releaseName, credentialsP
 		return nil, fmt.Errorf("Credential with name %v does not exist", credentialsParam.Credentials)
 	}
 
-	log.Info().Msgf("Storing gke credential %v on disk at path %v...", credentialsParam.Credentials, os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
-	err = ioutil.WriteFile(os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"), []byte(credential.AdditionalProperties.ServiceAccountKeyfile), 0666)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed writing service account keyfile")
-	}
-
-	err = c.authWithGcloud(ctx, os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
-	if err != nil {
-		return nil, fmt.Errorf("Failed to authenticate with credentials %v", os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
-	}
-
-	var serviceAccountKeyFile struct {
-		ClientEmail string `json:"client_email"`
-	}
-	json.Unmarshal([]byte(credential.AdditionalProperties.ServiceAccountKeyfile), &serviceAccountKeyFile)
-	log.Info().Msgf("Using service account %v...", serviceAccountKeyFile.ClientEmail)
-
 	return
 }
 
@@ -2,6 +2,8 @@ package credentials
 
 import (
 	"context"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	"github.com/estafette/estafette-extension-gke/api"
@@ -45,3 +47,34 @@ func TestGetCredentialsByName(t *testing.T) {
 		assert.Nil(t, credential)
 	})
 }
+
+func TestGetCredential(t *testing.T) {
+
+	t.Run("ReturnsCredentialFromFileWithoutAuthenticating", func(t *testing.T) {
+
+		client, err := NewClient(context.Background())
+		assert.Nil(t, err)
+
+		credentialsPath := filepath.Join(t.TempDir(), "kubernetes_engine.json")
+		err = ioutil.WriteFile(credentialsPath, []byte(`[{"name":"gke-production","type":"kubernetes-engine","additionalProperties":{"project":"myproject","cluster":"production"}}]`), 0600)
+		assert.Nil(t, err)
+
+		// act
+		credential, err := client.GetCredential(context.Background(), `{}`, "production", credentialsPath)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "gke-production", credential.Name)
+		assert.Equal(t, "production", credential.AdditionalProperties.Cluster)
+	})
+
+	t.Run("ReturnsErrorIfCredentialFileDoesNotExist", func(t *testing.T) {
+
+		client, err := NewClient(context.Background())
+		assert.Nil(t, err)
+
+		// act
+		_, err = client.GetCredential(context.Background(), `{}`, "production", filepath.Join(t.TempDir(), "kubernetes_engine.json"))
+
+		assert.NotNil(t, err)
+	})
+}
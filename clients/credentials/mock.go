@@ -35,6 +35,21 @@ func (m *MockClient) EXPECT() *MockClientMockRecorder {
 	return m.recorder
 }
 
+// GetCredential mocks base method.
+func (m *MockClient) GetCredential(ctx context.Context, paramsJSON, releaseName, credentialsPath string) (*api.GKECredentials, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCredential", ctx, paramsJSON, releaseName, credentialsPath)
+	ret0, _ := ret[0].(*api.GKECredentials)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCredential indicates an expected call of GetCredential.
+func (mr *MockClientMockRecorder) GetCredential(ctx, paramsJSON, releaseName, credentialsPath interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCredential", reflect.TypeOf((*MockClient)(nil).GetCredential), ctx, paramsJSON, releaseName, credentialsPath)
+}
+
 // GetCredentialsByName mocks base method.
 func (m *MockClient) GetCredentialsByName(c []api.GKECredentials, credentialName string) *api.GKECredentials {
 	m.ctrl.T.Helper()
@@ -0,0 +1,44 @@
+package kubernetes
+
+import "k8s.io/apimachinery/pkg/runtime/schema"
+
+const (
+	// ChangeApplied is recorded for resources that are created or updated
+	ChangeApplied = "applied"
+
+	// ChangeDeleted is recorded for resources that are deleted
+	ChangeDeleted = "deleted"
+
+	// ChangePatched is recorded for resources that are patched
+	ChangePatched = "patched"
+)
+
+// ResourceChange records a change made to a resource in the cluster; dry runs and deletes of resources that don't exist aren't recorded
+type ResourceChange struct {
+	Operation string `json:"operation"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// Changes returns the changes made to the cluster by this client, in the order they've been made
+func (c *client) Changes() []ResourceChange {
+	return c.changes
+}
+
+func (c *client) recordChange(operation, kind, namespace, name string) {
+	c.changes = append(c.changes, ResourceChange{
+		Operation: operation,
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+	})
+}
+
+func (c *client) recordResourceChange(operation string, resource schema.GroupVersionResource, namespace, name string) {
+	kind := kindForResource(resource)
+	if kind == "" {
+		kind = resource.Resource
+	}
+	c.recordChange(operation, kind, namespace, name)
+}
@@ -0,0 +1,1241 @@
+package kubernetes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// FieldManager is the field manager used for server-side apply
+	FieldManager = "estafette-extension-gke"
+)
+
+var (
+	// ErrNotInitialized is returned when the client is used before Init has been called
+	ErrNotInitialized = wrapError{msg: "The kubernetes client is not initialized"}
+
+	// ErrResourceNotFound is returned when a resource does not exist in the cluster
+	ErrResourceNotFound = wrapError{msg: "The resource is not found"}
+
+	// ErrInvalidManifest is returned when a rendered manifest can't be decoded or lacks required fields
+	ErrInvalidManifest = wrapError{msg: "The manifest is invalid"}
+
+	// ErrUnknownResourceKind is returned when the cluster doesn't serve the kind used in a manifest
+	ErrUnknownResourceKind = wrapError{msg: "The resource kind is unknown to the cluster"}
+
+	// ErrRolloutFailed is returned when a rollout won't finish successfully, for example because its progress deadline is exceeded
+	ErrRolloutFailed = wrapError{msg: "The rollout failed"}
+
+	// ErrRolloutTimedOut is returned when a rollout doesn't finish before the deadline of the context or its progress deadline
+	ErrRolloutTimedOut = wrapError{msg: "The rollout timed out"}
+
+	// ErrJobFailed is returned when a job reaches its backoff limit or active deadline without completing
+	ErrJobFailed = wrapError{msg: "The job failed"}
+
+	// ErrPodFailed is returned when a pod run to completion exits with an error or doesn't finish in time
+	ErrPodFailed = wrapError{msg: "The pod failed"}
+
+	// ErrLeaseHeld is returned when a lease is held by another holder
+	ErrLeaseHeld = wrapError{msg: "The lease is held by another release"}
+
+	// ErrForbidden is returned when the credentials don't allow an operation on a resource
+	ErrForbidden = wrapError{msg: "The operation is forbidden"}
+)
+
+var (
+	ResourceServices                 = schema.GroupVersionResource{Version: "v1", Resource: "services"}
+	ResourceConfigMaps               = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	ResourceSecrets                  = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	ResourceServiceAccounts          = schema.GroupVersionResource{Version: "v1", Resource: "serviceaccounts"}
+	ResourcePods                     = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	ResourceEndpoints                = schema.GroupVersionResource{Version: "v1", Resource: "endpoints"}
+	ResourceDeployments              = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	ResourceStatefulSets             = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}
+	ResourceJobs                     = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"}
+	ResourceCronJobs                 = schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "cronjobs"}
+	ResourceIngresses                = schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"}
+	ResourceHorizontalPodAutoscalers = schema.GroupVersionResource{Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"}
+	ResourcePodDisruptionBudgets     = schema.GroupVersionResource{Group: "policy", Version: "v1", Resource: "poddisruptionbudgets"}
+	ResourceBackendConfigs           = schema.GroupVersionResource{Group: "cloud.google.com", Version: "v1", Resource: "backendconfigs"}
+	ResourceVerticalPodAutoscalers   = schema.GroupVersionResource{Group: "autoscaling.k8s.io", Version: "v1", Resource: "verticalpodautoscalers"}
+)
+
+//go:generate mockgen -package=kubernetes -destination ./mock.go -source=client.go
+type Client interface {
+	Init(ctx context.Context, kubeContextName string) (err error)
+	ValidateManifests(ctx context.Context, namespace string, manifests []byte) (err error)
+	ApplyManifests(ctx context.Context, namespace string, manifests []byte, dryRun bool) (err error)
+	DiffManifests(ctx context.Context, namespace string, manifests []byte) (diffs []ResourceDiff, err error)
+	DeleteResource(ctx context.Context, resource schema.GroupVersionResource, namespace, name string) (err error)
+	DeleteResources(ctx context.Context, resources []schema.GroupVersionResource, namespace, labelSelector string, dryRun bool) (err error)
+	ListResources(ctx context.Context, resources []schema.GroupVersionResource, namespace, labelSelector string) (items []unstructured.Unstructured, err error)
+	PatchResource(ctx context.Context, resource schema.GroupVersionResource, namespace, name string, patchType types.PatchType, patch []byte) (err error)
+	RemoveAnnotations(ctx context.Context, resource schema.GroupVersionResource, namespace, name string, annotations ...string) (err error)
+	WaitForDeletion(ctx context.Context, resource schema.GroupVersionResource, namespace, name string, timeout time.Duration) (err error)
+	GetDeployment(ctx context.Context, namespace, name string) (deployment *appsv1.Deployment, err error)
+	ListDeployments(ctx context.Context, namespace, labelSelector string) (deployments []appsv1.Deployment, err error)
+	GetService(ctx context.Context, namespace, name string) (service *corev1.Service, err error)
+	GetIngress(ctx context.Context, namespace, name string) (ingress *networkingv1.Ingress, err error)
+	GetPodDisruptionBudget(ctx context.Context, namespace, name string) (pdb *policyv1.PodDisruptionBudget, err error)
+	GetConfigMap(ctx context.Context, namespace, name string) (configMap *corev1.ConfigMap, err error)
+	GetSecret(ctx context.Context, namespace, name string) (secret *corev1.Secret, err error)
+	CreateOrUpdateConfigMap(ctx context.Context, configMap *corev1.ConfigMap) (err error)
+	CreateOrUpdateSecret(ctx context.Context, secret *corev1.Secret) (err error)
+	ListDeploymentReplicaSets(ctx context.Context, namespace, name string) (replicaSets []appsv1.ReplicaSet, err error)
+	RollbackDeployment(ctx context.Context, namespace, name string, replicaSet appsv1.ReplicaSet) (err error)
+	ListStatefulSetRevisions(ctx context.Context, namespace, name string) (revisions []appsv1.ControllerRevision, err error)
+	RollbackStatefulSet(ctx context.Context, namespace, name string, revision appsv1.ControllerRevision) (err error)
+	RestartDeployment(ctx context.Context, namespace, name string) (err error)
+	WaitForDeploymentRollout(ctx context.Context, namespace, name string) (err error)
+	WaitForStatefulSetRollout(ctx context.Context, namespace, name string) (err error)
+	WaitForJobCompletion(ctx context.Context, namespace, name string, timeout time.Duration) (err error)
+	SuspendCronJob(ctx context.Context, namespace, name string, suspend bool) (err error)
+	TriggerCronJob(ctx context.Context, namespace, name string) (jobName string, err error)
+	GetPodLogs(ctx context.Context, namespace, labelSelector, container string, tailLines int64) (logs string, err error)
+	GetPreviousContainerLogs(ctx context.Context, namespace, pod, container string, tailLines int64) (logs string, err error)
+	ListPods(ctx context.Context, namespace, labelSelector string) (pods []corev1.Pod, err error)
+	ListEvents(ctx context.Context, namespace, kind, name string) (events []corev1.Event, err error)
+	FollowPodLogs(ctx context.Context, namespace, labelSelector, container string, done <-chan struct{}) (err error)
+	RunPod(ctx context.Context, pod *corev1.Pod, timeout time.Duration) (logs string, err error)
+	AcquireLease(ctx context.Context, namespace, name string, holder LeaseHolder, duration time.Duration) (current LeaseHolder, err error)
+	RenewLease(ctx context.Context, namespace, name, identity string) (err error)
+	ReleaseLease(ctx context.Context, namespace, name, identity string) (err error)
+	Changes() []ResourceChange
+}
+
+// NewClient returns a new kubernetes.Client; it needs to be initialized with Init once the kube config for the cluster is available
+func NewClient(ctx context.Context) (Client, error) {
+	return &client{
+		pollInterval: 2 * time.Second,
+	}, nil
+}
+
+// NewClientWithClientsets returns an initialized kubernetes.Client for the passed in clientsets, which can be fakes in unit tests
+func NewClientWithClientsets(kubeClientset k8s.Interface, dynamicClient dynamic.Interface, mapper meta.RESTMapper) Client {
+	return &client{
+		kubeClientset: kubeClientset,
+		dynamicClient: dynamicClient,
+		mapper:        mapper,
+		pollInterval:  10 * time.Millisecond,
+	}
+}
+
+type client struct {
+	kubeClientset k8s.Interface
+	dynamicClient dynamic.Interface
+	mapper        meta.RESTMapper
+	pollInterval  time.Duration
+	changes       []ResourceChange
+}
+
+func (c *client) Init(ctx context.Context, kubeContextName string) (err error) {
+
+	log.Info().Msgf("Creating kubernetes clients for context %v", kubeContextName)
+
+	// KUBECONFIG is honoured by the default loading rules
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{CurrentContext: kubeContextName}).ClientConfig()
+	if err != nil {
+		return fmt.Errorf("Can't load kube config for context %v: %w", kubeContextName, err)
+	}
+
+	c.kubeClientset, err = k8s.NewForConfig(config)
+	if err != nil {
+		return
+	}
+
+	c.dynamicClient, err = dynamic.NewForConfig(config)
+	if err != nil {
+		return
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return
+	}
+	c.mapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+
+	return nil
+}
+
+// ValidateManifests performs a server-side dry run of applying the manifests, so schema validation, admission webhooks and quota reject them before
+// any of them gets applied; a dry run doesn't create a new namespace, so the resources in a namespace that doesn't exist yet only get their kind checked
+func (c *client) ValidateManifests(ctx context.Context, namespace string, manifests []byte) (err error) {
+	if c.dynamicClient == nil {
+		return ErrNotInitialized
+	}
+
+	objects, err := DecodeManifests(manifests)
+	if err != nil {
+		return
+	}
+
+	for _, obj := range objects {
+		_, err = c.applyObject(ctx, namespace, obj, true)
+		if isNamespaceNotFound(err) {
+			log.Info().Msgf("Namespace of %v/%v doesn't exist yet, skipping its server dry run", strings.ToLower(obj.GetKind()), obj.GetName())
+			continue
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	return nil
+}
+
+func (c *client) ApplyManifests(ctx context.Context, namespace string, manifests []byte, dryRun bool) (err error) {
+	if c.dynamicClient == nil {
+		return ErrNotInitialized
+	}
+
+	objects, err := DecodeManifests(manifests)
+	if err != nil {
+		return
+	}
+
+	for _, obj := range objects {
+		applied, err := c.applyObject(ctx, namespace, obj, dryRun)
+		if err != nil {
+			return err
+		}
+
+		suffix := ""
+		if dryRun {
+			suffix = " (server dry run)"
+		} else {
+			c.recordChange(ChangeApplied, applied.GetKind(), applied.GetNamespace(), applied.GetName())
+		}
+		log.Info().Msgf("%v/%v applied%v", strings.ToLower(applied.GetKind()), applied.GetName(), suffix)
+	}
+
+	return nil
+}
+
+func (c *client) DiffManifests(ctx context.Context, namespace string, manifests []byte) (diffs []ResourceDiff, err error) {
+	if c.dynamicClient == nil {
+		return nil, ErrNotInitialized
+	}
+
+	objects, err := DecodeManifests(manifests)
+	if err != nil {
+		return
+	}
+
+	for _, obj := range objects {
+		mapping, err := c.getMapping(obj)
+		if err != nil {
+			return diffs, err
+		}
+
+		diff := ResourceDiff{
+			Kind:      obj.GetKind(),
+			Namespace: c.getNamespace(mapping, namespace, obj),
+			Name:      obj.GetName(),
+		}
+
+		live, err := c.getResourceInterface(mapping, diff.Namespace).Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return diffs, fmt.Errorf("Can't get %v %v: %w", strings.ToLower(diff.Kind), diff.Name, err)
+		}
+		if err == nil {
+			diff.Live = live
+		}
+
+		merged, err := c.applyObject(ctx, namespace, obj, true)
+		if err != nil {
+			return diffs, err
+		}
+		diff.Merged = merged
+
+		diffs = append(diffs, diff)
+	}
+
+	return diffs, nil
+}
+
+func (c *client) DeleteResource(ctx context.Context, resource schema.GroupVersionResource, namespace, name string) (err error) {
+	if c.dynamicClient == nil {
+		return ErrNotInitialized
+	}
+
+	propagationPolicy := metav1.DeletePropagationBackground
+	err = c.dynamicClient.Resource(resource).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagationPolicy})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Can't delete %v %v in namespace %v: %w", resource.Resource, name, namespace, err)
+	}
+
+	c.recordResourceChange(ChangeDeleted, resource, namespace, name)
+	log.Info().Msgf("%v %v deleted", resource.Resource, name)
+
+	return nil
+}
+
+func (c *client) DeleteResources(ctx context.Context, resources []schema.GroupVersionResource, namespace, labelSelector string, dryRun bool) (err error) {
+	if c.dynamicClient == nil {
+		return ErrNotInitialized
+	}
+
+	deleteOptions := metav1.DeleteOptions{}
+	propagationPolicy := metav1.DeletePropagationBackground
+	deleteOptions.PropagationPolicy = &propagationPolicy
+	suffix := ""
+	if dryRun {
+		deleteOptions.DryRun = []string{metav1.DryRunAll}
+		suffix = " (server dry run)"
+	}
+
+	for _, r := range resources {
+		list, err := c.dynamicClient.Resource(r).Namespace(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+		if apierrors.IsNotFound(err) {
+			// the resource type isn't served by this cluster, for example a crd that's not installed
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("Can't list %v in namespace %v: %w", r.Resource, namespace, err)
+		}
+
+		for _, item := range list.Items {
+			err = c.dynamicClient.Resource(r).Namespace(namespace).Delete(ctx, item.GetName(), deleteOptions)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("Can't delete %v %v in namespace %v: %w", r.Resource, item.GetName(), namespace, err)
+			}
+			if !dryRun {
+				c.recordResourceChange(ChangeDeleted, r, namespace, item.GetName())
+			}
+
+			log.Info().Msgf("%v %v deleted%v", r.Resource, item.GetName(), suffix)
+		}
+	}
+
+	return nil
+}
+
+func (c *client) ListResources(ctx context.Context, resources []schema.GroupVersionResource, namespace, labelSelector string) (items []unstructured.Unstructured, err error) {
+	if c.dynamicClient == nil {
+		return nil, ErrNotInitialized
+	}
+
+	for _, r := range resources {
+		list, err := c.dynamicClient.Resource(r).Namespace(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+		if apierrors.IsNotFound(err) {
+			// the resource type isn't served by this cluster, for example a crd that's not installed
+			continue
+		}
+		if err != nil {
+			return items, fmt.Errorf("Can't list %v in namespace %v: %w", r.Resource, namespace, err)
+		}
+		for _, item := range list.Items {
+			// the list kind isn't always set on items returned by a list call
+			if item.GetKind() == "" || item.GetAPIVersion() == "" {
+				item.SetAPIVersion(r.GroupVersion().String())
+				item.SetKind(kindForResource(r))
+			}
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+func (c *client) PatchResource(ctx context.Context, resource schema.GroupVersionResource, namespace, name string, patchType types.PatchType, patch []byte) (err error) {
+	if c.dynamicClient == nil {
+		return ErrNotInitialized
+	}
+
+	_, err = c.dynamicClient.Resource(resource).Namespace(namespace).Patch(ctx, name, patchType, patch, metav1.PatchOptions{FieldManager: FieldManager})
+	if apierrors.IsNotFound(err) {
+		return ErrResourceNotFound.wrap(err)
+	}
+	if err != nil {
+		return fmt.Errorf("Can't patch %v %v in namespace %v: %w", resource.Resource, name, namespace, err)
+	}
+
+	c.recordResourceChange(ChangePatched, resource, namespace, name)
+	log.Info().Msgf("%v %v patched", resource.Resource, name)
+
+	return nil
+}
+
+func (c *client) RemoveAnnotations(ctx context.Context, resource schema.GroupVersionResource, namespace, name string, annotations ...string) (err error) {
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	// a json merge patch with null values removes the keys
+	annotationsPatch := map[string]interface{}{}
+	for _, a := range annotations {
+		annotationsPatch[a] = nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotationsPatch,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	err = c.PatchResource(ctx, resource, namespace, name, types.MergePatchType, patch)
+	if errors.Is(err, ErrResourceNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+func (c *client) WaitForDeletion(ctx context.Context, resource schema.GroupVersionResource, namespace, name string, timeout time.Duration) (err error) {
+	if c.dynamicClient == nil {
+		return ErrNotInitialized
+	}
+
+	return wait.PollUntilContextTimeout(ctx, c.pollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		_, err := c.dynamicClient.Resource(resource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+func (c *client) GetDeployment(ctx context.Context, namespace, name string) (deployment *appsv1.Deployment, err error) {
+	if c.kubeClientset == nil {
+		return nil, ErrNotInitialized
+	}
+
+	deployment, err = c.kubeClientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, ErrResourceNotFound.wrap(err)
+	}
+
+	return
+}
+
+func (c *client) ListDeployments(ctx context.Context, namespace, labelSelector string) (deployments []appsv1.Deployment, err error) {
+	if c.kubeClientset == nil {
+		return nil, ErrNotInitialized
+	}
+
+	list, err := c.kubeClientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return
+	}
+
+	return list.Items, nil
+}
+
+func (c *client) GetService(ctx context.Context, namespace, name string) (service *corev1.Service, err error) {
+	if c.kubeClientset == nil {
+		return nil, ErrNotInitialized
+	}
+
+	service, err = c.kubeClientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, ErrResourceNotFound.wrap(err)
+	}
+
+	return
+}
+
+func (c *client) GetIngress(ctx context.Context, namespace, name string) (ingress *networkingv1.Ingress, err error) {
+	if c.kubeClientset == nil {
+		return nil, ErrNotInitialized
+	}
+
+	ingress, err = c.kubeClientset.NetworkingV1().Ingresses(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, ErrResourceNotFound.wrap(err)
+	}
+
+	return
+}
+
+func (c *client) GetPodDisruptionBudget(ctx context.Context, namespace, name string) (pdb *policyv1.PodDisruptionBudget, err error) {
+	if c.kubeClientset == nil {
+		return nil, ErrNotInitialized
+	}
+
+	pdb, err = c.kubeClientset.PolicyV1().PodDisruptionBudgets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, ErrResourceNotFound.wrap(err)
+	}
+
+	return
+}
+
+func (c *client) GetConfigMap(ctx context.Context, namespace, name string) (configMap *corev1.ConfigMap, err error) {
+	if c.kubeClientset == nil {
+		return nil, ErrNotInitialized
+	}
+
+	configMap, err = c.kubeClientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, ErrResourceNotFound.wrap(err)
+	}
+
+	return
+}
+
+func (c *client) GetSecret(ctx context.Context, namespace, name string) (secret *corev1.Secret, err error) {
+	if c.kubeClientset == nil {
+		return nil, ErrNotInitialized
+	}
+
+	secret, err = c.kubeClientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, ErrResourceNotFound.wrap(err)
+	}
+
+	return
+}
+
+func (c *client) CreateOrUpdateConfigMap(ctx context.Context, configMap *corev1.ConfigMap) (err error) {
+	if c.kubeClientset == nil {
+		return ErrNotInitialized
+	}
+
+	configMaps := c.kubeClientset.CoreV1().ConfigMaps(configMap.Namespace)
+	_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{FieldManager: FieldManager})
+	if apierrors.IsAlreadyExists(err) {
+		var existing *corev1.ConfigMap
+		existing, err = configMaps.Get(ctx, configMap.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("Can't get configmap %v in namespace %v: %w", configMap.Name, configMap.Namespace, err)
+		}
+		configMap.ResourceVersion = existing.ResourceVersion
+		_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{FieldManager: FieldManager})
+	}
+	if err != nil {
+		return fmt.Errorf("Can't create or update configmap %v in namespace %v: %w", configMap.Name, configMap.Namespace, err)
+	}
+	c.recordResourceChange(ChangeApplied, ResourceConfigMaps, configMap.Namespace, configMap.Name)
+
+	return nil
+}
+
+func (c *client) CreateOrUpdateSecret(ctx context.Context, secret *corev1.Secret) (err error) {
+	if c.kubeClientset == nil {
+		return ErrNotInitialized
+	}
+
+	secrets := c.kubeClientset.CoreV1().Secrets(secret.Namespace)
+	_, err = secrets.Create(ctx, secret, metav1.CreateOptions{FieldManager: FieldManager})
+	if apierrors.IsAlreadyExists(err) {
+		var existing *corev1.Secret
+		existing, err = secrets.Get(ctx, secret.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("Can't get secret %v in namespace %v: %w", secret.Name, secret.Namespace, err)
+		}
+		secret.ResourceVersion = existing.ResourceVersion
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{FieldManager: FieldManager})
+	}
+	if err != nil {
+		return fmt.Errorf("Can't create or update secret %v in namespace %v: %w", secret.Name, secret.Namespace, err)
+	}
+	c.recordResourceChange(ChangeApplied, ResourceSecrets, secret.Namespace, secret.Name)
+
+	return nil
+}
+
+// ListDeploymentReplicaSets returns the replicasets owned by a deployment, ordered by revision
+func (c *client) ListDeploymentReplicaSets(ctx context.Context, namespace, name string) (replicaSets []appsv1.ReplicaSet, err error) {
+	if c.kubeClientset == nil {
+		return nil, ErrNotInitialized
+	}
+
+	deployment, err := c.GetDeployment(ctx, namespace, name)
+	if err != nil {
+		return
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse selector of deployment %v in namespace %v: %w", name, namespace, err)
+	}
+
+	list, err := c.kubeClientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("Can't list replicasets for deployment %v in namespace %v: %w", name, namespace, err)
+	}
+
+	for _, rs := range list.Items {
+		if controllerRef := metav1.GetControllerOf(&rs); controllerRef != nil && controllerRef.UID == deployment.UID {
+			replicaSets = append(replicaSets, rs)
+		}
+	}
+
+	sort.Slice(replicaSets, func(i, j int) bool {
+		return Revision(&replicaSets[i]) < Revision(&replicaSets[j])
+	})
+
+	return replicaSets, nil
+}
+
+// RollbackDeployment replaces the pod template of a deployment with the one of a previous replicaset, like kubectl rollout undo does
+func (c *client) RollbackDeployment(ctx context.Context, namespace, name string, replicaSet appsv1.ReplicaSet) (err error) {
+	if c.kubeClientset == nil {
+		return ErrNotInitialized
+	}
+
+	template := replicaSet.Spec.Template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+
+	patch, err := json.Marshal([]map[string]interface{}{
+		{"op": "replace", "path": "/spec/template", "value": template},
+	})
+	if err != nil {
+		return
+	}
+
+	_, err = c.kubeClientset.AppsV1().Deployments(namespace).Patch(ctx, name, types.JSONPatchType, patch, metav1.PatchOptions{FieldManager: FieldManager})
+	if apierrors.IsNotFound(err) {
+		return ErrResourceNotFound.wrap(err)
+	}
+	if err != nil {
+		return fmt.Errorf("Can't roll back deployment %v in namespace %v: %w", name, namespace, err)
+	}
+
+	c.recordResourceChange(ChangePatched, ResourceDeployments, namespace, name)
+	log.Info().Msgf("deployment %v rolled back to revision %v", name, Revision(&replicaSet))
+
+	return nil
+}
+
+// ListStatefulSetRevisions returns the controller revisions of a statefulset, oldest first; the last one is the current revision
+func (c *client) ListStatefulSetRevisions(ctx context.Context, namespace, name string) (revisions []appsv1.ControllerRevision, err error) {
+	if c.kubeClientset == nil {
+		return nil, ErrNotInitialized
+	}
+
+	statefulset, err := c.kubeClientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, ErrResourceNotFound.wrap(err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Can't get statefulset %v in namespace %v: %w", name, namespace, err)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(statefulset.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse selector of statefulset %v in namespace %v: %w", name, namespace, err)
+	}
+
+	list, err := c.kubeClientset.AppsV1().ControllerRevisions(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("Can't list controller revisions for statefulset %v in namespace %v: %w", name, namespace, err)
+	}
+
+	for _, revision := range list.Items {
+		if controllerRef := metav1.GetControllerOf(&revision); controllerRef != nil && controllerRef.UID == statefulset.UID {
+			revisions = append(revisions, revision)
+		}
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+
+	return revisions, nil
+}
+
+// RollbackStatefulSet restores the pod template of a statefulset from a previous controller revision, like kubectl rollout undo does; since a statefulset
+// doesn't replace pods that never become ready, pods of other revisions that aren't ready are deleted so they're recreated from the restored template
+func (c *client) RollbackStatefulSet(ctx context.Context, namespace, name string, revision appsv1.ControllerRevision) (err error) {
+	if c.kubeClientset == nil {
+		return ErrNotInitialized
+	}
+
+	// the data of a statefulset revision is a strategic merge patch replacing the pod template
+	statefulset, err := c.kubeClientset.AppsV1().StatefulSets(namespace).Patch(ctx, name, types.StrategicMergePatchType, revision.Data.Raw, metav1.PatchOptions{FieldManager: FieldManager})
+	if apierrors.IsNotFound(err) {
+		return ErrResourceNotFound.wrap(err)
+	}
+	if err != nil {
+		return fmt.Errorf("Can't roll back statefulset %v in namespace %v: %w", name, namespace, err)
+	}
+
+	c.recordResourceChange(ChangePatched, ResourceStatefulSets, namespace, name)
+	log.Info().Msgf("statefulset %v rolled back to revision %v", name, revision.Revision)
+
+	selector, err := metav1.LabelSelectorAsSelector(statefulset.Spec.Selector)
+	if err != nil {
+		return fmt.Errorf("Can't parse selector of statefulset %v in namespace %v: %w", name, namespace, err)
+	}
+	pods, err := c.kubeClientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return fmt.Errorf("Can't list pods of statefulset %v in namespace %v: %w", name, namespace, err)
+	}
+	for _, pod := range pods.Items {
+		if pod.Labels[appsv1.ControllerRevisionHashLabelKey] == revision.Name || podReady(pod) {
+			continue
+		}
+		log.Info().Msgf("Deleting pod %v of statefulset %v, it isn't ready and doesn't run revision %v...", pod.Name, name, revision.Revision)
+		err = c.kubeClientset.CoreV1().Pods(namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("Can't delete pod %v of statefulset %v in namespace %v: %w", pod.Name, name, namespace, err)
+		}
+	}
+
+	return nil
+}
+
+func podReady(pod corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func (c *client) RestartDeployment(ctx context.Context, namespace, name string) (err error) {
+	if c.kubeClientset == nil {
+		return ErrNotInitialized
+	}
+
+	// same approach as kubectl rollout restart, changing the pod template triggers a new rollout
+	patch := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":"%v"}}}}}`, time.Now().UTC().Format(time.RFC3339))
+	_, err = c.kubeClientset.AppsV1().Deployments(namespace).Patch(ctx, name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{FieldManager: FieldManager})
+	if apierrors.IsNotFound(err) {
+		return ErrResourceNotFound.wrap(err)
+	}
+	if err != nil {
+		return fmt.Errorf("Can't restart deployment %v in namespace %v: %w", name, namespace, err)
+	}
+
+	c.recordResourceChange(ChangePatched, ResourceDeployments, namespace, name)
+	log.Info().Msgf("deployment %v restarted", name)
+
+	return nil
+}
+
+func (c *client) WaitForDeploymentRollout(ctx context.Context, namespace, name string) (err error) {
+	if c.kubeClientset == nil {
+		return ErrNotInitialized
+	}
+
+	lastStatus := ""
+	err = wait.PollUntilContextCancel(ctx, c.pollInterval, true, func(ctx context.Context) (bool, error) {
+		deployment, err := c.kubeClientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, fmt.Errorf("Can't get deployment %v in namespace %v: %w", name, namespace, err)
+		}
+
+		status, done, err := deploymentRolloutStatus(deployment)
+		if status != lastStatus {
+			log.Info().Msg(status)
+			lastStatus = status
+		}
+
+		return done, err
+	})
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrRolloutTimedOut.wrap(fmt.Errorf("deployment %v didn't finish rolling out before the deadline", name))
+	}
+
+	return err
+}
+
+func (c *client) WaitForStatefulSetRollout(ctx context.Context, namespace, name string) (err error) {
+	if c.kubeClientset == nil {
+		return ErrNotInitialized
+	}
+
+	lastStatus := ""
+	err = wait.PollUntilContextCancel(ctx, c.pollInterval, true, func(ctx context.Context) (bool, error) {
+		statefulset, err := c.kubeClientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, fmt.Errorf("Can't get statefulset %v in namespace %v: %w", name, namespace, err)
+		}
+
+		status, done, err := statefulSetRolloutStatus(statefulset)
+		if status != lastStatus {
+			log.Info().Msg(status)
+			lastStatus = status
+		}
+
+		return done, err
+	})
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrRolloutTimedOut.wrap(fmt.Errorf("statefulset %v didn't finish rolling out before the deadline", name))
+	}
+
+	return err
+}
+
+func (c *client) WaitForJobCompletion(ctx context.Context, namespace, name string, timeout time.Duration) (err error) {
+	if c.kubeClientset == nil {
+		return ErrNotInitialized
+	}
+
+	lastStatus := ""
+	err = wait.PollUntilContextTimeout(ctx, c.pollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		job, err := c.kubeClientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, fmt.Errorf("Can't get job %v in namespace %v: %w", name, namespace, err)
+		}
+
+		status, done, err := jobCompletionStatus(job)
+		if status != lastStatus {
+			log.Info().Msg(status)
+			lastStatus = status
+		}
+
+		return done, err
+	})
+	if ctx.Err() != nil {
+		// the release got cancelled, so the job didn't run out of time
+		return ctx.Err()
+	}
+	if wait.Interrupted(err) {
+		return ErrJobFailed.wrap(fmt.Errorf("job %v didn't complete within %v", name, timeout))
+	}
+
+	return err
+}
+
+func (c *client) GetPodLogs(ctx context.Context, namespace, labelSelector, container string, tailLines int64) (logs string, err error) {
+	if c.kubeClientset == nil {
+		return "", ErrNotInitialized
+	}
+
+	pods, err := c.kubeClientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return "", fmt.Errorf("Can't list pods for selector %v in namespace %v: %w", labelSelector, namespace, err)
+	}
+
+	var sb strings.Builder
+	for _, pod := range pods.Items {
+		containers := []string{}
+		if container != "" {
+			containers = append(containers, container)
+		} else {
+			for _, c := range pod.Spec.InitContainers {
+				containers = append(containers, c.Name)
+			}
+			for _, c := range pod.Spec.Containers {
+				containers = append(containers, c.Name)
+			}
+		}
+
+		for _, cn := range containers {
+			logOptions := &corev1.PodLogOptions{Container: cn}
+			if tailLines > 0 {
+				logOptions.TailLines = &tailLines
+			}
+
+			data, err := c.kubeClientset.CoreV1().Pods(namespace).GetLogs(pod.Name, logOptions).DoRaw(ctx)
+			if err != nil {
+				// containers that haven't started don't have logs yet
+				sb.WriteString(fmt.Sprintf("==> %v/%v <==\nFailed retrieving logs: %v\n", pod.Name, cn, err))
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("==> %v/%v <==\n", pod.Name, cn))
+			sb.Write(data)
+			if len(data) > 0 && data[len(data)-1] != '\n' {
+				sb.WriteString("\n")
+			}
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// GetPreviousContainerLogs returns the logs of the previous, terminated instance of a container, like kubectl logs --previous
+func (c *client) GetPreviousContainerLogs(ctx context.Context, namespace, pod, container string, tailLines int64) (logs string, err error) {
+	if c.kubeClientset == nil {
+		return "", ErrNotInitialized
+	}
+
+	logOptions := &corev1.PodLogOptions{Container: container, Previous: true}
+	if tailLines > 0 {
+		logOptions.TailLines = &tailLines
+	}
+
+	data, err := c.kubeClientset.CoreV1().Pods(namespace).GetLogs(pod, logOptions).DoRaw(ctx)
+	if err != nil {
+		return "", fmt.Errorf("Can't get previous logs of container %v in pod %v in namespace %v: %w", container, pod, namespace, err)
+	}
+
+	return string(data), nil
+}
+
+func (c *client) ListPods(ctx context.Context, namespace, labelSelector string) (pods []corev1.Pod, err error) {
+	if c.kubeClientset == nil {
+		return nil, ErrNotInitialized
+	}
+
+	list, err := c.kubeClientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("Can't list pods for selector %v in namespace %v: %w", labelSelector, namespace, err)
+	}
+
+	return list.Items, nil
+}
+
+// ListEvents returns the events for a single object, oldest first
+func (c *client) ListEvents(ctx context.Context, namespace, kind, name string) (events []corev1.Event, err error) {
+	if c.kubeClientset == nil {
+		return nil, ErrNotInitialized
+	}
+
+	fieldSelector := fmt.Sprintf("involvedObject.kind=%v,involvedObject.name=%v", kind, name)
+	list, err := c.kubeClientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{FieldSelector: fieldSelector})
+	if err != nil {
+		return nil, fmt.Errorf("Can't list events for %v %v in namespace %v: %w", kind, name, namespace, err)
+	}
+
+	events = list.Items
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(events[i]).Before(eventTime(events[j]))
+	})
+
+	return events, nil
+}
+
+func eventTime(event corev1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// FollowPodLogs logs the output of each pod matching the label selector as soon as it's started; once done is closed it follows the pods it
+// hasn't followed yet and returns after their logs have ended, while cancelling the context stops following right away
+func (c *client) FollowPodLogs(ctx context.Context, namespace, labelSelector, container string, done <-chan struct{}) (err error) {
+	if c.kubeClientset == nil {
+		return ErrNotInitialized
+	}
+
+	followed := map[string]bool{}
+	for {
+		// check before listing, so pods that started just before done got closed are still followed
+		finished := false
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+
+		pods, err := c.kubeClientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("Can't list pods for selector %v in namespace %v: %w", labelSelector, namespace, err)
+		}
+
+		for _, pod := range pods.Items {
+			// pods of a previous run of the job might still be terminating
+			if followed[pod.Name] || pod.Status.Phase == corev1.PodPending || pod.DeletionTimestamp != nil {
+				continue
+			}
+			followed[pod.Name] = true
+
+			stream, err := c.kubeClientset.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Container: container, Follow: true}).Stream(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				log.Warn().Err(err).Msgf("Failed following logs of pod %v", pod.Name)
+				continue
+			}
+
+			scanner := bufio.NewScanner(stream)
+			for scanner.Scan() {
+				log.Info().Msgf("%v: %v", pod.Name, scanner.Text())
+			}
+			stream.Close()
+		}
+
+		if finished {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-done:
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+// RunPod creates a pod, waits for it to run to completion and returns its logs; the pod is removed afterwards
+func (c *client) RunPod(ctx context.Context, pod *corev1.Pod, timeout time.Duration) (logs string, err error) {
+	if c.kubeClientset == nil {
+		return "", ErrNotInitialized
+	}
+
+	pods := c.kubeClientset.CoreV1().Pods(pod.Namespace)
+
+	// a pod of a previous run might still be around if the extension got interrupted
+	err = pods.Delete(ctx, pod.Name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return "", fmt.Errorf("Can't delete previous pod %v in namespace %v: %w", pod.Name, pod.Namespace, err)
+	}
+	err = wait.PollUntilContextTimeout(ctx, c.pollInterval, time.Minute, true, func(ctx context.Context) (bool, error) {
+		_, err := pods.Get(ctx, pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return "", fmt.Errorf("Failed waiting for previous pod %v in namespace %v to be deleted: %w", pod.Name, pod.Namespace, err)
+	}
+
+	_, err = pods.Create(ctx, pod, metav1.CreateOptions{FieldManager: FieldManager})
+	if err != nil {
+		return "", fmt.Errorf("Can't create pod %v in namespace %v: %w", pod.Name, pod.Namespace, err)
+	}
+	defer func() {
+		deleteErr := pods.Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+		if deleteErr != nil && !apierrors.IsNotFound(deleteErr) {
+			log.Warn().Err(deleteErr).Msgf("Failed deleting pod %v", pod.Name)
+		}
+	}()
+
+	phase := corev1.PodPending
+	waitErr := wait.PollUntilContextTimeout(ctx, c.pollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		current, err := pods.Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, fmt.Errorf("Can't get pod %v in namespace %v: %w", pod.Name, pod.Namespace, err)
+		}
+		phase = current.Status.Phase
+
+		return phase == corev1.PodSucceeded || phase == corev1.PodFailed, nil
+	})
+
+	data, logsErr := pods.GetLogs(pod.Name, &corev1.PodLogOptions{}).DoRaw(ctx)
+	if logsErr == nil {
+		logs = string(data)
+	}
+
+	if wait.Interrupted(waitErr) {
+		return logs, ErrPodFailed.wrap(fmt.Errorf("pod %v didn't finish within %v", pod.Name, timeout))
+	}
+	if waitErr != nil {
+		return logs, waitErr
+	}
+	if phase == corev1.PodFailed {
+		return logs, ErrPodFailed.wrap(fmt.Errorf("pod %v exited with an error", pod.Name))
+	}
+
+	return logs, nil
+}
+
+func (c *client) applyObject(ctx context.Context, namespace string, obj *unstructured.Unstructured, dryRun bool) (applied *unstructured.Unstructured, err error) {
+	mapping, err := c.getMapping(obj)
+	if err != nil {
+		return
+	}
+
+	ns := c.getNamespace(mapping, namespace, obj)
+	if ns != "" {
+		obj.SetNamespace(ns)
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return
+	}
+
+	patchOptions := metav1.PatchOptions{
+		FieldManager: FieldManager,
+		Force:        &[]bool{true}[0],
+	}
+	if dryRun {
+		patchOptions.DryRun = []string{metav1.DryRunAll}
+	}
+
+	applied, err = c.getResourceInterface(mapping, ns).Patch(ctx, obj.GetName(), types.ApplyPatchType, data, patchOptions)
+	if err != nil {
+		return nil, fmt.Errorf("Can't apply %v %v in namespace %v: %w", strings.ToLower(obj.GetKind()), obj.GetName(), ns, err)
+	}
+
+	return applied, nil
+}
+
+func (c *client) getMapping(obj *unstructured.Unstructured) (mapping *meta.RESTMapping, err error) {
+	if c.mapper == nil {
+		return nil, ErrNotInitialized
+	}
+
+	gvk := obj.GroupVersionKind()
+	mapping, err = c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		// the discovery cache might be stale, for example when a crd has just been installed
+		if resettable, ok := c.mapper.(meta.ResettableRESTMapper); ok {
+			resettable.Reset()
+			mapping, err = c.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		}
+	}
+	if meta.IsNoMatchError(err) {
+		return nil, ErrUnknownResourceKind.wrap(fmt.Errorf("%v %v: %w", gvk.String(), obj.GetName(), err))
+	}
+
+	return
+}
+
+func (c *client) getNamespace(mapping *meta.RESTMapping, namespace string, obj *unstructured.Unstructured) string {
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return ""
+	}
+	if obj.GetNamespace() != "" {
+		return obj.GetNamespace()
+	}
+	return namespace
+}
+
+func (c *client) getResourceInterface(mapping *meta.RESTMapping, namespace string) dynamic.ResourceInterface {
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return c.dynamicClient.Resource(mapping.Resource)
+	}
+	return c.dynamicClient.Resource(mapping.Resource).Namespace(namespace)
+}
+
+// isNamespaceNotFound returns true if a request failed because the namespace it targets doesn't exist
+func isNamespaceNotFound(err error) bool {
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) || status.Status().Reason != metav1.StatusReasonNotFound {
+		return false
+	}
+
+	return status.Status().Details != nil && status.Status().Details.Kind == "namespaces"
+}
+
+// DecodeManifests splits a multi-document yaml stream into objects, skipping empty documents
+func DecodeManifests(manifests []byte) (objects []*unstructured.Unstructured, err error) {
+	decoder := yamlutil.NewYAMLOrJSONDecoder(bytes.NewReader(manifests), 4096)
+
+	for i := 0; ; i++ {
+		content := map[string]interface{}{}
+		err = decoder.Decode(&content)
+		if err == io.EOF {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, ErrInvalidManifest.wrap(fmt.Errorf("document %v: %w", i, err))
+		}
+		if len(content) == 0 {
+			// empty documents are the result of conditional templates
+			continue
+		}
+
+		obj := &unstructured.Unstructured{Object: content}
+		if obj.GetAPIVersion() == "" || obj.GetKind() == "" {
+			return nil, ErrInvalidManifest.wrap(fmt.Errorf("document %v has no apiVersion or kind", i))
+		}
+		if obj.GetName() == "" {
+			return nil, ErrInvalidManifest.wrap(fmt.Errorf("document %v of kind %v has no metadata.name", i, obj.GetKind()))
+		}
+
+		objects = append(objects, obj)
+	}
+}
+
+// FilterManifests returns only the objects in a multi-document yaml stream that have one of the passed in kinds
+func FilterManifests(manifests []byte, kinds ...string) (filtered []byte, err error) {
+	objects, err := DecodeManifests(manifests)
+	if err != nil {
+		return
+	}
+
+	matching := []*unstructured.Unstructured{}
+	for _, obj := range objects {
+		for _, kind := range kinds {
+			if obj.GetKind() == kind {
+				matching = append(matching, obj)
+				break
+			}
+		}
+	}
+
+	return encodeManifests(matching)
+}
+
+func encodeManifests(objects []*unstructured.Unstructured) (manifests []byte, err error) {
+	var buffer bytes.Buffer
+	for _, obj := range objects {
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, fmt.Errorf("Can't marshal %v %v: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		buffer.WriteString("---\n")
+		buffer.Write(data)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func kindForResource(resource schema.GroupVersionResource) string {
+	switch resource {
+	case ResourceServices:
+		return "Service"
+	case ResourceConfigMaps:
+		return "ConfigMap"
+	case ResourceSecrets:
+		return "Secret"
+	case ResourceServiceAccounts:
+		return "ServiceAccount"
+	case ResourcePods:
+		return "Pod"
+	case ResourceEndpoints:
+		return "Endpoints"
+	case ResourceDeployments:
+		return "Deployment"
+	case ResourceStatefulSets:
+		return "StatefulSet"
+	case ResourceJobs:
+		return "Job"
+	case ResourceCronJobs:
+		return "CronJob"
+	case ResourceIngresses:
+		return "Ingress"
+	case ResourceHorizontalPodAutoscalers:
+		return "HorizontalPodAutoscaler"
+	case ResourcePodDisruptionBudgets:
+		return "PodDisruptionBudget"
+	case ResourceBackendConfigs:
+		return "BackendConfig"
+	case ResourceVerticalPodAutoscalers:
+		return "VerticalPodAutoscaler"
+	}
+
+	return ""
+}
@@ -0,0 +1,1128 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestDecodeManifests(t *testing.T) {
+
+	t.Run("SkipsEmptyDocuments", func(t *testing.T) {
+
+		manifests := []byte("---\napiVersion: v1\nkind: Service\nmetadata:\n  name: myapp\n---\n\n---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: myapp\n")
+
+		// act
+		objects, err := DecodeManifests(manifests)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(objects))
+		assert.Equal(t, "Service", objects[0].GetKind())
+		assert.Equal(t, "Deployment", objects[1].GetKind())
+	})
+
+	t.Run("ReturnsErrInvalidManifestIfNameIsMissing", func(t *testing.T) {
+
+		manifests := []byte("apiVersion: v1\nkind: Service\nmetadata:\n  labels:\n    app: myapp\n")
+
+		// act
+		_, err := DecodeManifests(manifests)
+
+		assert.True(t, errors.Is(err, ErrInvalidManifest))
+	})
+}
+
+func TestValidateManifests(t *testing.T) {
+
+	t.Run("ReturnsErrUnknownResourceKindIfKindIsNotServed", func(t *testing.T) {
+
+		client := newFakeClient()
+		manifests := []byte("apiVersion: cloud.google.com/v1\nkind: BackendConfig\nmetadata:\n  name: myapp\n")
+
+		// act
+		err := client.ValidateManifests(context.Background(), "mynamespace", manifests)
+
+		assert.True(t, errors.Is(err, ErrUnknownResourceKind))
+	})
+
+	t.Run("ReturnsNilIfServerAcceptsDryRun", func(t *testing.T) {
+
+		dynamicClient := newFakeDynamicClient()
+		patched := []string{}
+		dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			patchAction := action.(k8stesting.PatchAction)
+			patched = append(patched, patchAction.GetResource().Resource+"/"+patchAction.GetName())
+			return true, &unstructured.Unstructured{}, nil
+		})
+		client := NewClientWithClientsets(fake.NewSimpleClientset(), dynamicClient, newFakeRESTMapper())
+		manifests := []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: myapp\n---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: myapp\n")
+
+		// act
+		err := client.ValidateManifests(context.Background(), "mynamespace", manifests)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"services/myapp", "deployments/myapp"}, patched)
+		assert.Equal(t, 0, len(client.Changes()))
+	})
+
+	t.Run("ReturnsErrorIfServerRejectsDryRun", func(t *testing.T) {
+
+		dynamicClient := newFakeDynamicClient()
+		dynamicClient.PrependReactor("patch", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "Deployment"}, "myapp", nil)
+		})
+		dynamicClient.PrependReactor("patch", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, &unstructured.Unstructured{}, nil
+		})
+		client := NewClientWithClientsets(fake.NewSimpleClientset(), dynamicClient, newFakeRESTMapper())
+		manifests := []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: myapp\n---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: myapp\n")
+
+		// act
+		err := client.ValidateManifests(context.Background(), "mynamespace", manifests)
+
+		assert.True(t, apierrors.IsInvalid(err))
+	})
+
+	t.Run("SkipsResourcesInNamespaceThatDoesNotExistYet", func(t *testing.T) {
+
+		dynamicClient := newFakeDynamicClient()
+		dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, "mynamespace")
+		})
+		client := NewClientWithClientsets(fake.NewSimpleClientset(), dynamicClient, newFakeRESTMapper())
+		manifests := []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: myapp\n---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: myapp\n")
+
+		// act
+		err := client.ValidateManifests(context.Background(), "mynamespace", manifests)
+
+		assert.Nil(t, err)
+	})
+}
+
+func TestDeleteResource(t *testing.T) {
+
+	t.Run("DeletesExistingResource", func(t *testing.T) {
+
+		client := newFakeClient(newUnstructuredService("myapp", "mynamespace", nil))
+
+		// act
+		err := client.DeleteResource(context.Background(), ResourceServices, "mynamespace", "myapp")
+
+		assert.Nil(t, err)
+		items, err := client.ListResources(context.Background(), []schema.GroupVersionResource{ResourceServices}, "mynamespace", "")
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(items))
+		assert.Equal(t, []ResourceChange{{Operation: ChangeDeleted, Kind: "Service", Namespace: "mynamespace", Name: "myapp"}}, client.Changes())
+	})
+
+	t.Run("IgnoresResourceThatDoesNotExist", func(t *testing.T) {
+
+		client := newFakeClient()
+
+		// act
+		err := client.DeleteResource(context.Background(), ResourceServices, "mynamespace", "myapp")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(client.Changes()))
+	})
+}
+
+func TestDeleteResources(t *testing.T) {
+
+	t.Run("DeletesOnlyResourcesMatchingLabelSelector", func(t *testing.T) {
+
+		client := newFakeClient(
+			newUnstructuredService("myapp", "mynamespace", map[string]interface{}{"app": "myapp"}),
+			newUnstructuredService("otherapp", "mynamespace", map[string]interface{}{"app": "otherapp"}),
+		)
+
+		// act
+		err := client.DeleteResources(context.Background(), []schema.GroupVersionResource{ResourceServices, ResourceDeployments}, "mynamespace", "app=myapp", false)
+
+		assert.Nil(t, err)
+		items, err := client.ListResources(context.Background(), []schema.GroupVersionResource{ResourceServices}, "mynamespace", "")
+		assert.Nil(t, err)
+		if assert.Equal(t, 1, len(items)) {
+			assert.Equal(t, "otherapp", items[0].GetName())
+		}
+		assert.Equal(t, []ResourceChange{{Operation: ChangeDeleted, Kind: "Service", Namespace: "mynamespace", Name: "myapp"}}, client.Changes())
+	})
+
+	t.Run("DoesNotRecordChangesForDryRun", func(t *testing.T) {
+
+		client := newFakeClient(newUnstructuredService("myapp", "mynamespace", map[string]interface{}{"app": "myapp"}))
+
+		// act
+		err := client.DeleteResources(context.Background(), []schema.GroupVersionResource{ResourceServices}, "mynamespace", "app=myapp", true)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(client.Changes()))
+	})
+}
+
+func TestRemoveAnnotations(t *testing.T) {
+
+	t.Run("IgnoresResourceThatDoesNotExist", func(t *testing.T) {
+
+		client := newFakeClient()
+
+		// act
+		err := client.RemoveAnnotations(context.Background(), ResourceServices, "mynamespace", "myapp", "estafette.io/cloudflare-state")
+
+		assert.Nil(t, err)
+	})
+}
+
+func TestGetService(t *testing.T) {
+
+	t.Run("ReturnsErrResourceNotFoundIfServiceDoesNotExist", func(t *testing.T) {
+
+		client := newFakeClient()
+
+		// act
+		_, err := client.GetService(context.Background(), "mynamespace", "myapp")
+
+		assert.True(t, errors.Is(err, ErrResourceNotFound))
+	})
+}
+
+func TestRestartDeployment(t *testing.T) {
+
+	t.Run("SetsRestartedAtAnnotationOnPodTemplate", func(t *testing.T) {
+
+		deployment := newDeployment("myapp", "mynamespace", 3)
+		kubeClientset := fake.NewSimpleClientset(deployment)
+		client := NewClientWithClientsets(kubeClientset, newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		err := client.RestartDeployment(context.Background(), "mynamespace", "myapp")
+
+		assert.Nil(t, err)
+		restarted, err := client.GetDeployment(context.Background(), "mynamespace", "myapp")
+		assert.Nil(t, err)
+		assert.NotEmpty(t, restarted.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"])
+	})
+
+	t.Run("ReturnsErrResourceNotFoundIfDeploymentDoesNotExist", func(t *testing.T) {
+
+		client := NewClientWithClientsets(fake.NewSimpleClientset(), newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		err := client.RestartDeployment(context.Background(), "mynamespace", "myapp")
+
+		assert.True(t, errors.Is(err, ErrResourceNotFound))
+	})
+}
+
+func TestListDeploymentReplicaSets(t *testing.T) {
+
+	t.Run("ReturnsReplicaSetsOwnedByDeploymentOrderedByRevision", func(t *testing.T) {
+
+		deployment := newDeployment("myapp", "mynamespace", 3)
+		deployment.UID = "deployment-uid"
+		deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "myapp"}}
+		kubeClientset := fake.NewSimpleClientset(
+			deployment,
+			newReplicaSet("myapp-b", "mynamespace", 2, deployment),
+			newReplicaSet("myapp-a", "mynamespace", 1, deployment),
+			newReplicaSet("myapp-other", "mynamespace", 3, newDeployment("other", "mynamespace", 1)),
+		)
+		client := NewClientWithClientsets(kubeClientset, newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		replicaSets, err := client.ListDeploymentReplicaSets(context.Background(), "mynamespace", "myapp")
+
+		assert.Nil(t, err)
+		if assert.Equal(t, 2, len(replicaSets)) {
+			assert.Equal(t, "myapp-a", replicaSets[0].Name)
+			assert.Equal(t, "myapp-b", replicaSets[1].Name)
+		}
+	})
+}
+
+func TestRollbackDeployment(t *testing.T) {
+
+	t.Run("ReplacesPodTemplateWithTemplateOfReplicaSetWithoutPodTemplateHash", func(t *testing.T) {
+
+		deployment := newDeployment("myapp", "mynamespace", 3)
+		deployment.Spec.Template.Labels = map[string]string{"app": "myapp", "version": "1.0.1"}
+		kubeClientset := fake.NewSimpleClientset(deployment)
+		client := NewClientWithClientsets(kubeClientset, newFakeDynamicClient(), newFakeRESTMapper())
+
+		replicaSet := newReplicaSet("myapp-a", "mynamespace", 1, deployment)
+		replicaSet.Spec.Template.Labels = map[string]string{"app": "myapp", "version": "1.0.0", appsv1.DefaultDeploymentUniqueLabelKey: "abc"}
+
+		// act
+		err := client.RollbackDeployment(context.Background(), "mynamespace", "myapp", *replicaSet)
+
+		assert.Nil(t, err)
+		rolledBack, err := client.GetDeployment(context.Background(), "mynamespace", "myapp")
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"app": "myapp", "version": "1.0.0"}, rolledBack.Spec.Template.Labels)
+	})
+}
+
+func TestListStatefulSetRevisions(t *testing.T) {
+
+	t.Run("ReturnsRevisionsOwnedByStatefulSetOrderedByRevision", func(t *testing.T) {
+
+		statefulset := newStatefulSet("myapp", "mynamespace")
+		kubeClientset := fake.NewSimpleClientset(
+			statefulset,
+			newControllerRevision("myapp-b", "mynamespace", 2, statefulset),
+			newControllerRevision("myapp-a", "mynamespace", 1, statefulset),
+			newControllerRevision("other-a", "mynamespace", 3, &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "other", UID: "other-uid"}}),
+		)
+		client := NewClientWithClientsets(kubeClientset, newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		revisions, err := client.ListStatefulSetRevisions(context.Background(), "mynamespace", "myapp")
+
+		assert.Nil(t, err)
+		if assert.Equal(t, 2, len(revisions)) {
+			assert.Equal(t, "myapp-a", revisions[0].Name)
+			assert.Equal(t, "myapp-b", revisions[1].Name)
+		}
+	})
+
+	t.Run("ReturnsErrResourceNotFoundIfStatefulSetDoesNotExist", func(t *testing.T) {
+
+		client := NewClientWithClientsets(fake.NewSimpleClientset(), newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		_, err := client.ListStatefulSetRevisions(context.Background(), "mynamespace", "myapp")
+
+		assert.True(t, errors.Is(err, ErrResourceNotFound))
+	})
+}
+
+func TestRollbackStatefulSet(t *testing.T) {
+
+	t.Run("RestoresPodTemplateOfRevisionAndDeletesPodsOfOtherRevisionsThatAreNotReady", func(t *testing.T) {
+
+		statefulset := newStatefulSet("myapp", "mynamespace")
+		statefulset.Spec.Template.Labels = map[string]string{"app": "myapp", "version": "1.0.1"}
+		revision := newControllerRevision("myapp-a", "mynamespace", 1, statefulset)
+		revision.Data.Raw = []byte(`{"spec":{"template":{"$patch":"replace","metadata":{"labels":{"app":"myapp","version":"1.0.0"}}}}}`)
+		readyCondition := corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionTrue}
+		kubeClientset := fake.NewSimpleClientset(
+			statefulset,
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "myapp-0", Namespace: "mynamespace", Labels: map[string]string{"app": "myapp", appsv1.ControllerRevisionHashLabelKey: "myapp-a"}}},
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "myapp-1", Namespace: "mynamespace", Labels: map[string]string{"app": "myapp", appsv1.ControllerRevisionHashLabelKey: "myapp-b"}}, Status: corev1.PodStatus{Conditions: []corev1.PodCondition{readyCondition}}},
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "myapp-2", Namespace: "mynamespace", Labels: map[string]string{"app": "myapp", appsv1.ControllerRevisionHashLabelKey: "myapp-b"}}},
+		)
+		client := NewClientWithClientsets(kubeClientset, newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		err := client.RollbackStatefulSet(context.Background(), "mynamespace", "myapp", *revision)
+
+		assert.Nil(t, err)
+		rolledBack, err := kubeClientset.AppsV1().StatefulSets("mynamespace").Get(context.Background(), "myapp", metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"app": "myapp", "version": "1.0.0"}, rolledBack.Spec.Template.Labels)
+		pods, err := client.ListPods(context.Background(), "mynamespace", "app=myapp")
+		assert.Nil(t, err)
+		if assert.Equal(t, 2, len(pods)) {
+			assert.Equal(t, "myapp-0", pods[0].Name)
+			assert.Equal(t, "myapp-1", pods[1].Name)
+		}
+	})
+}
+
+func TestCreateOrUpdateConfigMap(t *testing.T) {
+
+	t.Run("CreatesConfigMapIfItDoesNotExist", func(t *testing.T) {
+
+		client := NewClientWithClientsets(fake.NewSimpleClientset(), newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		err := client.CreateOrUpdateConfigMap(context.Background(), &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp-configs", Namespace: "mynamespace"},
+			Data:       map[string]string{"config.yaml": "a: b"},
+		})
+
+		assert.Nil(t, err)
+		configMap, err := client.GetConfigMap(context.Background(), "mynamespace", "myapp-configs")
+		assert.Nil(t, err)
+		assert.Equal(t, "a: b", configMap.Data["config.yaml"])
+	})
+
+	t.Run("UpdatesConfigMapIfItExists", func(t *testing.T) {
+
+		client := NewClientWithClientsets(fake.NewSimpleClientset(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp-configs", Namespace: "mynamespace"},
+			Data:       map[string]string{"config.yaml": "a: b"},
+		}), newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		err := client.CreateOrUpdateConfigMap(context.Background(), &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp-configs", Namespace: "mynamespace"},
+			Data:       map[string]string{"config.yaml": "a: c"},
+		})
+
+		assert.Nil(t, err)
+		configMap, err := client.GetConfigMap(context.Background(), "mynamespace", "myapp-configs")
+		assert.Nil(t, err)
+		assert.Equal(t, "a: c", configMap.Data["config.yaml"])
+	})
+}
+
+func TestWaitForDeploymentRollout(t *testing.T) {
+
+	t.Run("ReturnsNilIfAllReplicasAreUpdatedAndAvailable", func(t *testing.T) {
+
+		deployment := newDeployment("myapp", "mynamespace", 3)
+		deployment.Status = appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3}
+		client := NewClientWithClientsets(fake.NewSimpleClientset(deployment), newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		err := client.WaitForDeploymentRollout(context.Background(), "mynamespace", "myapp")
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrRolloutFailedIfProgressDeadlineIsExceeded", func(t *testing.T) {
+
+		deployment := newDeployment("myapp", "mynamespace", 3)
+		deployment.Status = appsv1.DeploymentStatus{
+			ObservedGeneration: 1,
+			Replicas:           4,
+			UpdatedReplicas:    1,
+			AvailableReplicas:  3,
+			Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+			},
+		}
+		client := NewClientWithClientsets(fake.NewSimpleClientset(deployment), newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		err := client.WaitForDeploymentRollout(context.Background(), "mynamespace", "myapp")
+
+		assert.True(t, errors.Is(err, ErrRolloutFailed))
+	})
+
+	t.Run("KeepsWaitingWhileOldReplicasArePendingTermination", func(t *testing.T) {
+
+		deployment := newDeployment("myapp", "mynamespace", 3)
+		deployment.Status = appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 4, UpdatedReplicas: 3, AvailableReplicas: 3}
+		client := NewClientWithClientsets(fake.NewSimpleClientset(deployment), newFakeDynamicClient(), newFakeRESTMapper())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		// act
+		err := client.WaitForDeploymentRollout(ctx, "mynamespace", "myapp")
+
+		assert.NotNil(t, err)
+		assert.False(t, errors.Is(err, ErrRolloutFailed))
+	})
+
+	t.Run("ReturnsErrRolloutTimedOutIfDeadlineOfContextExpires", func(t *testing.T) {
+
+		deployment := newDeployment("myapp", "mynamespace", 3)
+		deployment.Status = appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 3}
+		client := NewClientWithClientsets(fake.NewSimpleClientset(deployment), newFakeDynamicClient(), newFakeRESTMapper())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		// act
+		err := client.WaitForDeploymentRollout(ctx, "mynamespace", "myapp")
+
+		assert.True(t, errors.Is(err, ErrRolloutTimedOut))
+	})
+
+	t.Run("ReturnsErrRolloutTimedOutIfProgressDeadlineIsExceeded", func(t *testing.T) {
+
+		deployment := newDeployment("myapp", "mynamespace", 3)
+		deployment.Status = appsv1.DeploymentStatus{
+			ObservedGeneration: 1,
+			Replicas:           4,
+			UpdatedReplicas:    1,
+			AvailableReplicas:  3,
+			Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+			},
+		}
+		client := NewClientWithClientsets(fake.NewSimpleClientset(deployment), newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		err := client.WaitForDeploymentRollout(context.Background(), "mynamespace", "myapp")
+
+		assert.True(t, errors.Is(err, ErrRolloutTimedOut))
+	})
+}
+
+func TestStatefulSetRolloutStatus(t *testing.T) {
+
+	t.Run("ReturnsNotDoneIfUpdateRevisionDiffersFromCurrentRevision", func(t *testing.T) {
+
+		replicas := int32(2)
+		statefulset := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp", Generation: 2},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas:       &replicas,
+				UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType},
+			},
+			Status: appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 2, UpdatedReplicas: 1, CurrentRevision: "myapp-1", UpdateRevision: "myapp-2"},
+		}
+
+		// act
+		_, done, err := statefulSetRolloutStatus(statefulset)
+
+		assert.Nil(t, err)
+		assert.False(t, done)
+	})
+}
+
+func TestWaitForJobCompletion(t *testing.T) {
+
+	t.Run("ReturnsNilIfJobIsComplete", func(t *testing.T) {
+
+		job := newJob("myapp-migrate", "mynamespace")
+		job.Status = batchv1.JobStatus{
+			Succeeded:  1,
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+		}
+		client := NewClientWithClientsets(fake.NewSimpleClientset(job), newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		err := client.WaitForJobCompletion(context.Background(), "mynamespace", "myapp-migrate", time.Second)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrJobFailedIfJobHasFailed", func(t *testing.T) {
+
+		job := newJob("myapp-migrate", "mynamespace")
+		job.Status = batchv1.JobStatus{
+			Failed:     1,
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded", Message: "Job has reached the specified backoff limit"}},
+		}
+		client := NewClientWithClientsets(fake.NewSimpleClientset(job), newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		err := client.WaitForJobCompletion(context.Background(), "mynamespace", "myapp-migrate", time.Second)
+
+		assert.True(t, errors.Is(err, ErrJobFailed))
+		assert.Contains(t, err.Error(), "backoff limit")
+	})
+
+	t.Run("ReturnsErrJobFailedIfJobDoesNotCompleteWithinTimeout", func(t *testing.T) {
+
+		job := newJob("myapp-migrate", "mynamespace")
+		job.Status = batchv1.JobStatus{Active: 1}
+		client := NewClientWithClientsets(fake.NewSimpleClientset(job), newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		err := client.WaitForJobCompletion(context.Background(), "mynamespace", "myapp-migrate", 50*time.Millisecond)
+
+		assert.True(t, errors.Is(err, ErrJobFailed))
+	})
+
+	t.Run("ReturnsContextErrorIfContextIsCancelled", func(t *testing.T) {
+
+		job := newJob("myapp-migrate", "mynamespace")
+		job.Status = batchv1.JobStatus{Active: 1}
+		client := NewClientWithClientsets(fake.NewSimpleClientset(job), newFakeDynamicClient(), newFakeRESTMapper())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		// act
+		err := client.WaitForJobCompletion(ctx, "mynamespace", "myapp-migrate", time.Minute)
+
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.False(t, errors.Is(err, ErrJobFailed))
+	})
+}
+
+func TestSuspendCronJob(t *testing.T) {
+
+	t.Run("SetsSuspendOnCronJob", func(t *testing.T) {
+
+		kubeClientset := fake.NewSimpleClientset(newCronJob("myapp", "mynamespace"))
+		client := NewClientWithClientsets(kubeClientset, newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		err := client.SuspendCronJob(context.Background(), "mynamespace", "myapp", true)
+
+		assert.Nil(t, err)
+		cronJob, err := kubeClientset.BatchV1().CronJobs("mynamespace").Get(context.Background(), "myapp", metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.True(t, *cronJob.Spec.Suspend)
+		assert.Equal(t, []ResourceChange{{Operation: ChangePatched, Kind: "CronJob", Namespace: "mynamespace", Name: "myapp"}}, client.Changes())
+	})
+
+	t.Run("ReturnsErrResourceNotFoundIfCronJobDoesNotExist", func(t *testing.T) {
+
+		client := NewClientWithClientsets(fake.NewSimpleClientset(), newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		err := client.SuspendCronJob(context.Background(), "mynamespace", "myapp", false)
+
+		assert.True(t, errors.Is(err, ErrResourceNotFound))
+	})
+}
+
+func TestTriggerCronJob(t *testing.T) {
+
+	t.Run("CreatesJobFromJobTemplateOwnedByCronJob", func(t *testing.T) {
+
+		kubeClientset := fake.NewSimpleClientset(newCronJob("myapp", "mynamespace"))
+		client := NewClientWithClientsets(kubeClientset, newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		jobName, err := client.TriggerCronJob(context.Background(), "mynamespace", "myapp")
+
+		assert.Nil(t, err)
+		assert.Regexp(t, `^myapp-manual-\d+$`, jobName)
+		job, err := kubeClientset.BatchV1().Jobs("mynamespace").Get(context.Background(), jobName, metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, "myapp", job.Labels["app"])
+		assert.Equal(t, "manual", job.Annotations["cronjob.kubernetes.io/instantiate"])
+		assert.Equal(t, "CronJob", job.OwnerReferences[0].Kind)
+		assert.Equal(t, "myapp", job.OwnerReferences[0].Name)
+		assert.Equal(t, "myapp:1.0.0", job.Spec.Template.Spec.Containers[0].Image)
+	})
+
+	t.Run("ShortensJobNameOfLongCronJobName", func(t *testing.T) {
+
+		name := "my-application-with-a-very-long-name-that-fills-up-the-limit"
+		client := NewClientWithClientsets(fake.NewSimpleClientset(newCronJob(name, "mynamespace")), newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		jobName, err := client.TriggerCronJob(context.Background(), "mynamespace", name)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 63, len(jobName))
+		assert.Regexp(t, `-manual-\d+$`, jobName)
+	})
+
+	t.Run("ReturnsErrResourceNotFoundIfCronJobDoesNotExist", func(t *testing.T) {
+
+		client := NewClientWithClientsets(fake.NewSimpleClientset(), newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		_, err := client.TriggerCronJob(context.Background(), "mynamespace", "myapp")
+
+		assert.True(t, errors.Is(err, ErrResourceNotFound))
+	})
+}
+
+func TestFollowPodLogs(t *testing.T) {
+
+	t.Run("ReturnsNilOnceContextIsDone", func(t *testing.T) {
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp-migrate-abc", Namespace: "mynamespace", Labels: map[string]string{"job-name": "myapp-migrate"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		client := NewClientWithClientsets(fake.NewSimpleClientset(pod), newFakeDynamicClient(), newFakeRESTMapper())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		// act
+		err := client.FollowPodLogs(ctx, "mynamespace", "job-name=myapp-migrate", "myapp", make(chan struct{}))
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsAfterFollowingRemainingPodsOnceDone", func(t *testing.T) {
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp-migrate-abc", Namespace: "mynamespace", Labels: map[string]string{"job-name": "myapp-migrate"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodSucceeded},
+		}
+		kubeClientset := fake.NewSimpleClientset(pod)
+		client := NewClientWithClientsets(kubeClientset, newFakeDynamicClient(), newFakeRESTMapper())
+		done := make(chan struct{})
+		close(done)
+
+		// act
+		err := client.FollowPodLogs(context.Background(), "mynamespace", "job-name=myapp-migrate", "myapp", done)
+
+		assert.Nil(t, err)
+		followed := 0
+		for _, action := range kubeClientset.Actions() {
+			if action.GetVerb() == "get" && action.GetSubresource() == "log" {
+				followed++
+			}
+		}
+		assert.Equal(t, 1, followed)
+	})
+}
+
+func TestRunPod(t *testing.T) {
+
+	t.Run("ReturnsLogsAndRemovesPodIfItSucceeds", func(t *testing.T) {
+
+		kubeClientset := fake.NewSimpleClientset()
+		kubeClientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+			pod.Status.Phase = corev1.PodSucceeded
+			return false, nil, nil
+		})
+		client := NewClientWithClientsets(kubeClientset, newFakeDynamicClient(), newFakeRESTMapper())
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "myapp-smoketests", Namespace: "mynamespace"}}
+
+		// act
+		logs, err := client.RunPod(context.Background(), pod, time.Second)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "fake logs", logs)
+		_, getErr := kubeClientset.CoreV1().Pods("mynamespace").Get(context.Background(), "myapp-smoketests", metav1.GetOptions{})
+		assert.True(t, apierrors.IsNotFound(getErr))
+	})
+
+	t.Run("ReturnsErrPodFailedIfItFails", func(t *testing.T) {
+
+		kubeClientset := fake.NewSimpleClientset()
+		kubeClientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+			pod.Status.Phase = corev1.PodFailed
+			return false, nil, nil
+		})
+		client := NewClientWithClientsets(kubeClientset, newFakeDynamicClient(), newFakeRESTMapper())
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "myapp-smoketests", Namespace: "mynamespace"}}
+
+		// act
+		_, err := client.RunPod(context.Background(), pod, time.Second)
+
+		assert.True(t, errors.Is(err, ErrPodFailed))
+	})
+
+	t.Run("ReturnsErrPodFailedIfItDoesNotFinishWithinTimeout", func(t *testing.T) {
+
+		client := NewClientWithClientsets(fake.NewSimpleClientset(), newFakeDynamicClient(), newFakeRESTMapper())
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "myapp-smoketests", Namespace: "mynamespace"}}
+
+		// act
+		_, err := client.RunPod(context.Background(), pod, 50*time.Millisecond)
+
+		assert.True(t, errors.Is(err, ErrPodFailed))
+	})
+}
+
+func TestGetPodLogs(t *testing.T) {
+
+	t.Run("ReturnsLogsForAllContainersOfMatchingPods", func(t *testing.T) {
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp-abc", Namespace: "mynamespace", Labels: map[string]string{"app": "myapp"}},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "myapp"}, {Name: "openresty"}},
+			},
+		}
+		client := NewClientWithClientsets(fake.NewSimpleClientset(pod), newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		logs, err := client.GetPodLogs(context.Background(), "mynamespace", "app=myapp", "", 0)
+
+		assert.Nil(t, err)
+		assert.Contains(t, logs, "==> myapp-abc/myapp <==")
+		assert.Contains(t, logs, "==> myapp-abc/openresty <==")
+	})
+}
+
+func TestListEvents(t *testing.T) {
+
+	t.Run("ReturnsEventsOldestFirst", func(t *testing.T) {
+
+		now := time.Now()
+		events := []runtime.Object{
+			&corev1.Event{ObjectMeta: metav1.ObjectMeta{Name: "myapp-abc.2", Namespace: "mynamespace"}, Reason: "BackOff", LastTimestamp: metav1.NewTime(now)},
+			&corev1.Event{ObjectMeta: metav1.ObjectMeta{Name: "myapp-abc.1", Namespace: "mynamespace"}, Reason: "Pulled", LastTimestamp: metav1.NewTime(now.Add(-time.Minute))},
+		}
+		client := NewClientWithClientsets(fake.NewSimpleClientset(events...), newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		list, err := client.ListEvents(context.Background(), "mynamespace", "Pod", "myapp-abc")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(list))
+		assert.Equal(t, "Pulled", list[0].Reason)
+		assert.Equal(t, "BackOff", list[1].Reason)
+	})
+}
+
+func TestAcquireLease(t *testing.T) {
+
+	holder := LeaseHolder{Identity: "123", ReleaseID: "123", TriggeredBy: "me@server.com"}
+
+	t.Run("CreatesLeaseIfItDoesNotExist", func(t *testing.T) {
+
+		kubeClientset := fake.NewSimpleClientset()
+		client := NewClientWithClientsets(kubeClientset, newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		_, err := client.AcquireLease(context.Background(), "mynamespace", "myapp-release-lock", holder, time.Minute)
+
+		assert.Nil(t, err)
+		lease, err := kubeClientset.CoordinationV1().Leases("mynamespace").Get(context.Background(), "myapp-release-lock", metav1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, "123", *lease.Spec.HolderIdentity)
+		assert.Equal(t, int32(60), *lease.Spec.LeaseDurationSeconds)
+		assert.Equal(t, "123", lease.Annotations[LeaseReleaseIDAnnotation])
+		assert.Equal(t, "me@server.com", lease.Annotations[LeaseTriggeredByAnnotation])
+	})
+
+	t.Run("ReturnsErrLeaseHeldWithCurrentHolderIfHeldByAnotherRelease", func(t *testing.T) {
+
+		kubeClientset := fake.NewSimpleClientset(newLease("myapp-release-lock", "mynamespace", "456", time.Now()))
+		client := NewClientWithClientsets(kubeClientset, newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		current, err := client.AcquireLease(context.Background(), "mynamespace", "myapp-release-lock", holder, time.Minute)
+
+		assert.True(t, errors.Is(err, ErrLeaseHeld))
+		assert.Equal(t, "456", current.Identity)
+		assert.Equal(t, "someone@server.com", current.TriggeredBy)
+	})
+
+	t.Run("TakesOverLeaseThatIsNoLongerRenewed", func(t *testing.T) {
+
+		kubeClientset := fake.NewSimpleClientset(newLease("myapp-release-lock", "mynamespace", "456", time.Now().Add(-5*time.Minute)))
+		client := NewClientWithClientsets(kubeClientset, newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		current, err := client.AcquireLease(context.Background(), "mynamespace", "myapp-release-lock", holder, time.Minute)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "123", current.Identity)
+		assert.Equal(t, "me@server.com", current.TriggeredBy)
+	})
+
+	t.Run("AcquiresLeaseAlreadyHeldBySameIdentity", func(t *testing.T) {
+
+		kubeClientset := fake.NewSimpleClientset(newLease("myapp-release-lock", "mynamespace", "123", time.Now()))
+		client := NewClientWithClientsets(kubeClientset, newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		_, err := client.AcquireLease(context.Background(), "mynamespace", "myapp-release-lock", holder, time.Minute)
+
+		assert.Nil(t, err)
+	})
+}
+
+func TestReleaseLease(t *testing.T) {
+
+	t.Run("DeletesLeaseHeldByIdentity", func(t *testing.T) {
+
+		kubeClientset := fake.NewSimpleClientset(newLease("myapp-release-lock", "mynamespace", "123", time.Now()))
+		client := NewClientWithClientsets(kubeClientset, newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		err := client.ReleaseLease(context.Background(), "mynamespace", "myapp-release-lock", "123")
+
+		assert.Nil(t, err)
+		_, err = kubeClientset.CoordinationV1().Leases("mynamespace").Get(context.Background(), "myapp-release-lock", metav1.GetOptions{})
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("LeavesLeaseHeldByAnotherRelease", func(t *testing.T) {
+
+		kubeClientset := fake.NewSimpleClientset(newLease("myapp-release-lock", "mynamespace", "456", time.Now()))
+		client := NewClientWithClientsets(kubeClientset, newFakeDynamicClient(), newFakeRESTMapper())
+
+		// act
+		err := client.ReleaseLease(context.Background(), "mynamespace", "myapp-release-lock", "123")
+
+		assert.Nil(t, err)
+		_, err = kubeClientset.CoordinationV1().Leases("mynamespace").Get(context.Background(), "myapp-release-lock", metav1.GetOptions{})
+		assert.Nil(t, err)
+	})
+}
+
+func TestNewInventory(t *testing.T) {
+
+	t.Run("ReturnsSameHashRegardlessOfOrder", func(t *testing.T) {
+
+		service := "apiVersion: v1\nkind: Service\nmetadata:\n  name: myapp\n"
+		deployment := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: myapp\n"
+
+		// act
+		inventory, err := NewInventory([]byte(service + "---\n" + deployment))
+
+		assert.Nil(t, err)
+		reversed, err := NewInventory([]byte(deployment + "---\n" + service))
+		assert.Nil(t, err)
+		assert.Equal(t, inventory.Hash, reversed.Hash)
+		assert.Equal(t, 16, len(inventory.Hash))
+		assert.True(t, inventory.Contains("Deployment", "myapp"))
+		assert.False(t, inventory.Contains("Ingress", "myapp"))
+	})
+
+	t.Run("ReturnsDifferentHashIfResourcesDiffer", func(t *testing.T) {
+
+		// act
+		inventory, err := NewInventory([]byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: myapp\n"))
+
+		assert.Nil(t, err)
+		other, err := NewInventory([]byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: myapp-canary\n"))
+		assert.Nil(t, err)
+		assert.NotEqual(t, inventory.Hash, other.Hash)
+	})
+}
+
+func TestInventoryLabel(t *testing.T) {
+
+	t.Run("LabelsNamespacedResourcesOnly", func(t *testing.T) {
+
+		manifests := []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: mynamespace\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: myapp\n  labels:\n    app: myapp\n---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: myapp-custom\n")
+		inventory, err := NewInventory(manifests)
+		assert.Nil(t, err)
+
+		// act
+		labeled, err := inventory.Label(manifests, "myapp", "stable")
+
+		assert.Nil(t, err)
+		objects, err := DecodeManifests(labeled)
+		assert.Nil(t, err)
+		if assert.Equal(t, 3, len(objects)) {
+			assert.Equal(t, 0, len(objects[0].GetLabels()))
+			assert.Equal(t, map[string]string{"app": "myapp", InventoryLabel: inventory.Hash, InventoryTrackLabel: "stable"}, objects[1].GetLabels())
+			assert.Equal(t, map[string]string{"app": "myapp", InventoryLabel: inventory.Hash, InventoryTrackLabel: "stable"}, objects[2].GetLabels())
+		}
+	})
+}
+
+func TestUnified(t *testing.T) {
+
+	t.Run("ReturnsEmptyStringIfOnlyServerManagedFieldsDiffer", func(t *testing.T) {
+
+		live := newUnstructuredService("myapp", "mynamespace", nil)
+		live.SetResourceVersion("1")
+		merged := live.DeepCopy()
+		merged.SetResourceVersion("2")
+
+		diff := ResourceDiff{Kind: "Service", Name: "myapp", Live: live, Merged: merged}
+
+		// act
+		unified := diff.Unified()
+
+		assert.Equal(t, "", unified)
+	})
+
+	t.Run("ReturnsChangedLines", func(t *testing.T) {
+
+		live := newUnstructuredService("myapp", "mynamespace", map[string]interface{}{"app": "myapp", "version": "1.0.0"})
+		merged := newUnstructuredService("myapp", "mynamespace", map[string]interface{}{"app": "myapp", "version": "1.0.1"})
+
+		diff := ResourceDiff{Kind: "Service", Name: "myapp", Live: live, Merged: merged}
+
+		// act
+		unified := diff.Unified()
+
+		assert.Contains(t, unified, "-    version: 1.0.0\n")
+		assert.Contains(t, unified, "+    version: 1.0.1\n")
+	})
+
+	t.Run("RedactsValuesOfNewSecrets", func(t *testing.T) {
+
+		merged := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]interface{}{"name": "myapp-secrets", "namespace": "mynamespace"},
+			"data":       map[string]interface{}{"password": "c2VjcmV0"},
+			"stringData": map[string]interface{}{"token": "plaintoken"},
+		}}
+
+		diff := ResourceDiff{Kind: "Secret", Name: "myapp-secrets", Merged: merged}
+
+		// act
+		unified := diff.Unified()
+
+		assert.Contains(t, unified, "+  password: (redacted)")
+		assert.NotContains(t, unified, "c2VjcmV0")
+		assert.NotContains(t, unified, "plaintoken")
+	})
+}
+
+func TestFieldChanges(t *testing.T) {
+
+	t.Run("ReturnsChangedAddedAndRemovedFieldsOrderedByPath", func(t *testing.T) {
+
+		live := newUnstructuredService("myapp", "mynamespace", map[string]interface{}{"app": "myapp", "version": "1.0.0", "team": "a"})
+		merged := newUnstructuredService("myapp", "mynamespace", map[string]interface{}{"app": "myapp", "version": "1.0.1", "estafette.io/color": "blue"})
+		merged.SetResourceVersion("2")
+
+		diff := ResourceDiff{Kind: "Service", Name: "myapp", Live: live, Merged: merged}
+
+		// act
+		changes := diff.FieldChanges()
+
+		assert.Equal(t, []FieldChange{
+			{Path: "metadata.labels.team", Live: "a"},
+			{Path: "metadata.labels.version", Live: "1.0.0", Merged: "1.0.1"},
+			{Path: `metadata.labels["estafette.io/color"]`, Merged: "blue"},
+		}, changes)
+	})
+
+	t.Run("RedactsValuesOfSecrets", func(t *testing.T) {
+
+		live := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]interface{}{"name": "myapp-secrets", "namespace": "mynamespace"},
+			"data":       map[string]interface{}{"password": "c2VjcmV0", "token": "dG9rZW4="},
+		}}
+		merged := live.DeepCopy()
+		unstructured.SetNestedField(merged.Object, "bmV3c2VjcmV0", "data", "password")
+
+		diff := ResourceDiff{Kind: "Secret", Name: "myapp-secrets", Live: live, Merged: merged}
+
+		// act
+		changes := diff.FieldChanges()
+
+		assert.Equal(t, []FieldChange{
+			{Path: "data.password", Live: "(redacted)", Merged: "(redacted, changed)"},
+		}, changes)
+		assert.NotContains(t, diff.Unified(), "c2VjcmV0")
+		assert.NotContains(t, diff.Unified(), "bmV3c2VjcmV0")
+	})
+}
+
+func newFakeClient(objects ...runtime.Object) Client {
+	return NewClientWithClientsets(fake.NewSimpleClientset(), newFakeDynamicClient(objects...), newFakeRESTMapper())
+}
+
+func newFakeDynamicClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	listKinds := map[schema.GroupVersionResource]string{}
+	for _, r := range []schema.GroupVersionResource{ResourceServices, ResourceConfigMaps, ResourceSecrets, ResourceServiceAccounts, ResourcePods, ResourceEndpoints, ResourceDeployments, ResourceStatefulSets, ResourceJobs, ResourceCronJobs, ResourceIngresses, ResourceHorizontalPodAutoscalers, ResourcePodDisruptionBudgets, ResourceBackendConfigs} {
+		listKinds[r] = kindForResource(r) + "List"
+	}
+
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, objects...)
+}
+
+func newFakeRESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Service"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+
+	return mapper
+}
+
+func newUnstructuredService(name, namespace string, labels map[string]interface{}) *unstructured.Unstructured {
+	metadata := map[string]interface{}{
+		"name":      name,
+		"namespace": namespace,
+	}
+	if labels != nil {
+		metadata["labels"] = labels
+	}
+
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   metadata,
+		},
+	}
+}
+
+func newDeployment(name, namespace string, replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Generation: 1},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+		},
+	}
+}
+
+func newStatefulSet(name, namespace string) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, UID: "statefulset-uid"},
+		Spec: appsv1.StatefulSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+		},
+	}
+}
+
+func newControllerRevision(name, namespace string, revision int64, owner *appsv1.StatefulSet) *appsv1.ControllerRevision {
+	return &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       namespace,
+			Labels:          map[string]string{"app": owner.Name},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(owner, appsv1.SchemeGroupVersion.WithKind("StatefulSet"))},
+		},
+		Revision: revision,
+	}
+}
+
+func newLease(name, namespace, identity string, renewTime time.Time) *coordinationv1.Lease {
+	durationSeconds := int32(60)
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Annotations: map[string]string{LeaseReleaseIDAnnotation: identity, LeaseTriggeredByAnnotation: "someone@server.com"},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &identity,
+			LeaseDurationSeconds: &durationSeconds,
+			AcquireTime:          &metav1.MicroTime{Time: renewTime},
+			RenewTime:            &metav1.MicroTime{Time: renewTime},
+		},
+	}
+}
+
+func newJob(name, namespace string) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+	}
+}
+
+func newReplicaSet(name, namespace string, revision int, owner *appsv1.Deployment) *appsv1.ReplicaSet {
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       namespace,
+			Labels:          map[string]string{"app": "myapp"},
+			Annotations:     map[string]string{RevisionAnnotation: fmt.Sprint(revision)},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(owner, appsv1.SchemeGroupVersion.WithKind("Deployment"))},
+		},
+	}
+}
+
+func newCronJob(name, namespace string) *batchv1.CronJob {
+	suspend := false
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, UID: "abc"},
+		Spec: batchv1.CronJobSpec{
+			Schedule: "0 3 * * *",
+			Suspend:  &suspend,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}},
+				Spec: batchv1.JobSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: name, Image: "myapp:1.0.0"}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
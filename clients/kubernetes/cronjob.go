@@ -0,0 +1,88 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// SuspendCronJob suspends or resumes a cronjob; a suspended cronjob doesn't spawn any jobs, but leaves the jobs it already spawned running
+func (c *client) SuspendCronJob(ctx context.Context, namespace, name string, suspend bool) (err error) {
+	if c.kubeClientset == nil {
+		return ErrNotInitialized
+	}
+
+	patch := fmt.Sprintf(`{"spec":{"suspend":%v}}`, suspend)
+	_, err = c.kubeClientset.BatchV1().CronJobs(namespace).Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{FieldManager: FieldManager})
+	if apierrors.IsNotFound(err) {
+		return ErrResourceNotFound.wrap(err)
+	}
+	if err != nil {
+		return fmt.Errorf("Can't set suspend to %v for cronjob %v in namespace %v: %w", suspend, name, namespace, err)
+	}
+
+	c.recordResourceChange(ChangePatched, ResourceCronJobs, namespace, name)
+	log.Info().Msgf("cronjob %v suspend set to %v", name, suspend)
+
+	return nil
+}
+
+// TriggerCronJob creates a job from the job template of a cronjob, the same way kubectl create job --from does, and returns the name of the job
+func (c *client) TriggerCronJob(ctx context.Context, namespace, name string) (jobName string, err error) {
+	if c.kubeClientset == nil {
+		return "", ErrNotInitialized
+	}
+
+	cronJob, err := c.kubeClientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", ErrResourceNotFound.wrap(err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Can't get cronjob %v in namespace %v: %w", name, namespace, err)
+	}
+
+	jobName = fmt.Sprintf("%v-manual-%v", name, time.Now().Unix())
+	if len(jobName) > 63 {
+		// keep the unique suffix if the name of the cronjob is too long
+		jobName = jobName[len(jobName)-63:]
+	}
+
+	annotations := map[string]string{"cronjob.kubernetes.io/instantiate": "manual"}
+	for key, value := range cronJob.Spec.JobTemplate.Annotations {
+		annotations[key] = value
+	}
+
+	controller := true
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        jobName,
+			Namespace:   namespace,
+			Labels:      cronJob.Spec.JobTemplate.Labels,
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "batch/v1",
+				Kind:       "CronJob",
+				Name:       cronJob.Name,
+				UID:        cronJob.UID,
+				Controller: &controller,
+			}},
+		},
+		Spec: *cronJob.Spec.JobTemplate.Spec.DeepCopy(),
+	}
+
+	_, err = c.kubeClientset.BatchV1().Jobs(namespace).Create(ctx, job, metav1.CreateOptions{FieldManager: FieldManager})
+	if err != nil {
+		return "", fmt.Errorf("Can't create job %v from cronjob %v in namespace %v: %w", jobName, name, namespace, err)
+	}
+
+	c.recordResourceChange(ChangeApplied, ResourceJobs, namespace, jobName)
+	log.Info().Msgf("job %v created from cronjob %v", jobName, name)
+
+	return jobName, nil
+}
@@ -0,0 +1,253 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// ResourceDiff holds the live state of a resource and the state it would have after applying the rendered manifest
+type ResourceDiff struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Live      *unstructured.Unstructured
+	Merged    *unstructured.Unstructured
+}
+
+// IsNew returns true if the resource doesn't exist in the cluster yet
+func (d ResourceDiff) IsNew() bool {
+	return d.Live == nil
+}
+
+// Unified returns a unified diff of the live and merged state, leaving out server-managed fields
+func (d ResourceDiff) Unified() string {
+	from := toComparableLines(toComparable(d.Live, nil))
+	to := toComparableLines(toComparable(d.Merged, d.Live))
+
+	header := fmt.Sprintf("--- %v/%v (live)\n+++ %v/%v (merged)\n", strings.ToLower(d.Kind), d.Name, strings.ToLower(d.Kind), d.Name)
+	body := unifiedLineDiff(from, to, 3)
+	if body == "" {
+		return ""
+	}
+
+	return header + body
+}
+
+// FieldChange is a field that differs between the live and merged state of a resource; Live is empty for a field that gets added and Merged is
+// empty for a field that gets removed
+type FieldChange struct {
+	Path   string
+	Live   string
+	Merged string
+}
+
+// FieldChanges returns the fields that differ between the live and merged state ordered by path, leaving out server-managed fields and redacting
+// the values of secrets
+func (d ResourceDiff) FieldChanges() []FieldChange {
+	live := map[string]string{}
+	if sanitized := toComparable(d.Live, nil); sanitized != nil {
+		flattenFields("", sanitized.Object, live)
+	}
+	merged := map[string]string{}
+	if sanitized := toComparable(d.Merged, d.Live); sanitized != nil {
+		flattenFields("", sanitized.Object, merged)
+	}
+
+	changes := []FieldChange{}
+	for path, value := range merged {
+		if liveValue, ok := live[path]; !ok || liveValue != value {
+			changes = append(changes, FieldChange{Path: path, Live: liveValue, Merged: value})
+		}
+	}
+	for path, value := range live {
+		if _, ok := merged[path]; !ok {
+			changes = append(changes, FieldChange{Path: path, Live: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
+
+// flattenFields collects the leaf values of an object by path, like spec.template.spec.containers[0].image
+func flattenFields(path string, value interface{}, fields map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 && path != "" {
+			fields[path] = "{}"
+		}
+		for key, child := range v {
+			flattenFields(fieldPath(path, key), child, fields)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			fields[path] = "[]"
+		}
+		for i, child := range v {
+			flattenFields(fmt.Sprintf("%v[%v]", path, i), child, fields)
+		}
+	case string:
+		fields[path] = v
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			fields[path] = fmt.Sprint(v)
+			return
+		}
+		fields[path] = string(data)
+	}
+}
+
+// fieldPath appends a key to a path, quoting keys that contain dots or slashes like those of labels and annotations
+func fieldPath(path, key string) string {
+	if strings.ContainsAny(key, "./") {
+		return fmt.Sprintf("%v[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func toComparableLines(sanitized *unstructured.Unstructured) []string {
+	if sanitized == nil {
+		return []string{}
+	}
+
+	data, err := yaml.Marshal(sanitized.Object)
+	if err != nil {
+		return []string{}
+	}
+
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// toComparable returns a copy of the object without server-managed fields; the values of a secret are redacted, and for its merged state the ones
+// that differ from the live state are marked, so a diff shows they change without revealing them
+func toComparable(obj, live *unstructured.Unstructured) *unstructured.Unstructured {
+	if obj == nil {
+		return nil
+	}
+
+	sanitized := obj.DeepCopy()
+	unstructured.RemoveNestedField(sanitized.Object, "status")
+	unstructured.RemoveNestedField(sanitized.Object, "metadata", "managedFields")
+	unstructured.RemoveNestedField(sanitized.Object, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(sanitized.Object, "metadata", "generation")
+	unstructured.RemoveNestedField(sanitized.Object, "metadata", "uid")
+	unstructured.RemoveNestedField(sanitized.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(sanitized.Object, "metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration")
+	if len(sanitized.GetAnnotations()) == 0 {
+		unstructured.RemoveNestedField(sanitized.Object, "metadata", "annotations")
+	}
+
+	if sanitized.GetKind() == "Secret" {
+		for _, field := range []string{"data", "stringData"} {
+			values, ok := sanitized.Object[field].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for key, value := range values {
+				values[key] = "(redacted)"
+				if live != nil {
+					if liveValue, found, _ := unstructured.NestedString(live.Object, field, key); !found || liveValue != fmt.Sprint(value) {
+						values[key] = "(redacted, changed)"
+					}
+				}
+			}
+		}
+	}
+
+	return sanitized
+}
+
+// unifiedLineDiff returns the changed lines prefixed with - or + surrounded by a number of unchanged context lines
+func unifiedLineDiff(from, to []string, context int) string {
+
+	// longest common subsequence table
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	lines := []line{}
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		if from[i] == to[j] {
+			lines = append(lines, line{' ', from[i]})
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			lines = append(lines, line{'-', from[i]})
+			i++
+		} else {
+			lines = append(lines, line{'+', to[j]})
+			j++
+		}
+	}
+	for ; i < len(from); i++ {
+		lines = append(lines, line{'-', from[i]})
+	}
+	for ; j < len(to); j++ {
+		lines = append(lines, line{'+', to[j]})
+	}
+
+	// only keep lines within context distance of a change
+	keep := make([]bool, len(lines))
+	hasChanges := false
+	for k, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		hasChanges = true
+		for n := k - context; n <= k+context; n++ {
+			if n >= 0 && n < len(lines) {
+				keep[n] = true
+			}
+		}
+	}
+	if !hasChanges {
+		return ""
+	}
+
+	var sb strings.Builder
+	skipped := false
+	for k, l := range lines {
+		if !keep[k] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			sb.WriteString("@@\n")
+			skipped = false
+		}
+		sb.WriteByte(l.op)
+		sb.WriteString(l.text)
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,80 @@
+package kubernetes
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// InventoryLabel holds a hash of the set of resources rendered by the release that last applied a resource
+	InventoryLabel = "estafette.io/inventory"
+
+	// InventoryTrackLabel holds the track - simple, stable or canary - of the release that last applied a resource
+	InventoryTrackLabel = "estafette.io/inventory-track"
+)
+
+// Inventory is the set of resources rendered for a release; resources labelled with another inventory weren't part of this release and can be pruned
+type Inventory struct {
+	Hash      string
+	Resources map[string]bool
+}
+
+// NewInventory returns the inventory for the resources in the manifests
+func NewInventory(manifests []byte) (inventory Inventory, err error) {
+	objects, err := DecodeManifests(manifests)
+	if err != nil {
+		return
+	}
+
+	inventory.Resources = map[string]bool{}
+	keys := []string{}
+	for _, obj := range objects {
+		key := inventoryKey(obj.GetKind(), obj.GetName())
+		if !inventory.Resources[key] {
+			inventory.Resources[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	// label values are limited to 63 characters, a shortened hash is still unique enough to tell releases apart
+	inventory.Hash = fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(keys, "\n"))))[0:16]
+
+	return
+}
+
+// Contains returns whether a resource is part of the inventory
+func (i Inventory) Contains(kind, name string) bool {
+	return i.Resources[inventoryKey(kind, name)]
+}
+
+// Label sets the inventory labels on all namespaced resources in the manifests, and the app label if it's missing so the resources can be found for pruning
+func (i Inventory) Label(manifests []byte, app, track string) (labeled []byte, err error) {
+	objects, err := DecodeManifests(manifests)
+	if err != nil {
+		return
+	}
+
+	for _, obj := range objects {
+		if obj.GetKind() != "Namespace" {
+			labels := obj.GetLabels()
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			if _, ok := labels["app"]; !ok {
+				labels["app"] = app
+			}
+			labels[InventoryLabel] = i.Hash
+			labels[InventoryTrackLabel] = track
+			obj.SetLabels(labels)
+		}
+	}
+
+	return encodeManifests(objects)
+}
+
+func inventoryKey(kind, name string) string {
+	return fmt.Sprintf("%v/%v", strings.ToLower(kind), name)
+}
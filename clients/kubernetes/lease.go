@@ -0,0 +1,180 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// LeaseReleaseIDAnnotation holds the release id of the release holding a lease
+	LeaseReleaseIDAnnotation = "estafette.io/release-id"
+
+	// LeaseTriggeredByAnnotation holds who or what triggered the release holding a lease
+	LeaseTriggeredByAnnotation = "estafette.io/triggered-by"
+)
+
+// LeaseHolder identifies the release holding a lease
+type LeaseHolder struct {
+	Identity    string
+	ReleaseID   string
+	TriggeredBy string
+	Since       time.Time
+}
+
+func (h LeaseHolder) String() string {
+	return fmt.Sprintf("release %v triggered by %v since %v", h.ReleaseID, h.TriggeredBy, h.Since.UTC().Format(time.RFC3339))
+}
+
+// AcquireLease takes the lease if it doesn't exist, isn't held, has expired or is already held by the same identity; if another holder has it
+// ErrLeaseHeld is returned along with that holder
+func (c *client) AcquireLease(ctx context.Context, namespace, name string, holder LeaseHolder, duration time.Duration) (current LeaseHolder, err error) {
+	if c.kubeClientset == nil {
+		return current, ErrNotInitialized
+	}
+
+	now := metav1.NewMicroTime(time.Now())
+	durationSeconds := int32(duration.Seconds())
+
+	lease, err := c.kubeClientset.CoordinationV1().Leases(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		}
+		setLeaseHolder(lease, holder, now, durationSeconds)
+		_, err = c.kubeClientset.CoordinationV1().Leases(namespace).Create(ctx, lease, metav1.CreateOptions{FieldManager: FieldManager})
+		if apierrors.IsAlreadyExists(err) {
+			// another release created it in the meantime
+			return c.AcquireLease(ctx, namespace, name, holder, duration)
+		}
+		if err != nil {
+			return current, leaseError(err, "create", name, namespace)
+		}
+		return holder, nil
+	}
+	if err != nil {
+		return current, leaseError(err, "get", name, namespace)
+	}
+
+	current = leaseHolder(lease)
+	if current.Identity != "" && current.Identity != holder.Identity && !leaseExpired(lease) {
+		return current, ErrLeaseHeld.wrap(fmt.Errorf("lease %v in namespace %v is held by %v", name, namespace, current))
+	}
+
+	if current.Identity == holder.Identity && lease.Spec.AcquireTime != nil {
+		// keep the original acquire time when taking the lease again
+		now = *lease.Spec.AcquireTime
+	}
+	setLeaseHolder(lease, holder, now, durationSeconds)
+
+	// the update fails with a conflict if another release took the lease since it was retrieved
+	_, err = c.kubeClientset.CoordinationV1().Leases(namespace).Update(ctx, lease, metav1.UpdateOptions{FieldManager: FieldManager})
+	if apierrors.IsConflict(err) {
+		return c.AcquireLease(ctx, namespace, name, holder, duration)
+	}
+	if err != nil {
+		return current, leaseError(err, "update", name, namespace)
+	}
+
+	return leaseHolder(lease), nil
+}
+
+// RenewLease extends a lease held by identity; ErrLeaseHeld is returned if another holder took it over
+func (c *client) RenewLease(ctx context.Context, namespace, name, identity string) (err error) {
+	if c.kubeClientset == nil {
+		return ErrNotInitialized
+	}
+
+	lease, err := c.kubeClientset.CoordinationV1().Leases(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return leaseError(err, "get", name, namespace)
+	}
+	if current := leaseHolder(lease); current.Identity != identity {
+		return ErrLeaseHeld.wrap(fmt.Errorf("lease %v in namespace %v has been taken over by %v", name, namespace, current))
+	}
+
+	lease.Spec.RenewTime = &metav1.MicroTime{Time: time.Now()}
+	_, err = c.kubeClientset.CoordinationV1().Leases(namespace).Update(ctx, lease, metav1.UpdateOptions{FieldManager: FieldManager})
+	if err != nil {
+		return leaseError(err, "update", name, namespace)
+	}
+
+	return nil
+}
+
+// ReleaseLease deletes a lease held by identity, so a waiting release can take it right away; a lease held by another holder is left as is
+func (c *client) ReleaseLease(ctx context.Context, namespace, name, identity string) (err error) {
+	if c.kubeClientset == nil {
+		return ErrNotInitialized
+	}
+
+	lease, err := c.kubeClientset.CoordinationV1().Leases(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return leaseError(err, "get", name, namespace)
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != identity {
+		return nil
+	}
+
+	// only delete the lease as it was retrieved, not after another release took it over
+	err = c.kubeClientset.CoordinationV1().Leases(namespace).Delete(ctx, name, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &lease.UID, ResourceVersion: &lease.ResourceVersion}})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return leaseError(err, "delete", name, namespace)
+	}
+
+	return nil
+}
+
+func setLeaseHolder(lease *coordinationv1.Lease, holder LeaseHolder, acquireTime metav1.MicroTime, durationSeconds int32) {
+	if lease.Annotations == nil {
+		lease.Annotations = map[string]string{}
+	}
+	lease.Annotations[LeaseReleaseIDAnnotation] = holder.ReleaseID
+	lease.Annotations[LeaseTriggeredByAnnotation] = holder.TriggeredBy
+
+	identity := holder.Identity
+	lease.Spec.HolderIdentity = &identity
+	lease.Spec.LeaseDurationSeconds = &durationSeconds
+	lease.Spec.AcquireTime = &acquireTime
+	lease.Spec.RenewTime = &metav1.MicroTime{Time: time.Now()}
+}
+
+func leaseHolder(lease *coordinationv1.Lease) (holder LeaseHolder) {
+	if lease.Spec.HolderIdentity != nil {
+		holder.Identity = *lease.Spec.HolderIdentity
+	}
+	if lease.Spec.AcquireTime != nil {
+		holder.Since = lease.Spec.AcquireTime.Time
+	}
+	holder.ReleaseID = lease.Annotations[LeaseReleaseIDAnnotation]
+	holder.TriggeredBy = lease.Annotations[LeaseTriggeredByAnnotation]
+
+	return holder
+}
+
+// leaseExpired tells whether the holder stopped renewing the lease, for example because its release got killed
+func leaseExpired(lease *coordinationv1.Lease) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+
+	return time.Since(lease.Spec.RenewTime.Time) > time.Duration(*lease.Spec.LeaseDurationSeconds)*time.Second
+}
+
+func leaseError(err error, verb, name, namespace string) error {
+	if apierrors.IsNotFound(err) {
+		return ErrResourceNotFound.wrap(err)
+	}
+	if apierrors.IsForbidden(err) {
+		return ErrForbidden.wrap(err)
+	}
+
+	return fmt.Errorf("Can't %v lease %v in namespace %v: %w", verb, name, namespace, err)
+}
@@ -0,0 +1,612 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: client.go
+
+// Package kubernetes is a generated GoMock package.
+package kubernetes
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	v1 "k8s.io/api/apps/v1"
+	v10 "k8s.io/api/core/v1"
+	v11 "k8s.io/api/networking/v1"
+	v12 "k8s.io/api/policy/v1"
+	unstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+)
+
+// MockClient is a mock of Client interface.
+type MockClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockClientMockRecorder
+}
+
+// MockClientMockRecorder is the mock recorder for MockClient.
+type MockClientMockRecorder struct {
+	mock *MockClient
+}
+
+// NewMockClient creates a new mock instance.
+func NewMockClient(ctrl *gomock.Controller) *MockClient {
+	mock := &MockClient{ctrl: ctrl}
+	mock.recorder = &MockClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClient) EXPECT() *MockClientMockRecorder {
+	return m.recorder
+}
+
+// AcquireLease mocks base method.
+func (m *MockClient) AcquireLease(ctx context.Context, namespace, name string, holder LeaseHolder, duration time.Duration) (LeaseHolder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcquireLease", ctx, namespace, name, holder, duration)
+	ret0, _ := ret[0].(LeaseHolder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcquireLease indicates an expected call of AcquireLease.
+func (mr *MockClientMockRecorder) AcquireLease(ctx, namespace, name, holder, duration interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireLease", reflect.TypeOf((*MockClient)(nil).AcquireLease), ctx, namespace, name, holder, duration)
+}
+
+// ApplyManifests mocks base method.
+func (m *MockClient) ApplyManifests(ctx context.Context, namespace string, manifests []byte, dryRun bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyManifests", ctx, namespace, manifests, dryRun)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApplyManifests indicates an expected call of ApplyManifests.
+func (mr *MockClientMockRecorder) ApplyManifests(ctx, namespace, manifests, dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyManifests", reflect.TypeOf((*MockClient)(nil).ApplyManifests), ctx, namespace, manifests, dryRun)
+}
+
+// Changes mocks base method.
+func (m *MockClient) Changes() []ResourceChange {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Changes")
+	ret0, _ := ret[0].([]ResourceChange)
+	return ret0
+}
+
+// Changes indicates an expected call of Changes.
+func (mr *MockClientMockRecorder) Changes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Changes", reflect.TypeOf((*MockClient)(nil).Changes))
+}
+
+// CreateOrUpdateConfigMap mocks base method.
+func (m *MockClient) CreateOrUpdateConfigMap(ctx context.Context, configMap *v10.ConfigMap) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrUpdateConfigMap", ctx, configMap)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOrUpdateConfigMap indicates an expected call of CreateOrUpdateConfigMap.
+func (mr *MockClientMockRecorder) CreateOrUpdateConfigMap(ctx, configMap interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrUpdateConfigMap", reflect.TypeOf((*MockClient)(nil).CreateOrUpdateConfigMap), ctx, configMap)
+}
+
+// CreateOrUpdateSecret mocks base method.
+func (m *MockClient) CreateOrUpdateSecret(ctx context.Context, secret *v10.Secret) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrUpdateSecret", ctx, secret)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOrUpdateSecret indicates an expected call of CreateOrUpdateSecret.
+func (mr *MockClientMockRecorder) CreateOrUpdateSecret(ctx, secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrUpdateSecret", reflect.TypeOf((*MockClient)(nil).CreateOrUpdateSecret), ctx, secret)
+}
+
+// DeleteResource mocks base method.
+func (m *MockClient) DeleteResource(ctx context.Context, resource schema.GroupVersionResource, namespace, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteResource", ctx, resource, namespace, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteResource indicates an expected call of DeleteResource.
+func (mr *MockClientMockRecorder) DeleteResource(ctx, resource, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteResource", reflect.TypeOf((*MockClient)(nil).DeleteResource), ctx, resource, namespace, name)
+}
+
+// DeleteResources mocks base method.
+func (m *MockClient) DeleteResources(ctx context.Context, resources []schema.GroupVersionResource, namespace, labelSelector string, dryRun bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteResources", ctx, resources, namespace, labelSelector, dryRun)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteResources indicates an expected call of DeleteResources.
+func (mr *MockClientMockRecorder) DeleteResources(ctx, resources, namespace, labelSelector, dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteResources", reflect.TypeOf((*MockClient)(nil).DeleteResources), ctx, resources, namespace, labelSelector, dryRun)
+}
+
+// DiffManifests mocks base method.
+func (m *MockClient) DiffManifests(ctx context.Context, namespace string, manifests []byte) ([]ResourceDiff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DiffManifests", ctx, namespace, manifests)
+	ret0, _ := ret[0].([]ResourceDiff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DiffManifests indicates an expected call of DiffManifests.
+func (mr *MockClientMockRecorder) DiffManifests(ctx, namespace, manifests interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiffManifests", reflect.TypeOf((*MockClient)(nil).DiffManifests), ctx, namespace, manifests)
+}
+
+// FollowPodLogs mocks base method.
+func (m *MockClient) FollowPodLogs(ctx context.Context, namespace, labelSelector, container string, done <-chan struct{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FollowPodLogs", ctx, namespace, labelSelector, container, done)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FollowPodLogs indicates an expected call of FollowPodLogs.
+func (mr *MockClientMockRecorder) FollowPodLogs(ctx, namespace, labelSelector, container, done interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FollowPodLogs", reflect.TypeOf((*MockClient)(nil).FollowPodLogs), ctx, namespace, labelSelector, container, done)
+}
+
+// GetConfigMap mocks base method.
+func (m *MockClient) GetConfigMap(ctx context.Context, namespace, name string) (*v10.ConfigMap, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConfigMap", ctx, namespace, name)
+	ret0, _ := ret[0].(*v10.ConfigMap)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConfigMap indicates an expected call of GetConfigMap.
+func (mr *MockClientMockRecorder) GetConfigMap(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConfigMap", reflect.TypeOf((*MockClient)(nil).GetConfigMap), ctx, namespace, name)
+}
+
+// GetDeployment mocks base method.
+func (m *MockClient) GetDeployment(ctx context.Context, namespace, name string) (*v1.Deployment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeployment", ctx, namespace, name)
+	ret0, _ := ret[0].(*v1.Deployment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeployment indicates an expected call of GetDeployment.
+func (mr *MockClientMockRecorder) GetDeployment(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeployment", reflect.TypeOf((*MockClient)(nil).GetDeployment), ctx, namespace, name)
+}
+
+// GetIngress mocks base method.
+func (m *MockClient) GetIngress(ctx context.Context, namespace, name string) (*v11.Ingress, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIngress", ctx, namespace, name)
+	ret0, _ := ret[0].(*v11.Ingress)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIngress indicates an expected call of GetIngress.
+func (mr *MockClientMockRecorder) GetIngress(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIngress", reflect.TypeOf((*MockClient)(nil).GetIngress), ctx, namespace, name)
+}
+
+// GetPodDisruptionBudget mocks base method.
+func (m *MockClient) GetPodDisruptionBudget(ctx context.Context, namespace, name string) (*v12.PodDisruptionBudget, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPodDisruptionBudget", ctx, namespace, name)
+	ret0, _ := ret[0].(*v12.PodDisruptionBudget)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPodDisruptionBudget indicates an expected call of GetPodDisruptionBudget.
+func (mr *MockClientMockRecorder) GetPodDisruptionBudget(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPodDisruptionBudget", reflect.TypeOf((*MockClient)(nil).GetPodDisruptionBudget), ctx, namespace, name)
+}
+
+// GetPodLogs mocks base method.
+func (m *MockClient) GetPodLogs(ctx context.Context, namespace, labelSelector, container string, tailLines int64) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPodLogs", ctx, namespace, labelSelector, container, tailLines)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPodLogs indicates an expected call of GetPodLogs.
+func (mr *MockClientMockRecorder) GetPodLogs(ctx, namespace, labelSelector, container, tailLines interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPodLogs", reflect.TypeOf((*MockClient)(nil).GetPodLogs), ctx, namespace, labelSelector, container, tailLines)
+}
+
+// GetPreviousContainerLogs mocks base method.
+func (m *MockClient) GetPreviousContainerLogs(ctx context.Context, namespace, pod, container string, tailLines int64) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPreviousContainerLogs", ctx, namespace, pod, container, tailLines)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPreviousContainerLogs indicates an expected call of GetPreviousContainerLogs.
+func (mr *MockClientMockRecorder) GetPreviousContainerLogs(ctx, namespace, pod, container, tailLines interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPreviousContainerLogs", reflect.TypeOf((*MockClient)(nil).GetPreviousContainerLogs), ctx, namespace, pod, container, tailLines)
+}
+
+// GetSecret mocks base method.
+func (m *MockClient) GetSecret(ctx context.Context, namespace, name string) (*v10.Secret, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSecret", ctx, namespace, name)
+	ret0, _ := ret[0].(*v10.Secret)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSecret indicates an expected call of GetSecret.
+func (mr *MockClientMockRecorder) GetSecret(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSecret", reflect.TypeOf((*MockClient)(nil).GetSecret), ctx, namespace, name)
+}
+
+// GetService mocks base method.
+func (m *MockClient) GetService(ctx context.Context, namespace, name string) (*v10.Service, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetService", ctx, namespace, name)
+	ret0, _ := ret[0].(*v10.Service)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetService indicates an expected call of GetService.
+func (mr *MockClientMockRecorder) GetService(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetService", reflect.TypeOf((*MockClient)(nil).GetService), ctx, namespace, name)
+}
+
+// Init mocks base method.
+func (m *MockClient) Init(ctx context.Context, kubeContextName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Init", ctx, kubeContextName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Init indicates an expected call of Init.
+func (mr *MockClientMockRecorder) Init(ctx, kubeContextName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Init", reflect.TypeOf((*MockClient)(nil).Init), ctx, kubeContextName)
+}
+
+// ListDeploymentReplicaSets mocks base method.
+func (m *MockClient) ListDeploymentReplicaSets(ctx context.Context, namespace, name string) ([]v1.ReplicaSet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeploymentReplicaSets", ctx, namespace, name)
+	ret0, _ := ret[0].([]v1.ReplicaSet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeploymentReplicaSets indicates an expected call of ListDeploymentReplicaSets.
+func (mr *MockClientMockRecorder) ListDeploymentReplicaSets(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeploymentReplicaSets", reflect.TypeOf((*MockClient)(nil).ListDeploymentReplicaSets), ctx, namespace, name)
+}
+
+// ListDeployments mocks base method.
+func (m *MockClient) ListDeployments(ctx context.Context, namespace, labelSelector string) ([]v1.Deployment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeployments", ctx, namespace, labelSelector)
+	ret0, _ := ret[0].([]v1.Deployment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeployments indicates an expected call of ListDeployments.
+func (mr *MockClientMockRecorder) ListDeployments(ctx, namespace, labelSelector interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeployments", reflect.TypeOf((*MockClient)(nil).ListDeployments), ctx, namespace, labelSelector)
+}
+
+// ListEvents mocks base method.
+func (m *MockClient) ListEvents(ctx context.Context, namespace, kind, name string) ([]v10.Event, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEvents", ctx, namespace, kind, name)
+	ret0, _ := ret[0].([]v10.Event)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEvents indicates an expected call of ListEvents.
+func (mr *MockClientMockRecorder) ListEvents(ctx, namespace, kind, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEvents", reflect.TypeOf((*MockClient)(nil).ListEvents), ctx, namespace, kind, name)
+}
+
+// ListPods mocks base method.
+func (m *MockClient) ListPods(ctx context.Context, namespace, labelSelector string) ([]v10.Pod, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPods", ctx, namespace, labelSelector)
+	ret0, _ := ret[0].([]v10.Pod)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPods indicates an expected call of ListPods.
+func (mr *MockClientMockRecorder) ListPods(ctx, namespace, labelSelector interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPods", reflect.TypeOf((*MockClient)(nil).ListPods), ctx, namespace, labelSelector)
+}
+
+// ListResources mocks base method.
+func (m *MockClient) ListResources(ctx context.Context, resources []schema.GroupVersionResource, namespace, labelSelector string) ([]unstructured.Unstructured, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListResources", ctx, resources, namespace, labelSelector)
+	ret0, _ := ret[0].([]unstructured.Unstructured)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListResources indicates an expected call of ListResources.
+func (mr *MockClientMockRecorder) ListResources(ctx, resources, namespace, labelSelector interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListResources", reflect.TypeOf((*MockClient)(nil).ListResources), ctx, resources, namespace, labelSelector)
+}
+
+// ListStatefulSetRevisions mocks base method.
+func (m *MockClient) ListStatefulSetRevisions(ctx context.Context, namespace, name string) ([]v1.ControllerRevision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListStatefulSetRevisions", ctx, namespace, name)
+	ret0, _ := ret[0].([]v1.ControllerRevision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListStatefulSetRevisions indicates an expected call of ListStatefulSetRevisions.
+func (mr *MockClientMockRecorder) ListStatefulSetRevisions(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListStatefulSetRevisions", reflect.TypeOf((*MockClient)(nil).ListStatefulSetRevisions), ctx, namespace, name)
+}
+
+// PatchResource mocks base method.
+func (m *MockClient) PatchResource(ctx context.Context, resource schema.GroupVersionResource, namespace, name string, patchType types.PatchType, patch []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchResource", ctx, resource, namespace, name, patchType, patch)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PatchResource indicates an expected call of PatchResource.
+func (mr *MockClientMockRecorder) PatchResource(ctx, resource, namespace, name, patchType, patch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchResource", reflect.TypeOf((*MockClient)(nil).PatchResource), ctx, resource, namespace, name, patchType, patch)
+}
+
+// ReleaseLease mocks base method.
+func (m *MockClient) ReleaseLease(ctx context.Context, namespace, name, identity string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseLease", ctx, namespace, name, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseLease indicates an expected call of ReleaseLease.
+func (mr *MockClientMockRecorder) ReleaseLease(ctx, namespace, name, identity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseLease", reflect.TypeOf((*MockClient)(nil).ReleaseLease), ctx, namespace, name, identity)
+}
+
+// RemoveAnnotations mocks base method.
+func (m *MockClient) RemoveAnnotations(ctx context.Context, resource schema.GroupVersionResource, namespace, name string, annotations ...string) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, resource, namespace, name}
+	for _, a := range annotations {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "RemoveAnnotations", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveAnnotations indicates an expected call of RemoveAnnotations.
+func (mr *MockClientMockRecorder) RemoveAnnotations(ctx, resource, namespace, name interface{}, annotations ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, resource, namespace, name}, annotations...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveAnnotations", reflect.TypeOf((*MockClient)(nil).RemoveAnnotations), varargs...)
+}
+
+// RenewLease mocks base method.
+func (m *MockClient) RenewLease(ctx context.Context, namespace, name, identity string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenewLease", ctx, namespace, name, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RenewLease indicates an expected call of RenewLease.
+func (mr *MockClientMockRecorder) RenewLease(ctx, namespace, name, identity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenewLease", reflect.TypeOf((*MockClient)(nil).RenewLease), ctx, namespace, name, identity)
+}
+
+// RestartDeployment mocks base method.
+func (m *MockClient) RestartDeployment(ctx context.Context, namespace, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestartDeployment", ctx, namespace, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestartDeployment indicates an expected call of RestartDeployment.
+func (mr *MockClientMockRecorder) RestartDeployment(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestartDeployment", reflect.TypeOf((*MockClient)(nil).RestartDeployment), ctx, namespace, name)
+}
+
+// RollbackDeployment mocks base method.
+func (m *MockClient) RollbackDeployment(ctx context.Context, namespace, name string, replicaSet v1.ReplicaSet) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RollbackDeployment", ctx, namespace, name, replicaSet)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RollbackDeployment indicates an expected call of RollbackDeployment.
+func (mr *MockClientMockRecorder) RollbackDeployment(ctx, namespace, name, replicaSet interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RollbackDeployment", reflect.TypeOf((*MockClient)(nil).RollbackDeployment), ctx, namespace, name, replicaSet)
+}
+
+// RollbackStatefulSet mocks base method.
+func (m *MockClient) RollbackStatefulSet(ctx context.Context, namespace, name string, revision v1.ControllerRevision) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RollbackStatefulSet", ctx, namespace, name, revision)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RollbackStatefulSet indicates an expected call of RollbackStatefulSet.
+func (mr *MockClientMockRecorder) RollbackStatefulSet(ctx, namespace, name, revision interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RollbackStatefulSet", reflect.TypeOf((*MockClient)(nil).RollbackStatefulSet), ctx, namespace, name, revision)
+}
+
+// RunPod mocks base method.
+func (m *MockClient) RunPod(ctx context.Context, pod *v10.Pod, timeout time.Duration) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunPod", ctx, pod, timeout)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunPod indicates an expected call of RunPod.
+func (mr *MockClientMockRecorder) RunPod(ctx, pod, timeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunPod", reflect.TypeOf((*MockClient)(nil).RunPod), ctx, pod, timeout)
+}
+
+// SuspendCronJob mocks base method.
+func (m *MockClient) SuspendCronJob(ctx context.Context, namespace, name string, suspend bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SuspendCronJob", ctx, namespace, name, suspend)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SuspendCronJob indicates an expected call of SuspendCronJob.
+func (mr *MockClientMockRecorder) SuspendCronJob(ctx, namespace, name, suspend interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SuspendCronJob", reflect.TypeOf((*MockClient)(nil).SuspendCronJob), ctx, namespace, name, suspend)
+}
+
+// TriggerCronJob mocks base method.
+func (m *MockClient) TriggerCronJob(ctx context.Context, namespace, name string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TriggerCronJob", ctx, namespace, name)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TriggerCronJob indicates an expected call of TriggerCronJob.
+func (mr *MockClientMockRecorder) TriggerCronJob(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TriggerCronJob", reflect.TypeOf((*MockClient)(nil).TriggerCronJob), ctx, namespace, name)
+}
+
+// ValidateManifests mocks base method.
+func (m *MockClient) ValidateManifests(ctx context.Context, namespace string, manifests []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateManifests", ctx, namespace, manifests)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateManifests indicates an expected call of ValidateManifests.
+func (mr *MockClientMockRecorder) ValidateManifests(ctx, namespace, manifests interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateManifests", reflect.TypeOf((*MockClient)(nil).ValidateManifests), ctx, namespace, manifests)
+}
+
+// WaitForDeletion mocks base method.
+func (m *MockClient) WaitForDeletion(ctx context.Context, resource schema.GroupVersionResource, namespace, name string, timeout time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForDeletion", ctx, resource, namespace, name, timeout)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaitForDeletion indicates an expected call of WaitForDeletion.
+func (mr *MockClientMockRecorder) WaitForDeletion(ctx, resource, namespace, name, timeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForDeletion", reflect.TypeOf((*MockClient)(nil).WaitForDeletion), ctx, resource, namespace, name, timeout)
+}
+
+// WaitForDeploymentRollout mocks base method.
+func (m *MockClient) WaitForDeploymentRollout(ctx context.Context, namespace, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForDeploymentRollout", ctx, namespace, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaitForDeploymentRollout indicates an expected call of WaitForDeploymentRollout.
+func (mr *MockClientMockRecorder) WaitForDeploymentRollout(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForDeploymentRollout", reflect.TypeOf((*MockClient)(nil).WaitForDeploymentRollout), ctx, namespace, name)
+}
+
+// WaitForJobCompletion mocks base method.
+func (m *MockClient) WaitForJobCompletion(ctx context.Context, namespace, name string, timeout time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForJobCompletion", ctx, namespace, name, timeout)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaitForJobCompletion indicates an expected call of WaitForJobCompletion.
+func (mr *MockClientMockRecorder) WaitForJobCompletion(ctx, namespace, name, timeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForJobCompletion", reflect.TypeOf((*MockClient)(nil).WaitForJobCompletion), ctx, namespace, name, timeout)
+}
+
+// WaitForStatefulSetRollout mocks base method.
+func (m *MockClient) WaitForStatefulSetRollout(ctx context.Context, namespace, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForStatefulSetRollout", ctx, namespace, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaitForStatefulSetRollout indicates an expected call of WaitForStatefulSetRollout.
+func (mr *MockClientMockRecorder) WaitForStatefulSetRollout(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForStatefulSetRollout", reflect.TypeOf((*MockClient)(nil).WaitForStatefulSetRollout), ctx, namespace, name)
+}
@@ -0,0 +1,109 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RevisionAnnotation is set by the deployment controller on deployments and their replicasets
+	RevisionAnnotation = "deployment.kubernetes.io/revision"
+)
+
+// Revision returns the revision of a deployment or replicaset, or 0 if it doesn't have one yet
+func Revision(obj metav1.Object) int64 {
+	revision, err := strconv.ParseInt(obj.GetAnnotations()[RevisionAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return revision
+}
+
+// deploymentRolloutStatus mirrors the checks done by kubectl rollout status for deployments
+func deploymentRolloutStatus(deployment *appsv1.Deployment) (status string, done bool, err error) {
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return fmt.Sprintf("Waiting for deployment %q spec update to be observed...", deployment.Name), false, nil
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Status == corev1.ConditionFalse && condition.Reason == "ProgressDeadlineExceeded" {
+			return "", false, ErrRolloutFailed.wrap(ErrRolloutTimedOut.wrap(fmt.Errorf("deployment %q exceeded its progress deadline", deployment.Name)))
+		}
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	if deployment.Status.UpdatedReplicas < replicas {
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d out of %d new replicas have been updated...", deployment.Name, deployment.Status.UpdatedReplicas, replicas), false, nil
+	}
+	if deployment.Status.Replicas > deployment.Status.UpdatedReplicas {
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d old replicas are pending termination...", deployment.Name, deployment.Status.Replicas-deployment.Status.UpdatedReplicas), false, nil
+	}
+	if deployment.Status.AvailableReplicas < deployment.Status.UpdatedReplicas {
+		return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d of %d updated replicas are available...", deployment.Name, deployment.Status.AvailableReplicas, deployment.Status.UpdatedReplicas), false, nil
+	}
+
+	return fmt.Sprintf("deployment %q successfully rolled out", deployment.Name), true, nil
+}
+
+// jobCompletionStatus mirrors the checks done by kubectl wait --for=condition=complete for jobs, but fails early once the job has failed
+func jobCompletionStatus(job *batchv1.Job) (status string, done bool, err error) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return fmt.Sprintf("job %q completed", job.Name), true, nil
+		case batchv1.JobFailed:
+			return "", false, ErrJobFailed.wrap(fmt.Errorf("job %q failed: %v", job.Name, condition.Message))
+		}
+	}
+
+	completions := int32(1)
+	if job.Spec.Completions != nil {
+		completions = *job.Spec.Completions
+	}
+
+	return fmt.Sprintf("Waiting for job %q to complete: %d out of %d pods succeeded, %d active, %d failed...", job.Name, job.Status.Succeeded, completions, job.Status.Active, job.Status.Failed), false, nil
+}
+
+// statefulSetRolloutStatus mirrors the checks done by kubectl rollout status for statefulsets
+func statefulSetRolloutStatus(statefulset *appsv1.StatefulSet) (status string, done bool, err error) {
+	if statefulset.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		return fmt.Sprintf("statefulset %q uses update strategy %v, not waiting for the rollout", statefulset.Name, statefulset.Spec.UpdateStrategy.Type), true, nil
+	}
+	if statefulset.Status.ObservedGeneration == 0 || statefulset.Generation > statefulset.Status.ObservedGeneration {
+		return fmt.Sprintf("Waiting for statefulset %q spec update to be observed...", statefulset.Name), false, nil
+	}
+
+	replicas := int32(1)
+	if statefulset.Spec.Replicas != nil {
+		replicas = *statefulset.Spec.Replicas
+	}
+
+	if statefulset.Status.ReadyReplicas < replicas {
+		return fmt.Sprintf("Waiting for %d pods to be ready...", replicas-statefulset.Status.ReadyReplicas), false, nil
+	}
+	if statefulset.Spec.UpdateStrategy.RollingUpdate != nil && statefulset.Spec.UpdateStrategy.RollingUpdate.Partition != nil {
+		partition := *statefulset.Spec.UpdateStrategy.RollingUpdate.Partition
+		if statefulset.Status.UpdatedReplicas < replicas-partition {
+			return fmt.Sprintf("Waiting for partitioned roll out to finish: %d out of %d new pods have been updated...", statefulset.Status.UpdatedReplicas, replicas-partition), false, nil
+		}
+		return fmt.Sprintf("partitioned roll out complete: %d new pods have been updated...", statefulset.Status.UpdatedReplicas), true, nil
+	}
+	if statefulset.Status.UpdateRevision != statefulset.Status.CurrentRevision {
+		return fmt.Sprintf("waiting for statefulset %q rolling update to complete %d pods at revision %s...", statefulset.Name, statefulset.Status.UpdatedReplicas, statefulset.Status.UpdateRevision), false, nil
+	}
+
+	return fmt.Sprintf("statefulset %q rolling update complete %d pods at revision %s...", statefulset.Name, statefulset.Status.CurrentReplicas, statefulset.Status.CurrentRevision), true, nil
+}
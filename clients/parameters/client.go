@@ -2,79 +2,251 @@ package parameters
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/estafette/estafette-extension-gke/clients/registry"
 	"github.com/rs/zerolog/log"
-	"gopkg.in/yaml.v2"
+)
+
+const (
+	// namespaceDefaultsName is the configmap holding the defaults for all applications in a namespace
+	namespaceDefaultsName = "estafette-gke-defaults"
+	namespaceDefaultsKey  = "defaults.yaml"
 )
 
 //go:generate mockgen -package=parameters -destination ./mock.go -source=client.go
 type Client interface {
-	Init(ctx context.Context, paramsYAML string, credential *api.GKECredentials, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID string) (parameters api.Params, err error)
+	Init(ctx context.Context, paramsYAML string, credential *api.GKECredentials, namespaceDefaults, resolveImages bool, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID string) (parameters api.Params, err error)
+	Explain(ctx context.Context, paramsYAML string, credential *api.GKECredentials, namespaceDefaults bool, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID string) (explanations []api.ParamExplanation, err error)
 }
 
-// NewClient returns a new gcp.Client
-func NewClient(ctx context.Context) (Client, error) {
-	return &client{}, nil
+// NewClient returns a new parameters.Client
+func NewClient(ctx context.Context, kubernetesClient kubernetes.Client, registryClient registry.Client) (Client, error) {
+	return &client{
+		kubernetesClient: kubernetesClient,
+		registryClient:   registryClient,
+	}, nil
 }
 
 type client struct {
+	kubernetesClient kubernetes.Client
+	registryClient   registry.Client
 }
 
-func (c *client) Init(ctx context.Context, paramsYAML string, credential *api.GKECredentials, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID string) (parameters api.Params, err error) {
+// Init merges, defaults and validates the parameters; with resolveImages the images of actions that deploy them get resolved in their
+// registries, which render leaves out so its output doesn't depend on the network
+func (c *client) Init(ctx context.Context, paramsYAML string, credential *api.GKECredentials, namespaceDefaults, resolveImages bool, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID string) (parameters api.Params, err error) {
 
-	// put all estafette labels in map
-	log.Info().Msg("Getting all estafette labels from envvars...")
-	estafetteLabels := map[string]string{}
-	for _, e := range os.Environ() {
-		kvPair := strings.SplitN(e, "=", 2)
+	parameters, _, unknownParams, err := c.mergeLayers(ctx, paramsYAML, credential, namespaceDefaults)
+	if err != nil {
+		return
+	}
 
-		if len(kvPair) == 2 {
-			envvarName := kvPair[0]
-			envvarValue := kvPair[1]
+	log.Info().Msg("Setting defaults for parameters that are not set in the manifest...")
+	parameters.SetDefaults(gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, api.ActionType(releaseAction), releaseID, getEstafetteLabels())
 
-			if strings.HasPrefix(envvarName, "ESTAFETTE_LABEL_") && !strings.HasSuffix(envvarName, "_DNS_SAFE") {
-				// strip prefix and convert to lowercase
-				key := strings.ToLower(strings.Replace(envvarName, "ESTAFETTE_LABEL_", "", 1))
-				estafetteLabels[key] = envvarValue
-			}
+	log.Info().Msg("Validating required parameters...")
+	valid, errors, warnings := parameters.ValidateRequiredProperties()
+
+	// misspelled parameters are silently ignored otherwise; strict makes them fail the release
+	for _, unknownParam := range unknownParams {
+		if parameters.Strict {
+			errors = append(errors, fmt.Errorf("%v", unknownParam))
+			valid = false
+		} else {
+			warnings = append(warnings, unknownParam)
 		}
 	}
+	if !valid {
+		return parameters, fmt.Errorf("Not all valid fields are set: %v", errors)
+	}
 
+	for _, warning := range warnings {
+		log.Printf("Warning: %s", warning)
+	}
+
+	if !resolveImages || !parameters.DeploysImages() {
+		return
+	}
+	registryCredentials := getRegistryCredentials(parameters, credential)
+
+	// check the images before pinning them, so a tag that was never pushed fails the release instead of the pods
+	log.Info().Msgf("Checking images exist for platform %v...", parameters.TargetPlatform())
+	imageErrors, imageWarnings := parameters.CheckImages(c.lookupImage(ctx, registryCredentials))
+	for _, warning := range imageWarnings {
+		log.Printf("Warning: %s", warning)
+	}
+	if len(imageErrors) > 0 {
+		return parameters, fmt.Errorf("Not all images can be deployed: %v", imageErrors)
+	}
+
+	// pin the images to the digest their tag points at, so a tag that gets pushed again doesn't change what a release runs
+	log.Info().Msg("Pinning images to digests...")
+	for _, warning := range parameters.PinImageDigests(func(image string) (string, error) {
+		manifest, err := c.registryClient.GetManifest(ctx, image, registryCredentials)
+		return manifest.Digest, err
+	}) {
+		log.Printf("Warning: %s", warning)
+	}
+
+	return
+}
+
+// Explain returns the effective value of every parameter together with the layer it comes from; parameters that don't validate are logged
+// instead of failing, since explaining them helps to find out why
+func (c *client) Explain(ctx context.Context, paramsYAML string, credential *api.GKECredentials, namespaceDefaults bool, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID string) (explanations []api.ParamExplanation, err error) {
+
+	parameters, sources, unknownParams, err := c.mergeLayers(ctx, paramsYAML, credential, namespaceDefaults)
+	if err != nil {
+		return
+	}
+
+	parameters.SetDefaults(gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, api.ActionType(releaseAction), releaseID, getEstafetteLabels())
+
+	_, errors, warnings := parameters.ValidateRequiredProperties()
+	for _, err := range errors {
+		log.Printf("Error: %v", err)
+	}
+	for _, warning := range append(warnings, unknownParams...) {
+		log.Printf("Warning: %s", warning)
+	}
+
+	return api.ExplainParams(parameters, sources)
+}
+
+// mergeLayers merges the defaults of the credential, the defaults of the namespace and the parameters of the stage, in that order; the
+// extension's built-in defaults get applied afterwards by SetDefaults, to whatever none of the layers set
+func (c *client) mergeLayers(ctx context.Context, paramsYAML string, credential *api.GKECredentials, namespaceDefaults bool) (parameters api.Params, sources map[string]string, unknownParams []string, err error) {
+
+	layers := []api.ParamsLayer{}
 	if credential.AdditionalProperties.Defaults != nil {
 		log.Info().Msgf("Using defaults from credential %v...", credential.Name)
-		// todo log just the specified defaults, not the entire parms object
-		// defaultsAsYAML, err := yaml.Marshal(credential.AdditionalProperties.Defaults)
-		// if err == nil {
-		// 	log.Printf(string(defaultsAsYAML))
-		// }
-		parameters = *credential.AdditionalProperties.Defaults
+		credentialLayer, err := api.NewParamsLayerFromParams(api.ParamsLayerCredential, credential.AdditionalProperties.Defaults)
+		if err != nil {
+			return parameters, nil, nil, err
+		}
+		layers = append(layers, credentialLayer)
 	}
 
 	log.Info().Msg("Unmarshalling parameters / custom properties...")
-	err = yaml.Unmarshal([]byte(paramsYAML), &parameters)
+	stageLayer, err := api.NewParamsLayer(api.ParamsLayerStage, []byte(paramsYAML))
 	if err != nil {
-		return parameters, fmt.Errorf("Failed unmarshalling parameters: %w", err)
+		return parameters, nil, nil, err
 	}
 
-	log.Info().Msg("Setting defaults for parameters that are not set in the manifest...")
-	parameters.SetDefaults(gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, api.ActionType(releaseAction), releaseID, estafetteLabels)
+	log.Info().Msg("Checking for unknown parameters...")
+	unknownParams, err = api.FindUnknownParams([]byte(paramsYAML))
+	if err != nil {
+		return parameters, nil, nil, err
+	}
 
-	log.Info().Msg("Validating required parameters...")
-	valid, errors, warnings := parameters.ValidateRequiredProperties()
-	if !valid {
-		return parameters, fmt.Errorf("Not all valid fields are set: %v", errors)
+	if namespaceDefaults {
+		// the namespace the defaults get read from can only be set by the credential or the stage
+		parameters, _, err = api.MergeParamsLayers(append(layers, stageLayer)...)
+		if err != nil {
+			return parameters, nil, nil, err
+		}
+		if parameters.Namespace != "" {
+			namespaceLayer, ok, err := c.getNamespaceDefaults(ctx, parameters.Namespace)
+			if err != nil {
+				return parameters, nil, nil, err
+			}
+			if ok {
+				layers = append(layers, namespaceLayer)
+			}
+		}
 	}
 
-	for _, warning := range warnings {
-		log.Printf("Warning: %s", warning)
+	parameters, sources, err = api.MergeParamsLayers(append(layers, stageLayer)...)
+	if err != nil {
+		return parameters, nil, nil, err
 	}
 
-	// replacing sidecar image tags with digest
-	parameters.ReplaceSidecarTagsWithDigest()
+	return parameters, sources, unknownParams, nil
+}
 
-	return
+// getNamespaceDefaults returns the defaults from the namespace's defaults configmap, if it exists
+func (c *client) getNamespaceDefaults(ctx context.Context, namespace string) (layer api.ParamsLayer, ok bool, err error) {
+	configMap, err := c.kubernetesClient.GetConfigMap(ctx, namespace, namespaceDefaultsName)
+	if errors.Is(err, kubernetes.ErrResourceNotFound) {
+		return layer, false, nil
+	}
+	if err != nil {
+		return layer, false, fmt.Errorf("Failed retrieving defaults of namespace %v: %w", namespace, err)
+	}
+
+	log.Info().Msgf("Using defaults from configmap %v in namespace %v...", namespaceDefaultsName, namespace)
+	layer, err = api.NewParamsLayer(api.ParamsLayerNamespace, []byte(configMap.Data[namespaceDefaultsKey]))
+	if err != nil {
+		return layer, false, fmt.Errorf("Failed reading defaults of namespace %v: %w", namespace, err)
+	}
+
+	if _, ok := layer.Values["namespace"]; ok {
+		log.Warn().Msgf("Ignoring namespace parameter in configmap %v in namespace %v", namespaceDefaultsName, namespace)
+		delete(layer.Values, "namespace")
+	}
+
+	return layer, true, nil
+}
+
+// lookupImage returns an api.ImageLookup retrieving the platforms of an image from its registry; a manifest that isn't found means the image
+// doesn't exist, any other error means the registry couldn't be asked
+func (c *client) lookupImage(ctx context.Context, credentials []registry.Credential) api.ImageLookup {
+	return func(image string) (platforms []string, exists bool, err error) {
+		registryPlatforms, err := c.registryClient.GetPlatforms(ctx, image, credentials)
+		if errors.Is(err, registry.ErrManifestNotFound) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, true, err
+		}
+		for _, platform := range registryPlatforms {
+			platforms = append(platforms, platform.String())
+		}
+
+		return platforms, true, nil
+	}
+}
+
+// getRegistryCredentials returns the credentials to retrieve image manifests with; the image pull secret is for docker hub, like the secret
+// the generator renders, and the service account of the credential gives access to container registry and artifact registry
+func getRegistryCredentials(parameters api.Params, credential *api.GKECredentials) (credentials []registry.Credential) {
+	if parameters.ImagePullSecretUser != "" && parameters.ImagePullSecretPassword != "" {
+		credentials = append(credentials, registry.Credential{Registry: "registry-1.docker.io", Username: parameters.ImagePullSecretUser, Password: parameters.ImagePullSecretPassword})
+	}
+
+	if credential != nil && credential.AdditionalProperties.ServiceAccountKeyfile != "" {
+		for _, registryHost := range []string{"gcr.io", "*.gcr.io", "*-docker.pkg.dev"} {
+			credentials = append(credentials, registry.Credential{Registry: registryHost, Username: "_json_key", Password: credential.AdditionalProperties.ServiceAccountKeyfile})
+		}
+	}
+
+	return credentials
+}
+
+// getEstafetteLabels returns all estafette labels from the envvars
+func getEstafetteLabels() map[string]string {
+	log.Info().Msg("Getting all estafette labels from envvars...")
+	estafetteLabels := map[string]string{}
+	for _, e := range os.Environ() {
+		kvPair := strings.SplitN(e, "=", 2)
+
+		if len(kvPair) == 2 {
+			envvarName := kvPair[0]
+			envvarValue := kvPair[1]
+
+			if strings.HasPrefix(envvarName, "ESTAFETTE_LABEL_") && !strings.HasSuffix(envvarName, "_DNS_SAFE") {
+				// strip prefix and convert to lowercase
+				key := strings.ToLower(strings.Replace(envvarName, "ESTAFETTE_LABEL_", "", 1))
+				estafetteLabels[key] = envvarValue
+			}
+		}
+	}
+
+	return estafetteLabels
 }
@@ -0,0 +1,268 @@
+package parameters
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/estafette/estafette-extension-gke/clients/registry"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestExplain(t *testing.T) {
+
+	credential := &api.GKECredentials{
+		Name: "gke-production",
+		AdditionalProperties: api.GKECredentialAdditionalProperties{
+			Defaults: &api.Params{
+				Namespace: "mynamespace",
+				Labels:    map[string]string{"team": "myteam"},
+			},
+		},
+	}
+
+	// avoids retrieving cloudflare's ips
+	paramsYAML := "container:\n  repository: myrepository\ntrustedips:\n- 10.0.0.0/8\nlabels:\n  costcenter: null\n"
+
+	t.Run("ReturnsValuesFromTheNamespaceDefaults", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		client, err := NewClient(context.Background(), kubernetesClient, nil)
+		assert.Nil(t, err)
+
+		kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", "estafette-gke-defaults").Return(&corev1.ConfigMap{
+			Data: map[string]string{"defaults.yaml": "namespace: othernamespace\nlabels:\n  costcenter: cc123\nvisibility: public\n"},
+		}, nil)
+
+		// act
+		explanations, err := client.Explain(context.Background(), paramsYAML, credential, true, "", "", "", "myapp", "1.0.0", "", "", "")
+
+		assert.Nil(t, err)
+		sources := map[string]string{}
+		for _, e := range explanations {
+			sources[e.Path] = e.Source + ":" + e.Value
+		}
+		assert.Equal(t, "credential:mynamespace", sources["namespace"])
+		assert.Equal(t, "credential:myteam", sources["labels.team"])
+		assert.Equal(t, "namespace:public", sources["visibility"])
+		assert.Equal(t, "stage:myrepository", sources["container.repository"])
+		assert.Equal(t, "built-in:myapp", sources["app"])
+		_, hasCostcenter := sources["labels.costcenter"]
+		assert.False(t, hasCostcenter)
+	})
+
+	t.Run("SkipsNamespaceDefaultsIfConfigMapDoesNotExist", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		client, err := NewClient(context.Background(), kubernetesClient, nil)
+		assert.Nil(t, err)
+
+		kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", "estafette-gke-defaults").Return(nil, kubernetes.ErrResourceNotFound)
+
+		// act
+		explanations, err := client.Explain(context.Background(), paramsYAML, credential, true, "", "", "", "myapp", "1.0.0", "", "", "")
+
+		assert.Nil(t, err)
+		assert.Contains(t, explanations, api.ParamExplanation{Path: "visibility", Value: "private", Source: "built-in"})
+	})
+
+	t.Run("DoesNotReadNamespaceDefaultsIfNotRequested", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		client, err := NewClient(context.Background(), kubernetesClient, nil)
+		assert.Nil(t, err)
+
+		// act
+		explanations, err := client.Explain(context.Background(), paramsYAML, credential, false, "", "", "", "myapp", "1.0.0", "", "", "")
+
+		assert.Nil(t, err)
+		assert.Contains(t, explanations, api.ParamExplanation{Path: "namespace", Value: "mynamespace", Source: "credential"})
+	})
+}
+
+func TestInit(t *testing.T) {
+
+	t.Run("ReturnsErrorForUnknownParamsIfStrict", func(t *testing.T) {
+
+		client, err := NewClient(context.Background(), nil, nil)
+		assert.Nil(t, err)
+
+		paramsYAML := "kind: job\nstrict: true\nnamespace: mynamespace\ncontainer:\n  repository: myrepository\ntrustedips:\n- 10.0.0.0/8\nvisiblity: public\n"
+
+		// act
+		_, err = client.Init(context.Background(), paramsYAML, &api.GKECredentials{}, false, true, "", "", "", "myapp", "1.0.0", "", "", "")
+
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "Unknown parameter visiblity; did you mean visibility?")
+		}
+	})
+
+	t.Run("PinsImagesToDigestsUsingServiceAccountOfCredential", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		registryClient := registry.NewMockClient(ctrl)
+		client, err := NewClient(context.Background(), nil, registryClient)
+		assert.Nil(t, err)
+
+		credential := &api.GKECredentials{AdditionalProperties: api.GKECredentialAdditionalProperties{ServiceAccountKeyfile: "{}"}}
+		paramsYAML := "kind: job\nnamespace: mynamespace\ncontainer:\n  repository: europe-docker.pkg.dev/myproject/images\ntrustedips:\n- 10.0.0.0/8\n"
+
+		registryClient.EXPECT().GetPlatforms(gomock.Any(), "europe-docker.pkg.dev/myproject/images/myapp:1.0.0", gomock.Any()).
+			Return([]registry.Platform{{OS: "linux", Architecture: "amd64"}}, nil)
+		registryClient.EXPECT().GetManifest(gomock.Any(), "europe-docker.pkg.dev/myproject/images/myapp:1.0.0", gomock.Any()).
+			DoAndReturn(func(ctx context.Context, image string, credentials []registry.Credential) (registry.Manifest, error) {
+				assert.Contains(t, credentials, registry.Credential{Registry: "*-docker.pkg.dev", Username: "_json_key", Password: "{}"})
+				return registry.Manifest{Digest: "sha256:abc"}, nil
+			})
+
+		// act
+		params, err := client.Init(context.Background(), paramsYAML, credential, false, true, "", "", "", "myapp", "1.0.0", "", "", "")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "sha256:abc", params.Container.ImageDigest)
+	})
+
+	t.Run("ReturnsErrorIfImageDoesNotExist", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		registryClient := registry.NewMockClient(ctrl)
+		client, err := NewClient(context.Background(), nil, registryClient)
+		assert.Nil(t, err)
+
+		paramsYAML := "kind: job\nnamespace: mynamespace\ncontainer:\n  repository: europe-docker.pkg.dev/myproject/images\ntrustedips:\n- 10.0.0.0/8\n"
+
+		registryClient.EXPECT().GetPlatforms(gomock.Any(), "europe-docker.pkg.dev/myproject/images/myapp:1.0.0", gomock.Any()).
+			Return(nil, fmt.Errorf("Manifest europe-docker.pkg.dev/myproject/images/myapp:1.0.0: %w", registry.ErrManifestNotFound))
+
+		// act
+		_, err = client.Init(context.Background(), paramsYAML, &api.GKECredentials{}, false, true, "", "", "", "myapp", "1.0.0", "", "", "")
+
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "Image europe-docker.pkg.dev/myproject/images/myapp:1.0.0 of container doesn't exist in its registry")
+		}
+	})
+
+	t.Run("ReturnsErrorIfImageDoesNotSupportArchitecture", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		registryClient := registry.NewMockClient(ctrl)
+		client, err := NewClient(context.Background(), nil, registryClient)
+		assert.Nil(t, err)
+
+		paramsYAML := "kind: job\nnamespace: mynamespace\narchitecture: arm64\ncontainer:\n  repository: europe-docker.pkg.dev/myproject/images\ntrustedips:\n- 10.0.0.0/8\n"
+
+		registryClient.EXPECT().GetPlatforms(gomock.Any(), "europe-docker.pkg.dev/myproject/images/myapp:1.0.0", gomock.Any()).
+			Return([]registry.Platform{{OS: "linux", Architecture: "amd64"}}, nil)
+
+		// act
+		_, err = client.Init(context.Background(), paramsYAML, &api.GKECredentials{}, false, true, "", "", "", "myapp", "1.0.0", "", "", "")
+
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "doesn't support platform linux/arm64, only linux/amd64")
+		}
+	})
+
+	t.Run("PinsImagesIfRegistryCannotBeChecked", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		registryClient := registry.NewMockClient(ctrl)
+		client, err := NewClient(context.Background(), nil, registryClient)
+		assert.Nil(t, err)
+
+		paramsYAML := "kind: job\nnamespace: mynamespace\ncontainer:\n  repository: europe-docker.pkg.dev/myproject/images\ntrustedips:\n- 10.0.0.0/8\n"
+
+		registryClient.EXPECT().GetPlatforms(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, registry.ErrUnauthorized)
+		registryClient.EXPECT().GetManifest(gomock.Any(), gomock.Any(), gomock.Any()).Return(registry.Manifest{}, registry.ErrUnauthorized)
+
+		// act
+		params, err := client.Init(context.Background(), paramsYAML, &api.GKECredentials{}, false, true, "", "", "", "myapp", "1.0.0", "", "", "")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "", params.Container.ImageDigest)
+	})
+
+	t.Run("SkipsRegistryIfImagesAreNotResolved", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// without expected calls any request to the registry fails the test
+		registryClient := registry.NewMockClient(ctrl)
+		client, err := NewClient(context.Background(), nil, registryClient)
+		assert.Nil(t, err)
+
+		paramsYAML := "kind: job\nnamespace: mynamespace\ncontainer:\n  repository: europe-docker.pkg.dev/myproject/images\ntrustedips:\n- 10.0.0.0/8\n"
+
+		// act
+		params, err := client.Init(context.Background(), paramsYAML, &api.GKECredentials{}, false, false, "", "", "", "myapp", "1.0.0", "", "", "")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "", params.Container.ImageDigest)
+	})
+
+	t.Run("SkipsRegistryForActionsThatDoNotDeployImages", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// without expected calls any request to the registry fails the test
+		registryClient := registry.NewMockClient(ctrl)
+		client, err := NewClient(context.Background(), nil, registryClient)
+		assert.Nil(t, err)
+
+		paramsYAML := "kind: deployment\nnamespace: mynamespace\ncontainer:\n  repository: europe-docker.pkg.dev/myproject/images\nhosts:\n- myapp.estafette.io\ntrustedips:\n- 10.0.0.0/8\n"
+
+		// act
+		params, err := client.Init(context.Background(), paramsYAML, &api.GKECredentials{}, false, true, "", "", "", "myapp", "1.0.0", "", "delete", "")
+
+		assert.Nil(t, err)
+		assert.Equal(t, api.ActionDelete, params.Action)
+		assert.Equal(t, "", params.Container.ImageDigest)
+	})
+}
+
+func TestGetRegistryCredentials(t *testing.T) {
+
+	t.Run("ReturnsImagePullSecretForDockerHub", func(t *testing.T) {
+
+		params := api.Params{ImagePullSecretUser: "me", ImagePullSecretPassword: "secret"}
+
+		// act
+		credentials := getRegistryCredentials(params, &api.GKECredentials{})
+
+		assert.Equal(t, []registry.Credential{{Registry: "registry-1.docker.io", Username: "me", Password: "secret"}}, credentials)
+	})
+
+	t.Run("ReturnsServiceAccountForGoogleRegistries", func(t *testing.T) {
+
+		credential := &api.GKECredentials{AdditionalProperties: api.GKECredentialAdditionalProperties{ServiceAccountKeyfile: "{}"}}
+
+		// act
+		credentials := getRegistryCredentials(api.Params{}, credential)
+
+		assert.Equal(t, 3, len(credentials))
+		assert.Equal(t, "*-docker.pkg.dev", credentials[2].Registry)
+		assert.Equal(t, "_json_key", credentials[2].Username)
+	})
+}
@@ -35,17 +35,32 @@ func (m *MockClient) EXPECT() *MockClientMockRecorder {
 	return m.recorder
 }
 
+// Explain mocks base method.
+func (m *MockClient) Explain(ctx context.Context, paramsYAML string, credential *api.GKECredentials, namespaceDefaults bool, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID string) ([]api.ParamExplanation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Explain", ctx, paramsYAML, credential, namespaceDefaults, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID)
+	ret0, _ := ret[0].([]api.ParamExplanation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Explain indicates an expected call of Explain.
+func (mr *MockClientMockRecorder) Explain(ctx, paramsYAML, credential, namespaceDefaults, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Explain", reflect.TypeOf((*MockClient)(nil).Explain), ctx, paramsYAML, credential, namespaceDefaults, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID)
+}
+
 // Init mocks base method.
-func (m *MockClient) Init(ctx context.Context, paramsYAML string, credential *api.GKECredentials, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID string) (api.Params, error) {
+func (m *MockClient) Init(ctx context.Context, paramsYAML string, credential *api.GKECredentials, namespaceDefaults, resolveImages bool, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID string) (api.Params, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Init", ctx, paramsYAML, credential, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID)
+	ret := m.ctrl.Call(m, "Init", ctx, paramsYAML, credential, namespaceDefaults, resolveImages, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID)
 	ret0, _ := ret[0].(api.Params)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Init indicates an expected call of Init.
-func (mr *MockClientMockRecorder) Init(ctx, paramsYAML, credential, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID interface{}) *gomock.Call {
+func (mr *MockClientMockRecorder) Init(ctx, paramsYAML, credential, namespaceDefaults, resolveImages, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Init", reflect.TypeOf((*MockClient)(nil).Init), ctx, paramsYAML, credential, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Init", reflect.TypeOf((*MockClient)(nil).Init), ctx, paramsYAML, credential, namespaceDefaults, resolveImages, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID)
 }
@@ -0,0 +1,128 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrQueryFailed is returned when prometheus can't be reached or doesn't return a successful response
+	ErrQueryFailed = wrapError{msg: "The prometheus query failed"}
+
+	// ErrNoData is returned when a query returns an empty result, for example because there's no traffic yet
+	ErrNoData = wrapError{msg: "The prometheus query returned no data"}
+)
+
+//go:generate mockgen -package=prometheus -destination ./mock.go -source=client.go
+type Client interface {
+	Query(ctx context.Context, serverURL, query string) (value float64, err error)
+}
+
+// NewClient returns a new prometheus.Client
+func NewClient(ctx context.Context) (Client, error) {
+	return &client{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}, nil
+}
+
+type client struct {
+	httpClient *http.Client
+}
+
+type queryResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Data      struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+type vectorSample struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value"`
+}
+
+// Query runs an instant query and returns the value of the first sample; the query is expected to aggregate to a single series
+func (c *client) Query(ctx context.Context, serverURL, query string) (value float64, err error) {
+
+	queryURL := fmt.Sprintf("%v/api/v1/query?query=%v", strings.TrimSuffix(serverURL, "/"), url.QueryEscape(query))
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, queryURL, nil)
+	if err != nil {
+		return 0, ErrQueryFailed.wrap(err)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return 0, ErrQueryFailed.wrap(err)
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return 0, ErrQueryFailed.wrap(err)
+	}
+
+	var queryResponse queryResponse
+	err = json.Unmarshal(body, &queryResponse)
+	if err != nil {
+		return 0, ErrQueryFailed.wrap(fmt.Errorf("response with status code %v can't be unmarshalled: %w", response.StatusCode, err))
+	}
+
+	if response.StatusCode != http.StatusOK || queryResponse.Status != "success" {
+		return 0, ErrQueryFailed.wrap(fmt.Errorf("response has status code %v and error %v: %v", response.StatusCode, queryResponse.ErrorType, queryResponse.Error))
+	}
+
+	switch queryResponse.Data.ResultType {
+	case "vector":
+		var samples []vectorSample
+		err = json.Unmarshal(queryResponse.Data.Result, &samples)
+		if err != nil {
+			return 0, ErrQueryFailed.wrap(err)
+		}
+		if len(samples) == 0 {
+			return 0, ErrNoData
+		}
+		return parseSampleValue(samples[0].Value)
+
+	case "scalar":
+		var sample []interface{}
+		err = json.Unmarshal(queryResponse.Data.Result, &sample)
+		if err != nil {
+			return 0, ErrQueryFailed.wrap(err)
+		}
+		return parseSampleValue(sample)
+	}
+
+	return 0, ErrQueryFailed.wrap(fmt.Errorf("result type %v is not supported, use a query that returns a vector or scalar", queryResponse.Data.ResultType))
+}
+
+// parseSampleValue parses a [ <unix_time>, "<value>" ] pair as returned by the prometheus api
+func parseSampleValue(sample []interface{}) (float64, error) {
+	if len(sample) != 2 {
+		return 0, ErrQueryFailed.wrap(fmt.Errorf("sample %v doesn't consist of a timestamp and value", sample))
+	}
+
+	valueString, ok := sample[1].(string)
+	if !ok {
+		return 0, ErrQueryFailed.wrap(fmt.Errorf("sample value %v is not a string", sample[1]))
+	}
+
+	value, err := strconv.ParseFloat(valueString, 64)
+	if err != nil {
+		return 0, ErrQueryFailed.wrap(err)
+	}
+
+	return value, nil
+}
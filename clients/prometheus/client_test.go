@@ -0,0 +1,85 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuery(t *testing.T) {
+
+	t.Run("ReturnsValueOfFirstVectorSample", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v1/query", r.URL.Path)
+			assert.Equal(t, "sum(rate(nginx_http_requests_total{app='myapp'}[5m]))", r.URL.Query().Get("query"))
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1700000000.123,"0.25"]}]}}`))
+		}))
+		defer server.Close()
+
+		client, err := NewClient(context.Background())
+		assert.Nil(t, err)
+
+		// act
+		value, err := client.Query(context.Background(), server.URL, "sum(rate(nginx_http_requests_total{app='myapp'}[5m]))")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0.25, value)
+	})
+
+	t.Run("ReturnsValueOfScalar", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"status":"success","data":{"resultType":"scalar","result":[1700000000.123,"NaN"]}}`))
+		}))
+		defer server.Close()
+
+		client, err := NewClient(context.Background())
+		assert.Nil(t, err)
+
+		// act
+		value, err := client.Query(context.Background(), server.URL+"/", "scalar(vector(0) / 0)")
+
+		assert.Nil(t, err)
+		assert.True(t, math.IsNaN(value))
+	})
+
+	t.Run("ReturnsErrNoDataIfVectorIsEmpty", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+		}))
+		defer server.Close()
+
+		client, err := NewClient(context.Background())
+		assert.Nil(t, err)
+
+		// act
+		_, err = client.Query(context.Background(), server.URL, "up{app='myapp'}")
+
+		assert.True(t, errors.Is(err, ErrNoData))
+	})
+
+	t.Run("ReturnsErrQueryFailedIfQueryIsInvalid", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`))
+		}))
+		defer server.Close()
+
+		client, err := NewClient(context.Background())
+		assert.Nil(t, err)
+
+		// act
+		_, err = client.Query(context.Background(), server.URL, "sum(")
+
+		assert.True(t, errors.Is(err, ErrQueryFailed))
+		assert.Contains(t, err.Error(), "parse error")
+	})
+}
@@ -0,0 +1,367 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrManifestNotFound is returned when the registry doesn't have a manifest for the tag or digest of an image
+	ErrManifestNotFound = wrapError{msg: "The image manifest is not found"}
+
+	// ErrUnauthorized is returned when the registry doesn't allow pulling an image with the credentials it's been given
+	ErrUnauthorized = wrapError{msg: "The registry denied access to the image"}
+
+	// ErrRequestFailed is returned when the registry can't be reached or doesn't return a successful response
+	ErrRequestFailed = wrapError{msg: "The registry request failed"}
+)
+
+const (
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+)
+
+// manifestMediaTypes lists the manifests the client accepts, multi-arch indexes included so their digest gets returned instead of the one of a
+// single platform
+var manifestMediaTypes = []string{MediaTypeOCIIndex, MediaTypeDockerManifestList, MediaTypeOCIManifest, MediaTypeDockerManifest}
+
+var challengeParamRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+//go:generate mockgen -package=registry -destination ./mock.go -source=client.go
+type Client interface {
+	GetManifest(ctx context.Context, image string, credentials []Credential) (manifest Manifest, err error)
+	GetPlatforms(ctx context.Context, image string, credentials []Credential) (platforms []Platform, err error)
+}
+
+// NewClient returns a new registry.Client, talking the oci distribution api to any registry
+func NewClient(ctx context.Context) (Client, error) {
+	return &client{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		authorizations: map[string]string{},
+		manifests:      map[string]Manifest{},
+	}, nil
+}
+
+type client struct {
+	httpClient *http.Client
+
+	// authorizations caches the authorization header per repository, so the token only gets retrieved once
+	authorizations map[string]string
+
+	// manifests caches the manifests per image, since the same images get checked and pinned
+	manifests map[string]Manifest
+}
+
+// Credential holds the username and password to pull images from the registries matching Registry, a host that can contain wildcards like
+// *.gcr.io
+type Credential struct {
+	Registry string
+	Username string
+	Password string
+}
+
+// Platform is the operating system and cpu architecture an image runs on
+type Platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+func (p Platform) String() string {
+	if p.Variant != "" {
+		return fmt.Sprintf("%v/%v/%v", p.OS, p.Architecture, p.Variant)
+	}
+
+	return fmt.Sprintf("%v/%v", p.OS, p.Architecture)
+}
+
+// Manifest holds the digest and media type of the manifest of an image and, for a multi-arch index, the manifests per platform or otherwise the
+// digest of the image config
+type Manifest struct {
+	Reference Reference
+	Digest    string
+	MediaType string
+	Manifests []PlatformManifest
+	Config    string
+}
+
+// PlatformManifest is the manifest of one of the platforms of a multi-arch index
+type PlatformManifest struct {
+	Digest   string   `json:"digest"`
+	Platform Platform `json:"platform"`
+}
+
+// IsIndex returns whether the manifest is a multi-arch index pointing at a manifest per platform
+func (m Manifest) IsIndex() bool {
+	return m.MediaType == MediaTypeOCIIndex || m.MediaType == MediaTypeDockerManifestList
+}
+
+// GetManifest retrieves the manifest of an image by its tag or digest, authenticating with the first of the credentials matching its registry
+func (c *client) GetManifest(ctx context.Context, image string, credentials []Credential) (manifest Manifest, err error) {
+	if manifest, ok := c.manifests[image]; ok {
+		return manifest, nil
+	}
+
+	reference, err := ParseReference(image)
+	if err != nil {
+		return manifest, err
+	}
+
+	manifestURL := fmt.Sprintf("https://%v/v2/%v/manifests/%v", reference.Registry, reference.Repository, reference.manifestReference())
+	response, body, err := c.get(ctx, reference, manifestURL, matchCredential(reference.Registry, credentials))
+	if err != nil {
+		return manifest, err
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return manifest, ErrManifestNotFound.wrap(fmt.Errorf("%v doesn't exist", reference))
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return manifest, ErrUnauthorized.wrap(fmt.Errorf("pulling %v returned status code %v", reference, response.StatusCode))
+	default:
+		return manifest, ErrRequestFailed.wrap(fmt.Errorf("retrieving manifest of %v returned status code %v", reference, response.StatusCode))
+	}
+
+	var manifestBody struct {
+		MediaType string             `json:"mediaType"`
+		Manifests []PlatformManifest `json:"manifests"`
+		Config    struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+	}
+	err = json.Unmarshal(body, &manifestBody)
+	if err != nil {
+		return manifest, ErrRequestFailed.wrap(fmt.Errorf("manifest of %v can't be unmarshalled: %w", reference, err))
+	}
+
+	manifest = Manifest{
+		Reference: reference,
+		Digest:    response.Header.Get("Docker-Content-Digest"),
+		MediaType: manifestBody.MediaType,
+	}
+	if manifest.Digest == "" {
+		// the digest header is optional, the digest of the manifest is the hash of its exact bytes
+		manifest.Digest = fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	}
+	if manifest.MediaType == "" {
+		manifest.MediaType, _, _ = mime.ParseMediaType(response.Header.Get("Content-Type"))
+	}
+	if manifest.IsIndex() {
+		manifest.Manifests = manifestBody.Manifests
+	} else {
+		manifest.Config = manifestBody.Config.Digest
+	}
+	c.manifests[image] = manifest
+
+	return manifest, nil
+}
+
+// GetPlatforms returns the platforms an image supports; those of a multi-arch index are listed in its manifest, the platform of a single
+// image is in its config
+func (c *client) GetPlatforms(ctx context.Context, image string, credentials []Credential) (platforms []Platform, err error) {
+	manifest, err := c.GetManifest(ctx, image, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.IsIndex() {
+		for _, m := range manifest.Manifests {
+			// buildx adds attestations to the index as manifests with platform unknown/unknown
+			if m.Platform.OS == "" || m.Platform.OS == "unknown" {
+				continue
+			}
+			platforms = append(platforms, m.Platform)
+		}
+		return platforms, nil
+	}
+
+	if manifest.Config == "" {
+		return nil, nil
+	}
+
+	reference := manifest.Reference
+	configURL := fmt.Sprintf("https://%v/v2/%v/blobs/%v", reference.Registry, reference.Repository, manifest.Config)
+	response, body, err := c.get(ctx, reference, configURL, matchCredential(reference.Registry, credentials))
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, ErrRequestFailed.wrap(fmt.Errorf("retrieving config of %v returned status code %v", reference, response.StatusCode))
+	}
+
+	var platform Platform
+	err = json.Unmarshal(body, &platform)
+	if err != nil {
+		return nil, ErrRequestFailed.wrap(fmt.Errorf("config of %v can't be unmarshalled: %w", reference, err))
+	}
+	if platform.OS == "" {
+		return nil, nil
+	}
+
+	return []Platform{platform}, nil
+}
+
+// get sends a request to the registry, and when it challenges for authentication authorizes and sends it again
+func (c *client) get(ctx context.Context, reference Reference, requestURL string, credential *Credential) (response *http.Response, body []byte, err error) {
+	repository := reference.Registry + "/" + reference.Repository
+
+	response, body, err = c.send(ctx, requestURL, c.authorizations[repository])
+	if err != nil || response.StatusCode != http.StatusUnauthorized {
+		return
+	}
+
+	authorization, err := c.authorize(ctx, reference, response.Header.Get("WWW-Authenticate"), credential)
+	if err != nil {
+		return
+	}
+	c.authorizations[repository] = authorization
+
+	return c.send(ctx, requestURL, authorization)
+}
+
+func (c *client) send(ctx context.Context, requestURL, authorization string) (response *http.Response, body []byte, err error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, nil, ErrRequestFailed.wrap(err)
+	}
+	request.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+
+	response, err = c.httpClient.Do(request)
+	if err != nil {
+		return nil, nil, ErrRequestFailed.wrap(err)
+	}
+	defer response.Body.Close()
+
+	body, err = ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, ErrRequestFailed.wrap(err)
+	}
+
+	return response, body, nil
+}
+
+// authorize returns the authorization header answering the challenge of the registry, retrieving a token for the bearer scheme
+func (c *client) authorize(ctx context.Context, reference Reference, challenge string, credential *Credential) (authorization string, err error) {
+	scheme, params := parseChallenge(challenge)
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if credential == nil {
+			return "", ErrUnauthorized.wrap(fmt.Errorf("registry %v requires credentials", reference.Registry))
+		}
+		return "Basic " + basicAuth(*credential), nil
+
+	case "bearer":
+		token, err := c.getToken(ctx, reference, params, credential)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	}
+
+	return "", ErrUnauthorized.wrap(fmt.Errorf("registry %v challenges for unsupported authentication %q", reference.Registry, challenge))
+}
+
+// getToken retrieves a token to pull from the repository from the realm of the challenge, anonymously if there's no credential for the registry
+func (c *client) getToken(ctx context.Context, reference Reference, params map[string]string, credential *Credential) (token string, err error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", ErrUnauthorized.wrap(fmt.Errorf("registry %v challenges with invalid realm %q", reference.Registry, params["realm"]))
+	}
+
+	query := realm.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", fmt.Sprintf("repository:%v:pull", reference.Repository))
+	realm.RawQuery = query.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", ErrRequestFailed.wrap(err)
+	}
+	if credential != nil {
+		request.Header.Set("Authorization", "Basic "+basicAuth(*credential))
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return "", ErrRequestFailed.wrap(err)
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", ErrUnauthorized.wrap(fmt.Errorf("retrieving token for %v from %v returned status code %v", reference.Repository, realm.Host, response.StatusCode))
+	default:
+		return "", ErrRequestFailed.wrap(fmt.Errorf("retrieving token for %v from %v returned status code %v", reference.Repository, realm.Host, response.StatusCode))
+	}
+
+	var tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&tokenResponse)
+	if err != nil {
+		return "", ErrRequestFailed.wrap(fmt.Errorf("token response from %v can't be unmarshalled: %w", realm.Host, err))
+	}
+
+	// docker hub returns both, other registries only one of them
+	token = tokenResponse.Token
+	if token == "" {
+		token = tokenResponse.AccessToken
+	}
+	if token == "" {
+		return "", ErrRequestFailed.wrap(fmt.Errorf("token response from %v doesn't hold a token", realm.Host))
+	}
+
+	return token, nil
+}
+
+// parseChallenge parses a WWW-Authenticate header like Bearer realm="https://auth.docker.io/token",service="registry.docker.io"
+func parseChallenge(challenge string) (scheme string, params map[string]string) {
+	params = map[string]string{}
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	if len(parts) == 2 {
+		for _, match := range challengeParamRegex.FindAllStringSubmatch(parts[1], -1) {
+			params[strings.ToLower(match[1])] = match[2]
+		}
+	}
+
+	return parts[0], params
+}
+
+// matchCredential returns the first credential whose registry matches the host of the registry
+func matchCredential(registry string, credentials []Credential) *Credential {
+	for i, credential := range credentials {
+		if matched, _ := path.Match(credential.Registry, registry); matched {
+			return &credentials[i]
+		}
+	}
+
+	return nil
+}
+
+func basicAuth(credential Credential) string {
+	return base64.StdEncoding.EncodeToString([]byte(credential.Username + ":" + credential.Password))
+}
@@ -0,0 +1,259 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	fakeManifest = `{"schemaVersion":2,"mediaType":"application/vnd.docker.distribution.manifest.v2+json","config":{"digest":"sha256:abc"}}`
+	fakeIndex    = `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[` +
+		`{"digest":"sha256:amd","platform":{"os":"linux","architecture":"amd64"}},` +
+		`{"digest":"sha256:arm","platform":{"os":"linux","architecture":"arm64","variant":"v8"}}]}`
+)
+
+func TestParseReference(t *testing.T) {
+
+	t.Run("ParsesReferences", func(t *testing.T) {
+
+		references := map[string]Reference{
+			"nginx":                            {Registry: "registry-1.docker.io", Repository: "library/nginx", Tag: "latest"},
+			"estafette/openresty-sidecar:1.0":  {Registry: "registry-1.docker.io", Repository: "estafette/openresty-sidecar", Tag: "1.0"},
+			"docker.io/library/nginx:1.25":     {Registry: "registry-1.docker.io", Repository: "library/nginx", Tag: "1.25"},
+			"gcr.io/endpoints-release/espv2":   {Registry: "gcr.io", Repository: "endpoints-release/espv2", Tag: "latest"},
+			"localhost:5000/myapp:1.0.0":       {Registry: "localhost:5000", Repository: "myapp", Tag: "1.0.0"},
+			"europe-docker.pkg.dev/p/r/app:v1": {Registry: "europe-docker.pkg.dev", Repository: "p/r/app", Tag: "v1"},
+			"nginx:1.25@sha256:abc":            {Registry: "registry-1.docker.io", Repository: "library/nginx", Tag: "1.25", Digest: "sha256:abc"},
+			"gcr.io/myproject/myapp@sha256:a":  {Registry: "gcr.io", Repository: "myproject/myapp", Digest: "sha256:a"},
+		}
+
+		for image, expected := range references {
+
+			// act
+			reference, err := ParseReference(image)
+
+			assert.Nil(t, err, image)
+			assert.Equal(t, expected, reference, image)
+		}
+	})
+
+	t.Run("ReturnsErrorForInvalidReferences", func(t *testing.T) {
+
+		for _, image := range []string{"", "Estafette/MyApp:1.0.0", "myapp@abc", "gcr.io/:1.0.0"} {
+
+			// act
+			_, err := ParseReference(image)
+
+			assert.NotNil(t, err, image)
+		}
+	})
+}
+
+func TestGetManifest(t *testing.T) {
+
+	t.Run("ReturnsDigestOfManifestUsingAnonymousToken", func(t *testing.T) {
+
+		server := newFakeRegistry(t, nil, map[string]string{"myapp:1.0.0": fakeManifest})
+		defer server.Close()
+		client := newTestClient(server)
+
+		// act
+		manifest, err := client.GetManifest(context.Background(), registryHost(server)+"/myapp:1.0.0", nil)
+
+		assert.Nil(t, err)
+		assert.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(fakeManifest))), manifest.Digest)
+		assert.Equal(t, MediaTypeDockerManifest, manifest.MediaType)
+		assert.False(t, manifest.IsIndex())
+	})
+
+	t.Run("ReturnsPlatformsOfMultiArchIndex", func(t *testing.T) {
+
+		server := newFakeRegistry(t, nil, map[string]string{"myapp:1.0.0": fakeIndex})
+		defer server.Close()
+		client := newTestClient(server)
+
+		// act
+		manifest, err := client.GetManifest(context.Background(), registryHost(server)+"/myapp:1.0.0", nil)
+
+		assert.Nil(t, err)
+		assert.True(t, manifest.IsIndex())
+		assert.Equal(t, 2, len(manifest.Manifests))
+		assert.Equal(t, "linux/arm64/v8", manifest.Manifests[1].Platform.String())
+	})
+
+	t.Run("AuthenticatesWithCredentialMatchingRegistry", func(t *testing.T) {
+
+		credential := &Credential{Registry: "127.0.0.1:*", Username: "_json_key", Password: "{}"}
+		server := newFakeRegistry(t, credential, map[string]string{"myproject/myapp:1.0.0": fakeManifest})
+		defer server.Close()
+		client := newTestClient(server)
+
+		// act
+		_, err := client.GetManifest(context.Background(), registryHost(server)+"/myproject/myapp:1.0.0", []Credential{{Registry: "*.gcr.io", Username: "other"}, *credential})
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrUnauthorizedWithoutMatchingCredential", func(t *testing.T) {
+
+		credential := &Credential{Registry: "127.0.0.1:*", Username: "_json_key", Password: "{}"}
+		server := newFakeRegistry(t, credential, map[string]string{"myapp:1.0.0": fakeManifest})
+		defer server.Close()
+		client := newTestClient(server)
+
+		// act
+		_, err := client.GetManifest(context.Background(), registryHost(server)+"/myapp:1.0.0", []Credential{{Registry: "*.gcr.io", Username: "other"}})
+
+		assert.True(t, errors.Is(err, ErrUnauthorized))
+	})
+
+	t.Run("ReturnsErrManifestNotFoundForTagThatIsNotPushed", func(t *testing.T) {
+
+		server := newFakeRegistry(t, nil, map[string]string{"myapp:1.0.0": fakeManifest})
+		defer server.Close()
+		client := newTestClient(server)
+
+		// act
+		_, err := client.GetManifest(context.Background(), registryHost(server)+"/myapp:1.0.1", nil)
+
+		assert.True(t, errors.Is(err, ErrManifestNotFound))
+	})
+
+	t.Run("RetrievesTokenOncePerRepository", func(t *testing.T) {
+
+		server := newFakeRegistry(t, nil, map[string]string{"myapp:1.0.0": fakeManifest, "myapp:1.0.1": fakeManifest})
+		defer server.Close()
+		client := newTestClient(server)
+		tokenRequests := 0
+		server.Config.Handler = countTokenRequests(server.Config.Handler, &tokenRequests)
+
+		// act
+		_, err := client.GetManifest(context.Background(), registryHost(server)+"/myapp:1.0.0", nil)
+		assert.Nil(t, err)
+		_, err = client.GetManifest(context.Background(), registryHost(server)+"/myapp:1.0.1", nil)
+		assert.Nil(t, err)
+
+		assert.Equal(t, 1, tokenRequests)
+	})
+}
+
+func TestGetPlatforms(t *testing.T) {
+
+	t.Run("ReturnsPlatformsOfMultiArchIndexWithoutAttestations", func(t *testing.T) {
+
+		index := `{"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[` +
+			`{"digest":"sha256:amd","platform":{"os":"linux","architecture":"amd64"}},` +
+			`{"digest":"sha256:att","platform":{"os":"unknown","architecture":"unknown"}}]}`
+		server := newFakeRegistry(t, nil, map[string]string{"myapp:1.0.0": index})
+		defer server.Close()
+		client := newTestClient(server)
+
+		// act
+		platforms, err := client.GetPlatforms(context.Background(), registryHost(server)+"/myapp:1.0.0", nil)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []Platform{{OS: "linux", Architecture: "amd64"}}, platforms)
+	})
+
+	t.Run("ReturnsPlatformFromConfigOfSingleImage", func(t *testing.T) {
+
+		server := newFakeRegistry(t, nil, map[string]string{
+			"myapp:1.0.0":      fakeManifest,
+			"myapp@sha256:abc": `{"architecture":"arm64","os":"linux","variant":"v8","config":{}}`,
+		})
+		defer server.Close()
+		client := newTestClient(server)
+
+		// act
+		platforms, err := client.GetPlatforms(context.Background(), registryHost(server)+"/myapp:1.0.0", nil)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []Platform{{OS: "linux", Architecture: "arm64", Variant: "v8"}}, platforms)
+	})
+
+	t.Run("ReturnsErrManifestNotFoundForTagThatIsNotPushed", func(t *testing.T) {
+
+		server := newFakeRegistry(t, nil, map[string]string{})
+		defer server.Close()
+		client := newTestClient(server)
+
+		// act
+		_, err := client.GetPlatforms(context.Background(), registryHost(server)+"/myapp:1.0.0", nil)
+
+		assert.True(t, errors.Is(err, ErrManifestNotFound))
+	})
+}
+
+func newTestClient(server *httptest.Server) Client {
+	return &client{
+		httpClient:     server.Client(),
+		authorizations: map[string]string{},
+		manifests:      map[string]Manifest{},
+	}
+}
+
+func registryHost(server *httptest.Server) string {
+	return strings.TrimPrefix(server.URL, "https://")
+}
+
+// newFakeRegistry returns a registry that hands out a token from its token endpoint, requiring the credential if it's set, and serves the
+// manifests by repository:reference and blobs by repository@digest to requests with that token
+func newFakeRegistry(t *testing.T, credential *Credential, manifests map[string]string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if credential != nil {
+				username, password, ok := r.BasicAuth()
+				if !ok || username != credential.Username || password != credential.Password {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+			}
+			assert.Equal(t, "fake-registry", r.URL.Query().Get("service"))
+			w.Write([]byte(`{"token":"` + strings.TrimSuffix(strings.TrimPrefix(r.URL.Query().Get("scope"), "repository:"), ":pull") + `"}`))
+			return
+		}
+
+		if !strings.HasPrefix(r.URL.Path, "/v2/") || !strings.Contains(r.URL.Path, "/manifests/") && !strings.Contains(r.URL.Path, "/blobs/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// blobs are served by repository@digest
+		repositoryAndReference := strings.Replace(strings.Replace(strings.TrimPrefix(r.URL.Path, "/v2/"), "/manifests/", ":", 1), "/blobs/", "@", 1)
+		repository := strings.FieldsFunc(repositoryAndReference, func(r rune) bool { return r == ':' || r == '@' })[0]
+
+		if r.Header.Get("Authorization") != "Bearer "+repository {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%v/token",service="fake-registry"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Contains(t, r.Header.Get("Accept"), MediaTypeOCIIndex)
+
+		manifest, ok := manifests[repositoryAndReference]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", MediaTypeDockerManifest)
+		w.Write([]byte(manifest))
+	}))
+
+	return server
+}
+
+func countTokenRequests(handler http.Handler, count *int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			*count++
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: client.go
+
+// Package registry is a generated GoMock package.
+package registry
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockClient is a mock of Client interface.
+type MockClient struct {
+	ctrl     *gomock.Controller
+	recorder *MockClientMockRecorder
+}
+
+// MockClientMockRecorder is the mock recorder for MockClient.
+type MockClientMockRecorder struct {
+	mock *MockClient
+}
+
+// NewMockClient creates a new mock instance.
+func NewMockClient(ctrl *gomock.Controller) *MockClient {
+	mock := &MockClient{ctrl: ctrl}
+	mock.recorder = &MockClientMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClient) EXPECT() *MockClientMockRecorder {
+	return m.recorder
+}
+
+// GetManifest mocks base method.
+func (m *MockClient) GetManifest(ctx context.Context, image string, credentials []Credential) (Manifest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetManifest", ctx, image, credentials)
+	ret0, _ := ret[0].(Manifest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetManifest indicates an expected call of GetManifest.
+func (mr *MockClientMockRecorder) GetManifest(ctx, image, credentials interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetManifest", reflect.TypeOf((*MockClient)(nil).GetManifest), ctx, image, credentials)
+}
+
+// GetPlatforms mocks base method.
+func (m *MockClient) GetPlatforms(ctx context.Context, image string, credentials []Credential) ([]Platform, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPlatforms", ctx, image, credentials)
+	ret0, _ := ret[0].([]Platform)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPlatforms indicates an expected call of GetPlatforms.
+func (mr *MockClientMockRecorder) GetPlatforms(ctx, image, credentials interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPlatforms", reflect.TypeOf((*MockClient)(nil).GetPlatforms), ctx, image, credentials)
+}
@@ -0,0 +1,85 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// dockerHubRegistry is the host serving the registry api of docker hub, which images without a registry are pulled from
+	dockerHubRegistry = "registry-1.docker.io"
+)
+
+// Reference is a parsed image reference like gcr.io/myproject/myapp:1.0.0 or nginx@sha256:...
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses an image reference the same way docker does, so images without a registry refer to docker hub and official images
+// get the library/ prefix
+func ParseReference(image string) (reference Reference, err error) {
+	name := strings.TrimSpace(image)
+	if name == "" {
+		return reference, fmt.Errorf("image reference is empty")
+	}
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		reference.Digest = name[i+1:]
+		name = name[:i]
+		if !strings.Contains(reference.Digest, ":") {
+			return reference, fmt.Errorf("image %v has an invalid digest %v", image, reference.Digest)
+		}
+	}
+
+	// a colon after the last slash separates the tag, any other colon is part of the registry's port
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		reference.Tag = name[i+1:]
+		name = name[:i]
+	}
+
+	reference.Registry = dockerHubRegistry
+	if i := strings.Index(name, "/"); i >= 0 && (strings.ContainsAny(name[:i], ".:") || name[:i] == "localhost") {
+		reference.Registry = name[:i]
+		name = name[i+1:]
+	}
+	if reference.Registry == "docker.io" || reference.Registry == "index.docker.io" {
+		reference.Registry = dockerHubRegistry
+	}
+	if reference.Registry == dockerHubRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	if name == "" || name != strings.ToLower(name) {
+		return reference, fmt.Errorf("image %v has an invalid repository %v", image, name)
+	}
+	reference.Repository = name
+
+	if reference.Tag == "" && reference.Digest == "" {
+		reference.Tag = "latest"
+	}
+
+	return reference, nil
+}
+
+// manifestReference returns the digest or tag to retrieve the manifest by; a digest takes precedence, like it does when pulling
+func (r Reference) manifestReference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+
+	return r.Tag
+}
+
+func (r Reference) String() string {
+	s := fmt.Sprintf("%v/%v", r.Registry, r.Repository)
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+
+	return s
+}
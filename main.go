@@ -2,16 +2,29 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
 	"runtime"
 
+	// embeds the tz database, so the timeZone of a cronjob can be validated without zoneinfo in the image
+	_ "time/tzdata"
+
 	"github.com/alecthomas/kingpin"
 	"github.com/estafette/estafette-extension-gke/api"
 	"github.com/estafette/estafette-extension-gke/clients/credentials"
 	"github.com/estafette/estafette-extension-gke/clients/gcp"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
 	"github.com/estafette/estafette-extension-gke/clients/parameters"
+	"github.com/estafette/estafette-extension-gke/clients/prometheus"
+	"github.com/estafette/estafette-extension-gke/clients/registry"
+	"github.com/estafette/estafette-extension-gke/services/analysis"
 	"github.com/estafette/estafette-extension-gke/services/builder"
+	"github.com/estafette/estafette-extension-gke/services/diagnostics"
 	"github.com/estafette/estafette-extension-gke/services/extension"
 	"github.com/estafette/estafette-extension-gke/services/generator"
+	"github.com/estafette/estafette-extension-gke/services/policy"
 	foundation "github.com/estafette/estafette-foundation"
 	"github.com/rs/zerolog/log"
 )
@@ -27,10 +40,17 @@ var (
 )
 
 var (
+	// commands
+	runCommand     = kingpin.Command("run", "Deploys to the gke cluster resolved from the credentials.").Default()
+	renderCommand  = kingpin.Command("render", "Renders the manifests to a directory, one file per resource, without accessing gcp or the cluster.")
+	schemaCommand  = kingpin.Command("schema", "Prints the json schema of the parameters, so editors can autocomplete and validate stages.")
+	explainCommand = kingpin.Command("explain", "Prints the effective value of every parameter and whether it comes from the extension, the credential, the namespace or the stage.")
+
 	// flags
-	paramsJSON      = kingpin.Flag("params", "Extension parameters, created from custom properties.").Envar("ESTAFETTE_EXTENSION_CUSTOM_PROPERTIES").Required().String()
-	paramsYAML      = kingpin.Flag("params-yaml", "Extension parameters, created from custom properties.").Envar("ESTAFETTE_EXTENSION_CUSTOM_PROPERTIES_YAML").Required().String()
+	paramsJSON      = kingpin.Flag("params", "Extension parameters, created from custom properties.").Envar("ESTAFETTE_EXTENSION_CUSTOM_PROPERTIES").String()
+	paramsYAML      = kingpin.Flag("params-yaml", "Extension parameters, created from custom properties.").Envar("ESTAFETTE_EXTENSION_CUSTOM_PROPERTIES_YAML").String()
 	credentialsPath = kingpin.Flag("credentials-path", "Path to GKE credentials configured at service level, passed in to this trusted extension.").Default("/credentials/kubernetes_engine.json").String()
+	policiesPath    = kingpin.Flag("policies-path", "Path to a file with policy rules the rendered manifests have to comply with, on top of those in the credential.").Envar("ESTAFETTE_EXTENSION_POLICIES_PATH").Default("/policies/gke.yaml").String()
 
 	// optional flags
 	gitSource        = kingpin.Flag("git-source", "Repository source.").Envar("ESTAFETTE_GIT_SOURCE").String()
@@ -47,6 +67,12 @@ var (
 	builderImageSHA  = kingpin.Flag("builder-image-sha", "The SHA of the image that is running the stage").Envar("ESTAFETTE_STAGE_IMAGE_SHA").String()
 	builderImageDate = kingpin.Flag("builder-image-date", "The creation date of the image that is running the stage").Envar("ESTAFETTE_STAGE_IMAGE_CREATED_DATE").String()
 
+	// render flags
+	outputDir = renderCommand.Flag("output-dir", "Directory to write the rendered manifests to.").Default("manifests").String()
+
+	// explain flags
+	namespaceDefaults = explainCommand.Flag("namespace-defaults", "Includes the defaults of the namespace, which requires access to the cluster.").Bool()
+
 	assistTroubleshootingOnError = false
 	paramsForTroubleshooting     = api.Params{}
 )
@@ -54,7 +80,22 @@ var (
 func main() {
 
 	// parse command line parameters
-	kingpin.Parse()
+	command := kingpin.Parse()
+
+	// print the schema before initializing logging, so the output is just the schema
+	if command == schemaCommand.FullCommand() {
+		schema, err := json.MarshalIndent(api.GenerateParamsSchema(), "", "  ")
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed marshalling parameters schema")
+		}
+		fmt.Println(string(schema))
+		return
+	}
+
+	// the schema command doesn't need any parameters, so the flag can't be marked as required
+	if *paramsYAML == "" {
+		log.Fatal().Msg("Flag --params-yaml or envvar ESTAFETTE_EXTENSION_CUSTOM_PROPERTIES_YAML is required")
+	}
 
 	// init log format from envvar ESTAFETTE_LOG_FORMAT
 	foundation.InitLoggingFromEnv(foundation.NewApplicationInfo(appgroup, app, version, branch, revision, buildDate))
@@ -67,19 +108,24 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed creating credentials.Client")
 	}
 
-	credential, err := credentialsClient.Init(ctx, *paramsJSON, *releaseName, *credentialsPath)
+	kubernetesClient, err := kubernetes.NewClient(ctx)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed initializing credentials")
+		log.Fatal().Err(err).Msg("Failed creating kubernetes.Client")
 	}
 
-	parametersClient, err := parameters.NewClient(ctx)
+	registryClient, err := registry.NewClient(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed creating registry.Client")
+	}
+
+	parametersClient, err := parameters.NewClient(ctx, kubernetesClient, registryClient)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed creating parameters.Client")
 	}
 
-	gcpClient, err := gcp.NewClient(ctx)
+	prometheusClient, err := prometheus.NewClient(ctx)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed creating gcp.Client")
+		log.Fatal().Err(err).Msg("Failed creating prometheus.Client")
 	}
 
 	builderService, err := builder.NewService(ctx)
@@ -92,12 +138,91 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed creating generator.Service")
 	}
 
-	extensionService, err := extension.NewService(ctx, credentialsClient, parametersClient, gcpClient, builderService, generatorService)
+	analysisService, err := analysis.NewService(ctx, prometheusClient)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed creating analysis.Service")
+	}
+
+	diagnosticsService, err := diagnostics.NewService(ctx, kubernetesClient)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed creating diagnostics.Service")
+	}
+
+	policyService, err := policy.NewService(ctx, *policiesPath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed creating policy.Service")
+	}
+
+	// the gcp client needs the keyfile written by credentialsClient.Init, so commands that don't access gcp get the service without it
+	newExtensionService := func(gcpClient gcp.Client) extension.Service {
+		extensionService, err := extension.NewService(ctx, credentialsClient, parametersClient, gcpClient, kubernetesClient, builderService, generatorService, analysisService, diagnosticsService, policyService)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed creating extension.Service")
+		}
+		return extensionService
+	}
+
+	if command == renderCommand.FullCommand() {
+		// resolve the credential for its defaults if it's available, but don't authenticate with it
+		credential, err := credentialsClient.GetCredential(ctx, *paramsJSON, *releaseName, *credentialsPath)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed resolving credential, rendering without credential defaults")
+			credential = &api.GKECredentials{}
+		}
+
+		err = newExtensionService(nil).Render(ctx, credential, *releaseName, *paramsYAML, *gitSource, *gitOwner, *gitName, *appLabel, *buildVersion, *releaseAction, *releaseID, *gitBranch, *gitRevision, *builderImageSHA, *builderImageDate, *triggeredBy, *outputDir)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed rendering manifests")
+		}
+		return
+	}
+
+	if command == explainCommand.FullCommand() && !*namespaceDefaults {
+		// like render, only resolve the credential for its defaults
+		credential, err := credentialsClient.GetCredential(ctx, *paramsJSON, *releaseName, *credentialsPath)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed resolving credential, explaining without credential defaults")
+			credential = &api.GKECredentials{}
+		}
+
+		err = newExtensionService(nil).Explain(ctx, credential, false, *releaseName, *paramsYAML, *gitSource, *gitOwner, *gitName, *appLabel, *buildVersion, *releaseAction, *releaseID)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed explaining parameters")
+		}
+		return
+	}
+
+	if *paramsJSON == "" {
+		log.Fatal().Msg("Flag --params or envvar ESTAFETTE_EXTENSION_CUSTOM_PROPERTIES is required")
+	}
+
+	credential, err := credentialsClient.Init(ctx, *paramsJSON, *releaseName, *credentialsPath)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed creating extension.Service")
+		log.Fatal().Err(err).Msg("Failed initializing credentials")
+	}
+
+	gcpClient, err := gcp.NewClient(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed creating gcp.Client")
+	}
+
+	extensionService := newExtensionService(gcpClient)
+
+	if command == explainCommand.FullCommand() {
+		err = extensionService.Explain(ctx, credential, true, *releaseName, *paramsYAML, *gitSource, *gitOwner, *gitName, *appLabel, *buildVersion, *releaseAction, *releaseID)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed explaining parameters")
+		}
+		return
 	}
 
 	err = extensionService.Run(ctx, credential, *releaseName, *paramsYAML, *gitSource, *gitOwner, *gitName, *appLabel, *buildVersion, *releaseAction, *releaseID, *gitBranch, *gitRevision, *builderImageSHA, *builderImageDate, *triggeredBy)
+	var driftErr *extension.DriftError
+	if errors.As(err, &driftErr) {
+		// exit with the configured code, so scheduled drift detection can tell drift apart from a failure
+		log.Warn().Msg(driftErr.Error())
+		os.Exit(driftErr.ExitCode)
+	}
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed running extension.Service")
 	}
@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+//go:generate mockgen -package=analysis -destination ./mock.go -source=service.go
+type Service interface {
+	Analyze(ctx context.Context, analysis api.CanaryAnalysisParams) (result Result, err error)
+}
+
+// NewService returns a new analysis.Service
+func NewService(ctx context.Context, prometheusClient prometheus.Client) (Service, error) {
+	return &service{
+		prometheusClient: prometheusClient,
+	}, nil
+}
+
+type service struct {
+	prometheusClient prometheus.Client
+}
+
+// Result holds the outcome of all checks of a single analysis run
+type Result struct {
+	Passed bool
+	Checks []CheckResult
+}
+
+// CheckResult holds the outcome of a single check
+type CheckResult struct {
+	Name   string
+	Value  float64
+	NoData bool
+	Passed bool
+	Reason string
+}
+
+// Analyze runs all checks against prometheus; the result only passes if every check passes, an error is returned if prometheus can't be queried
+func (s *service) Analyze(ctx context.Context, analysis api.CanaryAnalysisParams) (result Result, err error) {
+
+	result.Passed = true
+
+	for _, check := range analysis.Checks {
+		checkResult := CheckResult{
+			Name: check.Name,
+		}
+
+		value, err := s.prometheusClient.Query(ctx, analysis.PrometheusURL, check.Query)
+		if err != nil && !errors.Is(err, prometheus.ErrNoData) {
+			return result, fmt.Errorf("Failed running query for check %v: %w", check.Name, err)
+		}
+
+		// a ratio without any traffic evaluates to NaN, which isn't any different from not having data at all
+		if errors.Is(err, prometheus.ErrNoData) || math.IsNaN(value) {
+			checkResult.NoData = true
+			checkResult.Passed = analysis.AllowNoData
+			checkResult.Reason = "query returned no data"
+		} else {
+			checkResult.Value = value
+			checkResult.Passed, checkResult.Reason = evaluate(check, value)
+		}
+
+		if checkResult.Passed {
+			log.Info().Msgf("Check %v passed: %v", check.Name, checkResult.Reason)
+		} else {
+			log.Warn().Msgf("Check %v failed: %v", check.Name, checkResult.Reason)
+			result.Passed = false
+		}
+
+		result.Checks = append(result.Checks, checkResult)
+	}
+
+	return result, nil
+}
+
+func evaluate(check api.CanaryCheckParams, value float64) (passed bool, reason string) {
+	if check.Min != nil && value < *check.Min {
+		return false, fmt.Sprintf("value %v is below min %v", value, *check.Min)
+	}
+	if check.Max != nil && value > *check.Max {
+		return false, fmt.Sprintf("value %v is above max %v", value, *check.Max)
+	}
+
+	return true, fmt.Sprintf("value %v is within range", value)
+}
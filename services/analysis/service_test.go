@@ -0,0 +1,190 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	errorRateQuery = "sum(rate(nginx_http_requests_total{app='myapp',track='canary',status=~'5..'}[5m])) / sum(rate(nginx_http_requests_total{app='myapp',track='canary'}[5m]))"
+	latencyQuery   = "histogram_quantile(0.99, sum(rate(nginx_http_request_duration_seconds_bucket{app='myapp',track='canary'}[5m])) by (le))"
+)
+
+func TestAnalyze(t *testing.T) {
+
+	t.Run("PassesIfAllChecksAreWithinRange", func(t *testing.T) {
+
+		server := newFakePrometheus(t, map[string]string{
+			errorRateQuery: "0.01",
+			latencyQuery:   "0.250",
+		})
+		defer server.Close()
+
+		service := newService(t)
+		maxErrorRate := 0.05
+		maxLatency := 0.5
+
+		// act
+		result, err := service.Analyze(context.Background(), api.CanaryAnalysisParams{
+			PrometheusURL: server.URL,
+			Checks: []api.CanaryCheckParams{
+				{Name: "error-rate", Query: errorRateQuery, Max: &maxErrorRate},
+				{Name: "latency", Query: latencyQuery, Max: &maxLatency},
+			},
+		})
+
+		assert.Nil(t, err)
+		assert.True(t, result.Passed)
+		assert.Equal(t, 2, len(result.Checks))
+		assert.Equal(t, 0.01, result.Checks[0].Value)
+	})
+
+	t.Run("FailsIfAnyCheckIsAboveMax", func(t *testing.T) {
+
+		server := newFakePrometheus(t, map[string]string{
+			errorRateQuery: "0.2",
+			latencyQuery:   "0.250",
+		})
+		defer server.Close()
+
+		service := newService(t)
+		maxErrorRate := 0.05
+		maxLatency := 0.5
+
+		// act
+		result, err := service.Analyze(context.Background(), api.CanaryAnalysisParams{
+			PrometheusURL: server.URL,
+			Checks: []api.CanaryCheckParams{
+				{Name: "error-rate", Query: errorRateQuery, Max: &maxErrorRate},
+				{Name: "latency", Query: latencyQuery, Max: &maxLatency},
+			},
+		})
+
+		assert.Nil(t, err)
+		assert.False(t, result.Passed)
+		assert.False(t, result.Checks[0].Passed)
+		assert.True(t, result.Checks[1].Passed)
+	})
+
+	t.Run("FailsIfCheckIsBelowMin", func(t *testing.T) {
+
+		server := newFakePrometheus(t, map[string]string{
+			"sum(up{app='myapp',track='canary'})": "1",
+		})
+		defer server.Close()
+
+		service := newService(t)
+		minUp := 2.0
+
+		// act
+		result, err := service.Analyze(context.Background(), api.CanaryAnalysisParams{
+			PrometheusURL: server.URL,
+			Checks: []api.CanaryCheckParams{
+				{Name: "up", Query: "sum(up{app='myapp',track='canary'})", Min: &minUp},
+			},
+		})
+
+		assert.Nil(t, err)
+		assert.False(t, result.Passed)
+	})
+
+	t.Run("FailsIfQueryReturnsNoDataAndNoDataIsNotAllowed", func(t *testing.T) {
+
+		server := newFakePrometheus(t, map[string]string{})
+		defer server.Close()
+
+		service := newService(t)
+		maxErrorRate := 0.05
+
+		// act
+		result, err := service.Analyze(context.Background(), api.CanaryAnalysisParams{
+			PrometheusURL: server.URL,
+			Checks: []api.CanaryCheckParams{
+				{Name: "error-rate", Query: errorRateQuery, Max: &maxErrorRate},
+			},
+		})
+
+		assert.Nil(t, err)
+		assert.False(t, result.Passed)
+		assert.True(t, result.Checks[0].NoData)
+	})
+
+	t.Run("PassesIfQueryReturnsNaNAndNoDataIsAllowed", func(t *testing.T) {
+
+		server := newFakePrometheus(t, map[string]string{
+			errorRateQuery: "NaN",
+		})
+		defer server.Close()
+
+		service := newService(t)
+		maxErrorRate := 0.05
+
+		// act
+		result, err := service.Analyze(context.Background(), api.CanaryAnalysisParams{
+			PrometheusURL: server.URL,
+			AllowNoData:   true,
+			Checks: []api.CanaryCheckParams{
+				{Name: "error-rate", Query: errorRateQuery, Max: &maxErrorRate},
+			},
+		})
+
+		assert.Nil(t, err)
+		assert.True(t, result.Passed)
+		assert.True(t, result.Checks[0].NoData)
+	})
+
+	t.Run("ReturnsErrorIfPrometheusFails", func(t *testing.T) {
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status":"error","errorType":"unavailable","error":"too many queries"}`))
+		}))
+		defer server.Close()
+
+		service := newService(t)
+		maxErrorRate := 0.05
+
+		// act
+		_, err := service.Analyze(context.Background(), api.CanaryAnalysisParams{
+			PrometheusURL: server.URL,
+			Checks: []api.CanaryCheckParams{
+				{Name: "error-rate", Query: errorRateQuery, Max: &maxErrorRate},
+			},
+		})
+
+		assert.True(t, errors.Is(err, prometheus.ErrQueryFailed))
+	})
+}
+
+func newService(t *testing.T) Service {
+	prometheusClient, err := prometheus.NewClient(context.Background())
+	assert.Nil(t, err)
+
+	service, err := NewService(context.Background(), prometheusClient)
+	assert.Nil(t, err)
+
+	return service
+}
+
+// newFakePrometheus returns a server that answers instant queries with a single sample for known queries and an empty vector otherwise
+func newFakePrometheus(t *testing.T, values map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/query", r.URL.Path)
+
+		value, ok := values[r.URL.Query().Get("query")]
+		if !ok {
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+			return
+		}
+
+		w.Write([]byte(fmt.Sprintf(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"app":"myapp"},"value":[1700000000.123,"%v"]}]}}`, value)))
+	}))
+}
@@ -66,6 +66,21 @@ func (mr *MockServiceMockRecorder) GetAtomicUpdateServiceTemplate() *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAtomicUpdateServiceTemplate", reflect.TypeOf((*MockService)(nil).GetAtomicUpdateServiceTemplate))
 }
 
+// GetHookTemplate mocks base method.
+func (m *MockService) GetHookTemplate() (*template.Template, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHookTemplate")
+	ret0, _ := ret[0].(*template.Template)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHookTemplate indicates an expected call of GetHookTemplate.
+func (mr *MockServiceMockRecorder) GetHookTemplate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHookTemplate", reflect.TypeOf((*MockService)(nil).GetHookTemplate))
+}
+
 // GetTemplates mocks base method.
 func (m *MockService) GetTemplates(params api.Params, includePodDisruptionBudget bool) []string {
 	m.ctrl.T.Helper()
@@ -108,3 +123,17 @@ func (mr *MockServiceMockRecorder) RenderTemplate(tmpl, templateData, logTemplat
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenderTemplate", reflect.TypeOf((*MockService)(nil).RenderTemplate), tmpl, templateData, logTemplate)
 }
+
+// ValidateManifests mocks base method.
+func (m *MockService) ValidateManifests(manifests []byte, kubernetesVersion string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateManifests", manifests, kubernetesVersion)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateManifests indicates an expected call of ValidateManifests.
+func (mr *MockServiceMockRecorder) ValidateManifests(manifests, kubernetesVersion interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateManifests", reflect.TypeOf((*MockService)(nil).ValidateManifests), manifests, kubernetesVersion)
+}
@@ -0,0 +1,136 @@
+package builder
+
+import (
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+const (
+	// the built-in schemas are those of the bundled k8s.io/api module; older target versions are validated against them as well, minus the api
+	// versions they didn't serve yet
+	minKubernetesMinorVersion     = 21
+	bundledKubernetesMinorVersion = 29
+)
+
+// apiLifecycle holds the minor kubernetes version that started serving an api version and the one that stopped serving it
+type apiLifecycle struct {
+	added   int
+	removed int
+}
+
+// apiLifecycles lists the api versions added or removed since the minimum supported kubernetes version, keyed by api version or by api version
+// and kind when the other kinds of the api version are served longer
+var apiLifecycles = map[string]apiLifecycle{
+	"extensions/v1beta1":        {removed: 22},
+	"networking.k8s.io/v1beta1": {removed: 22},
+	"batch/v1/CronJob":          {added: 21},
+	"batch/v1beta1":             {removed: 25},
+	"policy/v1":                 {added: 21},
+	"policy/v1beta1":            {removed: 25},
+	"autoscaling/v2":            {added: 23},
+	"autoscaling/v2beta1":       {removed: 25},
+	"autoscaling/v2beta2":       {removed: 26},
+}
+
+func lookupAPILifecycle(gvk schema.GroupVersionKind) (lifecycle apiLifecycle, found bool) {
+	apiVersion := gvk.GroupVersion().String()
+	if lifecycle, found = apiLifecycles[apiVersion+"/"+gvk.Kind]; found {
+		return
+	}
+	lifecycle, found = apiLifecycles[apiVersion]
+	return
+}
+
+// newSchemaObject returns an empty object of the go type describing the schema of a kind, or nil if there's no schema for it
+func newSchemaObject(gvk schema.GroupVersionKind) interface{} {
+	if newObject, ok := customResourceSchemas[gvk]; ok {
+		return newObject()
+	}
+	if obj, err := scheme.Scheme.New(gvk); err == nil {
+		return obj
+	}
+
+	return nil
+}
+
+// customResourceSchemas holds the schemas of the custom resources the templates use, which aren't part of k8s.io/api
+var customResourceSchemas = map[schema.GroupVersionKind]func() interface{}{
+	{Group: "cloud.google.com", Version: "v1beta1", Kind: "BackendConfig"}:      func() interface{} { return &backendConfig{} },
+	{Group: "cloud.google.com", Version: "v1", Kind: "BackendConfig"}:           func() interface{} { return &backendConfig{} },
+	{Group: "autoscaling.k8s.io", Version: "v1", Kind: "VerticalPodAutoscaler"}: func() interface{} { return &verticalPodAutoscaler{} },
+}
+
+// backendConfig follows the BackendConfig crd of the gke ingress controller
+type backendConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              struct {
+		IAP *struct {
+			Enabled                bool `json:"enabled"`
+			OAuthClientCredentials *struct {
+				SecretName   string `json:"secretName"`
+				ClientID     string `json:"clientID,omitempty"`
+				ClientSecret string `json:"clientSecret,omitempty"`
+			} `json:"oauthclientCredentials,omitempty"`
+		} `json:"iap,omitempty"`
+		CDN            map[string]interface{} `json:"cdn,omitempty"`
+		SecurityPolicy *struct {
+			Name string `json:"name"`
+		} `json:"securityPolicy,omitempty"`
+		TimeoutSec         *int64 `json:"timeoutSec,omitempty"`
+		ConnectionDraining *struct {
+			DrainingTimeoutSec int64 `json:"drainingTimeoutSec,omitempty"`
+		} `json:"connectionDraining,omitempty"`
+		SessionAffinity *struct {
+			AffinityType         string `json:"affinityType,omitempty"`
+			AffinityCookieTtlSec *int64 `json:"affinityCookieTtlSec,omitempty"`
+		} `json:"sessionAffinity,omitempty"`
+		CustomRequestHeaders *struct {
+			Headers []string `json:"headers,omitempty"`
+		} `json:"customRequestHeaders,omitempty"`
+		HealthCheck *struct {
+			CheckIntervalSec   *int64  `json:"checkIntervalSec,omitempty"`
+			TimeoutSec         *int64  `json:"timeoutSec,omitempty"`
+			HealthyThreshold   *int64  `json:"healthyThreshold,omitempty"`
+			UnhealthyThreshold *int64  `json:"unhealthyThreshold,omitempty"`
+			Type               *string `json:"type,omitempty"`
+			Port               *int64  `json:"port,omitempty"`
+			RequestPath        *string `json:"requestPath,omitempty"`
+		} `json:"healthCheck,omitempty"`
+		Logging *struct {
+			Enable     bool     `json:"enable,omitempty"`
+			SampleRate *float64 `json:"sampleRate,omitempty"`
+		} `json:"logging,omitempty"`
+	} `json:"spec,omitempty"`
+	Status map[string]interface{} `json:"status,omitempty"`
+}
+
+// verticalPodAutoscaler follows the VerticalPodAutoscaler crd of the kubernetes autoscaler
+type verticalPodAutoscaler struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              struct {
+		TargetRef    *autoscalingv1.CrossVersionObjectReference `json:"targetRef"`
+		UpdatePolicy *struct {
+			UpdateMode  *string `json:"updateMode,omitempty"`
+			MinReplicas *int32  `json:"minReplicas,omitempty"`
+		} `json:"updatePolicy,omitempty"`
+		ResourcePolicy *struct {
+			ContainerPolicies []struct {
+				ContainerName       string                `json:"containerName,omitempty"`
+				Mode                *string               `json:"mode,omitempty"`
+				MinAllowed          corev1.ResourceList   `json:"minAllowed,omitempty"`
+				MaxAllowed          corev1.ResourceList   `json:"maxAllowed,omitempty"`
+				ControlledResources []corev1.ResourceName `json:"controlledResources,omitempty"`
+				ControlledValues    *string               `json:"controlledValues,omitempty"`
+			} `json:"containerPolicies,omitempty"`
+		} `json:"resourcePolicy,omitempty"`
+		Recommenders []struct {
+			Name string `json:"name"`
+		} `json:"recommenders,omitempty"`
+	} `json:"spec"`
+	Status map[string]interface{} `json:"status,omitempty"`
+}
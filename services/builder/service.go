@@ -19,8 +19,10 @@ type Service interface {
 	BuildTemplates(params api.Params, includePodDisruptionBudget bool) (*template.Template, error)
 	GetTemplates(params api.Params, includePodDisruptionBudget bool) []string
 	GetAtomicUpdateServiceTemplate() (*template.Template, error)
+	GetHookTemplate() (*template.Template, error)
 	RenderConfig(params api.Params) (renderedConfigFiles map[string]string)
 	RenderTemplate(tmpl *template.Template, templateData api.TemplateData, logTemplate bool) (bytes.Buffer, error)
+	ValidateManifests(manifests []byte, kubernetesVersion string) error
 }
 
 // NewService returns a new extension.Service
@@ -40,6 +42,11 @@ func (s *service) BuildTemplates(params api.Params, includePodDisruptionBudget b
 		return nil, nil
 	}
 
+	return s.mergeTemplates(templatesToMerge)
+}
+
+func (s *service) mergeTemplates(templatesToMerge []string) (*template.Template, error) {
+
 	log.Info().Msgf("Merging templates %v...", strings.Join(templatesToMerge, ", "))
 
 	templateStrings := []string{}
@@ -48,7 +55,8 @@ func (s *service) BuildTemplates(params api.Params, includePodDisruptionBudget b
 		if err != nil {
 			log.Fatal().Err(err).Msgf("Failed reading file %v. Do you have a git-clone stage before running this extension? For releases git-clone is not automatically handled to save time in case it's not needed. ", t)
 		}
-		templateStrings = append(templateStrings, string(data))
+		// mark where the output of each template starts, so validation errors can point to it
+		templateStrings = append(templateStrings, sourceMarker+t+"\n"+string(data))
 	}
 	templateString := strings.Join(templateStrings, "\n---\n")
 
@@ -59,7 +67,7 @@ func (s *service) BuildTemplates(params api.Params, includePodDisruptionBudget b
 
 func (s *service) GetTemplates(params api.Params, includePodDisruptionBudget bool) []string {
 
-	if params.Action == api.ActionRollbackCanary || params.Action == api.ActionUnknown || params.Action == api.ActionRestartCanary || params.Action == api.ActionRestartStable || params.Action == api.ActionRestartSimple {
+	if params.Action == api.ActionRollbackCanary || params.Action == api.ActionRollbackStable || params.Action == api.ActionRollbackSimple || params.Action == api.ActionUnknown || params.Action == api.ActionRestartCanary || params.Action == api.ActionRestartStable || params.Action == api.ActionRestartSimple || params.Action == api.ActionSwitchBlueGreen || params.Action == api.ActionHistory || params.Action == api.ActionSuspendCronJob || params.Action == api.ActionResumeCronJob || params.Action == api.ActionTriggerCronJob {
 		return []string{}
 	}
 
@@ -107,6 +115,9 @@ func (s *service) GetTemplates(params api.Params, includePodDisruptionBudget boo
 		if params.StrategyType != api.StrategyTypeAtomicUpdate {
 			templatesToMerge = append(templatesToMerge, "service.yaml", "service-with-track.yaml")
 		}
+		if params.StrategyType == api.StrategyTypeBlueGreen {
+			templatesToMerge = append(templatesToMerge, "service-preview.yaml")
+		}
 
 		if params.CertificateSecret == "" {
 			templatesToMerge = append(templatesToMerge, "certificate-secret.yaml")
@@ -131,7 +142,7 @@ func (s *service) GetTemplates(params api.Params, includePodDisruptionBudget boo
 	if includePodDisruptionBudget && (params.Kind == api.KindDeployment || params.Kind == api.KindHeadlessDeployment || params.Kind == api.KindStatefulset) && (params.Action == api.ActionDeploySimple || params.Action == api.ActionDeployStable || params.Action == api.ActionDiffSimple || params.Action == api.ActionDiffStable) {
 		templatesToMerge = append(templatesToMerge, "poddisruptionbudget.yaml")
 	}
-	if (params.Kind == api.KindDeployment || params.Kind == api.KindHeadlessDeployment) && params.Autoscale.Enabled != nil && *params.Autoscale.Enabled && params.StrategyType != "Recreate" && (params.Action == api.ActionDeploySimple || params.Action == api.ActionDeployStable || params.Action == api.ActionDiffSimple || params.Action == api.ActionDiffStable || params.Action == api.ActionDeployCanary || params.Action == api.ActionDiffCanary) {
+	if (params.Kind == api.KindDeployment || params.Kind == api.KindHeadlessDeployment) && params.Autoscale.Enabled != nil && *params.Autoscale.Enabled && params.StrategyType != "Recreate" && (params.Action == api.ActionDeploySimple || params.Action == api.ActionDeployStable || params.Action == api.ActionDiffSimple || params.Action == api.ActionDiffStable || params.Action == api.ActionDeployCanary || params.Action == api.ActionDiffCanary || params.Action == api.ActionDeployProgressive) {
 		templatesToMerge = append(templatesToMerge, "horizontalpodautoscaler.yaml")
 	}
 	if (params.Kind == api.KindDeployment || params.Kind == api.KindHeadlessDeployment) && params.VerticalPodAutoscaler.Enabled != nil && *params.VerticalPodAutoscaler.Enabled && (params.Action == api.ActionDeploySimple || params.Action == api.ActionDeployStable || params.Action == api.ActionDiffSimple || params.Action == api.ActionDiffStable) {
@@ -199,11 +210,17 @@ func (s *service) GetAtomicUpdateServiceTemplate() (*template.Template, error) {
 	return template.New("service.yaml").Funcs(sprig.TxtFuncMap()).ParseFiles("/templates/service.yaml")
 }
 
+func (s *service) GetHookTemplate() (*template.Template, error) {
+
+	// parse job template, hooks run the application container as a job
+	return template.New("job.yaml").Funcs(sprig.TxtFuncMap()).ParseFiles("/templates/job.yaml")
+}
+
 func (s *service) RenderConfig(params api.Params) (renderedConfigFiles map[string]string) {
 
 	renderedConfigFiles = map[string]string{}
 
-	if params.Action != api.ActionRollbackCanary && (len(params.Configs.Files) > 0 || len(params.Configs.InlineFiles) > 0) {
+	if params.Action != api.ActionRollbackCanary && params.Action != api.ActionRollbackStable && params.Action != api.ActionRollbackSimple && (len(params.Configs.Files) > 0 || len(params.Configs.InlineFiles) > 0) {
 		log.Info().Msg("Prerendering config files...")
 
 		// render files passed with configs.files property, replacing placeholders with values specified in configs.data property
@@ -217,6 +217,77 @@ func TestGetTemplates(t *testing.T) {
 		assert.Equal(t, 0, len(templates))
 	})
 
+	t.Run("ReturnsEmptyListIfActionIsRollbackStable", func(t *testing.T) {
+
+		ctx := context.Background()
+		service, err := NewService(ctx)
+		assert.Nil(t, err)
+
+		params := api.Params{
+			Action: api.ActionRollbackStable,
+			Kind:   api.KindDeployment,
+		}
+
+		// act
+		templates := service.GetTemplates(params, true)
+
+		assert.Equal(t, 0, len(templates))
+	})
+
+	t.Run("ReturnsEmptyListIfActionIsSwitchBlueGreen", func(t *testing.T) {
+
+		ctx := context.Background()
+		service, err := NewService(ctx)
+		assert.Nil(t, err)
+
+		params := api.Params{
+			Action:       api.ActionSwitchBlueGreen,
+			Kind:         api.KindDeployment,
+			StrategyType: api.StrategyTypeBlueGreen,
+		}
+
+		// act
+		templates := service.GetTemplates(params, true)
+
+		assert.Equal(t, 0, len(templates))
+	})
+
+	t.Run("ReturnsEmptyListIfActionIsHistory", func(t *testing.T) {
+
+		ctx := context.Background()
+		service, err := NewService(ctx)
+		assert.Nil(t, err)
+
+		params := api.Params{
+			Action: api.ActionHistory,
+			Kind:   api.KindDeployment,
+		}
+
+		// act
+		templates := service.GetTemplates(params, true)
+
+		assert.Equal(t, 0, len(templates))
+	})
+
+	t.Run("IncludesPreviewServiceIfStrategyTypeIsBlueGreen", func(t *testing.T) {
+
+		ctx := context.Background()
+		service, err := NewService(ctx)
+		assert.Nil(t, err)
+
+		params := api.Params{
+			Action:       api.ActionDeploySimple,
+			Kind:         api.KindDeployment,
+			StrategyType: api.StrategyTypeBlueGreen,
+		}
+
+		// act
+		templates := service.GetTemplates(params, true)
+
+		assert.True(t, stringArrayContains(templates, "/templates/service.yaml"))
+		assert.True(t, stringArrayContains(templates, "/templates/service-preview.yaml"))
+	})
+
 	t.Run("ReturnsOnlyHorizontalPodAutoscalerAndPodDisruptionBudgetIfActionIsDeployCanary", func(t *testing.T) {
 
 		ctx := context.Background()
@@ -392,6 +463,54 @@ func TestInjectSteps(t *testing.T) {
 		assert.Equal(t, "apiVersion: autoscaling/v2\nkind: HorizontalPodAutoscaler\nmetadata:\n  name: myapp-canary\n  namespace: mynamespace\n  labels:\n    \"app\": \"myapp\"\n    \"team\": \"myteam\"\nspec:\n  scaleTargetRef:\n    apiVersion: apps/v1\n    kind: Deployment\n    name: myapp-canary\n  minReplicas: 3\n  maxReplicas: 19\n  metrics:\n  - type: Resource\n    resource:\n      name: cpu\n      target:\n        type: Utilization\n        averageUtilization: 65", renderedTemplate.String())
 		assert.True(t, strings.Contains(renderedTemplate.String(), "mynamespace"))
 	})
+
+	t.Run("RenderPreviewService", func(t *testing.T) {
+
+		data := api.TemplateData{
+			Name:             "myapp",
+			Namespace:        "mynamespace",
+			AppLabelSelector: "myapp",
+			Labels: map[string]string{
+				"app":                "myapp",
+				"estafette.io/color": "green",
+			},
+			Service: api.ServiceData{
+				Name: "myapp",
+			},
+			Container: api.ContainerData{
+				Port: 5000,
+			},
+			UseBlueGreen:       true,
+			BlueGreenColor:     "green",
+			BlueGreenLiveColor: "blue",
+		}
+		tmpl, err := template.New("service-preview.yaml").Funcs(sprig.TxtFuncMap()).ParseFiles("../../templates/service-preview.yaml")
+		assert.Nil(t, err)
+
+		// act
+		var renderedTemplate bytes.Buffer
+		err = tmpl.Execute(&renderedTemplate, data)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "\napiVersion: v1\nkind: Service\nmetadata:\n  name: myapp-preview\n  namespace: mynamespace\n  labels:\n    \"app\": \"myapp\"\n    \"estafette.io/color\": \"green\"\n  annotations:\n    service.alpha.kubernetes.io/app-protocols: '{\"https\":\"HTTPS\"}'\nspec:\n  type: ClusterIP\n  ports:\n  - name: web\n    port: 5000\n    targetPort: web\n    protocol: TCP\n  selector:\n    \"app\": \"myapp\"\n    \"estafette.io/color\": \"green\"\n", renderedTemplate.String())
+	})
+
+	t.Run("RenderCronJobSuspendOnlyIfSet", func(t *testing.T) {
+
+		suspend := false
+		tmpl, err := template.New("cronjob.yaml").Funcs(sprig.TxtFuncMap()).ParseFiles("../../templates/cronjob.yaml")
+		assert.Nil(t, err)
+
+		// act
+		var withoutSuspend, withSuspend bytes.Buffer
+		err = tmpl.Execute(&withoutSuspend, api.TemplateData{Name: "myapp", Namespace: "mynamespace", Schedule: "*/5 * * * *"})
+		assert.Nil(t, err)
+		err = tmpl.Execute(&withSuspend, api.TemplateData{Name: "myapp", Namespace: "mynamespace", Schedule: "*/5 * * * *", Suspend: &suspend})
+		assert.Nil(t, err)
+
+		assert.NotContains(t, withoutSuspend.String(), "suspend:")
+		assert.Contains(t, withSuspend.String(), "\n  suspend: false\n")
+	})
 }
 
 func stringArrayContains(array []string, search string) bool {
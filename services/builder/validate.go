@@ -0,0 +1,293 @@
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	yamlv3 "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	sjson "sigs.k8s.io/json"
+	"sigs.k8s.io/yaml"
+)
+
+// sourceMarker precedes the output of every template in the merged manifests, so validation errors can point to the template
+const sourceMarker = "# Source: "
+
+var (
+	kubernetesVersionRegex = regexp.MustCompile(`^v?1\.(\d+)(\.\d+)?$`)
+	yamlLineRegex          = regexp.MustCompile(`line (\d+): `)
+	unknownFieldRegex      = regexp.MustCompile(`^(unknown|duplicate) field "(.+)"$`)
+	typeErrorRegex         = regexp.MustCompile(`cannot unmarshal (\S+) into Go struct field [^.\s]+\.(\S+) of type (\S+)`)
+	pathSegmentRegex       = regexp.MustCompile(`^(.*?)(?:\[(\d+)\])?$`)
+)
+
+// ValidateManifests validates every rendered document against the schema of its kind for the target kubernetes version, without accessing a
+// cluster; errors point to the template and line the document got rendered from
+func (s *service) ValidateManifests(manifests []byte, kubernetesVersion string) (err error) {
+	minorVersion, err := parseKubernetesMinorVersion(kubernetesVersion)
+	if err != nil {
+		return err
+	}
+
+	templateLines := map[string][]string{}
+	validationErrors := []string{}
+	for _, doc := range splitRenderedDocuments(manifests) {
+		validationErrors = append(validationErrors, doc.validate(minorVersion, templateLines)...)
+	}
+	if len(validationErrors) > 0 {
+		return fmt.Errorf("Rendered manifests aren't valid for kubernetes %v:\n%v", kubernetesVersion, strings.Join(validationErrors, "\n"))
+	}
+
+	return nil
+}
+
+func parseKubernetesMinorVersion(kubernetesVersion string) (minorVersion int, err error) {
+	matches := kubernetesVersionRegex.FindStringSubmatch(kubernetesVersion)
+	if matches == nil {
+		return 0, fmt.Errorf("Kubernetes version %v is invalid; use a version like 1.%v", kubernetesVersion, bundledKubernetesMinorVersion)
+	}
+	minorVersion, _ = strconv.Atoi(matches[1])
+	if minorVersion < minKubernetesMinorVersion || minorVersion > bundledKubernetesMinorVersion {
+		return 0, fmt.Errorf("Kubernetes version %v isn't supported; manifests can be validated for 1.%v up to 1.%v", kubernetesVersion, minKubernetesMinorVersion, bundledKubernetesMinorVersion)
+	}
+
+	return minorVersion, nil
+}
+
+// renderedDocument is a single yaml document of the rendered manifests along with where it came from
+type renderedDocument struct {
+	index   int
+	content string
+
+	// start is the line of the rendered manifests the document starts at, sourceStart the line of the marker of the template it got rendered from
+	start       int
+	source      string
+	sourceStart int
+}
+
+// splitRenderedDocuments splits the rendered manifests into documents, keeping track of the template each one got rendered from
+func splitRenderedDocuments(manifests []byte) (docs []renderedDocument) {
+	source, sourceStart := "", 0
+	start := 0
+	lines := []string{}
+
+	flush := func() {
+		if len(lines) > 0 {
+			docs = append(docs, renderedDocument{index: len(docs), content: strings.Join(lines, "\n"), start: start, source: source, sourceStart: sourceStart})
+		}
+		lines = []string{}
+	}
+
+	for i, line := range strings.Split(string(manifests), "\n") {
+		if strings.TrimRight(line, " ") == "---" {
+			flush()
+			start = i + 1
+			continue
+		}
+		if strings.HasPrefix(line, sourceMarker) {
+			source = strings.TrimSpace(strings.TrimPrefix(line, sourceMarker))
+			sourceStart = i
+		}
+		lines = append(lines, line)
+	}
+	flush()
+
+	return docs
+}
+
+func (d renderedDocument) validate(minorVersion int, templateLines map[string][]string) (validationErrors []string) {
+	var root yamlv3.Node
+	err := yamlv3.Unmarshal([]byte(d.content), &root)
+	if err != nil {
+		line := 1
+		if matches := yamlLineRegex.FindStringSubmatch(err.Error()); matches != nil {
+			line, _ = strconv.Atoi(matches[1])
+		}
+		return []string{fmt.Sprintf("%v: %v", d.location(line, "", templateLines), yamlLineRegex.ReplaceAllString(err.Error(), ""))}
+	}
+	if len(root.Content) == 0 {
+		// empty documents are the result of conditional templates
+		return nil
+	}
+
+	data, err := yaml.YAMLToJSON([]byte(d.content))
+	if err != nil {
+		return []string{fmt.Sprintf("%v: %v", d.location(1, "", templateLines), err)}
+	}
+
+	var meta struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+		Metadata   struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}
+	err = json.Unmarshal(data, &meta)
+	if err != nil || meta.APIVersion == "" || meta.Kind == "" {
+		return []string{fmt.Sprintf("%v: document has no apiVersion or kind", d.location(1, "", templateLines))}
+	}
+	resource := fmt.Sprintf("%v %v", meta.Kind, meta.Metadata.Name)
+	if meta.Metadata.Name == "" {
+		validationErrors = append(validationErrors, fmt.Sprintf("%v: %v has no metadata.name", d.location(d.line(&root, "metadata"), "metadata", templateLines), meta.Kind))
+	}
+
+	gv, err := schema.ParseGroupVersion(meta.APIVersion)
+	if err != nil {
+		return append(validationErrors, fmt.Sprintf("%v: %v has an invalid apiVersion: %v", d.location(d.line(&root, "apiVersion"), "apiVersion", templateLines), resource, err))
+	}
+	gvk := gv.WithKind(meta.Kind)
+	if lifecycle, found := lookupAPILifecycle(gvk); found {
+		if lifecycle.added > minorVersion {
+			validationErrors = append(validationErrors, fmt.Sprintf("%v: %v uses apiVersion %v, which is only served from kubernetes 1.%v", d.location(d.line(&root, "apiVersion"), "apiVersion", templateLines), resource, meta.APIVersion, lifecycle.added))
+		}
+		if lifecycle.removed > 0 && lifecycle.removed <= minorVersion {
+			validationErrors = append(validationErrors, fmt.Sprintf("%v: %v uses apiVersion %v, which is no longer served since kubernetes 1.%v", d.location(d.line(&root, "apiVersion"), "apiVersion", templateLines), resource, meta.APIVersion, lifecycle.removed))
+		}
+	}
+
+	obj := newSchemaObject(gvk)
+	if obj == nil {
+		log.Warn().Msgf("There's no schema for %v in apiVersion %v, skipping validation of %v", meta.Kind, meta.APIVersion, resource)
+		return validationErrors
+	}
+
+	strictErrors, err := sjson.UnmarshalStrict(data, obj)
+	for _, e := range strictErrors {
+		message := e.Error()
+		path := ""
+		if matches := unknownFieldRegex.FindStringSubmatch(message); matches != nil {
+			path = matches[2]
+		}
+		validationErrors = append(validationErrors, fmt.Sprintf("%v: %v: %v", d.location(d.line(&root, path), lastPathKey(path), templateLines), resource, message))
+	}
+	if err != nil {
+		message := err.Error()
+		path := ""
+		if matches := typeErrorRegex.FindStringSubmatch(message); matches != nil {
+			path = matches[2]
+			message = fmt.Sprintf("field %v has a %v value, but should be of type %v", path, matches[1], matches[3])
+		}
+		validationErrors = append(validationErrors, fmt.Sprintf("%v: %v: %v", d.location(d.line(&root, path), lastPathKey(path), templateLines), resource, message))
+	}
+
+	return validationErrors
+}
+
+// line returns the line within the document of the field at path, like spec.template.spec.containers[0].image; without an index the first element
+// of a list that has the rest of the path is used
+func (d renderedDocument) line(root *yamlv3.Node, path string) int {
+	if len(root.Content) == 0 {
+		return 1
+	}
+	node := root.Content[0]
+	if path == "" {
+		return node.Line
+	}
+
+	return findNodeLine(node, strings.Split(path, "."), node.Line)
+}
+
+func findNodeLine(node *yamlv3.Node, segments []string, deepestLine int) int {
+	if len(segments) == 0 {
+		return deepestLine
+	}
+
+	matches := pathSegmentRegex.FindStringSubmatch(segments[0])
+	key, index := matches[1], matches[2]
+	if node.Kind != yamlv3.MappingNode {
+		return deepestLine
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != key {
+			continue
+		}
+		value := node.Content[i+1]
+		if index != "" {
+			n, _ := strconv.Atoi(index)
+			if value.Kind != yamlv3.SequenceNode || n >= len(value.Content) {
+				return node.Content[i].Line
+			}
+			if len(segments) == 1 {
+				return value.Content[n].Line
+			}
+			return findNodeLine(value.Content[n], segments[1:], value.Content[n].Line)
+		}
+		if value.Kind == yamlv3.SequenceNode && len(segments) > 1 {
+			for _, item := range value.Content {
+				if line := findNodeLine(item, segments[1:], -1); line != -1 {
+					return line
+				}
+			}
+			return node.Content[i].Line
+		}
+		if len(segments) == 1 {
+			return node.Content[i].Line
+		}
+		return findNodeLine(value, segments[1:], node.Content[i].Line)
+	}
+
+	return deepestLine
+}
+
+// location returns the template file and line a line of the document got rendered from; the rendered output of a template shifts from its source
+// with conditionals and loops, so the line closest to it that holds the key is used
+func (d renderedDocument) location(line int, key string, templateLines map[string][]string) string {
+	if d.source == "" {
+		return fmt.Sprintf("document %v line %v", d.index, line)
+	}
+
+	lines, ok := templateLines[d.source]
+	if !ok {
+		data, err := ioutil.ReadFile(d.source)
+		if err == nil {
+			lines = strings.Split(string(data), "\n")
+		}
+		templateLines[d.source] = lines
+	}
+
+	renderedLine := d.start + line - 1 - d.sourceStart
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "{{-") {
+		// the leading action trims the newline of the source marker, so the output starts on the line of the marker
+		renderedLine++
+	}
+	if key == "" {
+		return fmt.Sprintf("%v:%v", d.source, renderedLine)
+	}
+
+	templateLine := renderedLine
+	closest := -1
+	for i, l := range lines {
+		trimmed := strings.TrimPrefix(strings.TrimSpace(l), "- ")
+		if !strings.HasPrefix(trimmed, key+":") && !strings.HasPrefix(trimmed, fmt.Sprintf("%q:", key)) {
+			continue
+		}
+		if closest == -1 || abs(i+1-renderedLine) < closest {
+			closest = abs(i + 1 - renderedLine)
+			templateLine = i + 1
+		}
+	}
+
+	return fmt.Sprintf("%v:%v", d.source, templateLine)
+}
+
+func lastPathKey(path string) string {
+	if path == "" {
+		return ""
+	}
+	segments := strings.Split(path, ".")
+
+	return pathSegmentRegex.FindStringSubmatch(segments[len(segments)-1])[1]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
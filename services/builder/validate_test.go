@@ -0,0 +1,260 @@
+package builder
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/services/generator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateManifests(t *testing.T) {
+
+	t.Run("ReturnsNilForEveryBundledTemplate", func(t *testing.T) {
+
+		service := &service{}
+		templates, err := filepath.Glob("../../templates/*.yaml")
+		assert.Nil(t, err)
+		tmpl, err := service.mergeTemplates(templates)
+		assert.Nil(t, err)
+
+		for _, templateData := range []api.TemplateData{newFullTemplateData(), newBlueGreenTemplateData()} {
+			rendered, err := service.RenderTemplate(tmpl, templateData, false)
+			assert.Nil(t, err)
+
+			// act
+			err = service.ValidateManifests(rendered.Bytes(), "1.29")
+
+			assert.Nil(t, err)
+		}
+	})
+
+	t.Run("ReturnsErrorPointingToTemplateLineOfUnknownField", func(t *testing.T) {
+
+		service := &service{}
+		templatePath := filepath.Join(t.TempDir(), "service.yaml")
+		err := ioutil.WriteFile(templatePath, []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: {{.Name}}\n  labels:\n    {{- range $key, $value := .Labels}}\n    {{ $key | quote }}: {{ $value | quote }}\n    {{- end}}\nspec:\n  type: ClusterIP\n  prots:\n  - port: 80\n"), 0644)
+		assert.Nil(t, err)
+		tmpl, err := service.mergeTemplates([]string{templatePath})
+		assert.Nil(t, err)
+		rendered, err := service.RenderTemplate(tmpl, api.TemplateData{Name: "myapp", Labels: map[string]string{"app": "myapp", "team": "myteam"}}, false)
+		assert.Nil(t, err)
+
+		// act
+		err = service.ValidateManifests(rendered.Bytes(), "1.29")
+
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), templatePath+`:11: Service myapp: unknown field "spec.prots"`)
+		}
+	})
+
+	t.Run("ReturnsErrorForFieldOfWrongType", func(t *testing.T) {
+
+		service := &service{}
+		manifests := []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: myapp\nspec:\n  replicas: three\n")
+
+		// act
+		err := service.ValidateManifests(manifests, "1.29")
+
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "document 0 line 6: Deployment myapp: field spec.replicas has a string value, but should be of type int32")
+		}
+	})
+
+	t.Run("ReturnsErrorForInvalidYAML", func(t *testing.T) {
+
+		service := &service{}
+		manifests := []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: myapp\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: myapp\n   namespace: mynamespace\n")
+
+		// act
+		err := service.ValidateManifests(manifests, "1.29")
+
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "document 1 line 5: yaml: ")
+		}
+	})
+
+	t.Run("ReturnsErrorIfApiVersionIsNotServedYetByTargetVersion", func(t *testing.T) {
+
+		service := &service{}
+		manifests := []byte("apiVersion: autoscaling/v2\nkind: HorizontalPodAutoscaler\nmetadata:\n  name: myapp\nspec:\n  maxReplicas: 3\n")
+
+		// act
+		err := service.ValidateManifests(manifests, "1.22")
+
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "document 0 line 1: HorizontalPodAutoscaler myapp uses apiVersion autoscaling/v2, which is only served from kubernetes 1.23")
+		}
+	})
+
+	t.Run("ReturnsErrorIfApiVersionIsNoLongerServedByTargetVersion", func(t *testing.T) {
+
+		service := &service{}
+		manifests := []byte("apiVersion: policy/v1beta1\nkind: PodDisruptionBudget\nmetadata:\n  name: myapp\n")
+
+		// act
+		err := service.ValidateManifests(manifests, "1.25")
+
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "uses apiVersion policy/v1beta1, which is no longer served since kubernetes 1.25")
+		}
+	})
+
+	t.Run("ValidatesCustomResourcesUsedByTemplates", func(t *testing.T) {
+
+		service := &service{}
+		manifests := []byte("apiVersion: cloud.google.com/v1beta1\nkind: BackendConfig\nmetadata:\n  name: myapp\nspec:\n  iap:\n    enable: true\n")
+
+		// act
+		err := service.ValidateManifests(manifests, "1.29")
+
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), `document 0 line 7: BackendConfig myapp: unknown field "spec.iap.enable"`)
+		}
+	})
+
+	t.Run("SkipsKindsWithoutSchema", func(t *testing.T) {
+
+		service := &service{}
+		manifests := []byte("apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: myapp\nspec:\n  anything: goes\n")
+
+		// act
+		err := service.ValidateManifests(manifests, "1.29")
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrorForUnsupportedKubernetesVersion", func(t *testing.T) {
+
+		service := &service{}
+
+		// act
+		err := service.ValidateManifests([]byte{}, "1.35")
+
+		if assert.NotNil(t, err) {
+			assert.Equal(t, "Kubernetes version 1.35 isn't supported; manifests can be validated for 1.21 up to 1.29", err.Error())
+		}
+	})
+}
+
+// newFullTemplateData returns template data that renders every part of the templates that doesn't exclude another
+func newFullTemplateData() api.TemplateData {
+	generatorService, _ := generator.NewService(context.Background())
+	return api.TemplateData{
+		Name:                       "myapp",
+		NameWithTrack:              "myapp-stable",
+		JobName:                    "myapp",
+		Namespace:                  "mynamespace",
+		Schedule:                   "*/5 * * * *",
+		RestartPolicy:              "OnFailure",
+		Completions:                1,
+		Parallelism:                1,
+		BackoffLimit:               6,
+		ProgressDeadlineSeconds:    600,
+		ConcurrencyPolicy:          "Forbid",
+		Labels:                     map[string]string{"app": "myapp", "team": "myteam"},
+		PodLabels:                  map[string]string{"app": "myapp", "team": "myteam"},
+		AppLabelSelector:           "myapp",
+		Hosts:                      []string{"myapp.example.com"},
+		HostsJoined:                "myapp.example.com",
+		InternalHosts:              []string{"myapp.internal.example.com"},
+		InternalHostsJoined:        "myapp.internal.example.com",
+		AllHosts:                   []string{"myapp.example.com", "myapp.internal.example.com"},
+		AllHostsJoined:             "myapp.example.com,myapp.internal.example.com",
+		ApigeeHosts:                []string{"myapp.apigee.example.com"},
+		ApigeeHostsJoined:          "myapp.apigee.example.com",
+		IngressPath:                "/",
+		InternalIngressPath:        "/",
+		UseIngress:                 true,
+		UseNginxIngress:            true,
+		PathType:                   "ImplementationSpecific",
+		UseDNSAnnotationsOnIngress: true,
+		UseCloudflareProxy:         true,
+		Service:                    api.ServiceData{ServiceType: "ClusterIP", Name: "myapp", UseDNSAnnotationsOnService: false, UseBackendConfigAnnotationOnService: true, UseNegAnnotationOnService: true},
+		MinReplicas:                3,
+		MaxReplicas:                100,
+		TargetCPUPercentage:        80,
+		VpaUpdateMode:              "Off",
+		Container: api.ContainerData{
+			Repository:                      "estafette",
+			Name:                            "myapp",
+			Tag:                             "1.0.0",
+			ImagePullPolicy:                 "IfNotPresent",
+			CPURequest:                      "100m",
+			MemoryRequest:                   "128Mi",
+			CPULimit:                        "200m",
+			MemoryLimit:                     "128Mi",
+			Port:                            5000,
+			EnvironmentVariables:            map[string]interface{}{"MY_ENV": "value", "MY_NUMBER": 3},
+			Liveness:                        api.ProbeData{Path: "/liveness", Port: 5000, InitialDelaySeconds: 30, TimeoutSeconds: 1, PeriodSeconds: 10, IncludeOnContainer: true, FailureThreshold: 3, SuccessThreshold: 1},
+			Readiness:                       api.ProbeData{Path: "/readiness", Port: 5000, TimeoutSeconds: 1, PeriodSeconds: 10, IncludeOnContainer: true, FailureThreshold: 3, SuccessThreshold: 1},
+			Metrics:                         api.MetricsData{Scrape: true, Path: "/metrics", Port: 5000},
+			UseLifecyclePreStopSleepCommand: true,
+			PreStopSleepSeconds:             20,
+		},
+		HasOpenrestySidecar:              true,
+		Sidecars:                         []api.SidecarData{{Type: "openresty", Image: "estafette/openresty-sidecar:1.13.6.2-alpine", CPURequest: "10m", MemoryRequest: "10Mi", MemoryLimit: "50Mi"}},
+		MountVolumes:                     true,
+		MountSslCertificate:              true,
+		MountApplicationSecrets:          true,
+		Secrets:                          map[string]interface{}{"secret-file.json": "c29tZSBzZWNyZXQ="},
+		SecretMountPath:                  "/secrets",
+		MountConfigmap:                   true,
+		ConfigmapFiles:                   map[string]string{"config.yaml": "key: value"},
+		ConfigMountPath:                  "/configs",
+		MountServiceAccountSecret:        true,
+		GoogleCloudCredentialsAppName:    "myapp",
+		StrategyType:                     "RollingUpdate",
+		RollingUpdateMaxSurge:            "25%",
+		RollingUpdateMaxUnavailable:      "0",
+		IncludeTrackLabel:                true,
+		TrackLabel:                       "stable",
+		NginxIngressBackendProtocol:      "HTTPS",
+		NginxIngressProxyBodySize:        "128m",
+		NginxIngressProxyBufferSize:      "4k",
+		NginxIngressProxyBuffersNumber:   "4",
+		NginxIngressProxyConnectTimeout:  60,
+		NginxIngressProxySendTimeout:     60,
+		NginxIngressProxyReadTimeout:     60,
+		NginxIngressClientBodyBufferSize: "8k",
+		UseHTTPS:                         true,
+		BackendConfigTimeout:             30,
+		IncludeReplicas:                  true,
+		Replicas:                         3,
+		PodManagementPolicy:              "OrderedReady",
+		StorageClass:                     "standard",
+		StorageSize:                      "1Gi",
+		StorageMountPath:                 "/data",
+		IapOauthCredentialsClientID:      "Y2xpZW50LWlk",
+		IapOauthCredentialsClientSecret:  "Y2xpZW50LXNlY3JldA==",
+		IsSimpleEnvvarValue:              generatorService.IsSimpleEnvvarValue,
+		ToYAML:                           generatorService.ToYAML,
+		RenderToYAML:                     generatorService.RenderToYAML,
+		UseCertificateSecret:             true,
+		CertificateSecretName:            "myapp-letsencrypt-certificate",
+		HasImagePullSecret:               true,
+		DockerConfig:                     map[string]map[string]map[string]string{"auths": {"https://index.docker.io/v1/": {"auth": "dXNlcjpwYXNzd29yZA=="}}},
+	}
+}
+
+// newBlueGreenTemplateData returns template data for the parts of the templates that newFullTemplateData excludes
+func newBlueGreenTemplateData() api.TemplateData {
+	templateData := newFullTemplateData()
+	templateData.NameWithTrack = "myapp-green"
+	templateData.IncludeTrackLabel = false
+	templateData.TrackLabel = ""
+	templateData.UseBlueGreen = true
+	templateData.BlueGreenColor = "green"
+	templateData.BlueGreenLiveColor = "blue"
+	templateData.StrategyType = "Recreate"
+	templateData.IncludeReplicas = false
+	templateData.UseNginxIngress = false
+	templateData.UseGCEIngress = true
+	templateData.HasOpenrestySidecar = false
+	templateData.Sidecars = []api.SidecarData{}
+
+	return templateData
+}
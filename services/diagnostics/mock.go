@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+
+// Package diagnostics is a generated GoMock package.
+package diagnostics
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockService is a mock of Service interface.
+type MockService struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceMockRecorder
+}
+
+// MockServiceMockRecorder is the mock recorder for MockService.
+type MockServiceMockRecorder struct {
+	mock *MockService
+}
+
+// NewMockService creates a new mock instance.
+func NewMockService(ctrl *gomock.Controller) *MockService {
+	mock := &MockService{ctrl: ctrl}
+	mock.recorder = &MockServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockService) EXPECT() *MockServiceMockRecorder {
+	return m.recorder
+}
+
+// DiagnoseDeployment mocks base method.
+func (m *MockService) DiagnoseDeployment(ctx context.Context, namespace, name string) (Diagnosis, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DiagnoseDeployment", ctx, namespace, name)
+	ret0, _ := ret[0].(Diagnosis)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DiagnoseDeployment indicates an expected call of DiagnoseDeployment.
+func (mr *MockServiceMockRecorder) DiagnoseDeployment(ctx, namespace, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiagnoseDeployment", reflect.TypeOf((*MockService)(nil).DiagnoseDeployment), ctx, namespace, name)
+}
+
+// DiagnosePods mocks base method.
+func (m *MockService) DiagnosePods(ctx context.Context, namespace, labelSelector string) (Diagnosis, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DiagnosePods", ctx, namespace, labelSelector)
+	ret0, _ := ret[0].(Diagnosis)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DiagnosePods indicates an expected call of DiagnosePods.
+func (mr *MockServiceMockRecorder) DiagnosePods(ctx, namespace, labelSelector interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiagnosePods", reflect.TypeOf((*MockService)(nil).DiagnosePods), ctx, namespace, labelSelector)
+}
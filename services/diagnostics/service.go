@@ -0,0 +1,282 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//go:generate mockgen -package=diagnostics -destination ./mock.go -source=service.go
+type Service interface {
+	DiagnoseDeployment(ctx context.Context, namespace, name string) (diagnosis Diagnosis, err error)
+	DiagnosePods(ctx context.Context, namespace, labelSelector string) (diagnosis Diagnosis, err error)
+}
+
+// NewService returns a new diagnostics.Service
+func NewService(ctx context.Context, kubernetesClient kubernetes.Client) (Service, error) {
+	return &service{
+		kubernetesClient: kubernetesClient,
+	}, nil
+}
+
+type service struct {
+	kubernetesClient kubernetes.Client
+}
+
+// Reason classifies why pods of a rollout don't become ready
+type Reason string
+
+const (
+	ReasonQuotaExceeded        Reason = "QuotaExceeded"
+	ReasonUnschedulable        Reason = "Unschedulable"
+	ReasonImagePull            Reason = "ImagePullBackOff"
+	ReasonContainerConfigError Reason = "CreateContainerConfigError"
+	ReasonOOMKilled            Reason = "OOMKilled"
+	ReasonCrashLoopBackOff     Reason = "CrashLoopBackOff"
+	ReasonReadinessProbeFailed Reason = "ReadinessProbeFailed"
+	ReasonUnknown              Reason = "Unknown"
+)
+
+// reasonPriority orders the reasons from most to least likely to be the root cause, a pod that can't be scheduled never gets to fail its probes
+var reasonPriority = []Reason{
+	ReasonQuotaExceeded,
+	ReasonUnschedulable,
+	ReasonImagePull,
+	ReasonContainerConfigError,
+	ReasonOOMKilled,
+	ReasonCrashLoopBackOff,
+	ReasonReadinessProbeFailed,
+	ReasonUnknown,
+}
+
+const (
+	maxDiagnosedPods = 5
+	previousLogLines = 50
+)
+
+// Diagnosis holds the root cause of a failed rollout, with the findings, events and logs it's based on
+type Diagnosis struct {
+	Reason       Reason
+	Findings     []Finding
+	Events       []string
+	PreviousLogs string
+}
+
+// Finding is a single problem found for a replicaset, pod or container
+type Finding struct {
+	Reason    Reason
+	Object    string
+	Container string
+	Message   string
+}
+
+// Summary returns a short description of the root cause, followed by a line for each finding with that cause
+func (d Diagnosis) Summary() string {
+	if len(d.Findings) == 0 {
+		return "No root cause found; the pods of the rollout don't report any known problem"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Root cause: %v", d.Reason))
+	for _, finding := range d.Findings {
+		if finding.Reason != d.Reason {
+			continue
+		}
+		sb.WriteString("\n- ")
+		sb.WriteString(finding.String())
+	}
+
+	return sb.String()
+}
+
+func (f Finding) String() string {
+	if f.Container != "" {
+		return fmt.Sprintf("%v container %v: %v", f.Object, f.Container, f.Message)
+	}
+	return fmt.Sprintf("%v: %v", f.Object, f.Message)
+}
+
+// DiagnoseDeployment inspects the replicaset of the current revision of a deployment and its pods
+func (s *service) DiagnoseDeployment(ctx context.Context, namespace, name string) (diagnosis Diagnosis, err error) {
+	deployment, err := s.kubernetesClient.GetDeployment(ctx, namespace, name)
+	if err != nil {
+		return diagnosis, fmt.Errorf("Failed retrieving deployment %v: %w", name, err)
+	}
+	replicaSets, err := s.kubernetesClient.ListDeploymentReplicaSets(ctx, namespace, name)
+	if err != nil {
+		return diagnosis, fmt.Errorf("Failed retrieving replicasets of deployment %v: %w", name, err)
+	}
+
+	var newReplicaSet *appsv1.ReplicaSet
+	for i := range replicaSets {
+		if kubernetes.Revision(&replicaSets[i]) == kubernetes.Revision(deployment) {
+			newReplicaSet = &replicaSets[i]
+			break
+		}
+	}
+	if newReplicaSet == nil {
+		return diagnosis, fmt.Errorf("Deployment %v has no replicaset for revision %v", name, kubernetes.Revision(deployment))
+	}
+
+	// a replicaset failing to create pods, for example due to a resource quota, leaves no pods to inspect
+	for _, condition := range newReplicaSet.Status.Conditions {
+		if condition.Type == appsv1.ReplicaSetReplicaFailure && condition.Status == corev1.ConditionTrue {
+			diagnosis.Findings = append(diagnosis.Findings, Finding{Reason: classifyReplicaFailure(condition.Message), Object: "replicaset/" + newReplicaSet.Name, Message: condition.Message})
+		}
+	}
+	s.addEvents(ctx, &diagnosis, namespace, "ReplicaSet", newReplicaSet.Name)
+
+	selector, err := metav1.LabelSelectorAsSelector(newReplicaSet.Spec.Selector)
+	if err != nil {
+		return diagnosis, fmt.Errorf("Failed parsing selector of replicaset %v: %w", newReplicaSet.Name, err)
+	}
+
+	return s.diagnosePods(ctx, diagnosis, namespace, selector.String())
+}
+
+// DiagnosePods inspects the pods matching the label selector
+func (s *service) DiagnosePods(ctx context.Context, namespace, labelSelector string) (diagnosis Diagnosis, err error) {
+	return s.diagnosePods(ctx, diagnosis, namespace, labelSelector)
+}
+
+func (s *service) diagnosePods(ctx context.Context, diagnosis Diagnosis, namespace, labelSelector string) (Diagnosis, error) {
+	pods, err := s.kubernetesClient.ListPods(ctx, namespace, labelSelector)
+	if err != nil {
+		return diagnosis, fmt.Errorf("Failed retrieving pods for selector %v: %w", labelSelector, err)
+	}
+
+	diagnosed := 0
+	for _, pod := range pods {
+		if diagnosed >= maxDiagnosedPods {
+			break
+		}
+
+		events, err := s.kubernetesClient.ListEvents(ctx, namespace, "Pod", pod.Name)
+		if err != nil {
+			events = nil
+		}
+
+		findings := diagnosePod(pod, events)
+		if len(findings) == 0 {
+			continue
+		}
+		diagnosed++
+		diagnosis.Findings = append(diagnosis.Findings, findings...)
+		diagnosis.Events = append(diagnosis.Events, formatEvents("pod/"+pod.Name, events)...)
+
+		// the logs of the container instance that terminated usually tell why it crashed
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.RestartCount == 0 || status.LastTerminationState.Terminated == nil {
+				continue
+			}
+			logs, err := s.kubernetesClient.GetPreviousContainerLogs(ctx, namespace, pod.Name, status.Name, previousLogLines)
+			if err != nil {
+				continue
+			}
+			diagnosis.PreviousLogs += fmt.Sprintf("==> %v/%v (previous) <==\n%v", pod.Name, status.Name, logs)
+			if !strings.HasSuffix(logs, "\n") {
+				diagnosis.PreviousLogs += "\n"
+			}
+		}
+	}
+
+	diagnosis.Reason = rootCause(diagnosis.Findings)
+
+	return diagnosis, nil
+}
+
+func (s *service) addEvents(ctx context.Context, diagnosis *Diagnosis, namespace, kind, name string) {
+	events, err := s.kubernetesClient.ListEvents(ctx, namespace, kind, name)
+	if err != nil {
+		return
+	}
+	diagnosis.Events = append(diagnosis.Events, formatEvents(strings.ToLower(kind)+"/"+name, events)...)
+}
+
+// diagnosePod classifies the problems of a single pod from its status and, for failing probes, its events
+func diagnosePod(pod corev1.Pod, events []corev1.Event) (findings []Finding) {
+	object := "pod/" + pod.Name
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse && condition.Reason == corev1.PodReasonUnschedulable {
+			findings = append(findings, Finding{Reason: ReasonUnschedulable, Object: object, Message: condition.Message})
+		}
+	}
+
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		if waiting := status.State.Waiting; waiting != nil {
+			switch waiting.Reason {
+			case "ErrImagePull", "ImagePullBackOff", "InvalidImageName":
+				findings = append(findings, Finding{Reason: ReasonImagePull, Object: object, Container: status.Name, Message: fmt.Sprintf("can't pull image %v: %v", status.Image, waiting.Message)})
+				continue
+			case "CreateContainerConfigError", "CreateContainerError":
+				findings = append(findings, Finding{Reason: ReasonContainerConfigError, Object: object, Container: status.Name, Message: waiting.Message})
+				continue
+			}
+		}
+
+		terminated := status.State.Terminated
+		if terminated == nil {
+			terminated = status.LastTerminationState.Terminated
+		}
+		if terminated != nil && terminated.Reason == "OOMKilled" {
+			findings = append(findings, Finding{Reason: ReasonOOMKilled, Object: object, Container: status.Name, Message: fmt.Sprintf("killed for exceeding its memory limit, restarted %v times", status.RestartCount)})
+			continue
+		}
+		if waiting := status.State.Waiting; waiting != nil && waiting.Reason == "CrashLoopBackOff" {
+			message := fmt.Sprintf("crash looping, restarted %v times", status.RestartCount)
+			if terminated != nil {
+				message += fmt.Sprintf(", last exit code %v (%v)", terminated.ExitCode, terminated.Reason)
+			}
+			findings = append(findings, Finding{Reason: ReasonCrashLoopBackOff, Object: object, Container: status.Name, Message: message})
+			continue
+		}
+
+		if status.State.Running != nil && !status.Ready {
+			for i := len(events) - 1; i >= 0; i-- {
+				if events[i].Reason == "Unhealthy" && strings.HasPrefix(events[i].Message, "Readiness probe failed") {
+					findings = append(findings, Finding{Reason: ReasonReadinessProbeFailed, Object: object, Container: status.Name, Message: events[i].Message})
+					break
+				}
+			}
+		}
+	}
+
+	return findings
+}
+
+func classifyReplicaFailure(message string) Reason {
+	if strings.Contains(message, "exceeded quota") || strings.Contains(message, "forbidden: failed quota") {
+		return ReasonQuotaExceeded
+	}
+	return ReasonUnknown
+}
+
+func rootCause(findings []Finding) Reason {
+	for _, reason := range reasonPriority {
+		for _, finding := range findings {
+			if finding.Reason == reason {
+				return reason
+			}
+		}
+	}
+
+	return ReasonUnknown
+}
+
+func formatEvents(object string, events []corev1.Event) (formatted []string) {
+	for _, event := range events {
+		count := ""
+		if event.Count > 1 {
+			count = fmt.Sprintf(" (x%v)", event.Count)
+		}
+		formatted = append(formatted, fmt.Sprintf("%v %v %v: %v%v", event.Type, event.Reason, object, event.Message, count))
+	}
+
+	return formatted
+}
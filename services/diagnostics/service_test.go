@@ -0,0 +1,182 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDiagnoseDeployment(t *testing.T) {
+
+	t.Run("ReturnsCrashLoopBackOffWithExitCodeEventsAndPreviousLogs", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service, _ := NewService(context.Background(), kubernetesClient)
+
+		pod := newPod("myapp-7d4b9-abcde")
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:                 "myapp",
+			RestartCount:         4,
+			State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}},
+		}}
+
+		kubernetesClient.EXPECT().GetDeployment(gomock.Any(), "mynamespace", "myapp").Return(newDeployment("myapp", 2), nil)
+		kubernetesClient.EXPECT().ListDeploymentReplicaSets(gomock.Any(), "mynamespace", "myapp").Return([]appsv1.ReplicaSet{*newReplicaSet("myapp-5c8f7", 1), *newReplicaSet("myapp-7d4b9", 2)}, nil)
+		kubernetesClient.EXPECT().ListEvents(gomock.Any(), "mynamespace", "ReplicaSet", "myapp-7d4b9").Return(nil, nil)
+		kubernetesClient.EXPECT().ListPods(gomock.Any(), "mynamespace", "pod-template-hash=myapp-7d4b9").Return([]corev1.Pod{pod}, nil)
+		kubernetesClient.EXPECT().ListEvents(gomock.Any(), "mynamespace", "Pod", "myapp-7d4b9-abcde").Return([]corev1.Event{
+			{Type: corev1.EventTypeWarning, Reason: "BackOff", Message: "Back-off restarting failed container", Count: 3},
+		}, nil)
+		kubernetesClient.EXPECT().GetPreviousContainerLogs(gomock.Any(), "mynamespace", "myapp-7d4b9-abcde", "myapp", int64(50)).Return("panic: missing DATABASE_URL\n", nil)
+
+		// act
+		diagnosis, err := service.DiagnoseDeployment(context.Background(), "mynamespace", "myapp")
+
+		assert.Nil(t, err)
+		assert.Equal(t, ReasonCrashLoopBackOff, diagnosis.Reason)
+		assert.Contains(t, diagnosis.Summary(), "pod/myapp-7d4b9-abcde container myapp: crash looping, restarted 4 times, last exit code 1 (Error)")
+		assert.Equal(t, []string{"Warning BackOff pod/myapp-7d4b9-abcde: Back-off restarting failed container (x3)"}, diagnosis.Events)
+		assert.Contains(t, diagnosis.PreviousLogs, "panic: missing DATABASE_URL")
+	})
+
+	t.Run("ReturnsQuotaExceededIfReplicaSetFailsToCreatePods", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service, _ := NewService(context.Background(), kubernetesClient)
+
+		replicaSet := newReplicaSet("myapp-7d4b9", 2)
+		replicaSet.Status.Conditions = []appsv1.ReplicaSetCondition{{
+			Type:    appsv1.ReplicaSetReplicaFailure,
+			Status:  corev1.ConditionTrue,
+			Reason:  "FailedCreate",
+			Message: `pods "myapp-7d4b9-xyz" is forbidden: exceeded quota: compute-resources, requested: limits.memory=2Gi`,
+		}}
+
+		kubernetesClient.EXPECT().GetDeployment(gomock.Any(), "mynamespace", "myapp").Return(newDeployment("myapp", 2), nil)
+		kubernetesClient.EXPECT().ListDeploymentReplicaSets(gomock.Any(), "mynamespace", "myapp").Return([]appsv1.ReplicaSet{*replicaSet}, nil)
+		kubernetesClient.EXPECT().ListEvents(gomock.Any(), "mynamespace", "ReplicaSet", "myapp-7d4b9").Return(nil, nil)
+		kubernetesClient.EXPECT().ListPods(gomock.Any(), "mynamespace", gomock.Any()).Return(nil, nil)
+
+		// act
+		diagnosis, err := service.DiagnoseDeployment(context.Background(), "mynamespace", "myapp")
+
+		assert.Nil(t, err)
+		assert.Equal(t, ReasonQuotaExceeded, diagnosis.Reason)
+		assert.Contains(t, diagnosis.Summary(), "exceeded quota")
+	})
+}
+
+func TestDiagnosePod(t *testing.T) {
+
+	t.Run("ReturnsUnschedulableWithSchedulerMessage", func(t *testing.T) {
+
+		pod := newPod("myapp-abc")
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable, Message: "0/3 nodes are available: 3 Insufficient cpu."}}
+
+		// act
+		findings := diagnosePod(pod, nil)
+
+		assert.Equal(t, []Finding{{Reason: ReasonUnschedulable, Object: "pod/myapp-abc", Message: "0/3 nodes are available: 3 Insufficient cpu."}}, findings)
+	})
+
+	t.Run("ReturnsImagePullBackOff", func(t *testing.T) {
+
+		pod := newPod("myapp-abc")
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "myapp", Image: "estafette/myapp:1.0.1", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}}}}
+
+		// act
+		findings := diagnosePod(pod, nil)
+
+		assert.Equal(t, ReasonImagePull, findings[0].Reason)
+		assert.Contains(t, findings[0].Message, "estafette/myapp:1.0.1")
+	})
+
+	t.Run("ReturnsOOMKilledOverCrashLoopBackOff", func(t *testing.T) {
+
+		pod := newPod("myapp-abc")
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:                 "myapp",
+			RestartCount:         2,
+			State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}},
+		}}
+
+		// act
+		findings := diagnosePod(pod, nil)
+
+		assert.Equal(t, 1, len(findings))
+		assert.Equal(t, ReasonOOMKilled, findings[0].Reason)
+	})
+
+	t.Run("ReturnsReadinessProbeFailedForRunningContainerThatIsNotReady", func(t *testing.T) {
+
+		pod := newPod("myapp-abc")
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "myapp", Ready: false, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}}
+		events := []corev1.Event{{Type: corev1.EventTypeWarning, Reason: "Unhealthy", Message: "Readiness probe failed: HTTP probe failed with statuscode: 503"}}
+
+		// act
+		findings := diagnosePod(pod, events)
+
+		assert.Equal(t, ReasonReadinessProbeFailed, findings[0].Reason)
+		assert.Equal(t, "Readiness probe failed: HTTP probe failed with statuscode: 503", findings[0].Message)
+	})
+
+	t.Run("ReturnsNothingForHealthyPod", func(t *testing.T) {
+
+		pod := newPod("myapp-abc")
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "myapp", Ready: true, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}}
+
+		// act
+		findings := diagnosePod(pod, nil)
+
+		assert.Equal(t, 0, len(findings))
+	})
+}
+
+func TestRootCause(t *testing.T) {
+
+	t.Run("PrefersUnschedulableOverReadinessProbeFailed", func(t *testing.T) {
+
+		findings := []Finding{{Reason: ReasonReadinessProbeFailed}, {Reason: ReasonUnschedulable}}
+
+		// act
+		reason := rootCause(findings)
+
+		assert.Equal(t, ReasonUnschedulable, reason)
+	})
+}
+
+func newDeployment(name string, revision int) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "mynamespace", Annotations: map[string]string{kubernetes.RevisionAnnotation: fmt.Sprint(revision)}},
+	}
+}
+
+func newReplicaSet(name string, revision int) *appsv1.ReplicaSet {
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "mynamespace", Annotations: map[string]string{kubernetes.RevisionAnnotation: fmt.Sprint(revision)}},
+		Spec: appsv1.ReplicaSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"pod-template-hash": name}},
+		},
+	}
+}
+
+func newPod(name string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "mynamespace"},
+	}
+}
@@ -0,0 +1,185 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	blueGreenColorLabel = "estafette.io/color"
+	blueGreenBlue       = "blue"
+	blueGreenGreen      = "green"
+
+	// blueGreenScaleDownAfterAnnotation holds the time after which the previous colour gets scaled down, set on its deployment by switch-bluegreen
+	blueGreenScaleDownAfterAnnotation = "estafette.io/scale-down-after"
+)
+
+// setBlueGreenColors determines the colour the service currently sends traffic to and deploys to the other one; if the service doesn't select a colour yet the first colour receives traffic right away
+func (s *service) setBlueGreenColors(ctx context.Context, params *api.Params) {
+	if params.StrategyType != api.StrategyTypeBlueGreen || (params.Action != api.ActionDeploySimple && params.Action != api.ActionDiffSimple) {
+		return
+	}
+
+	liveColor := ""
+	service, err := s.kubernetesClient.GetService(ctx, params.Namespace, blueGreenServiceName(*params))
+	if err != nil && !errors.Is(err, kubernetes.ErrResourceNotFound) {
+		log.Info().Msgf("Failed retrieving service to determine the live colour: %v", err)
+	}
+	if err == nil {
+		liveColor = service.Spec.Selector[blueGreenColorLabel]
+	}
+
+	switch liveColor {
+	case blueGreenBlue:
+		params.BlueGreenColor = blueGreenGreen
+	case blueGreenGreen:
+		params.BlueGreenColor = blueGreenBlue
+	default:
+		params.BlueGreenColor = blueGreenBlue
+		liveColor = blueGreenBlue
+	}
+	params.BlueGreenLiveColor = liveColor
+
+	if params.BlueGreenColor == params.BlueGreenLiveColor {
+		log.Info().Msgf("Service doesn't select a colour yet, deploying to %v and sending traffic to it right away...", params.BlueGreenColor)
+	} else {
+		log.Info().Msgf("Colour %v is live, deploying to %v; use action %v to send traffic to it...", params.BlueGreenLiveColor, params.BlueGreenColor, api.ActionSwitchBlueGreen)
+	}
+}
+
+// blueGreenServiceName returns the name of the service whose selector switches between the colours, matching the one the generator sets
+func blueGreenServiceName(params api.Params) string {
+	if (params.Visibility == api.VisibilityESP || params.Visibility == api.VisibilityESPv2) && params.EspServiceTypeClusterIP {
+		return params.App + "-cluster-ip"
+	}
+
+	return params.App
+}
+
+// switchBlueGreen sends traffic to the colour the preview service selects, and records until when the previous colour keeps running so switching back
+// is instant; the release doesn't wait for that, a later switch scales the previous colour down once the scale down delay has passed
+func (s *service) switchBlueGreen(ctx context.Context, params api.Params, templateData api.TemplateData) (err error) {
+	scaleDownDelay, err := time.ParseDuration(params.BlueGreen.ScaleDownDelay)
+	if err != nil {
+		return fmt.Errorf("Failed parsing bluegreen scale down delay %v: %w", params.BlueGreen.ScaleDownDelay, err)
+	}
+
+	serviceName := templateData.Service.Name
+	previewServiceName := serviceName + "-preview"
+
+	previewService, err := s.kubernetesClient.GetService(ctx, templateData.Namespace, previewServiceName)
+	if err != nil {
+		return fmt.Errorf("Failed retrieving preview service %v; deploy with action deploy-simple first: %w", previewServiceName, err)
+	}
+	service, err := s.kubernetesClient.GetService(ctx, templateData.Namespace, serviceName)
+	if err != nil {
+		return fmt.Errorf("Failed retrieving service %v: %w", serviceName, err)
+	}
+
+	color := previewService.Spec.Selector[blueGreenColorLabel]
+	previousColor := service.Spec.Selector[blueGreenColorLabel]
+	if color == "" {
+		return fmt.Errorf("Preview service %v doesn't select a colour", previewServiceName)
+	}
+	if color == previousColor {
+		return fmt.Errorf("Colour %v already receives all traffic; deploy a new version with action deploy-simple before switching", color)
+	}
+
+	// only switch to a colour that can handle the traffic, it might have been scaled down after a previous switch
+	deploymentName := fmt.Sprintf("%v-%v", templateData.Name, color)
+	deployment, err := s.kubernetesClient.GetDeployment(ctx, templateData.Namespace, deploymentName)
+	if err != nil {
+		return fmt.Errorf("Failed retrieving deployment %v to switch to: %w", deploymentName, err)
+	}
+	if scaleDownAfter, ok := deployment.Annotations[blueGreenScaleDownAfterAnnotation]; ok {
+		deadline, parseErr := time.Parse(time.RFC3339, scaleDownAfter)
+		if parseErr == nil && time.Now().After(deadline) {
+			err = s.scaleDownBlueGreenColor(ctx, templateData, serviceName, color)
+			if err != nil {
+				return
+			}
+			return fmt.Errorf("Colour %v was kept running until %v to switch back to it and has been scaled down; deploy it again with action deploy-simple", color, scaleDownAfter)
+		}
+	}
+	if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas == 0 || deployment.Status.ReadyReplicas < *deployment.Spec.Replicas {
+		return fmt.Errorf("Deployment %v has %v ready replicas and isn't able to receive all traffic; deploy it again with action deploy-simple", deploymentName, deployment.Status.ReadyReplicas)
+	}
+
+	log.Info().Msgf("Switching service %v from colour %v to %v...", serviceName, previousColor, color)
+	err = s.kubernetesClient.PatchResource(ctx, kubernetes.ResourceServices, templateData.Namespace, serviceName, types.MergePatchType, blueGreenSelectorPatch(color))
+	if err != nil {
+		return fmt.Errorf("Failed switching service %v to colour %v: %w", serviceName, color, err)
+	}
+
+	// switching back to a colour cancels its scale down
+	err = s.cancelBlueGreenScaleDown(ctx, templateData, deploymentName)
+	if err != nil {
+		return
+	}
+
+	if previousColor == "" {
+		return nil
+	}
+
+	// point the preview service at the previous colour, so switching again rolls back
+	err = s.kubernetesClient.PatchResource(ctx, kubernetes.ResourceServices, templateData.Namespace, previewServiceName, types.MergePatchType, blueGreenSelectorPatch(previousColor))
+	if err != nil {
+		return fmt.Errorf("Failed switching preview service %v to colour %v: %w", previewServiceName, previousColor, err)
+	}
+
+	if scaleDownDelay == 0 {
+		return s.scaleDownBlueGreenColor(ctx, templateData, serviceName, previousColor)
+	}
+
+	previousDeploymentName := fmt.Sprintf("%v-%v", templateData.Name, previousColor)
+	scaleDownAfter := time.Now().UTC().Add(scaleDownDelay).Format(time.RFC3339)
+	log.Info().Msgf("Keeping colour %v running until %v, switch again before then to roll back instantly...", previousColor, scaleDownAfter)
+	err = s.kubernetesClient.PatchResource(ctx, kubernetes.ResourceDeployments, templateData.Namespace, previousDeploymentName, types.MergePatchType, []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, blueGreenScaleDownAfterAnnotation, scaleDownAfter)))
+	if err != nil {
+		return fmt.Errorf("Failed recording when to scale down deployment %v: %w", previousDeploymentName, err)
+	}
+
+	return nil
+}
+
+// cancelBlueGreenScaleDown removes the scale down deadline of a colour that receives traffic again or got deployed anew
+func (s *service) cancelBlueGreenScaleDown(ctx context.Context, templateData api.TemplateData, deploymentName string) (err error) {
+	err = s.kubernetesClient.RemoveAnnotations(ctx, kubernetes.ResourceDeployments, templateData.Namespace, deploymentName, blueGreenScaleDownAfterAnnotation)
+	if err != nil {
+		return fmt.Errorf("Failed removing scale down deadline of deployment %v: %w", deploymentName, err)
+	}
+
+	return nil
+}
+
+// scaleDownBlueGreenColor scales the deployment of a colour to zero, unless the service has been switched back to it in the meantime
+func (s *service) scaleDownBlueGreenColor(ctx context.Context, templateData api.TemplateData, serviceName, color string) (err error) {
+	service, err := s.kubernetesClient.GetService(ctx, templateData.Namespace, serviceName)
+	if err != nil {
+		return fmt.Errorf("Failed retrieving service %v before scaling down colour %v: %w", serviceName, color, err)
+	}
+	if service.Spec.Selector[blueGreenColorLabel] == color {
+		log.Info().Msgf("Service %v has been switched back to colour %v, not scaling it down", serviceName, color)
+		return nil
+	}
+
+	deploymentName := fmt.Sprintf("%v-%v", templateData.Name, color)
+	log.Info().Msgf("Scaling down deployment %v...", deploymentName)
+	err = s.kubernetesClient.PatchResource(ctx, kubernetes.ResourceDeployments, templateData.Namespace, deploymentName, types.MergePatchType, []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:null}},"spec":{"replicas":0}}`, blueGreenScaleDownAfterAnnotation)))
+	if err != nil {
+		return fmt.Errorf("Failed scaling down deployment %v: %w", deploymentName, err)
+	}
+
+	return nil
+}
+
+func blueGreenSelectorPatch(color string) []byte {
+	return []byte(fmt.Sprintf(`{"spec":{"selector":{%q:%q}}}`, blueGreenColorLabel, color))
+}
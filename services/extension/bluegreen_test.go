@@ -0,0 +1,278 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestSetBlueGreenColors(t *testing.T) {
+
+	t.Run("DeploysToGreenIfServiceSelectsBlue", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{App: "myapp", Namespace: "mynamespace", Kind: api.KindDeployment, Action: api.ActionDeploySimple, StrategyType: api.StrategyTypeBlueGreen}
+
+		kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp").Return(newBlueGreenService("myapp", "blue"), nil)
+
+		// act
+		service.setBlueGreenColors(context.Background(), &params)
+
+		assert.Equal(t, "green", params.BlueGreenColor)
+		assert.Equal(t, "blue", params.BlueGreenLiveColor)
+	})
+
+	t.Run("DeploysToBlueAndSendsTrafficToItIfServiceDoesNotExist", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{App: "myapp", Namespace: "mynamespace", Kind: api.KindDeployment, Action: api.ActionDeploySimple, StrategyType: api.StrategyTypeBlueGreen}
+
+		kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp").Return(nil, kubernetes.ErrResourceNotFound)
+
+		// act
+		service.setBlueGreenColors(context.Background(), &params)
+
+		assert.Equal(t, "blue", params.BlueGreenColor)
+		assert.Equal(t, "blue", params.BlueGreenLiveColor)
+	})
+
+	t.Run("DoesNothingIfStrategyTypeIsNotBlueGreen", func(t *testing.T) {
+
+		service := &service{}
+		params := api.Params{App: "myapp", Namespace: "mynamespace", Kind: api.KindDeployment, Action: api.ActionDeploySimple, StrategyType: api.StrategyTypeRollingUpdate}
+
+		// act
+		service.setBlueGreenColors(context.Background(), &params)
+
+		assert.Equal(t, "", params.BlueGreenColor)
+		assert.Equal(t, "", params.BlueGreenLiveColor)
+	})
+}
+
+func TestSwitchBlueGreen(t *testing.T) {
+
+	t.Run("SwitchesServiceToPreviewColorAndRecordsWhenToScaleDownPreviousColorWithoutWaiting", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{BlueGreen: api.BlueGreenParams{ScaleDownDelay: "15m"}}
+		templateData := api.TemplateData{Name: "myapp", Namespace: "mynamespace", Service: api.ServiceData{Name: "myapp"}}
+
+		var annotationPatch []byte
+		gomock.InOrder(
+			kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp-preview").Return(newBlueGreenService("myapp-preview", "green"), nil),
+			kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp").Return(newBlueGreenService("myapp", "blue"), nil),
+			kubernetesClient.EXPECT().GetDeployment(gomock.Any(), "mynamespace", "myapp-green").Return(newBlueGreenDeployment("myapp-green", 3, 3), nil),
+			kubernetesClient.EXPECT().PatchResource(gomock.Any(), kubernetes.ResourceServices, "mynamespace", "myapp", types.MergePatchType, []byte(`{"spec":{"selector":{"estafette.io/color":"green"}}}`)).Return(nil),
+			kubernetesClient.EXPECT().RemoveAnnotations(gomock.Any(), kubernetes.ResourceDeployments, "mynamespace", "myapp-green", "estafette.io/scale-down-after").Return(nil),
+			kubernetesClient.EXPECT().PatchResource(gomock.Any(), kubernetes.ResourceServices, "mynamespace", "myapp-preview", types.MergePatchType, []byte(`{"spec":{"selector":{"estafette.io/color":"blue"}}}`)).Return(nil),
+			kubernetesClient.EXPECT().PatchResource(gomock.Any(), kubernetes.ResourceDeployments, "mynamespace", "myapp-blue", types.MergePatchType, gomock.Any()).DoAndReturn(func(ctx context.Context, resource schema.GroupVersionResource, namespace, name string, patchType types.PatchType, patch []byte) error {
+				annotationPatch = patch
+				return nil
+			}),
+		)
+
+		start := time.Now()
+
+		// act
+		err := service.switchBlueGreen(context.Background(), params, templateData)
+
+		assert.Nil(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+		var patch struct {
+			Metadata struct {
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+		}
+		if assert.Nil(t, json.Unmarshal(annotationPatch, &patch)) {
+			scaleDownAfter, err := time.Parse(time.RFC3339, patch.Metadata.Annotations["estafette.io/scale-down-after"])
+			assert.Nil(t, err)
+			assert.WithinDuration(t, start.Add(15*time.Minute), scaleDownAfter, 5*time.Second)
+		}
+	})
+
+	t.Run("ScalesDownPreviousColorRightAwayIfScaleDownDelayIsZero", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{BlueGreen: api.BlueGreenParams{ScaleDownDelay: "0s"}}
+		templateData := api.TemplateData{Name: "myapp", Namespace: "mynamespace", Service: api.ServiceData{Name: "myapp"}}
+
+		gomock.InOrder(
+			kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp-preview").Return(newBlueGreenService("myapp-preview", "green"), nil),
+			kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp").Return(newBlueGreenService("myapp", "blue"), nil),
+			kubernetesClient.EXPECT().GetDeployment(gomock.Any(), "mynamespace", "myapp-green").Return(newBlueGreenDeployment("myapp-green", 3, 3), nil),
+			kubernetesClient.EXPECT().PatchResource(gomock.Any(), kubernetes.ResourceServices, "mynamespace", "myapp", types.MergePatchType, []byte(`{"spec":{"selector":{"estafette.io/color":"green"}}}`)).Return(nil),
+			kubernetesClient.EXPECT().RemoveAnnotations(gomock.Any(), kubernetes.ResourceDeployments, "mynamespace", "myapp-green", "estafette.io/scale-down-after").Return(nil),
+			kubernetesClient.EXPECT().PatchResource(gomock.Any(), kubernetes.ResourceServices, "mynamespace", "myapp-preview", types.MergePatchType, []byte(`{"spec":{"selector":{"estafette.io/color":"blue"}}}`)).Return(nil),
+			kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp").Return(newBlueGreenService("myapp", "green"), nil),
+			kubernetesClient.EXPECT().PatchResource(gomock.Any(), kubernetes.ResourceDeployments, "mynamespace", "myapp-blue", types.MergePatchType, []byte(`{"metadata":{"annotations":{"estafette.io/scale-down-after":null}},"spec":{"replicas":0}}`)).Return(nil),
+		)
+
+		// act
+		err := service.switchBlueGreen(context.Background(), params, templateData)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ScalesDownPreviewColorInsteadOfSwitchingBackToItAfterScaleDownDelay", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{BlueGreen: api.BlueGreenParams{ScaleDownDelay: "15m"}}
+		templateData := api.TemplateData{Name: "myapp", Namespace: "mynamespace", Service: api.ServiceData{Name: "myapp"}}
+
+		blue := newBlueGreenDeployment("myapp-blue", 3, 3)
+		blue.Annotations = map[string]string{"estafette.io/scale-down-after": time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)}
+
+		gomock.InOrder(
+			kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp-preview").Return(newBlueGreenService("myapp-preview", "blue"), nil),
+			kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp").Return(newBlueGreenService("myapp", "green"), nil),
+			kubernetesClient.EXPECT().GetDeployment(gomock.Any(), "mynamespace", "myapp-blue").Return(blue, nil),
+			kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp").Return(newBlueGreenService("myapp", "green"), nil),
+			kubernetesClient.EXPECT().PatchResource(gomock.Any(), kubernetes.ResourceDeployments, "mynamespace", "myapp-blue", types.MergePatchType, []byte(`{"metadata":{"annotations":{"estafette.io/scale-down-after":null}},"spec":{"replicas":0}}`)).Return(nil),
+		)
+
+		// act
+		err := service.switchBlueGreen(context.Background(), params, templateData)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("SwitchesBackToPreviewColorWithinScaleDownDelay", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{BlueGreen: api.BlueGreenParams{ScaleDownDelay: "15m"}}
+		templateData := api.TemplateData{Name: "myapp", Namespace: "mynamespace", Service: api.ServiceData{Name: "myapp"}}
+
+		blue := newBlueGreenDeployment("myapp-blue", 3, 3)
+		blue.Annotations = map[string]string{"estafette.io/scale-down-after": time.Now().Add(10 * time.Minute).UTC().Format(time.RFC3339)}
+
+		gomock.InOrder(
+			kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp-preview").Return(newBlueGreenService("myapp-preview", "blue"), nil),
+			kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp").Return(newBlueGreenService("myapp", "green"), nil),
+			kubernetesClient.EXPECT().GetDeployment(gomock.Any(), "mynamespace", "myapp-blue").Return(blue, nil),
+			kubernetesClient.EXPECT().PatchResource(gomock.Any(), kubernetes.ResourceServices, "mynamespace", "myapp", types.MergePatchType, []byte(`{"spec":{"selector":{"estafette.io/color":"blue"}}}`)).Return(nil),
+			kubernetesClient.EXPECT().RemoveAnnotations(gomock.Any(), kubernetes.ResourceDeployments, "mynamespace", "myapp-blue", "estafette.io/scale-down-after").Return(nil),
+			kubernetesClient.EXPECT().PatchResource(gomock.Any(), kubernetes.ResourceServices, "mynamespace", "myapp-preview", types.MergePatchType, []byte(`{"spec":{"selector":{"estafette.io/color":"green"}}}`)).Return(nil),
+			kubernetesClient.EXPECT().PatchResource(gomock.Any(), kubernetes.ResourceDeployments, "mynamespace", "myapp-green", types.MergePatchType, gomock.Any()).Return(nil),
+		)
+
+		// act
+		err := service.switchBlueGreen(context.Background(), params, templateData)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("DoesNotScaleDownPreviousColorIfServiceHasBeenSwitchedBack", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		templateData := api.TemplateData{Name: "myapp", Namespace: "mynamespace"}
+
+		kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp").Return(newBlueGreenService("myapp", "blue"), nil)
+
+		// act
+		err := service.scaleDownBlueGreenColor(context.Background(), templateData, "myapp", "blue")
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrorIfPreviewColorIsNotReady", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{BlueGreen: api.BlueGreenParams{ScaleDownDelay: "15m"}}
+		templateData := api.TemplateData{Name: "myapp", Namespace: "mynamespace", Service: api.ServiceData{Name: "myapp"}}
+
+		kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp-preview").Return(newBlueGreenService("myapp-preview", "blue"), nil)
+		kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp").Return(newBlueGreenService("myapp", "green"), nil)
+		kubernetesClient.EXPECT().GetDeployment(gomock.Any(), "mynamespace", "myapp-blue").Return(newBlueGreenDeployment("myapp-blue", 0, 0), nil)
+
+		// act
+		err := service.switchBlueGreen(context.Background(), params, templateData)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("ReturnsErrorIfPreviewColorAlreadyReceivesTraffic", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{BlueGreen: api.BlueGreenParams{ScaleDownDelay: "15m"}}
+		templateData := api.TemplateData{Name: "myapp", Namespace: "mynamespace", Service: api.ServiceData{Name: "myapp"}}
+
+		kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp-preview").Return(newBlueGreenService("myapp-preview", "blue"), nil)
+		kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp").Return(newBlueGreenService("myapp", "blue"), nil)
+
+		// act
+		err := service.switchBlueGreen(context.Background(), params, templateData)
+
+		assert.NotNil(t, err)
+	})
+}
+
+func newBlueGreenService(name, color string) *corev1.Service {
+	service := &corev1.Service{
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "myapp", "estafette.io/color": color},
+		},
+	}
+	service.Name = name
+
+	return service
+}
+
+func newBlueGreenDeployment(name string, replicas, readyReplicas int32) *appsv1.Deployment {
+	deployment := &appsv1.Deployment{
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+		},
+		Status: appsv1.DeploymentStatus{
+			ReadyReplicas: readyReplicas,
+		},
+	}
+	deployment.Name = name
+
+	return deployment
+}
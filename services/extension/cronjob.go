@@ -0,0 +1,45 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/rs/zerolog/log"
+)
+
+// isCronJobAction returns whether the action operates on a deployed cronjob instead of rendering manifests
+func isCronJobAction(action api.ActionType) bool {
+	return action == api.ActionSuspendCronJob || action == api.ActionResumeCronJob || action == api.ActionTriggerCronJob
+}
+
+// runCronJobAction suspends, resumes or triggers the cronjob of the application; a triggered job is waited for like the job of kind job
+func (s *service) runCronJobAction(ctx context.Context, params api.Params, templateData api.TemplateData) (err error) {
+	if params.DryRun {
+		log.Info().Msgf("Dry run, skipping action %v for cronjob %v in namespace %v", params.Action, templateData.Name, templateData.Namespace)
+		return nil
+	}
+
+	switch params.Action {
+	case api.ActionSuspendCronJob, api.ActionResumeCronJob:
+		suspend := params.Action == api.ActionSuspendCronJob
+		err = s.kubernetesClient.SuspendCronJob(ctx, templateData.Namespace, templateData.Name, suspend)
+		if err != nil {
+			return fmt.Errorf("Failed setting suspend to %v for cronjob %v: %w", suspend, templateData.Name, err)
+		}
+
+	case api.ActionTriggerCronJob:
+		templateData.JobName, err = s.kubernetesClient.TriggerCronJob(ctx, templateData.Namespace, templateData.Name)
+		if err != nil {
+			return fmt.Errorf("Failed triggering cronjob %v: %w", templateData.Name, err)
+		}
+
+		err = s.waitForJob(ctx, params, templateData)
+		s.reportRollout(ctx, templateData, err)
+		if err != nil {
+			return fmt.Errorf("Failed waiting for job %v triggered from cronjob %v: %w", templateData.JobName, templateData.Name, err)
+		}
+	}
+
+	return nil
+}
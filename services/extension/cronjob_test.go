@@ -0,0 +1,130 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCronJobAction(t *testing.T) {
+
+	t.Run("SuspendsCronJob", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{Kind: api.KindCronJob, Action: api.ActionSuspendCronJob}
+		templateData := api.TemplateData{Name: "myapp", Namespace: "mynamespace"}
+
+		kubernetesClient.EXPECT().SuspendCronJob(gomock.Any(), "mynamespace", "myapp", true).Return(nil)
+
+		// act
+		err := service.runCronJobAction(context.Background(), params, templateData)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ResumesCronJob", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{Kind: api.KindCronJob, Action: api.ActionResumeCronJob}
+		templateData := api.TemplateData{Name: "myapp", Namespace: "mynamespace"}
+
+		kubernetesClient.EXPECT().SuspendCronJob(gomock.Any(), "mynamespace", "myapp", false).Return(nil)
+
+		// act
+		err := service.runCronJobAction(context.Background(), params, templateData)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("TriggersCronJobAndWaitsForJob", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{Kind: api.KindCronJob, Action: api.ActionTriggerCronJob, JobTimeout: "30m"}
+		templateData := api.TemplateData{Name: "myapp", Namespace: "mynamespace"}
+
+		gomock.InOrder(
+			kubernetesClient.EXPECT().TriggerCronJob(gomock.Any(), "mynamespace", "myapp").Return("myapp-manual-123", nil),
+			kubernetesClient.EXPECT().WaitForJobCompletion(gomock.Any(), "mynamespace", "myapp-manual-123", 30*time.Minute).Return(nil),
+			kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceJobs, "mynamespace", "myapp-manual-123").Return(nil),
+		)
+		kubernetesClient.EXPECT().FollowPodLogs(gomock.Any(), "mynamespace", "job-name=myapp-manual-123", "myapp", gomock.Any()).Return(nil)
+
+		// act
+		err := service.runCronJobAction(context.Background(), params, templateData)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrorIfTriggeredJobFailed", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, report: &api.Report{}}
+		params := api.Params{Kind: api.KindCronJob, Action: api.ActionTriggerCronJob, JobTimeout: "30m"}
+		templateData := api.TemplateData{Name: "myapp", Namespace: "mynamespace"}
+
+		kubernetesClient.EXPECT().TriggerCronJob(gomock.Any(), "mynamespace", "myapp").Return("myapp-manual-123", nil)
+		kubernetesClient.EXPECT().WaitForJobCompletion(gomock.Any(), "mynamespace", "myapp-manual-123", 30*time.Minute).Return(kubernetes.ErrJobFailed)
+		kubernetesClient.EXPECT().FollowPodLogs(gomock.Any(), "mynamespace", "job-name=myapp-manual-123", "myapp", gomock.Any()).Return(nil)
+
+		// act
+		err := service.runCronJobAction(context.Background(), params, templateData)
+
+		assert.True(t, errors.Is(err, kubernetes.ErrJobFailed))
+		assert.Equal(t, api.RolloutOutcomeFailed, service.report.Rollout)
+	})
+
+	t.Run("ReturnsErrResourceNotFoundIfCronJobDoesNotExist", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{Kind: api.KindCronJob, Action: api.ActionSuspendCronJob}
+		templateData := api.TemplateData{Name: "myapp", Namespace: "mynamespace"}
+
+		kubernetesClient.EXPECT().SuspendCronJob(gomock.Any(), "mynamespace", "myapp", true).Return(kubernetes.ErrResourceNotFound)
+
+		// act
+		err := service.runCronJobAction(context.Background(), params, templateData)
+
+		assert.True(t, errors.Is(err, kubernetes.ErrResourceNotFound))
+	})
+
+	t.Run("SkipsActionOnDryRun", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{Kind: api.KindCronJob, Action: api.ActionTriggerCronJob, JobTimeout: "30m", DryRun: true}
+		templateData := api.TemplateData{Name: "myapp", Namespace: "mynamespace"}
+
+		// act
+		err := service.runCronJobAction(context.Background(), params, templateData)
+
+		assert.Nil(t, err)
+	})
+}
@@ -0,0 +1,135 @@
+package extension
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// maxDiffValueLength keeps long values like certificates from stretching the diff table
+const maxDiffValueLength = 60
+
+// DriftError is returned by the diff actions when the live state differs from the rendered manifests and diff.exitCodeOnDrift is set, so scheduled
+// drift detection can tell drift apart from a failure by its exit code
+type DriftError struct {
+	ExitCode int
+	Summary  api.DiffSummary
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("The live state differs from the rendered manifests, %v", e.Summary)
+}
+
+// isDiffAction tells whether the action only shows what a release would change
+func isDiffAction(params api.Params) bool {
+	return params.Action == api.ActionDiffSimple || params.Action == api.ActionDiffCanary || params.Action == api.ActionDiffStable || params.Action == api.ActionDiffDelete
+}
+
+// summarizeDiff turns the diffs of the rendered manifests and the resources a release would delete into a diff summary; resources without changes
+// are left out
+func summarizeDiff(diffs []kubernetes.ResourceDiff, removed []unstructured.Unstructured) api.DiffSummary {
+	summary := api.DiffSummary{
+		Added:   []api.DiffResource{},
+		Changed: []api.DiffResource{},
+		Removed: []api.DiffResource{},
+	}
+
+	for _, d := range diffs {
+		resource := api.DiffResource{Kind: d.Kind, Namespace: d.Namespace, Name: d.Name}
+		if d.IsNew() {
+			summary.Added = append(summary.Added, resource)
+			continue
+		}
+
+		for _, c := range d.FieldChanges() {
+			resource.Fields = append(resource.Fields, api.DiffField{Path: c.Path, Live: c.Live, Rendered: c.Merged})
+		}
+		if len(resource.Fields) > 0 {
+			summary.Changed = append(summary.Changed, resource)
+		}
+	}
+
+	for _, item := range removed {
+		summary.Removed = append(summary.Removed, api.DiffResource{Kind: item.GetKind(), Namespace: item.GetNamespace(), Name: item.GetName()})
+	}
+
+	return summary
+}
+
+// reportDiff prints the diff summary as a table, adds it to the report and writes it to diff.outputPath if set; a DriftError is returned for drift
+// if diff.exitCodeOnDrift is set
+func (s *service) reportDiff(params api.Params, summary api.DiffSummary) (err error) {
+	if summary.HasDrift() {
+		log.Info().Msgf("Diff summary, %v:\n%v", summary, formatDiffTable(summary))
+	} else {
+		log.Info().Msg("Diff summary, the live state matches the rendered manifests")
+	}
+
+	if s.report != nil {
+		s.report.Diff = &summary
+	}
+
+	if params.Diff.OutputPath != "" {
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Failed marshalling diff summary: %w", err)
+		}
+
+		log.Info().Msgf("Writing diff summary to %v...", params.Diff.OutputPath)
+		err = ioutil.WriteFile(params.Diff.OutputPath, data, 0644)
+		if err != nil {
+			return fmt.Errorf("Failed writing diff summary to %v: %w", params.Diff.OutputPath, err)
+		}
+	}
+
+	if summary.HasDrift() && params.Diff.ExitCodeOnDrift != 0 {
+		return &DriftError{ExitCode: params.Diff.ExitCodeOnDrift, Summary: summary}
+	}
+
+	return nil
+}
+
+// formatDiffTable lists a row for every added and removed resource and for every changed field of a changed resource
+func formatDiffTable(summary api.DiffSummary) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "CHANGE\tKIND\tNAME\tFIELD\tLIVE\tRENDERED")
+	for _, r := range summary.Added {
+		fmt.Fprintf(w, "add\t%v\t%v\t-\t-\t-\n", r.Kind, r.Name)
+	}
+	for _, r := range summary.Changed {
+		for i, f := range r.Fields {
+			if i == 0 {
+				fmt.Fprintf(w, "change\t%v\t%v\t%v\t%v\t%v\n", r.Kind, r.Name, f.Path, truncateDiffValue(f.Live), truncateDiffValue(f.Rendered))
+				continue
+			}
+			fmt.Fprintf(w, "\t\t\t%v\t%v\t%v\n", f.Path, truncateDiffValue(f.Live), truncateDiffValue(f.Rendered))
+		}
+	}
+	for _, r := range summary.Removed {
+		fmt.Fprintf(w, "remove\t%v\t%v\t-\t-\t-\n", r.Kind, r.Name)
+	}
+	w.Flush()
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func truncateDiffValue(value string) string {
+	value = strings.ReplaceAll(value, "\n", "\\n")
+	if value == "" {
+		return "-"
+	}
+	if len(value) > maxDiffValueLength {
+		return value[:maxDiffValueLength-3] + "..."
+	}
+
+	return value
+}
@@ -0,0 +1,115 @@
+package extension
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSummarizeDiff(t *testing.T) {
+
+	t.Run("ListsAddedChangedAndRemovedResources", func(t *testing.T) {
+
+		live := newUnstructured("apps/v1", "Deployment", "myapp")
+		unstructured.SetNestedField(live.Object, int64(3), "spec", "replicas")
+		merged := live.DeepCopy()
+		unstructured.SetNestedField(merged.Object, int64(5), "spec", "replicas")
+		unchanged := newUnstructured("v1", "ConfigMap", "myapp-configs")
+		added := newUnstructured("v1", "Service", "myapp")
+
+		diffs := []kubernetes.ResourceDiff{
+			{Kind: "Service", Namespace: "mynamespace", Name: "myapp", Merged: &added},
+			{Kind: "Deployment", Namespace: "mynamespace", Name: "myapp", Live: &live, Merged: merged},
+			{Kind: "ConfigMap", Namespace: "mynamespace", Name: "myapp-configs", Live: &unchanged, Merged: unchanged.DeepCopy()},
+		}
+		removed := []unstructured.Unstructured{newUnstructured("networking.k8s.io/v1", "Ingress", "myapp-internal")}
+
+		// act
+		summary := summarizeDiff(diffs, removed)
+
+		assert.Equal(t, []api.DiffResource{{Kind: "Service", Namespace: "mynamespace", Name: "myapp"}}, summary.Added)
+		assert.Equal(t, []api.DiffResource{{Kind: "Deployment", Namespace: "mynamespace", Name: "myapp", Fields: []api.DiffField{{Path: "spec.replicas", Live: "3", Rendered: "5"}}}}, summary.Changed)
+		if assert.Equal(t, 1, len(summary.Removed)) {
+			assert.Equal(t, "Ingress", summary.Removed[0].Kind)
+			assert.Equal(t, "myapp-internal", summary.Removed[0].Name)
+		}
+	})
+}
+
+func TestReportDiff(t *testing.T) {
+
+	drift := api.DiffSummary{
+		Added:   []api.DiffResource{},
+		Changed: []api.DiffResource{{Kind: "Deployment", Name: "myapp", Fields: []api.DiffField{{Path: "spec.replicas", Live: "3", Rendered: "5"}}}},
+		Removed: []api.DiffResource{},
+	}
+
+	t.Run("WritesSummaryToOutputPathAndReport", func(t *testing.T) {
+
+		outputPath := filepath.Join(t.TempDir(), "diff.json")
+		service := &service{report: &api.Report{}}
+
+		// act
+		err := service.reportDiff(api.Params{Diff: api.DiffParams{OutputPath: outputPath}}, drift)
+
+		assert.Nil(t, err)
+		assert.Equal(t, &drift, service.report.Diff)
+		data, err := ioutil.ReadFile(outputPath)
+		assert.Nil(t, err)
+		var summary api.DiffSummary
+		assert.Nil(t, json.Unmarshal(data, &summary))
+		assert.Equal(t, drift, summary)
+	})
+
+	t.Run("ReturnsDriftErrorWithExitCodeIfExitCodeOnDriftIsSet", func(t *testing.T) {
+
+		service := &service{}
+
+		// act
+		err := service.reportDiff(api.Params{Diff: api.DiffParams{ExitCodeOnDrift: 3}}, drift)
+
+		var driftErr *DriftError
+		if assert.True(t, errors.As(err, &driftErr)) {
+			assert.Equal(t, 3, driftErr.ExitCode)
+		}
+	})
+
+	t.Run("ReturnsNilWithoutDrift", func(t *testing.T) {
+
+		service := &service{}
+
+		// act
+		err := service.reportDiff(api.Params{Diff: api.DiffParams{ExitCodeOnDrift: 3}}, api.DiffSummary{})
+
+		assert.Nil(t, err)
+	})
+}
+
+func TestFormatDiffTable(t *testing.T) {
+
+	t.Run("ListsEveryChangedFieldOnItsOwnRow", func(t *testing.T) {
+
+		summary := api.DiffSummary{
+			Added: []api.DiffResource{{Kind: "Service", Name: "myapp"}},
+			Changed: []api.DiffResource{{Kind: "Deployment", Name: "myapp", Fields: []api.DiffField{
+				{Path: "spec.replicas", Live: "3", Rendered: "5"},
+				{Path: "spec.paused", Live: "true"},
+			}}},
+		}
+
+		// act
+		table := formatDiffTable(summary)
+
+		assert.Equal(t, "CHANGE  KIND        NAME   FIELD          LIVE  RENDERED\n"+
+			"add     Service     myapp  -              -     -\n"+
+			"change  Deployment  myapp  spec.replicas  3     5\n"+
+			"                           spec.paused    true  -", table)
+	})
+}
@@ -0,0 +1,54 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/estafette/estafette-extension-gke/api"
+)
+
+// Explain prints the effective value of every parameter and the layer it comes from; the defaults of the namespace are only included when
+// namespaceDefaults is set, since reading them requires access to the cluster
+func (s *service) Explain(ctx context.Context, credential *api.GKECredentials, namespaceDefaults bool, releaseName, paramsYAML, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseAction, releaseID string) (err error) {
+
+	if namespaceDefaults {
+		kubeContextName, err := s.gcpClient.LoadGKEClusterKubeConfig(ctx, credential)
+		if err != nil {
+			return fmt.Errorf("Failed creating kube config for gke cluster: %w", err)
+		}
+
+		err = s.kubernetesClient.Init(ctx, kubeContextName)
+		if err != nil {
+			return fmt.Errorf("Failed initializing kubernetes client: %w", err)
+		}
+	}
+
+	explanations, err := s.parametersClient.Explain(ctx, paramsYAML, credential, namespaceDefaults, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID)
+	if err != nil {
+		return fmt.Errorf("Failed explaining parameters: %w", err)
+	}
+
+	fmt.Println(formatExplainTable(explanations))
+
+	return nil
+}
+
+// formatExplainTable lists a row for every parameter with its effective value and the layer it comes from
+func formatExplainTable(explanations []api.ParamExplanation) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "PARAMETER\tVALUE\tSOURCE")
+	for _, e := range explanations {
+		value := strings.ReplaceAll(e.Value, "\n", "\\n")
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\n", e.Path, value, e.Source)
+	}
+	w.Flush()
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -0,0 +1,28 @@
+package extension
+
+import (
+	"testing"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatExplainTable(t *testing.T) {
+
+	t.Run("ListsEveryParameterWithItsValueAndSource", func(t *testing.T) {
+
+		explanations := []api.ParamExplanation{
+			{Path: "app", Value: "myapp", Source: api.ParamsLayerBuiltIn},
+			{Path: "container.env.LOG_LEVEL", Value: "debug", Source: api.ParamsLayerStage},
+			{Path: "request.configurationSnippet", Value: "a\nb", Source: api.ParamsLayerNamespace},
+		}
+
+		// act
+		table := formatExplainTable(explanations)
+
+		assert.Equal(t, `PARAMETER                     VALUE  SOURCE
+app                           myapp  built-in
+container.env.LOG_LEVEL       debug  stage
+request.configurationSnippet  a\nb   namespace`, table)
+	})
+}
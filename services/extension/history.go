@@ -0,0 +1,109 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	releaseHistoryKey = "releases.json"
+
+	// maxReleaseHistory keeps the configmap well below its size limit
+	maxReleaseHistory = 100
+)
+
+// releaseHistoryName returns the name of the configmap holding the release history of an application
+func releaseHistoryName(params api.Params) string {
+	return params.App + "-release-history"
+}
+
+// getReleaseHistory returns the recorded releases of an application, oldest first
+func (s *service) getReleaseHistory(ctx context.Context, params api.Params) (records []api.ReleaseRecord, err error) {
+	configMap, err := s.kubernetesClient.GetConfigMap(ctx, params.Namespace, releaseHistoryName(params))
+	if errors.Is(err, kubernetes.ErrResourceNotFound) {
+		return []api.ReleaseRecord{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed retrieving release history %v: %w", releaseHistoryName(params), err)
+	}
+
+	err = json.Unmarshal([]byte(configMap.Data[releaseHistoryKey]), &records)
+	if err != nil {
+		return nil, fmt.Errorf("Failed unmarshalling release history %v: %w", releaseHistoryName(params), err)
+	}
+
+	return records, nil
+}
+
+// recordRelease appends a release to the release history of the application, dropping the oldest releases beyond maxReleaseHistory; a release
+// that can't be recorded doesn't fail the release
+func (s *service) recordRelease(ctx context.Context, params api.Params, record api.ReleaseRecord) {
+	records, err := s.getReleaseHistory(ctx, params)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed recording release in the release history")
+		return
+	}
+
+	records = append(records, record)
+	if len(records) > maxReleaseHistory {
+		records = records[len(records)-maxReleaseHistory:]
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed marshalling release history")
+		return
+	}
+
+	log.Info().Msgf("Recording release in release history %v...", releaseHistoryName(params))
+	err = s.kubernetesClient.CreateOrUpdateConfigMap(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      releaseHistoryName(params),
+			Namespace: params.Namespace,
+			// without the app label, so the delete action keeps the audit trail
+			Labels: map[string]string{
+				"estafette.io/release-history-of": api.SanitizeLabel(params.App),
+			},
+		},
+		Data: map[string]string{
+			releaseHistoryKey: string(data),
+		},
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed recording release in the release history")
+	}
+}
+
+// showReleaseHistory prints the recorded releases of an application, most recent first
+func (s *service) showReleaseHistory(ctx context.Context, params api.Params) (err error) {
+	records, err := s.getReleaseHistory(ctx, params)
+	if err != nil {
+		return
+	}
+	if len(records) == 0 {
+		log.Info().Msgf("There are no releases recorded for %v in namespace %v", params.App, params.Namespace)
+		return nil
+	}
+
+	log.Info().Msgf("Last %v releases of %v in namespace %v:", len(records), params.App, params.Namespace)
+	for i := len(records) - 1; i >= 0; i-- {
+		log.Info().Msg(records[i].String())
+	}
+
+	return nil
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,109 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRecordRelease(t *testing.T) {
+
+	params := api.Params{App: "myapp", Namespace: "mynamespace"}
+
+	t.Run("CreatesReleaseHistoryIfItDoesNotExist", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		record := api.ReleaseRecord{ReleaseID: "123", Action: api.ActionDeploySimple, Succeeded: true}
+
+		kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", "myapp-release-history").Return(nil, kubernetes.ErrResourceNotFound)
+		kubernetesClient.EXPECT().CreateOrUpdateConfigMap(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, configMap *corev1.ConfigMap) error {
+			assert.Equal(t, "myapp-release-history", configMap.Name)
+			assert.Equal(t, map[string]string{"estafette.io/release-history-of": "myapp"}, configMap.Labels)
+			var records []api.ReleaseRecord
+			assert.Nil(t, json.Unmarshal([]byte(configMap.Data["releases.json"]), &records))
+			assert.Equal(t, []api.ReleaseRecord{record}, records)
+			return nil
+		})
+
+		// act
+		service.recordRelease(context.Background(), params, record)
+	})
+
+	t.Run("AppendsReleaseAndDropsOldestReleasesBeyondMaximum", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		existing := []api.ReleaseRecord{}
+		for i := 1; i <= maxReleaseHistory; i++ {
+			existing = append(existing, api.ReleaseRecord{ReleaseID: fmt.Sprint(i)})
+		}
+		data, _ := json.Marshal(existing)
+
+		kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", "myapp-release-history").Return(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp-release-history", Namespace: "mynamespace"},
+			Data:       map[string]string{"releases.json": string(data)},
+		}, nil)
+		kubernetesClient.EXPECT().CreateOrUpdateConfigMap(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, configMap *corev1.ConfigMap) error {
+			var records []api.ReleaseRecord
+			assert.Nil(t, json.Unmarshal([]byte(configMap.Data["releases.json"]), &records))
+			if assert.Equal(t, maxReleaseHistory, len(records)) {
+				assert.Equal(t, "2", records[0].ReleaseID)
+				assert.Equal(t, "101", records[maxReleaseHistory-1].ReleaseID)
+			}
+			return nil
+		})
+
+		// act
+		service.recordRelease(context.Background(), params, api.ReleaseRecord{ReleaseID: "101"})
+	})
+
+	t.Run("DoesNotOverwriteReleaseHistoryThatCannotBeRead", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+
+		kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", "myapp-release-history").Return(&corev1.ConfigMap{
+			Data: map[string]string{"releases.json": "not json"},
+		}, nil)
+
+		// act
+		service.recordRelease(context.Background(), params, api.ReleaseRecord{ReleaseID: "123"})
+	})
+}
+
+func TestShowReleaseHistory(t *testing.T) {
+
+	t.Run("ReturnsNilIfNoReleasesAreRecorded", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+
+		kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", "myapp-release-history").Return(nil, kubernetes.ErrResourceNotFound)
+
+		// act
+		err := service.showReleaseHistory(context.Background(), api.Params{App: "myapp", Namespace: "mynamespace"})
+
+		assert.Nil(t, err)
+	})
+}
@@ -0,0 +1,132 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/rs/zerolog/log"
+)
+
+// releaseLockDuration is how long the lease stays valid without being renewed, so the lock of a release that got killed frees up shortly after
+const releaseLockDuration = time.Minute
+
+// releaseLockName returns the name of the lease that keeps releases of the same application from running at the same time
+func releaseLockName(params api.Params) string {
+	return params.App + "-release-lock"
+}
+
+// mutatesCluster tells whether a release changes resources in the cluster, and should therefore hold the release lock
+func mutatesCluster(params api.Params) bool {
+	if params.DryRun {
+		return false
+	}
+
+	switch params.Action {
+	case api.ActionDiffSimple, api.ActionDiffCanary, api.ActionDiffStable, api.ActionDiffDelete:
+		return false
+	}
+
+	return true
+}
+
+// acquireReleaseLock takes the release lock of the application, waiting for another release holding it to finish or failing right away depending on
+// lock.onConflict; the lock is renewed in the background until the returned function releases it
+func (s *service) acquireReleaseLock(ctx context.Context, params api.Params, releaseID, triggeredBy string) (release func(), err error) {
+	timeout, err := time.ParseDuration(params.Lock.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("Failed parsing lock timeout %v: %w", params.Lock.Timeout, err)
+	}
+
+	name := releaseLockName(params)
+	holder := kubernetes.LeaseHolder{
+		Identity:    releaseLockIdentity(releaseID),
+		ReleaseID:   releaseID,
+		TriggeredBy: triggeredBy,
+	}
+
+	deadline := time.Now().Add(timeout)
+	lastHolder := ""
+	for {
+		current, err := s.kubernetesClient.AcquireLease(ctx, params.Namespace, name, holder, releaseLockDuration)
+		if err == nil {
+			break
+		}
+		if errors.Is(err, kubernetes.ErrForbidden) {
+			log.Warn().Err(err).Msgf("Not allowed to use lease %v, continuing without protection against concurrent releases; allow the credentials to get, create, update and delete leases in namespace %v", name, params.Namespace)
+			return func() {}, nil
+		}
+		if errors.Is(err, kubernetes.ErrResourceNotFound) {
+			// the namespace gets created by this release, so no other release of the application can be running in it yet
+			log.Info().Msgf("Namespace %v doesn't exist yet, continuing without release lock %v", params.Namespace, name)
+			return func() {}, nil
+		}
+		if !errors.Is(err, kubernetes.ErrLeaseHeld) {
+			return nil, fmt.Errorf("Failed taking release lock %v: %w", name, err)
+		}
+		if params.Lock.OnConflict == "fail" {
+			return nil, fmt.Errorf("Another release of %v is in progress, %v: %w", params.App, current, err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Another release of %v didn't finish within lock timeout %v, %v: %w", params.App, timeout, current, err)
+		}
+		if current.Identity != lastHolder {
+			log.Info().Msgf("Waiting up to %v for %v of %v to finish...", timeout, current, params.App)
+			lastHolder = current.Identity
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(s.lockPollInterval):
+		}
+	}
+	log.Info().Msgf("Took release lock %v in namespace %v", name, params.Namespace)
+
+	// renew the lease well within its duration until the release is done
+	renewCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-renewCtx.Done():
+				return
+			case <-time.After(releaseLockDuration / 3):
+			}
+
+			err := s.kubernetesClient.RenewLease(renewCtx, params.Namespace, name, holder.Identity)
+			if err != nil && renewCtx.Err() == nil {
+				log.Warn().Err(err).Msgf("Failed renewing release lock %v", name)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+
+		err := s.kubernetesClient.ReleaseLease(ctx, params.Namespace, name, holder.Identity)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed releasing release lock %v, it frees up once it's no longer renewed", name)
+		}
+	}, nil
+}
+
+// releaseLockIdentity identifies the release holding the lock; each estafette release runs in its own container, so without a release id the hostname is unique as well
+func releaseLockIdentity(releaseID string) string {
+	if releaseID != "" {
+		return releaseID
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Sprint(time.Now().UnixNano())
+	}
+
+	return hostname
+}
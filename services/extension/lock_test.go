@@ -0,0 +1,148 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquireReleaseLock(t *testing.T) {
+
+	holder := kubernetes.LeaseHolder{Identity: "123", ReleaseID: "123", TriggeredBy: "me@server.com"}
+	otherHolder := kubernetes.LeaseHolder{Identity: "456", ReleaseID: "456", TriggeredBy: "someone@server.com", Since: time.Now()}
+
+	t.Run("TakesLeaseNamedAfterAppAndReleasesIt", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{App: "myapp", Namespace: "mynamespace", Lock: api.LockParams{OnConflict: "wait", Timeout: "30m"}}
+
+		kubernetesClient.EXPECT().AcquireLease(gomock.Any(), "mynamespace", "myapp-release-lock", holder, time.Minute).Return(holder, nil)
+		kubernetesClient.EXPECT().ReleaseLease(gomock.Any(), "mynamespace", "myapp-release-lock", "123").Return(nil)
+
+		// act
+		release, err := service.acquireReleaseLock(context.Background(), params, "123", "me@server.com")
+
+		assert.Nil(t, err)
+		release()
+	})
+
+	t.Run("WaitsForOtherReleaseToFinish", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{App: "myapp", Namespace: "mynamespace", Lock: api.LockParams{OnConflict: "wait", Timeout: "30m"}}
+
+		gomock.InOrder(
+			kubernetesClient.EXPECT().AcquireLease(gomock.Any(), "mynamespace", "myapp-release-lock", holder, time.Minute).Return(otherHolder, kubernetes.ErrLeaseHeld).Times(2),
+			kubernetesClient.EXPECT().AcquireLease(gomock.Any(), "mynamespace", "myapp-release-lock", holder, time.Minute).Return(holder, nil),
+		)
+		kubernetesClient.EXPECT().ReleaseLease(gomock.Any(), "mynamespace", "myapp-release-lock", "123").Return(nil)
+
+		// act
+		release, err := service.acquireReleaseLock(context.Background(), params, "123", "me@server.com")
+
+		assert.Nil(t, err)
+		release()
+	})
+
+	t.Run("FailsRightAwayIfOnConflictIsFail", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{App: "myapp", Namespace: "mynamespace", Lock: api.LockParams{OnConflict: "fail", Timeout: "30m"}}
+
+		kubernetesClient.EXPECT().AcquireLease(gomock.Any(), "mynamespace", "myapp-release-lock", holder, time.Minute).Return(otherHolder, kubernetes.ErrLeaseHeld)
+
+		// act
+		_, err := service.acquireReleaseLock(context.Background(), params, "123", "me@server.com")
+
+		assert.True(t, errors.Is(err, kubernetes.ErrLeaseHeld))
+		assert.Contains(t, err.Error(), "release 456 triggered by someone@server.com")
+	})
+
+	t.Run("FailsIfOtherReleaseDoesNotFinishWithinTimeout", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, lockPollInterval: time.Millisecond}
+		params := api.Params{App: "myapp", Namespace: "mynamespace", Lock: api.LockParams{OnConflict: "wait", Timeout: "10ms"}}
+
+		kubernetesClient.EXPECT().AcquireLease(gomock.Any(), "mynamespace", "myapp-release-lock", holder, time.Minute).Return(otherHolder, kubernetes.ErrLeaseHeld).MinTimes(1)
+
+		// act
+		_, err := service.acquireReleaseLock(context.Background(), params, "123", "me@server.com")
+
+		assert.True(t, errors.Is(err, kubernetes.ErrLeaseHeld))
+	})
+
+	t.Run("ContinuesWithoutLockIfLeasesAreForbidden", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{App: "myapp", Namespace: "mynamespace", Lock: api.LockParams{OnConflict: "fail", Timeout: "30m"}}
+
+		kubernetesClient.EXPECT().AcquireLease(gomock.Any(), "mynamespace", "myapp-release-lock", holder, time.Minute).Return(kubernetes.LeaseHolder{}, kubernetes.ErrForbidden)
+
+		// act
+		release, err := service.acquireReleaseLock(context.Background(), params, "123", "me@server.com")
+
+		assert.Nil(t, err)
+		release()
+	})
+
+	t.Run("ContinuesWithoutLockIfNamespaceDoesNotExistYet", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{App: "myapp", Namespace: "mynamespace", Lock: api.LockParams{OnConflict: "fail", Timeout: "30m"}}
+
+		kubernetesClient.EXPECT().AcquireLease(gomock.Any(), "mynamespace", "myapp-release-lock", holder, time.Minute).Return(kubernetes.LeaseHolder{}, kubernetes.ErrResourceNotFound)
+
+		// act
+		release, err := service.acquireReleaseLock(context.Background(), params, "123", "me@server.com")
+
+		assert.Nil(t, err)
+		release()
+	})
+}
+
+func TestMutatesCluster(t *testing.T) {
+
+	t.Run("ReturnsFalseForDiffActionsAndDryRuns", func(t *testing.T) {
+
+		assert.False(t, mutatesCluster(api.Params{Action: api.ActionDiffSimple}))
+		assert.False(t, mutatesCluster(api.Params{Action: api.ActionDiffDelete}))
+		assert.False(t, mutatesCluster(api.Params{Action: api.ActionDeploySimple, DryRun: true}))
+	})
+
+	t.Run("ReturnsTrueForActionsThatChangeResources", func(t *testing.T) {
+
+		assert.True(t, mutatesCluster(api.Params{Action: api.ActionDeploySimple}))
+		assert.True(t, mutatesCluster(api.Params{Action: api.ActionRestartStable}))
+		assert.True(t, mutatesCluster(api.Params{Action: api.ActionDelete}))
+	})
+}
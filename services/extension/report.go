@@ -0,0 +1,153 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/estafette/estafette-extension-gke/clients/registry"
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// startStep ends the running step as successful and starts timing the next one
+func (s *service) startStep(name string) {
+	s.finishStep(nil)
+	s.currentStep = name
+	s.currentStepStart = time.Now()
+}
+
+func (s *service) finishStep(err error) {
+	if s.report == nil || s.currentStep == "" {
+		return
+	}
+	s.report.AddStep(s.currentStep, s.currentStepStart, err)
+	s.currentStep = ""
+}
+
+// setRolloutOutcome overrides the outcome recorded by reportRollout, for rollouts that timed out
+func (s *service) setRolloutOutcome(outcome api.RolloutOutcome) {
+	if s.report == nil {
+		return
+	}
+	s.report.Rollout = outcome
+}
+
+// reportRollout records the outcome of a rollout and, for deployments that finished, the replica counts and digest of the running image
+func (s *service) reportRollout(ctx context.Context, templateData api.TemplateData, err error) {
+	if s.report == nil {
+		return
+	}
+	if err != nil {
+		s.report.Rollout = api.RolloutOutcomeFailed
+		return
+	}
+	s.report.Rollout = api.RolloutOutcomeSucceeded
+
+	deployment, getErr := s.kubernetesClient.GetDeployment(ctx, templateData.Namespace, templateData.NameWithTrack)
+	if getErr != nil {
+		// statefulsets and failures to retrieve the deployment leave the replica counts out of the report
+		return
+	}
+	if deployment.Spec.Replicas != nil {
+		s.report.Replicas = &api.ReportReplicas{
+			Desired:   *deployment.Spec.Replicas,
+			Updated:   deployment.Status.UpdatedReplicas,
+			Ready:     deployment.Status.ReadyReplicas,
+			Available: deployment.Status.AvailableReplicas,
+		}
+	}
+
+	if s.report.ImageDigest != "" {
+		// the image got pinned to its digest before rendering
+		return
+	}
+	selector, selectorErr := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if selectorErr != nil {
+		return
+	}
+	pods, listErr := s.kubernetesClient.ListResources(ctx, []schema.GroupVersionResource{kubernetes.ResourcePods}, templateData.Namespace, selector.String())
+	if listErr != nil {
+		log.Warn().Err(listErr).Msg("Failed retrieving pods to determine the image digest")
+		return
+	}
+	s.report.ImageDigest = imageDigest(pods, templateData.Name, s.report.Image)
+}
+
+// imageDigest returns the digest of the image the container runs in any of the pods, taken from the image id reported by the kubelet
+func imageDigest(pods []unstructured.Unstructured, container, image string) string {
+	reference, referenceErr := registry.ParseReference(image)
+	for _, pod := range pods {
+		statuses, _, _ := unstructured.NestedSlice(pod.Object, "status", "containerStatuses")
+		for _, st := range statuses {
+			status, ok := st.(map[string]interface{})
+			if !ok || status["name"] != container {
+				continue
+			}
+			statusImage, _ := status["image"].(string)
+			imageID, _ := status["imageID"].(string)
+			if image != "" && referenceErr == nil && !runsImage(statusImage, reference) {
+				// a pod of the previous replicaset that's still terminating
+				continue
+			}
+			if parts := strings.SplitN(imageID, "@", 2); len(parts) == 2 {
+				return parts[1]
+			}
+		}
+	}
+
+	return ""
+}
+
+// runsImage returns whether the image reported by the kubelet is the image of the release; the kubelet reports the image normalized, like
+// docker.io/library/nginx:1.25, and may leave out the tag of an image that's pulled by digest, so the repository is compared and the tag only
+// if it's reported
+func runsImage(statusImage string, image registry.Reference) bool {
+	reference, err := registry.ParseReference(statusImage)
+	if err != nil || reference.Registry != image.Registry || reference.Repository != image.Repository {
+		return false
+	}
+
+	return reference.Tag == "" || reference.Tag == image.Tag
+}
+
+// writeReport completes the report with the outcome of the run and writes it to the reportPath parameter, if set
+func (s *service) writeReport(err error) {
+	s.finishStep(err)
+
+	if s.reportPath == "" {
+		return
+	}
+
+	s.report.FinishedAt = time.Now().UTC()
+	s.report.Succeeded = err == nil
+	if err != nil {
+		s.report.Error = err.Error()
+	}
+	for _, c := range s.kubernetesClient.Changes() {
+		s.report.Resources = append(s.report.Resources, api.ReportResource{
+			Operation: c.Operation,
+			Kind:      c.Kind,
+			Namespace: c.Namespace,
+			Name:      c.Name,
+		})
+	}
+
+	data, marshalErr := json.MarshalIndent(s.report, "", "  ")
+	if marshalErr != nil {
+		log.Warn().Err(marshalErr).Msg("Failed marshalling report")
+		return
+	}
+
+	log.Info().Msgf("Writing report to %v...", s.reportPath)
+	writeErr := ioutil.WriteFile(s.reportPath, data, 0644)
+	if writeErr != nil {
+		log.Warn().Err(writeErr).Msgf("Failed writing report to %v", s.reportPath)
+	}
+}
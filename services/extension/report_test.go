@@ -0,0 +1,174 @@
+package extension
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestWriteReport(t *testing.T) {
+
+	t.Run("WritesResourcesStepsAndOutcome", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		reportPath := filepath.Join(t.TempDir(), "report.json")
+		service := &service{
+			kubernetesClient: kubernetesClient,
+			report:           &api.Report{App: "myapp", Rollout: api.RolloutOutcomeFailed, Resources: []api.ReportResource{}, Steps: []api.ReportStep{}},
+			reportPath:       reportPath,
+		}
+		service.startStep("apply")
+		service.startStep("rollout")
+
+		kubernetesClient.EXPECT().Changes().Return([]kubernetes.ResourceChange{
+			{Operation: kubernetes.ChangeApplied, Kind: "Deployment", Namespace: "mynamespace", Name: "myapp"},
+			{Operation: kubernetes.ChangeDeleted, Kind: "Ingress", Namespace: "mynamespace", Name: "myapp-internal"},
+		})
+
+		// act
+		service.writeReport(errors.New("Failed waiting for rollout"))
+
+		data, err := ioutil.ReadFile(reportPath)
+		assert.Nil(t, err)
+		var report api.Report
+		err = json.Unmarshal(data, &report)
+		assert.Nil(t, err)
+		assert.False(t, report.Succeeded)
+		assert.Equal(t, "Failed waiting for rollout", report.Error)
+		assert.Equal(t, api.RolloutOutcomeFailed, report.Rollout)
+		assert.Equal(t, []api.ReportResource{
+			{Operation: "applied", Kind: "Deployment", Namespace: "mynamespace", Name: "myapp"},
+			{Operation: "deleted", Kind: "Ingress", Namespace: "mynamespace", Name: "myapp-internal"},
+		}, report.Resources)
+		if assert.Equal(t, 2, len(report.Steps)) {
+			assert.Equal(t, "apply", report.Steps[0].Name)
+			assert.Equal(t, "", report.Steps[0].Error)
+			assert.Equal(t, "rollout", report.Steps[1].Name)
+			assert.Equal(t, "Failed waiting for rollout", report.Steps[1].Error)
+		}
+	})
+
+	t.Run("DoesNotWriteReportIfPathIsNotSet", func(t *testing.T) {
+
+		service := &service{report: &api.Report{}}
+		service.startStep("initialize")
+
+		// act
+		service.writeReport(nil)
+
+		assert.Equal(t, 1, len(service.report.Steps))
+		assert.True(t, service.report.FinishedAt.IsZero())
+	})
+}
+
+func TestReportRollout(t *testing.T) {
+
+	t.Run("RecordsReplicasAndDigestOfUpdatedPods", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, report: &api.Report{Image: "estafette/myapp:1.0.1"}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp-stable", Namespace: "mynamespace"}
+
+		replicas := int32(3)
+		kubernetesClient.EXPECT().GetDeployment(gomock.Any(), "mynamespace", "myapp-stable").Return(&appsv1.Deployment{
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "myapp", "track": "stable"}},
+			},
+			Status: appsv1.DeploymentStatus{UpdatedReplicas: 3, ReadyReplicas: 3, AvailableReplicas: 3},
+		}, nil)
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), []schema.GroupVersionResource{kubernetes.ResourcePods}, "mynamespace", "app=myapp,track=stable").Return([]unstructured.Unstructured{
+			newPodWithImage("myapp", "docker.io/estafette/myapp:1.0.0", "docker-pullable://estafette/myapp@sha256:old"),
+			newPodWithImage("myapp", "docker.io/estafette/myapp:1.0.1", "docker-pullable://estafette/myapp@sha256:new"),
+		}, nil)
+
+		// act
+		service.reportRollout(context.Background(), templateData, nil)
+
+		assert.Equal(t, api.RolloutOutcomeSucceeded, service.report.Rollout)
+		assert.Equal(t, &api.ReportReplicas{Desired: 3, Updated: 3, Ready: 3, Available: 3}, service.report.Replicas)
+		assert.Equal(t, "sha256:new", service.report.ImageDigest)
+	})
+
+	t.Run("RecordsDigestOfPodsRunningImagePulledByDigest", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, report: &api.Report{Image: "estafette/myapp:1.0.1"}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp", Namespace: "mynamespace"}
+
+		kubernetesClient.EXPECT().GetDeployment(gomock.Any(), "mynamespace", "myapp").Return(&appsv1.Deployment{
+			Spec: appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "myapp"}}},
+		}, nil)
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), []schema.GroupVersionResource{kubernetes.ResourcePods}, "mynamespace", "app=myapp").Return([]unstructured.Unstructured{
+			newPodWithImage("myapp", "docker.io/estafette/myapp@sha256:new", "docker-pullable://estafette/myapp@sha256:new"),
+		}, nil)
+
+		// act
+		service.reportRollout(context.Background(), templateData, nil)
+
+		assert.Equal(t, "sha256:new", service.report.ImageDigest)
+	})
+
+	t.Run("KeepsDigestOfPinnedImageWithoutListingPods", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, report: &api.Report{Image: "estafette/myapp:1.0.1", ImageDigest: "sha256:pinned"}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp", Namespace: "mynamespace"}
+
+		kubernetesClient.EXPECT().GetDeployment(gomock.Any(), "mynamespace", "myapp").Return(&appsv1.Deployment{
+			Spec: appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "myapp"}}},
+		}, nil)
+
+		// act
+		service.reportRollout(context.Background(), templateData, nil)
+
+		assert.Equal(t, "sha256:pinned", service.report.ImageDigest)
+	})
+
+	t.Run("RecordsFailedRollout", func(t *testing.T) {
+
+		service := &service{report: &api.Report{}}
+
+		// act
+		service.reportRollout(context.Background(), api.TemplateData{}, errors.New("progress deadline exceeded"))
+
+		assert.Equal(t, api.RolloutOutcomeFailed, service.report.Rollout)
+		assert.Nil(t, service.report.Replicas)
+	})
+}
+
+func newPodWithImage(container, image, imageID string) unstructured.Unstructured {
+	return unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"status": map[string]interface{}{
+			"containerStatuses": []interface{}{
+				map[string]interface{}{"name": container, "image": image, "imageID": imageID},
+			},
+		},
+	}}
+}
@@ -0,0 +1,147 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const atomicIDLabel = "estafette.io/atomic-id"
+
+// rollbackAfterFailedRollout restores the previous version of a workload whose rollout failed and waits for it to be healthy again, so a failed release
+// doesn't leave a half rolled out workload behind; it returns the rollout error, annotated with the result of the rollback
+func (s *service) rollbackAfterFailedRollout(ctx context.Context, params api.Params, templateData api.TemplateData, rolloutErr error) (err error) {
+	timeout, err := time.ParseDuration(params.RollingUpdate.Timeout)
+	if err != nil {
+		return fmt.Errorf("Failed waiting for rollout and parsing rollingupdate timeout %v to roll it back: %v: %w", params.RollingUpdate.Timeout, err, rolloutErr)
+	}
+	rollbackCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch {
+	case templateData.TrackLabel == "canary":
+		log.Warn().Msg("Rollout failed, rolling back the canary...")
+		err = s.deleteCanaryResources(rollbackCtx, templateData.Name, templateData.Namespace)
+
+	case templateData.UseBlueGreen && templateData.BlueGreenColor != templateData.BlueGreenLiveColor:
+		log.Warn().Msgf("Rollout failed, colour %v doesn't receive traffic so it doesn't need to be rolled back; don't switch to it", templateData.BlueGreenColor)
+		return fmt.Errorf("Failed waiting for rollout: %w", rolloutErr)
+
+	case params.StrategyType == api.StrategyTypeAtomicUpdate:
+		log.Warn().Msg("Rollout failed, rolling back to the previous atomic deployment...")
+		err = s.rollbackAtomicUpdate(rollbackCtx, params, templateData)
+
+	case params.Kind == api.KindStatefulset:
+		log.Warn().Msgf("Rollout failed, rolling back statefulset %v...", templateData.Name)
+		err = s.rollbackStatefulSet(rollbackCtx, templateData.Name, templateData.Namespace)
+
+	default:
+		log.Warn().Msgf("Rollout failed, rolling back deployment %v...", templateData.NameWithTrack)
+		err = s.rollbackDeployment(rollbackCtx, templateData.NameWithTrack, templateData.Namespace)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed rolling back after failed rollout")
+		return fmt.Errorf("Failed waiting for rollout and rolling it back failed: %v: %w", err, rolloutErr)
+	}
+
+	s.setRolloutOutcome(api.RolloutOutcomeUndone)
+
+	return fmt.Errorf("Failed waiting for rollout, the previous version has been restored: %w", rolloutErr)
+}
+
+// rollbackStatefulSet restores the pod template, configs and secrets of the previous revision of a statefulset and waits for it to be rolled out
+func (s *service) rollbackStatefulSet(ctx context.Context, name, namespace string) (err error) {
+	revisions, err := s.kubernetesClient.ListStatefulSetRevisions(ctx, namespace, name)
+	if err != nil {
+		return fmt.Errorf("Failed retrieving revisions of statefulset %v: %w", name, err)
+	}
+	if len(revisions) < 2 {
+		return fmt.Errorf("Statefulset %v has no revision before its current revision to roll back to", name)
+	}
+
+	// revisions are ordered oldest first, so the previous revision is the one before the last
+	currentRevision := revisions[len(revisions)-1].Revision
+	previousRevision := revisions[len(revisions)-2]
+
+	log.Info().Msgf("Rolling back statefulset %v from revision %v to revision %v...", name, currentRevision, previousRevision.Revision)
+
+	err = s.restoreConfigsAndSecrets(ctx, name, namespace, previousRevision.Revision)
+	if err != nil {
+		return
+	}
+
+	err = s.kubernetesClient.RollbackStatefulSet(ctx, namespace, name, previousRevision)
+	if err != nil {
+		return fmt.Errorf("Failed rolling back statefulset %v to revision %v: %w", name, previousRevision.Revision, err)
+	}
+
+	log.Info().Msg("Waiting for the rollback to finish...")
+	err = s.kubernetesClient.WaitForStatefulSetRollout(ctx, namespace, name)
+	if err != nil {
+		return fmt.Errorf("Failed waiting for rollback of statefulset %v: %w", name, err)
+	}
+
+	// restoring a template moves its controller revision to a new revision number, which needs its own snapshot
+	s.snapshotConfigsAndSecrets(ctx, api.KindStatefulset, name, namespace)
+
+	return nil
+}
+
+// rollbackAtomicUpdate points the service back at the most recent previous atomic deployment, waits for that deployment to be healthy and removes the
+// deployment, configs and secrets of the failed atomic id
+func (s *service) rollbackAtomicUpdate(ctx context.Context, params api.Params, templateData api.TemplateData) (err error) {
+	app := api.SanitizeLabel(params.App)
+
+	deployments, err := s.kubernetesClient.ListDeployments(ctx, templateData.Namespace, fmt.Sprintf("app in (%v),%v,%v notin (%v)", app, atomicIDLabel, atomicIDLabel, params.AtomicID))
+	if err != nil {
+		return fmt.Errorf("Failed retrieving previous atomic deployments: %w", err)
+	}
+	if len(deployments) == 0 {
+		return fmt.Errorf("There's no previous atomic deployment to roll back to")
+	}
+	sort.Slice(deployments, func(i, j int) bool {
+		return deployments[i].CreationTimestamp.Before(&deployments[j].CreationTimestamp)
+	})
+	previous := deployments[len(deployments)-1]
+	previousAtomicID := previous.Labels[atomicIDLabel]
+
+	// the service only moves to the new atomic id after a successful rollout, but make sure it still selects the previous one
+	service, err := s.kubernetesClient.GetService(ctx, templateData.Namespace, templateData.Service.Name)
+	if err != nil && !errors.Is(err, kubernetes.ErrResourceNotFound) {
+		return fmt.Errorf("Failed retrieving service %v: %w", templateData.Service.Name, err)
+	}
+	if err == nil && service.Spec.Selector[atomicIDLabel] != previousAtomicID {
+		log.Info().Msgf("Reverting selector of service %v to atomic id %v...", templateData.Service.Name, previousAtomicID)
+		patch := []byte(fmt.Sprintf(`{"spec":{"selector":{%q:%q}}}`, atomicIDLabel, previousAtomicID))
+		err = s.kubernetesClient.PatchResource(ctx, kubernetes.ResourceServices, templateData.Namespace, templateData.Service.Name, types.MergePatchType, patch)
+		if err != nil {
+			return fmt.Errorf("Failed reverting selector of service %v to atomic id %v: %w", templateData.Service.Name, previousAtomicID, err)
+		}
+	}
+
+	log.Info().Msgf("Waiting for previous atomic deployment %v to be healthy...", previous.Name)
+	err = s.kubernetesClient.WaitForDeploymentRollout(ctx, templateData.Namespace, previous.Name)
+	if err != nil {
+		return fmt.Errorf("Failed waiting for previous atomic deployment %v: %w", previous.Name, err)
+	}
+
+	log.Info().Msgf("Removing deployment, configmaps, secrets, hpa and pdb of failed atomic id %v...", params.AtomicID)
+	err = s.kubernetesClient.DeleteResources(ctx, []schema.GroupVersionResource{kubernetes.ResourceDeployments, kubernetes.ResourceHorizontalPodAutoscalers, kubernetes.ResourcePodDisruptionBudgets}, templateData.Namespace, fmt.Sprintf("app in (%v),%v in (%v)", app, atomicIDLabel, params.AtomicID), false)
+	if err != nil {
+		return fmt.Errorf("Failed removing deployment of failed atomic id %v: %w", params.AtomicID, err)
+	}
+	err = s.kubernetesClient.DeleteResources(ctx, []schema.GroupVersionResource{kubernetes.ResourceConfigMaps, kubernetes.ResourceSecrets}, templateData.Namespace, fmt.Sprintf("app in (%v),type in (application),%v in (%v)", app, atomicIDLabel, params.AtomicID), false)
+	if err != nil {
+		return fmt.Errorf("Failed removing configs and secrets of failed atomic id %v: %w", params.AtomicID, err)
+	}
+
+	return nil
+}
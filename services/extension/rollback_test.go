@@ -0,0 +1,220 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestRollbackAfterFailedRollout(t *testing.T) {
+
+	t.Run("RollsBackDeploymentAndReportsRolloutAsUndone", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, report: &api.Report{}}
+		params := api.Params{Kind: api.KindDeployment, AutoRollback: true, RollingUpdate: api.RollingUpdateParams{Timeout: "5m", OnTimeout: "fail"}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp", Namespace: "mynamespace"}
+		rolloutErr := kubernetes.ErrRolloutFailed
+
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "myapp", Annotations: map[string]string{kubernetes.RevisionAnnotation: "2"}}}
+		replicaSets := []appsv1.ReplicaSet{
+			{ObjectMeta: metav1.ObjectMeta{Name: "myapp-a", Annotations: map[string]string{kubernetes.RevisionAnnotation: "1"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "myapp-b", Annotations: map[string]string{kubernetes.RevisionAnnotation: "2"}}},
+		}
+
+		kubernetesClient.EXPECT().GetDeployment(gomock.Any(), "mynamespace", "myapp").Return(deployment, nil).AnyTimes()
+		kubernetesClient.EXPECT().ListDeploymentReplicaSets(gomock.Any(), "mynamespace", "myapp").Return(replicaSets, nil).AnyTimes()
+		kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", gomock.Any()).Return(nil, kubernetes.ErrResourceNotFound).AnyTimes()
+		kubernetesClient.EXPECT().GetSecret(gomock.Any(), "mynamespace", gomock.Any()).Return(nil, kubernetes.ErrResourceNotFound).AnyTimes()
+		kubernetesClient.EXPECT().RollbackDeployment(gomock.Any(), "mynamespace", "myapp", replicaSets[0]).Return(nil)
+		kubernetesClient.EXPECT().WaitForDeploymentRollout(gomock.Any(), "mynamespace", "myapp").DoAndReturn(func(ctx context.Context, namespace, name string) error {
+			deadline, ok := ctx.Deadline()
+			assert.True(t, ok)
+			assert.True(t, time.Until(deadline) <= 5*time.Minute)
+			return nil
+		})
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), gomock.Any(), "mynamespace", "estafette.io/snapshot-of=myapp").Return(nil, nil)
+
+		// act
+		err := service.rollbackAfterFailedRollout(context.Background(), params, templateData, rolloutErr)
+
+		assert.True(t, errors.Is(err, kubernetes.ErrRolloutFailed))
+		assert.Equal(t, api.RolloutOutcomeUndone, service.report.Rollout)
+	})
+
+	t.Run("RemovesCanaryIfCanaryRolloutFails", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, report: &api.Report{}}
+		params := api.Params{Kind: api.KindDeployment, AutoRollback: true, RollingUpdate: api.RollingUpdateParams{Timeout: "5m", OnTimeout: "fail"}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp-canary", Namespace: "mynamespace", TrackLabel: "canary"}
+
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), gomock.Any(), "mynamespace", gomock.Any()).Return(nil).Times(12)
+
+		// act
+		err := service.rollbackAfterFailedRollout(context.Background(), params, templateData, kubernetes.ErrRolloutFailed)
+
+		assert.True(t, errors.Is(err, kubernetes.ErrRolloutFailed))
+		assert.Equal(t, api.RolloutOutcomeUndone, service.report.Rollout)
+	})
+
+	t.Run("LeavesIdleBlueGreenColorAsIs", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, report: &api.Report{Rollout: api.RolloutOutcomeFailed}}
+		params := api.Params{Kind: api.KindDeployment, StrategyType: api.StrategyTypeBlueGreen, AutoRollback: true, RollingUpdate: api.RollingUpdateParams{Timeout: "5m", OnTimeout: "fail"}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp-green", Namespace: "mynamespace", UseBlueGreen: true, BlueGreenColor: "green", BlueGreenLiveColor: "blue"}
+
+		// act
+		err := service.rollbackAfterFailedRollout(context.Background(), params, templateData, kubernetes.ErrRolloutFailed)
+
+		assert.True(t, errors.Is(err, kubernetes.ErrRolloutFailed))
+		assert.Equal(t, api.RolloutOutcomeFailed, service.report.Rollout)
+	})
+
+	t.Run("KeepsRolloutOutcomeIfRollbackFails", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, report: &api.Report{Rollout: api.RolloutOutcomeFailed}}
+		params := api.Params{Kind: api.KindStatefulset, AutoRollback: true, RollingUpdate: api.RollingUpdateParams{Timeout: "5m", OnTimeout: "fail"}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp", Namespace: "mynamespace"}
+
+		kubernetesClient.EXPECT().ListStatefulSetRevisions(gomock.Any(), "mynamespace", "myapp").Return([]appsv1.ControllerRevision{{ObjectMeta: metav1.ObjectMeta{Name: "myapp-a"}, Revision: 1}}, nil)
+
+		// act
+		err := service.rollbackAfterFailedRollout(context.Background(), params, templateData, kubernetes.ErrRolloutFailed)
+
+		assert.True(t, errors.Is(err, kubernetes.ErrRolloutFailed))
+		assert.Equal(t, api.RolloutOutcomeFailed, service.report.Rollout)
+	})
+}
+
+func TestRollbackStatefulSet(t *testing.T) {
+
+	t.Run("RestoresConfigsAndRollsBackToPreviousRevision", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		revisions := []appsv1.ControllerRevision{
+			{ObjectMeta: metav1.ObjectMeta{Name: "myapp-a"}, Revision: 3},
+			{ObjectMeta: metav1.ObjectMeta{Name: "myapp-b"}, Revision: 4},
+		}
+		configMapSnapshot := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp-configs-rev3", Namespace: "mynamespace", Labels: map[string]string{"app": "myapp", "estafette.io/snapshot-of": "myapp", "estafette.io/snapshot-revision": "3"}},
+			Data:       map[string]string{"config.yaml": "a: b"},
+		}
+
+		kubernetesClient.EXPECT().ListStatefulSetRevisions(gomock.Any(), "mynamespace", "myapp").Return(revisions, nil)
+		kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", "myapp-configs-rev3").Return(configMapSnapshot, nil)
+		kubernetesClient.EXPECT().CreateOrUpdateConfigMap(gomock.Any(), &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp-configs", Namespace: "mynamespace", Labels: map[string]string{"app": "myapp", "type": "application"}},
+			Data:       map[string]string{"config.yaml": "a: b"},
+		}).Return(nil)
+		kubernetesClient.EXPECT().GetSecret(gomock.Any(), "mynamespace", "myapp-secrets-rev3").Return(nil, kubernetes.ErrResourceNotFound)
+		kubernetesClient.EXPECT().RollbackStatefulSet(gomock.Any(), "mynamespace", "myapp", revisions[0]).Return(nil)
+		kubernetesClient.EXPECT().WaitForStatefulSetRollout(gomock.Any(), "mynamespace", "myapp").Return(nil)
+
+		// the restored revision is renumbered to 5, so the snapshot is taken for that revision
+		restoredRevisions := []appsv1.ControllerRevision{revisions[1], {ObjectMeta: metav1.ObjectMeta{Name: "myapp-a"}, Revision: 5}}
+		kubernetesClient.EXPECT().ListStatefulSetRevisions(gomock.Any(), "mynamespace", "myapp").Return(restoredRevisions, nil).Times(2)
+		kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", "myapp-configs").Return(nil, kubernetes.ErrResourceNotFound)
+		kubernetesClient.EXPECT().GetSecret(gomock.Any(), "mynamespace", "myapp-secrets").Return(nil, kubernetes.ErrResourceNotFound)
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), gomock.Any(), "mynamespace", "estafette.io/snapshot-of=myapp").Return(nil, nil)
+
+		// act
+		err := service.rollbackStatefulSet(context.Background(), "myapp", "mynamespace")
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrorIfThereIsNoPreviousRevision", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+
+		kubernetesClient.EXPECT().ListStatefulSetRevisions(gomock.Any(), "mynamespace", "myapp").Return([]appsv1.ControllerRevision{{ObjectMeta: metav1.ObjectMeta{Name: "myapp-a"}, Revision: 1}}, nil)
+
+		// act
+		err := service.rollbackStatefulSet(context.Background(), "myapp", "mynamespace")
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRollbackAtomicUpdate(t *testing.T) {
+
+	t.Run("RevertsServiceToPreviousAtomicIDAndRemovesFailedAtomicDeployment", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{App: "myapp", Kind: api.KindDeployment, StrategyType: api.StrategyTypeAtomicUpdate, AtomicID: "c"}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp-c", Namespace: "mynamespace", Service: api.ServiceData{Name: "myapp"}}
+		now := time.Now()
+		deployments := []appsv1.Deployment{
+			{ObjectMeta: metav1.ObjectMeta{Name: "myapp-b", Labels: map[string]string{"estafette.io/atomic-id": "b"}, CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "myapp-a", Labels: map[string]string{"estafette.io/atomic-id": "a"}, CreationTimestamp: metav1.NewTime(now.Add(-2 * time.Hour))}},
+		}
+
+		kubernetesClient.EXPECT().ListDeployments(gomock.Any(), "mynamespace", "app in (myapp),estafette.io/atomic-id,estafette.io/atomic-id notin (c)").Return(deployments, nil)
+		kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp").Return(&corev1.Service{Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "myapp", "estafette.io/atomic-id": "c"}}}, nil)
+		kubernetesClient.EXPECT().PatchResource(gomock.Any(), kubernetes.ResourceServices, "mynamespace", "myapp", types.MergePatchType, []byte(`{"spec":{"selector":{"estafette.io/atomic-id":"b"}}}`)).Return(nil)
+		kubernetesClient.EXPECT().WaitForDeploymentRollout(gomock.Any(), "mynamespace", "myapp-b").Return(nil)
+		kubernetesClient.EXPECT().DeleteResources(gomock.Any(), []schema.GroupVersionResource{kubernetes.ResourceDeployments, kubernetes.ResourceHorizontalPodAutoscalers, kubernetes.ResourcePodDisruptionBudgets}, "mynamespace", "app in (myapp),estafette.io/atomic-id in (c)", false).Return(nil)
+		kubernetesClient.EXPECT().DeleteResources(gomock.Any(), []schema.GroupVersionResource{kubernetes.ResourceConfigMaps, kubernetes.ResourceSecrets}, "mynamespace", "app in (myapp),type in (application),estafette.io/atomic-id in (c)", false).Return(nil)
+
+		// act
+		err := service.rollbackAtomicUpdate(context.Background(), params, templateData)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrorIfThereIsNoPreviousAtomicDeployment", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{App: "myapp", Kind: api.KindDeployment, StrategyType: api.StrategyTypeAtomicUpdate, AtomicID: "c"}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp-c", Namespace: "mynamespace", Service: api.ServiceData{Name: "myapp"}}
+
+		kubernetesClient.EXPECT().ListDeployments(gomock.Any(), "mynamespace", gomock.Any()).Return(nil, nil)
+
+		// act
+		err := service.rollbackAtomicUpdate(context.Background(), params, templateData)
+
+		assert.NotNil(t, err)
+	})
+}
@@ -1,71 +1,147 @@
 package extension
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"strconv"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/estafette/estafette-extension-gke/api"
 	"github.com/estafette/estafette-extension-gke/clients/credentials"
 	"github.com/estafette/estafette-extension-gke/clients/gcp"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
 	"github.com/estafette/estafette-extension-gke/clients/parameters"
+	"github.com/estafette/estafette-extension-gke/services/analysis"
 	"github.com/estafette/estafette-extension-gke/services/builder"
+	"github.com/estafette/estafette-extension-gke/services/diagnostics"
 	"github.com/estafette/estafette-extension-gke/services/generator"
-	foundation "github.com/estafette/estafette-foundation"
+	"github.com/estafette/estafette-extension-gke/services/policy"
 	"github.com/rs/zerolog/log"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	snapshotOfLabel       = "estafette.io/snapshot-of"
+	snapshotRevisionLabel = "estafette.io/snapshot-revision"
 )
 
 //go:generate mockgen -package=extension -destination ./mock.go -source=service.go
 type Service interface {
 	Run(ctx context.Context, credential *api.GKECredentials, releaseName, paramsYAML, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseAction, releaseID, gitBranch, gitRevision, builderImageSHA, builderImageDate, triggeredBy string) (err error)
+	Render(ctx context.Context, credential *api.GKECredentials, releaseName, paramsYAML, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseAction, releaseID, gitBranch, gitRevision, builderImageSHA, builderImageDate, triggeredBy, outputDir string) (err error)
+	Explain(ctx context.Context, credential *api.GKECredentials, namespaceDefaults bool, releaseName, paramsYAML, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseAction, releaseID string) (err error)
 }
 
 // NewService returns a new extension.Service
-func NewService(ctx context.Context, credentialsClient credentials.Client, parametersClient parameters.Client, gcpClient gcp.Client, builderService builder.Service, generatorService generator.Service) (Service, error) {
+func NewService(ctx context.Context, credentialsClient credentials.Client, parametersClient parameters.Client, gcpClient gcp.Client, kubernetesClient kubernetes.Client, builderService builder.Service, generatorService generator.Service, analysisService analysis.Service, diagnosticsService diagnostics.Service, policyService policy.Service) (Service, error) {
 	return &service{
-		credentialsClient: credentialsClient,
-		parametersClient:  parametersClient,
-		gcpClient:         gcpClient,
-		builderService:    builderService,
-		generatorService:  generatorService,
+		credentialsClient:  credentialsClient,
+		parametersClient:   parametersClient,
+		gcpClient:          gcpClient,
+		kubernetesClient:   kubernetesClient,
+		builderService:     builderService,
+		generatorService:   generatorService,
+		analysisService:    analysisService,
+		diagnosticsService: diagnosticsService,
+		policyService:      policyService,
+		lockPollInterval:   10 * time.Second,
+		logsDrainTimeout:   30 * time.Second,
 	}, nil
 }
 
 type service struct {
-	credentialsClient credentials.Client
-	parametersClient  parameters.Client
-	gcpClient         gcp.Client
-	builderService    builder.Service
-	generatorService  generator.Service
+	credentialsClient  credentials.Client
+	parametersClient   parameters.Client
+	gcpClient          gcp.Client
+	kubernetesClient   kubernetes.Client
+	builderService     builder.Service
+	generatorService   generator.Service
+	analysisService    analysis.Service
+	diagnosticsService diagnostics.Service
+	policyService      policy.Service
 
 	assistTroubleshootingOnError bool
 	paramsForTroubleshooting     api.Params
+
+	lockPollInterval time.Duration
+	releaseLockHeld  bool
+
+	logsDrainTimeout time.Duration
+
+	report           *api.Report
+	reportPath       string
+	currentStep      string
+	currentStepStart time.Time
 }
 
 func (s *service) Run(ctx context.Context, credential *api.GKECredentials, releaseName, paramsYAML, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseAction, releaseID, gitBranch, gitRevision, builderImageSHA, builderImageDate, triggeredBy string) (err error) {
 
-	params, err := s.parametersClient.Init(ctx, paramsYAML, credential, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID)
+	// the report covers the entire run, including the promotion of a progressive canary which calls Run again
+	if s.report == nil {
+		s.report = &api.Report{
+			BuildVersion: buildVersion,
+			ReleaseID:    releaseID,
+			StartedAt:    time.Now().UTC(),
+			Rollout:      api.RolloutOutcomeSkipped,
+			Resources:    []api.ReportResource{},
+			Steps:        []api.ReportStep{},
+		}
+		defer func() {
+			s.writeReport(err)
+		}()
+	}
+	s.startStep("initialize")
+
+	// the kubernetes client is needed to read the defaults of the namespace
+	kubeContextName, err := s.gcpClient.LoadGKEClusterKubeConfig(ctx, credential)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed initializing parameters")
+		return fmt.Errorf("Failed creating kube config for gke cluster: %w", err)
 	}
 
-	_, err = s.gcpClient.LoadGKEClusterKubeConfig(ctx, credential)
+	err = s.kubernetesClient.Init(ctx, kubeContextName)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed creating kube config for gke cluster")
+		return fmt.Errorf("Failed initializing kubernetes client: %w", err)
+	}
+
+	params, err := s.parametersClient.Init(ctx, paramsYAML, credential, true, true, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID)
+	if s.report.App == "" {
+		s.reportPath = params.ReportPath
+		s.report.App = params.App
+		s.report.Namespace = params.Namespace
+		s.report.Kind = params.Kind
+		s.report.Action = params.Action
+	}
+	if err != nil {
+		return fmt.Errorf("Failed initializing parameters: %w", err)
+	}
+
+	if params.Action == api.ActionHistory {
+		s.startStep("history")
+		return s.showReleaseHistory(ctx, params)
 	}
 
 	// combine templates
 	tmpl, err := s.builderService.BuildTemplates(params, true)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed building templates")
+		return fmt.Errorf("Failed building templates: %w", err)
 	}
 
 	tmplNoPDB, err := s.builderService.BuildTemplates(params, false)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed building templates without poddisruptionbudget")
+		return fmt.Errorf("Failed building templates without poddisruptionbudget: %w", err)
 	}
 
 	// pre-render config files if they exist
@@ -79,6 +155,44 @@ func (s *service) Run(ctx context.Context, credential *api.GKECredentials, relea
 		return
 	}
 
+	// hold the release lock for the rest of the run, so concurrent releases of the application don't interleave their changes; the promotion of a
+	// progressive canary calls Run again while already holding it
+	if !s.releaseLockHeld && mutatesCluster(params) {
+		s.startStep("lock")
+		var releaseLock func()
+		releaseLock, err = s.acquireReleaseLock(ctx, params, releaseID, triggeredBy)
+		if err != nil {
+			return
+		}
+		s.releaseLockHeld = true
+		defer func() {
+			// record the release while still holding the lock, so concurrent releases don't overwrite each other's record
+			s.recordRelease(ctx, params, api.ReleaseRecord{
+				ReleaseID:       releaseID,
+				Action:          params.Action,
+				Kind:            params.Kind,
+				BuildVersion:    buildVersion,
+				Image:           s.report.Image,
+				ImageDigest:     s.report.ImageDigest,
+				ParamsHash:      api.ParamsHash(params),
+				GitBranch:       gitBranch,
+				GitRevision:     gitRevision,
+				BuilderImageSHA: builderImageSHA,
+				TriggeredBy:     triggeredBy,
+				Succeeded:       err == nil,
+				Rollout:         s.report.Rollout,
+				Error:           errorMessage(err),
+				StartedAt:       s.report.StartedAt,
+				FinishedAt:      time.Now().UTC(),
+			})
+			releaseLock()
+			s.releaseLockHeld = false
+		}()
+	}
+
+	// determine which colour receives traffic, to deploy to the other one
+	s.setBlueGreenColors(ctx, &params)
+
 	// checking number of replicas for existing deployment to make switching deployment type safe
 	currentReplicas := params.Replicas
 	if params.Kind == api.KindDeployment || params.Kind == api.KindHeadlessDeployment {
@@ -87,33 +201,78 @@ func (s *service) Run(ctx context.Context, credential *api.GKECredentials, relea
 
 	// generate the data required for rendering the templates
 	templateData := s.generatorService.GenerateTemplateData(params, currentReplicas, gitSource, gitOwner, gitName, gitBranch, gitRevision, releaseID, builderImageSHA, builderImageDate, triggeredBy)
+	if tmpl != nil && templateData.Container.Repository != "" {
+		s.report.Image = fmt.Sprintf("%v/%v:%v", templateData.Container.Repository, templateData.Container.Name, templateData.Container.Tag)
+		// a pinned image runs exactly this digest, otherwise it's read from the pods after the rollout
+		s.report.ImageDigest = templateData.Container.Digest
+	}
 
 	if params.Action == api.ActionDelete || params.Action == api.ActionDiffDelete {
+		s.startStep("delete")
+		resources := []schema.GroupVersionResource{
+			kubernetes.ResourceServices,
+			kubernetes.ResourceIngresses,
+			kubernetes.ResourceDeployments,
+			kubernetes.ResourceStatefulSets,
+			kubernetes.ResourceCronJobs,
+			kubernetes.ResourceJobs,
+			kubernetes.ResourceConfigMaps,
+			kubernetes.ResourceSecrets,
+			kubernetes.ResourceHorizontalPodAutoscalers,
+			kubernetes.ResourcePodDisruptionBudgets,
+			kubernetes.ResourceServiceAccounts,
+		}
+		labelSelector := fmt.Sprintf("app=%v", templateData.AppLabelSelector)
+
+		if params.Action == api.ActionDiffDelete {
+			s.startStep("diff")
+			var items []unstructured.Unstructured
+			items, err = s.kubernetesClient.ListResources(ctx, resources, templateData.Namespace, labelSelector)
+			if err != nil {
+				return fmt.Errorf("Failed listing resources with label %v: %w", labelSelector, err)
+			}
+
+			return s.reportDiff(params, summarizeDiff(nil, items))
+		}
+
 		log.Info().Msgf("Deleting all resources with label app=%v in namespace %v...", templateData.AppLabelSelector, templateData.Namespace)
-		args := []string{"delete", "svc,ing,deploy,sts,cronjob,job,cm,secret,hpa,pdb,sa", "-l", fmt.Sprintf("app=%v", templateData.AppLabelSelector), "-n", templateData.Namespace, "--ignore-not-found=true"}
-		if params.DryRun || params.Action == api.ActionDiffDelete {
-			args = append(args, "--dry-run=client")
+		err = s.kubernetesClient.DeleteResources(ctx, resources, templateData.Namespace, labelSelector, params.DryRun)
+		if err != nil {
+			return fmt.Errorf("Failed deleting resources with label app=%v: %w", templateData.AppLabelSelector, err)
 		}
-		foundation.RunCommandWithArgs(ctx, "kubectl", args)
 
-		return
+		return nil
+	}
+
+	if isCronJobAction(params.Action) {
+		s.startStep(string(params.Action))
+		return s.runCronJobAction(ctx, params, templateData)
 	}
 
 	// render the template
 	renderedTemplate, err := s.builderService.RenderTemplate(tmpl, templateData, true)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed rendering templates")
+		return fmt.Errorf("Failed rendering templates: %w", err)
 	}
 	renderedNoPDBTemplate, err := s.builderService.RenderTemplate(tmplNoPDB, templateData, false)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed rendering templates without poddisruptionbudget")
+		return fmt.Errorf("Failed rendering templates without poddisruptionbudget: %w", err)
+	}
+
+	// label the resources with the inventory of this release, so resources of previous releases that aren't rendered anymore get pruned
+	var inventory *kubernetes.Inventory
+	if tmpl != nil && usesInventory(params) {
+		inventory, err = s.labelInventory(templateData, &renderedTemplate, &renderedNoPDBTemplate)
+		if err != nil {
+			return
+		}
 	}
 
 	if tmpl != nil {
 		log.Info().Msg("Storing rendered manifest on disk...")
 		err = ioutil.WriteFile("/kubernetes.yaml", renderedTemplate.Bytes(), 0600)
 		if err != nil {
-			log.Fatal().Err(err).Msg("Failed writing manifest")
+			return fmt.Errorf("Failed writing manifest: %w", err)
 		}
 	}
 
@@ -121,259 +280,1137 @@ func (s *service) Run(ctx context.Context, credential *api.GKECredentials, relea
 		log.Info().Msg("Storing rendered manifest without poddisruptionbudget on disk...")
 		err = ioutil.WriteFile("/kubernetes-no-pdb.yaml", renderedNoPDBTemplate.Bytes(), 0600)
 		if err != nil {
-			log.Fatal().Err(err).Msg("Failed writing manifest without poddisruptionbudget")
+			return fmt.Errorf("Failed writing manifest without poddisruptionbudget: %w", err)
 		}
 	}
 
+	var diffs []kubernetes.ResourceDiff
+	var diffErr error
 	if tmpl != nil {
+		s.startStep("validate")
+
 		// visibility public is deprecated, so fail if creating new public service
-		s.failIfCreatingNewPublicService(ctx, params, templateData, templateData.Name, templateData.Namespace)
+		err = s.failIfCreatingNewPublicService(ctx, params, templateData, templateData.Name, templateData.Namespace)
+		if err != nil {
+			return
+		}
 
 		// fix resources before server-side dry-run to avoid failure
 		s.cleanupJobIfRequired(ctx, params, templateData, templateData.Name, templateData.Namespace)
-		s.patchServiceIfRequired(ctx, params, templateData, templateData.Name, templateData.Namespace)
-		s.patchDeploymentIfRequired(ctx, params, templateData.Name, templateData.Namespace)
+		err = s.patchServiceIfRequired(ctx, params, templateData, templateData.Name, templateData.Namespace)
+		if err != nil {
+			return
+		}
+		err = s.patchDeploymentIfRequired(ctx, params, templateData.Name, templateData.Namespace)
+		if err != nil {
+			return
+		}
+
+		log.Info().Msgf("Validating the manifests against the schemas of kubernetes %v...", params.KubernetesVersion)
+		err = s.builderService.ValidateManifests(renderedTemplate.Bytes(), params.KubernetesVersion)
+		if err != nil {
+			return fmt.Errorf("Failed validating manifests: %w", err)
+		}
 
-		// always perform a dryrun to ensure we're not ending up in a semi broken state where half of the templates is successfully applied and others not
-		// await https://github.com/kubernetes/kubernetes/issues/83562 to switch back to server-side dry-run and not fail for new namespaces
+		err = s.evaluatePolicies(ctx, credential, params, renderedTemplate.Bytes())
+		if err != nil {
+			return
+		}
+
+		// always perform a server-side dryrun to ensure we're not ending up in a semi broken state where half of the templates is successfully applied and others not;
+		// resources in a namespace that doesn't exist yet can't be dry run, so for those only their kind gets checked
 		log.Info().Msg("Performing a dryrun to test the validity of the manifests...")
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"apply", "-f", "/kubernetes-no-pdb.yaml", "-n", templateData.Namespace, "--dry-run=client"})
+		err = s.kubernetesClient.ValidateManifests(ctx, templateData.Namespace, renderedNoPDBTemplate.Bytes())
+		if err != nil {
+			return fmt.Errorf("Failed validating manifests: %w", err)
+		}
 
 		log.Info().Msg("Performing a diff to show what's changed...")
-		_ = foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"diff", "-f", "/kubernetes-no-pdb.yaml", "-n", templateData.Namespace})
+		diffs, diffErr = s.showDiff(ctx, renderedNoPDBTemplate.Bytes(), templateData.Namespace)
+	}
+
+	if isDiffAction(params) {
+		s.startStep("diff")
+		if diffErr != nil {
+			return fmt.Errorf("Failed performing a diff: %w", diffErr)
+		}
+		var candidates []pruneCandidate
+		candidates, err = s.pruneCandidates(ctx, templateData, inventory)
+		if err != nil {
+			return
+		}
+		removed := []unstructured.Unstructured{}
+		for _, c := range candidates {
+			removed = append(removed, c.item)
+		}
+
+		return s.reportDiff(params, summarizeDiff(diffs, removed))
+	}
+
+	if !params.DryRun {
+
+		// ensure that from now on any error runs the troubleshooting assistant
+		s.assistTroubleshootingOnError = true
+		s.paramsForTroubleshooting = params
+
+		if tmpl != nil && len(params.Hooks.PreDeploy) > 0 {
+			s.startStep("pre-deploy-hooks")
+			err = s.runPreDeployHooks(ctx, params, templateData, renderedTemplate.Bytes())
+			if err != nil {
+				return
+			}
+		}
+
+		if tmpl != nil {
+			s.startStep("apply")
+			err = s.deployGoogleEndpointsServiceIfRequired(ctx, params)
+			if err != nil {
+				return
+			}
+			err = s.removePoddisruptionBudgetIfRequired(ctx, params, templateData.NameWithTrack, templateData.Namespace)
+			if err != nil {
+				return
+			}
+			err = s.removeIngressIfRequired(ctx, params, templateData, templateData.Name, templateData.Namespace)
+			if err != nil {
+				return
+			}
+			s.removeExtensionCloudFlareExtensionStateAnnotation(ctx, params, templateData.Name, templateData.Namespace)
+
+			log.Info().Msg("Applying the manifests for real...")
+			err = s.kubernetesClient.ApplyManifests(ctx, templateData.Namespace, renderedTemplate.Bytes(), false)
+			if err != nil {
+				return fmt.Errorf("Failed applying manifests: %w", err)
+			}
+
+			s.startStep("rollout")
+			if params.Kind == api.KindDeployment || params.Kind == api.KindHeadlessDeployment || params.Kind == api.KindStatefulset {
+				err = s.waitForRollout(ctx, params, templateData)
+			}
+			if params.Kind == api.KindJob {
+				err = s.waitForJob(ctx, params, templateData)
+				s.reportRollout(ctx, templateData, err)
+			}
+		}
+
+		if err != nil {
+			s.assistTroubleshooting(ctx, templateData, releaseID, buildVersion, err)
+			if params.AutoRollback && tmpl != nil && params.Kind != api.KindJob {
+				return s.rollbackAfterFailedRollout(ctx, params, templateData, err)
+			}
+			return fmt.Errorf("Failed waiting for rollout: %w", err)
+		}
+
+		if tmpl != nil && len(params.Smoketests.Checks) > 0 {
+			s.startStep("smoketests")
+			err = s.runSmoketests(ctx, params, templateData)
+			if err != nil {
+				s.assistTroubleshooting(ctx, templateData, releaseID, buildVersion, err)
+				if params.Smoketests.RollbackOnFailure {
+					s.rollbackAfterFailedSmoketests(ctx, params, templateData)
+				}
+				return
+			}
+		}
+
+		if tmpl != nil && (params.Kind == api.KindDeployment || params.Kind == api.KindHeadlessDeployment) && (params.Action == api.ActionDeploySimple || params.Action == api.ActionDeployStable) && params.StrategyType != api.StrategyTypeAtomicUpdate {
+			// keep a copy of the configs and secrets for this revision, so rollback-simple, rollback-stable and autoRollback can restore them
+			s.snapshotConfigsAndSecrets(ctx, api.KindDeployment, templateData.NameWithTrack, templateData.Namespace)
+		}
+		if tmpl != nil && params.Kind == api.KindStatefulset && params.AutoRollback {
+			// only autoRollback rolls back statefulsets, so there's no need for snapshots without it
+			s.snapshotConfigsAndSecrets(ctx, api.KindStatefulset, templateData.Name, templateData.Namespace)
+		}
+
+		s.startStep("cleanup")
+		err = s.handleAtomicUpdate(ctx, params, templateData)
+		if err != nil {
+			return
+		}
+
+		// clean up old stuff
+		err = s.cleanupOldResources(ctx, params, templateData, inventory)
+		if err != nil {
+			return
+		}
+
+		if params.Action == api.ActionDeployProgressive {
+			s.startStep("canary-analysis")
+			err = s.runProgressiveCanary(ctx, params, templateData)
+			if err != nil {
+				s.assistTroubleshooting(ctx, templateData, releaseID, buildVersion, err)
+				return
+			}
+
+			log.Info().Msg("Canary passed the analysis at every step, promoting it to stable...")
+			return s.Run(ctx, credential, releaseName, paramsYAML, gitSource, gitOwner, gitName, appLabel, buildVersion, string(api.ActionDeployStable), releaseID, gitBranch, gitRevision, builderImageSHA, builderImageDate, triggeredBy)
+		}
+
+		s.assistTroubleshooting(ctx, templateData, releaseID, buildVersion, nil)
+	}
+
+	return nil
+}
+
+// Render writes the manifests a stage would apply to outputDir, one file per resource, without accessing gcp or the cluster
+func (s *service) Render(ctx context.Context, credential *api.GKECredentials, releaseName, paramsYAML, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseAction, releaseID, gitBranch, gitRevision, builderImageSHA, builderImageDate, triggeredBy, outputDir string) (err error) {
+
+	params, err := s.parametersClient.Init(ctx, paramsYAML, credential, false, false, gitSource, gitOwner, gitName, appLabel, buildVersion, releaseName, releaseAction, releaseID)
+	if err != nil {
+		return fmt.Errorf("Failed initializing parameters: %w", err)
+	}
+
+	tmpl, err := s.builderService.BuildTemplates(params, true)
+	if err != nil {
+		return fmt.Errorf("Failed building templates: %w", err)
+	}
+
+	err = os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		return fmt.Errorf("Failed creating output directory %v: %w", outputDir, err)
+	}
+
+	params.Configs.RenderedFileContent = s.builderService.RenderConfig(params)
+	if params.Kind == api.KindConfigToFile {
+		for filename, data := range params.Configs.RenderedFileContent {
+			err = ioutil.WriteFile(filepath.Join(outputDir, filepath.Base(filename)), []byte(data), 0644)
+			if err != nil {
+				return fmt.Errorf("Failed writing config file %v: %w", filename, err)
+			}
+		}
+
+		return nil
+	}
+
+	if tmpl == nil {
+		log.Info().Msgf("Action %v doesn't render any manifests", params.Action)
+		return nil
+	}
+
+	// without access to the cluster the number of replicas of an existing deployment is unknown, so render as if it's a new deployment
+	templateData := s.generatorService.GenerateTemplateData(params, params.Replicas, gitSource, gitOwner, gitName, gitBranch, gitRevision, releaseID, builderImageSHA, builderImageDate, triggeredBy)
+
+	renderedTemplate, err := s.builderService.RenderTemplate(tmpl, templateData, false)
+	if err != nil {
+		return fmt.Errorf("Failed rendering templates: %w", err)
+	}
+
+	// label the resources with the inventory like a release does, so the files show exactly what gets applied
+	if usesInventory(params) {
+		_, err = s.labelInventory(templateData, &renderedTemplate)
+		if err != nil {
+			return
+		}
+	}
+
+	log.Info().Msgf("Validating the manifests against the schemas of kubernetes %v...", params.KubernetesVersion)
+	err = s.builderService.ValidateManifests(renderedTemplate.Bytes(), params.KubernetesVersion)
+	if err != nil {
+		return fmt.Errorf("Failed validating manifests: %w", err)
+	}
+
+	err = s.evaluatePolicies(ctx, credential, params, renderedTemplate.Bytes())
+	if err != nil {
+		return
+	}
+
+	return s.writeManifests(renderedTemplate.Bytes(), outputDir)
+}
+
+// evaluatePolicies checks the rendered manifests against the policy rules of the platform team, failing on any violation of a rule with severity
+// error
+func (s *service) evaluatePolicies(ctx context.Context, credential *api.GKECredentials, params api.Params, manifests []byte) (err error) {
+	log.Info().Msg("Evaluating policy rules against the manifests...")
+	valid, violations, warnings, err := s.policyService.Evaluate(ctx, credential, params, manifests)
+	if err != nil {
+		return fmt.Errorf("Failed evaluating policy rules: %w", err)
+	}
+
+	for _, warning := range warnings {
+		log.Printf("Warning: %s", warning)
+	}
+
+	if !valid {
+		return fmt.Errorf("Manifests violate policy rules: %v", violations)
+	}
+
+	return nil
+}
+
+// writeManifests writes each resource to a file named after its kind and name, so the output is stable between runs and easy to review
+func (s *service) writeManifests(manifests []byte, outputDir string) (err error) {
+	objects, err := kubernetes.DecodeManifests(manifests)
+	if err != nil {
+		return fmt.Errorf("Failed splitting rendered manifests: %w", err)
+	}
+
+	// remove the files of an earlier render, so resources that aren't rendered anymore don't look like they still get applied
+	staleFiles, err := filepath.Glob(filepath.Join(outputDir, "*.yaml"))
+	if err != nil {
+		return fmt.Errorf("Failed listing manifests in %v: %w", outputDir, err)
+	}
+	for _, f := range staleFiles {
+		err = os.Remove(f)
+		if err != nil {
+			return fmt.Errorf("Failed removing stale manifest %v: %w", f, err)
+		}
+	}
+
+	filenames := map[string]bool{}
+	for _, obj := range objects {
+		filename := fmt.Sprintf("%v-%v.yaml", strings.ToLower(obj.GetKind()), obj.GetName())
+		if filenames[filename] {
+			return fmt.Errorf("Rendered manifests contain %v %v more than once", obj.GetKind(), obj.GetName())
+		}
+		filenames[filename] = true
+
+		data, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return fmt.Errorf("Failed marshalling %v %v: %w", obj.GetKind(), obj.GetName(), err)
+		}
+
+		log.Info().Msgf("Writing %v %v to %v...", obj.GetKind(), obj.GetName(), filename)
+		err = ioutil.WriteFile(filepath.Join(outputDir, filename), data, 0644)
+		if err != nil {
+			return fmt.Errorf("Failed writing %v: %w", filename, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *service) cleanupOldResources(ctx context.Context, params api.Params, templateData api.TemplateData, inventory *kubernetes.Inventory) (err error) {
+	switch params.Kind {
+	case api.KindDeployment:
+		switch params.Action {
+		case api.ActionDeployCanary, api.ActionDeployProgressive:
+			if err = s.pruneInventory(ctx, templateData, inventory); err != nil {
+				return
+			}
+			if err = s.deleteLegacyResources(ctx, params, templateData); err != nil {
+				return
+			}
+		case api.ActionDeployStable, api.ActionDeploySimple:
+			if err = s.pruneInventory(ctx, templateData, inventory); err != nil {
+				return
+			}
+			if err = s.deleteLegacyResources(ctx, params, templateData); err != nil {
+				return
+			}
+			// the colour is running the new version, so a scale down recorded by an earlier switch away from it no longer applies
+			if templateData.UseBlueGreen {
+				if err = s.cancelBlueGreenScaleDown(ctx, templateData, templateData.NameWithTrack); err != nil {
+					return
+				}
+			}
+			if err = s.removeEstafetteCloudflareAnnotations(ctx, templateData, templateData.Name, templateData.Namespace); err != nil {
+				return
+			}
+			if err = s.removeBackendConfigAnnotation(ctx, templateData, templateData.Name, templateData.Namespace); err != nil {
+				return
+			}
+			if err = s.removeNegAnnotation(ctx, templateData, templateData.Name, templateData.Namespace); err != nil {
+				return
+			}
+		case api.ActionRollbackCanary:
+			if err = s.deleteCanaryResources(ctx, templateData.Name, templateData.Namespace); err != nil {
+				return
+			}
+		case api.ActionRestartCanary:
+			if err = s.restartDeployment(ctx, fmt.Sprintf("%v-canary", templateData.Name), templateData.Namespace); err != nil {
+				return
+			}
+		case api.ActionRestartStable:
+			if err = s.restartDeployment(ctx, fmt.Sprintf("%v-stable", templateData.Name), templateData.Namespace); err != nil {
+				return
+			}
+		case api.ActionRestartSimple:
+			if err = s.restartDeployment(ctx, templateData.Name, templateData.Namespace); err != nil {
+				return
+			}
+		case api.ActionRollbackStable:
+			if err = s.rollbackDeployment(ctx, fmt.Sprintf("%v-stable", templateData.Name), templateData.Namespace); err != nil {
+				return
+			}
+		case api.ActionRollbackSimple:
+			if err = s.rollbackDeployment(ctx, templateData.Name, templateData.Namespace); err != nil {
+				return
+			}
+		case api.ActionSwitchBlueGreen:
+			if err = s.switchBlueGreen(ctx, params, templateData); err != nil {
+				return
+			}
+		}
+
+	case api.KindHeadlessDeployment:
+		switch params.Action {
+		case api.ActionDeployCanary, api.ActionDeployStable, api.ActionDeploySimple:
+			if err = s.pruneInventory(ctx, templateData, inventory); err != nil {
+				return
+			}
+			if err = s.deleteLegacyResources(ctx, params, templateData); err != nil {
+				return
+			}
+		case api.ActionRollbackCanary:
+			if err = s.deleteCanaryResources(ctx, templateData.Name, templateData.Namespace); err != nil {
+				return
+			}
+		case api.ActionRestartCanary:
+			if err = s.restartDeployment(ctx, fmt.Sprintf("%v-canary", templateData.Name), templateData.Namespace); err != nil {
+				return
+			}
+		case api.ActionRestartStable:
+			if err = s.restartDeployment(ctx, fmt.Sprintf("%v-stable", templateData.Name), templateData.Namespace); err != nil {
+				return
+			}
+		case api.ActionRestartSimple:
+			if err = s.restartDeployment(ctx, templateData.Name, templateData.Namespace); err != nil {
+				return
+			}
+		case api.ActionRollbackStable:
+			if err = s.rollbackDeployment(ctx, fmt.Sprintf("%v-stable", templateData.Name), templateData.Namespace); err != nil {
+				return
+			}
+		case api.ActionRollbackSimple:
+			if err = s.rollbackDeployment(ctx, templateData.Name, templateData.Namespace); err != nil {
+				return
+			}
+		}
+	case api.KindStatefulset:
+		if err = s.pruneInventory(ctx, templateData, inventory); err != nil {
+			return
+		}
+		if err = s.deleteLegacyResources(ctx, params, templateData); err != nil {
+			return
+		}
+		if err = s.removeEstafetteCloudflareAnnotations(ctx, templateData, templateData.Name, templateData.Namespace); err != nil {
+			return
+		}
+		if err = s.removeBackendConfigAnnotation(ctx, templateData, templateData.Name, templateData.Namespace); err != nil {
+			return
+		}
+	}
+
+	return nil
+}
+
+// usesInventory returns whether the release prunes resources it no longer renders; jobs, cronjobs and configs don't, since those are often deployed next to another kind for the same app
+func usesInventory(params api.Params) bool {
+	if params.Kind != api.KindDeployment && params.Kind != api.KindHeadlessDeployment && params.Kind != api.KindStatefulset {
+		return false
+	}
+
+	switch params.Action {
+	case api.ActionDeploySimple, api.ActionDeployCanary, api.ActionDeployStable, api.ActionDeployProgressive, api.ActionDiffSimple, api.ActionDiffCanary, api.ActionDiffStable:
+		return true
+	}
+
+	return false
+}
+
+func (s *service) labelInventory(templateData api.TemplateData, manifests ...*bytes.Buffer) (*kubernetes.Inventory, error) {
+	inventory, err := kubernetes.NewInventory(manifests[0].Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("Failed determining inventory: %w", err)
+	}
+
+	log.Info().Msgf("Labelling resources with inventory %v...", inventory.Hash)
+	for _, m := range manifests {
+		labeled, err := inventory.Label(m.Bytes(), templateData.AppLabelSelector, inventoryTrack(templateData))
+		if err != nil {
+			return nil, fmt.Errorf("Failed labelling resources with inventory: %w", err)
+		}
+		m.Reset()
+		m.Write(labeled)
+	}
+
+	return &inventory, nil
+}
+
+func inventoryTrack(templateData api.TemplateData) string {
+	if templateData.IncludeTrackLabel {
+		return templateData.TrackLabel
+	}
+
+	return "simple"
+}
+
+// deleteLegacyResources deletes the resources of previous releases by name; resources applied before they got an inventory label aren't pruned,
+// so without it a switch between simple and canary releases or a change of parameters would leave them running after upgrading the extension
+func (s *service) deleteLegacyResources(ctx context.Context, params api.Params, templateData api.TemplateData) (err error) {
+	if params.Kind == api.KindStatefulset {
+		if err = s.deleteConfigsForParamsChange(ctx, params, templateData.Name, templateData.Namespace); err != nil {
+			return
+		}
+		if err = s.deleteSecretsForParamsChange(ctx, params, templateData.Name, templateData.Namespace); err != nil {
+			return
+		}
+		if err = s.deleteServiceAccountSecretForParamsChange(ctx, params, templateData.GoogleCloudCredentialsAppName, templateData.Namespace); err != nil {
+			return
+		}
+		if err = s.deleteIngressForVisibilityChange(ctx, templateData, templateData.Name, templateData.Namespace); err != nil {
+			return
+		}
+		return s.deleteBackendConfigAndIAPOauthSecret(ctx, templateData, templateData.Name, templateData.Namespace)
+	}
+
+	switch params.Action {
+	case api.ActionDeployCanary, api.ActionDeployProgressive:
+		if err = s.deleteConfigsForParamsChange(ctx, params, templateData.NameWithTrack, templateData.Namespace); err != nil {
+			return
+		}
+		return s.deleteSecretsForParamsChange(ctx, params, templateData.NameWithTrack, templateData.Namespace)
+
+	case api.ActionDeployStable:
+		if err = s.deleteCanaryResources(ctx, templateData.Name, templateData.Namespace); err != nil {
+			return
+		}
+		if err = s.deleteResourcesForTypeSwitch(ctx, templateData.Name, templateData.Namespace); err != nil {
+			return
+		}
+		if err = s.deleteConfigsForParamsChange(ctx, params, templateData.NameWithTrack, templateData.Namespace); err != nil {
+			return
+		}
+		if err = s.deleteSecretsForParamsChange(ctx, params, templateData.NameWithTrack, templateData.Namespace); err != nil {
+			return
+		}
+
+	case api.ActionDeploySimple:
+		if err = s.deleteResourcesForTypeSwitch(ctx, fmt.Sprintf("%v-canary", templateData.Name), templateData.Namespace); err != nil {
+			return
+		}
+		if err = s.deleteResourcesForTypeSwitch(ctx, fmt.Sprintf("%v-stable", templateData.Name), templateData.Namespace); err != nil {
+			return
+		}
+		if err = s.deleteConfigsForParamsChange(ctx, params, templateData.Name, templateData.Namespace); err != nil {
+			return
+		}
+		if err = s.deleteSecretsForParamsChange(ctx, params, templateData.Name, templateData.Namespace); err != nil {
+			return
+		}
+
+	default:
+		return nil
+	}
+
+	if err = s.deleteServiceAccountSecretForParamsChange(ctx, params, templateData.GoogleCloudCredentialsAppName, templateData.Namespace); err != nil {
+		return
+	}
+	if params.Kind == api.KindDeployment {
+		if err = s.deleteIngressForVisibilityChange(ctx, templateData, templateData.Name, templateData.Namespace); err != nil {
+			return
+		}
+		if err = s.deleteBackendConfigAndIAPOauthSecret(ctx, templateData, templateData.Name, templateData.Namespace); err != nil {
+			return
+		}
+	}
+
+	return s.deleteHorizontalPodAutoscaler(ctx, params, templateData.NameWithTrack, templateData.Namespace)
+}
+
+func (s *service) deleteResourcesForTypeSwitch(ctx context.Context, name, namespace string) (err error) {
+	// clean up resources in case a switch from simple to canary releases or vice versa has been made
+	log.Info().Msgf("Deleting deployment, configmap, secret, hpa and pdb %v if they exist...", name)
+	if err = s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceDeployments, namespace, name); err != nil {
+		return
+	}
+	if err = s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceConfigMaps, namespace, fmt.Sprintf("%v-configs", name)); err != nil {
+		return
+	}
+	if err = s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceSecrets, namespace, fmt.Sprintf("%v-secrets", name)); err != nil {
+		return
+	}
+	if err = s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceHorizontalPodAutoscalers, namespace, name); err != nil {
+		return
+	}
+
+	return s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourcePodDisruptionBudgets, namespace, name)
+}
+
+func (s *service) deleteConfigsForParamsChange(ctx context.Context, params api.Params, name, namespace string) (err error) {
+	if len(params.Configs.Files) == 0 && len(params.Configs.InlineFiles) == 0 {
+		log.Info().Msg("Deleting application configs if it exists, because no configs are specified...")
+		return s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceConfigMaps, namespace, fmt.Sprintf("%v-configs", name))
+	}
+
+	return nil
+}
+
+func (s *service) deleteSecretsForParamsChange(ctx context.Context, params api.Params, name, namespace string) (err error) {
+	if !params.HasSecrets() {
+		log.Info().Msg("Deleting application secrets if it exists, because no secrets are specified...")
+		return s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceSecrets, namespace, fmt.Sprintf("%v-secrets", name))
+	}
+
+	return nil
+}
+
+func (s *service) deleteServiceAccountSecretForParamsChange(ctx context.Context, params api.Params, name, namespace string) (err error) {
+	if !params.UseGoogleCloudCredentials && params.LegacyGoogleCloudServiceAccountKeyFile == "" {
+		log.Info().Msg("Deleting service account secret if it exists, because no use of service account is specified...")
+		return s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceSecrets, namespace, fmt.Sprintf("%v-gcp-service-account", name))
+	}
+
+	return nil
+}
+
+func (s *service) deleteIngressForVisibilityChange(ctx context.Context, templateData api.TemplateData, name, namespace string) (err error) {
+	if !templateData.UseNginxIngress && !templateData.UseGCEIngress {
+		// public uses service of type loadbalancer and doesn't need ingress
+		log.Info().Msg("Deleting ingress if it exists, which is used for visibility private, iap or public-whitelist...")
+		return s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceIngresses, namespace, name)
+	}
+
+	return nil
+}
+
+func (s *service) deleteBackendConfigAndIAPOauthSecret(ctx context.Context, templateData api.TemplateData, name, namespace string) (err error) {
+	if !templateData.Service.UseBackendConfigAnnotationOnService {
+		log.Info().Msg("Deleting iap oauth secret if it exists, because visibility is not set to iap...")
+		if err = s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceSecrets, namespace, fmt.Sprintf("%v--iap-oauth-credentials", name)); err != nil {
+			return
+		}
+		log.Info().Msg("Deleting iap backend config if it exists, because visibility is not set to iap...")
+		return s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceBackendConfigs, namespace, name)
+	}
+
+	return nil
+}
+
+func (s *service) deleteHorizontalPodAutoscaler(ctx context.Context, params api.Params, name, namespace string) (err error) {
+	if (params.Autoscale.Enabled == nil || !*params.Autoscale.Enabled) && (params.Action == api.ActionDeploySimple || params.Action == api.ActionDeployStable) {
+		log.Info().Msgf("Deleting HorizontalPodAutoscaler %v, since autoscaling is disabled...", name)
+		return s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceHorizontalPodAutoscalers, namespace, name)
+	}
+
+	return nil
+}
+
+// pruneInventory deletes resources applied by previous releases of the app that aren't rendered by this release
+func (s *service) pruneInventory(ctx context.Context, templateData api.TemplateData, inventory *kubernetes.Inventory) (err error) {
+	if inventory == nil {
+		return nil
+	}
+
+	log.Info().Msgf("Pruning resources with label app=%v that aren't part of inventory %v...", templateData.AppLabelSelector, inventory.Hash)
+	candidates, err := s.pruneCandidates(ctx, templateData, inventory)
+	if err != nil {
+		return
+	}
+
+	for _, c := range candidates {
+		log.Info().Msgf("Pruning %v %v, it's no longer part of the release...", strings.ToLower(c.item.GetKind()), c.item.GetName())
+		err = s.kubernetesClient.DeleteResource(ctx, c.resource, templateData.Namespace, c.item.GetName())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type pruneCandidate struct {
+	resource schema.GroupVersionResource
+	item     unstructured.Unstructured
+}
+
+// pruneCandidates returns the resources applied by previous releases of the app that aren't rendered by this release
+func (s *service) pruneCandidates(ctx context.Context, templateData api.TemplateData, inventory *kubernetes.Inventory) (candidates []pruneCandidate, err error) {
+	if inventory == nil {
+		return nil, nil
+	}
+
+	track := inventoryTrack(templateData)
+	labelSelector := fmt.Sprintf("app=%v,%v,%v!=%v", templateData.AppLabelSelector, kubernetes.InventoryLabel, kubernetes.InventoryLabel, inventory.Hash)
+	if track == "canary" {
+		labelSelector += fmt.Sprintf(",%v=canary", kubernetes.InventoryTrackLabel)
+	}
+
+	for _, r := range []schema.GroupVersionResource{
+		kubernetes.ResourceIngresses,
+		kubernetes.ResourceServices,
+		kubernetes.ResourceDeployments,
+		kubernetes.ResourceStatefulSets,
+		kubernetes.ResourceHorizontalPodAutoscalers,
+		kubernetes.ResourceVerticalPodAutoscalers,
+		kubernetes.ResourcePodDisruptionBudgets,
+		kubernetes.ResourceConfigMaps,
+		kubernetes.ResourceSecrets,
+		kubernetes.ResourceServiceAccounts,
+		kubernetes.ResourceBackendConfigs,
+	} {
+		items, err := s.kubernetesClient.ListResources(ctx, []schema.GroupVersionResource{r}, templateData.Namespace, labelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("Failed listing %v to prune: %w", r.Resource, err)
+		}
+
+		for _, item := range items {
+			if inventory.Contains(item.GetKind(), item.GetName()) {
+				continue
+			}
+			// the service, ingresses and other resources without track are shared with the stable release, so a canary release only prunes its own
+			if track == "canary" && !strings.HasPrefix(item.GetName(), templateData.NameWithTrack) {
+				continue
+			}
+			// the resources of the other colour keep serving traffic until the switch
+			if templateData.UseBlueGreen && item.GetLabels()[blueGreenColorLabel] != "" && item.GetLabels()[blueGreenColorLabel] != templateData.BlueGreenColor {
+				continue
+			}
+
+			candidates = append(candidates, pruneCandidate{resource: r, item: item})
+		}
+	}
+
+	return candidates, nil
+}
+
+func (s *service) assistTroubleshooting(ctx context.Context, templateData api.TemplateData, releaseID, buildVersion string, err error) {
+	if s.assistTroubleshootingOnError {
+		log.Info().Msgf("Showing current ingresses, services, configmaps, secrets, deployments, jobs, cronjobs, poddisruptionbudgets, horizontalpodautoscalers, pods, endpoints for app=%v...", s.paramsForTroubleshooting.App)
+		items, listErr := s.kubernetesClient.ListResources(ctx, []schema.GroupVersionResource{
+			kubernetes.ResourceIngresses,
+			kubernetes.ResourceServices,
+			kubernetes.ResourceConfigMaps,
+			kubernetes.ResourceSecrets,
+			kubernetes.ResourceDeployments,
+			kubernetes.ResourceJobs,
+			kubernetes.ResourceCronJobs,
+			kubernetes.ResourceStatefulSets,
+			kubernetes.ResourcePodDisruptionBudgets,
+			kubernetes.ResourceHorizontalPodAutoscalers,
+			kubernetes.ResourcePods,
+			kubernetes.ResourceEndpoints,
+		}, s.paramsForTroubleshooting.Namespace, fmt.Sprintf("app=%v", s.paramsForTroubleshooting.App))
+		if listErr != nil {
+			log.Warn().Err(listErr).Msg("Failed retrieving resources")
+		}
+		troubleshooting := &api.ReportTroubleshooting{}
+		for _, item := range items {
+			resource := fmt.Sprintf("%v/%v (created %v)", strings.ToLower(item.GetKind()), item.GetName(), item.GetCreationTimestamp().UTC().Format(time.RFC3339))
+			log.Info().Msg(resource)
+			troubleshooting.Resources = append(troubleshooting.Resources, resource)
+		}
+
+		if err != nil {
+			s.diagnoseRollout(ctx, templateData, troubleshooting)
+
+			log.Info().Msg("Rollout failed, trying to show logs...")
+			if releaseID != "" {
+				troubleshooting.Logs = s.showLogs(ctx, templateData.Namespace, fmt.Sprintf("app=%v,estafette.io/release-id=%v", templateData.AppLabelSelector, api.SanitizeLabel(releaseID)), "", 0)
+			} else if buildVersion != "" {
+				troubleshooting.Logs = s.showLogs(ctx, templateData.Namespace, fmt.Sprintf("app=%v,version=%v", templateData.AppLabelSelector, api.SanitizeLabel(buildVersion)), "", 0)
+			}
+		} else if s.paramsForTroubleshooting.Action == api.ActionDeployCanary || s.paramsForTroubleshooting.Action == api.ActionDeployProgressive {
+			log.Info().Msg("Showing logs for canary deployment...")
+			troubleshooting.Logs = s.showLogs(ctx, s.paramsForTroubleshooting.Namespace, fmt.Sprintf("app=%v,track=canary", s.paramsForTroubleshooting.App), s.paramsForTroubleshooting.App, 50)
+		}
+
+		if s.report != nil {
+			s.report.Troubleshooting = troubleshooting
+		}
+	}
+}
+
+// diagnoseRollout inspects the pods of the rollout to print a short summary of why it failed, with their events and the logs of crashed containers
+func (s *service) diagnoseRollout(ctx context.Context, templateData api.TemplateData, troubleshooting *api.ReportTroubleshooting) {
+	var diagnosis diagnostics.Diagnosis
+	var err error
+	switch s.paramsForTroubleshooting.Kind {
+	case api.KindDeployment, api.KindHeadlessDeployment:
+		diagnosis, err = s.diagnosticsService.DiagnoseDeployment(ctx, templateData.Namespace, templateData.NameWithTrack)
+	case api.KindStatefulset:
+		diagnosis, err = s.diagnosticsService.DiagnosePods(ctx, templateData.Namespace, fmt.Sprintf("app=%v", templateData.AppLabelSelector))
+	case api.KindJob:
+		diagnosis, err = s.diagnosticsService.DiagnosePods(ctx, templateData.Namespace, fmt.Sprintf("job-name=%v", templateData.JobName))
+	default:
+		return
+	}
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed diagnosing the rollout")
+		return
+	}
+
+	summary := diagnosis.Summary()
+	log.Warn().Msg(summary)
+	if len(diagnosis.Events) > 0 {
+		log.Info().Msgf("Events:\n%v", strings.Join(diagnosis.Events, "\n"))
+	}
+	if diagnosis.PreviousLogs != "" {
+		log.Info().Msgf("Logs of crashed containers:\n%v", diagnosis.PreviousLogs)
+	}
+
+	troubleshooting.RootCause = string(diagnosis.Reason)
+	troubleshooting.Summary = summary
+	troubleshooting.Events = diagnosis.Events
+	troubleshooting.PreviousLogs = diagnosis.PreviousLogs
+}
+
+func (s *service) showLogs(ctx context.Context, namespace, labelSelector, container string, tailLines int64) string {
+	logs, err := s.kubernetesClient.GetPodLogs(ctx, namespace, labelSelector, container, tailLines)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed retrieving logs for pods with labels %v", labelSelector)
+		return ""
+	}
+	log.Info().Msg(logs)
+
+	return logs
+}
+
+// showDiff logs what applying the manifests would change; a failing diff is returned for the diff actions to fail on, but isn't essential for a
+// release, for example it fails for a namespace that doesn't exist yet
+func (s *service) showDiff(ctx context.Context, manifests []byte, namespace string) (diffs []kubernetes.ResourceDiff, err error) {
+	diffs, err = s.kubernetesClient.DiffManifests(ctx, namespace, manifests)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed performing a diff")
+	}
+
+	for _, d := range diffs {
+		if d.IsNew() {
+			log.Info().Msgf("%v/%v will be created", strings.ToLower(d.Kind), d.Name)
+		}
+		if unified := d.Unified(); unified != "" {
+			log.Info().Msg(unified)
+		}
+	}
+
+	return diffs, err
+}
+
+func (s *service) deleteCanaryResources(ctx context.Context, name, namespace string) (err error) {
+	log.Info().Msgf("Delete canary deployment, ingress, hpa and svc...")
+	for _, r := range []schema.GroupVersionResource{kubernetes.ResourceDeployments, kubernetes.ResourceServices, kubernetes.ResourceIngresses, kubernetes.ResourceHorizontalPodAutoscalers} {
+		for _, n := range []string{fmt.Sprintf("%v-canary", name), fmt.Sprintf("%v-canary-internal", name), fmt.Sprintf("%v-canary-apigee", name)} {
+			err = s.kubernetesClient.DeleteResource(ctx, r, namespace, n)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *service) runProgressiveCanary(ctx context.Context, params api.Params, templateData api.TemplateData) (err error) {
+	stepInterval, err := time.ParseDuration(params.Canary.StepInterval)
+	if err != nil {
+		return fmt.Errorf("Failed parsing canary step interval %v: %w", params.Canary.StepInterval, err)
+	}
+
+	for i, weight := range params.Canary.Steps {
+		log.Info().Msgf("Step %v/%v: sending %v%% of traffic to the canary...", i+1, len(params.Canary.Steps), weight)
+		err = s.setCanaryWeight(ctx, templateData, weight)
+		if err != nil {
+			return
+		}
+
+		log.Info().Msgf("Waiting %v before analyzing the canary...", stepInterval)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(stepInterval):
+		}
+
+		result, analysisErr := s.analysisService.Analyze(ctx, params.Canary.Analysis)
+		if analysisErr == nil && result.Passed {
+			continue
+		}
+
+		log.Warn().Msgf("Canary failed the analysis at weight %v, rolling back the canary...", weight)
+		err = s.deleteCanaryResources(ctx, templateData.Name, templateData.Namespace)
+		if err != nil {
+			return fmt.Errorf("Failed rolling back canary after failed analysis: %w", err)
+		}
+		if analysisErr != nil {
+			return fmt.Errorf("Failed analyzing canary at weight %v, the canary has been rolled back: %w", weight, analysisErr)
+		}
+
+		return fmt.Errorf("Canary failed the analysis at weight %v, the canary has been rolled back", weight)
+	}
+
+	return nil
+}
+
+func (s *service) setCanaryWeight(ctx context.Context, templateData api.TemplateData, weight int) (err error) {
+	ingresses, err := s.kubernetesClient.ListResources(ctx, []schema.GroupVersionResource{kubernetes.ResourceIngresses}, templateData.Namespace, fmt.Sprintf("app=%v", templateData.AppLabelSelector))
+	if err != nil {
+		return fmt.Errorf("Failed retrieving ingresses to set canary weight: %w", err)
+	}
+
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{"nginx.ingress.kubernetes.io/canary-weight":"%v"}}}`, weight))
+	for _, ingress := range ingresses {
+		annotations := ingress.GetAnnotations()
+		if annotations["nginx.ingress.kubernetes.io/canary"] != "true" || annotations["nginx.ingress.kubernetes.io/canary-weight"] == fmt.Sprint(weight) {
+			continue
+		}
+
+		err = s.kubernetesClient.PatchResource(ctx, kubernetes.ResourceIngresses, templateData.Namespace, ingress.GetName(), types.MergePatchType, patch)
+		if err != nil {
+			return fmt.Errorf("Failed setting canary weight on ingress %v: %w", ingress.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+func (s *service) restartDeployment(ctx context.Context, name, namespace string) (err error) {
+	log.Info().Msgf("Restarting deployment rollout...")
+	err = s.kubernetesClient.RestartDeployment(ctx, namespace, name)
+	if err != nil {
+		return
+	}
+
+	return s.kubernetesClient.WaitForDeploymentRollout(ctx, namespace, name)
+}
+
+func (s *service) rollbackDeployment(ctx context.Context, name, namespace string) (err error) {
+	deployment, err := s.kubernetesClient.GetDeployment(ctx, namespace, name)
+	if err != nil {
+		return fmt.Errorf("Failed retrieving deployment %v to roll back: %w", name, err)
+	}
+
+	replicaSets, err := s.kubernetesClient.ListDeploymentReplicaSets(ctx, namespace, name)
+	if err != nil {
+		return fmt.Errorf("Failed retrieving revisions of deployment %v: %w", name, err)
+	}
+
+	// the previous revision is the most recent one before the current revision
+	currentRevision := kubernetes.Revision(deployment)
+	var previousReplicaSet *appsv1.ReplicaSet
+	for i := range replicaSets {
+		revision := kubernetes.Revision(&replicaSets[i])
+		if revision < currentRevision && (previousReplicaSet == nil || revision > kubernetes.Revision(previousReplicaSet)) {
+			previousReplicaSet = &replicaSets[i]
+		}
+	}
+	if previousReplicaSet == nil {
+		return fmt.Errorf("Deployment %v has no revision before revision %v to roll back to", name, currentRevision)
+	}
+	previousRevision := kubernetes.Revision(previousReplicaSet)
+
+	log.Info().Msgf("Rolling back deployment %v from revision %v to revision %v...", name, currentRevision, previousRevision)
+
+	// restore configs and secrets first, so the pods of the previous revision start with the configs and secrets they were released with
+	err = s.restoreConfigsAndSecrets(ctx, name, namespace, previousRevision)
+	if err != nil {
+		return
+	}
+
+	err = s.kubernetesClient.RollbackDeployment(ctx, namespace, name, *previousReplicaSet)
+	if err != nil {
+		return fmt.Errorf("Failed rolling back deployment %v to revision %v: %w", name, previousRevision, err)
+	}
+
+	log.Info().Msg("Waiting for the rollback to finish...")
+	err = s.kubernetesClient.WaitForDeploymentRollout(ctx, namespace, name)
+	if err != nil {
+		return fmt.Errorf("Failed waiting for rollback of deployment %v: %w", name, err)
+	}
+
+	// the rollback creates a new revision, which needs its own snapshot for a subsequent rollback
+	s.snapshotConfigsAndSecrets(ctx, api.KindDeployment, name, namespace)
+
+	return nil
+}
+
+func (s *service) snapshotConfigsAndSecrets(ctx context.Context, kind api.Kind, name, namespace string) {
+	revision, err := s.currentRevision(ctx, kind, name, namespace)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed retrieving revision of %v %v, not taking a snapshot of its configs and secrets", kind, name)
+		return
+	}
+
+	configMap, err := s.kubernetesClient.GetConfigMap(ctx, namespace, fmt.Sprintf("%v-configs", name))
+	if err == nil {
+		log.Info().Msgf("Storing snapshot of configmap %v for revision %v...", configMap.Name, revision)
+		err = s.kubernetesClient.CreateOrUpdateConfigMap(ctx, &corev1.ConfigMap{
+			ObjectMeta: snapshotObjectMeta(configMap.ObjectMeta, revision),
+			Data:       configMap.Data,
+			BinaryData: configMap.BinaryData,
+		})
+	}
+	if err != nil && !errors.Is(err, kubernetes.ErrResourceNotFound) {
+		log.Warn().Err(err).Msgf("Failed storing snapshot of configmap %v-configs for revision %v", name, revision)
+	}
+
+	secret, err := s.kubernetesClient.GetSecret(ctx, namespace, fmt.Sprintf("%v-secrets", name))
+	if err == nil {
+		log.Info().Msgf("Storing snapshot of secret %v for revision %v...", secret.Name, revision)
+		err = s.kubernetesClient.CreateOrUpdateSecret(ctx, &corev1.Secret{
+			ObjectMeta: snapshotObjectMeta(secret.ObjectMeta, revision),
+			Type:       secret.Type,
+			Data:       secret.Data,
+		})
+	}
+	if err != nil && !errors.Is(err, kubernetes.ErrResourceNotFound) {
+		log.Warn().Err(err).Msgf("Failed storing snapshot of secret %v-secrets for revision %v", name, revision)
+	}
+
+	s.pruneSnapshots(ctx, kind, name, namespace)
+}
+
+func (s *service) restoreConfigsAndSecrets(ctx context.Context, name, namespace string, revision int64) (err error) {
+	configMapName := fmt.Sprintf("%v-configs", name)
+	configMapSnapshot, err := s.kubernetesClient.GetConfigMap(ctx, namespace, snapshotName(configMapName, revision))
+	if errors.Is(err, kubernetes.ErrResourceNotFound) {
+		log.Info().Msgf("There's no snapshot of configmap %v for revision %v, leaving it as is", configMapName, revision)
+	} else if err != nil {
+		return fmt.Errorf("Failed retrieving snapshot of configmap %v for revision %v: %w", configMapName, revision, err)
+	} else {
+		log.Info().Msgf("Restoring configmap %v from revision %v...", configMapName, revision)
+		err = s.kubernetesClient.CreateOrUpdateConfigMap(ctx, &corev1.ConfigMap{
+			ObjectMeta: restoredObjectMeta(configMapSnapshot.ObjectMeta, configMapName),
+			Data:       configMapSnapshot.Data,
+			BinaryData: configMapSnapshot.BinaryData,
+		})
+		if err != nil {
+			return fmt.Errorf("Failed restoring configmap %v from revision %v: %w", configMapName, revision, err)
+		}
+	}
+
+	secretName := fmt.Sprintf("%v-secrets", name)
+	secretSnapshot, err := s.kubernetesClient.GetSecret(ctx, namespace, snapshotName(secretName, revision))
+	if errors.Is(err, kubernetes.ErrResourceNotFound) {
+		log.Info().Msgf("There's no snapshot of secret %v for revision %v, leaving it as is", secretName, revision)
+	} else if err != nil {
+		return fmt.Errorf("Failed retrieving snapshot of secret %v for revision %v: %w", secretName, revision, err)
+	} else {
+		log.Info().Msgf("Restoring secret %v from revision %v...", secretName, revision)
+		err = s.kubernetesClient.CreateOrUpdateSecret(ctx, &corev1.Secret{
+			ObjectMeta: restoredObjectMeta(secretSnapshot.ObjectMeta, secretName),
+			Type:       secretSnapshot.Type,
+			Data:       secretSnapshot.Data,
+		})
+		if err != nil {
+			return fmt.Errorf("Failed restoring secret %v from revision %v: %w", secretName, revision, err)
+		}
 	}
 
-	if !params.DryRun && params.Action != api.ActionDiffSimple && params.Action != api.ActionDiffCanary && params.Action != api.ActionDiffStable {
-
-		// ensure that from now on any error runs the troubleshooting assistant
-		s.assistTroubleshootingOnError = true
-		s.paramsForTroubleshooting = params
-
-		if tmpl != nil {
-			s.deployGoogleEndpointsServiceIfRequired(ctx, params)
-			s.removePoddisruptionBudgetIfRequired(ctx, params, templateData.NameWithTrack, templateData.Namespace)
-			s.removeIngressIfRequired(ctx, params, templateData, templateData.Name, templateData.Namespace)
-			s.removeExtensionCloudFlareExtensionStateAnnotation(ctx, params, templateData.Name, templateData.Namespace)
+	return nil
+}
 
-			log.Info().Msg("Applying the manifests for real...")
-			foundation.RunCommandWithArgs(ctx, "kubectl", []string{"apply", "-f", "/kubernetes.yaml", "-n", templateData.Namespace})
+// pruneSnapshots removes snapshots for revisions the deployment no longer keeps a replicaset for, or the statefulset a controller revision, since those can't be rolled back to anyway
+func (s *service) pruneSnapshots(ctx context.Context, kind api.Kind, name, namespace string) {
+	revisions, err := s.keptRevisions(ctx, kind, name, namespace)
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed retrieving revisions of %v %v, not pruning snapshots", kind, name)
+		return
+	}
 
-			if params.Kind == api.KindDeployment || params.Kind == api.KindHeadlessDeployment {
-				log.Info().Msg("Waiting for the deployment to finish...")
-				err = foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"rollout", "status", "deployment", templateData.NameWithTrack, "-n", templateData.Namespace})
-			}
-			if params.Kind == api.KindStatefulset {
-				log.Info().Msg("Waiting for the statefulset to finish...")
-				err = foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"rollout", "status", "statefulset", templateData.Name, "-n", templateData.Namespace})
-			}
+	snapshots, err := s.kubernetesClient.ListResources(ctx, []schema.GroupVersionResource{kubernetes.ResourceConfigMaps, kubernetes.ResourceSecrets}, namespace, fmt.Sprintf("%v=%v", snapshotOfLabel, api.SanitizeLabel(name)))
+	if err != nil {
+		log.Warn().Err(err).Msgf("Failed retrieving snapshots of %v %v, not pruning them", kind, name)
+		return
+	}
+	for _, snapshot := range snapshots {
+		if revisions[snapshot.GetLabels()[snapshotRevisionLabel]] {
+			continue
 		}
 
+		resource := kubernetes.ResourceConfigMaps
+		if snapshot.GetKind() == "Secret" {
+			resource = kubernetes.ResourceSecrets
+		}
+		err = s.kubernetesClient.DeleteResource(ctx, resource, namespace, snapshot.GetName())
 		if err != nil {
-			s.assistTroubleshooting(ctx, templateData, releaseID, buildVersion, err)
+			log.Warn().Err(err).Msgf("Failed pruning snapshot %v", snapshot.GetName())
 		}
+	}
+}
 
-		s.handleAtomicUpdate(ctx, params, templateData)
+// currentRevision returns the revision a deployment or statefulset currently rolls out
+func (s *service) currentRevision(ctx context.Context, kind api.Kind, name, namespace string) (revision int64, err error) {
+	if kind == api.KindStatefulset {
+		revisions, err := s.kubernetesClient.ListStatefulSetRevisions(ctx, namespace, name)
+		if err != nil {
+			return 0, err
+		}
+		if len(revisions) == 0 {
+			return 0, fmt.Errorf("Statefulset %v has no revisions", name)
+		}
+		return revisions[len(revisions)-1].Revision, nil
+	}
 
-		// clean up old stuff
-		switch params.Kind {
-		case api.KindDeployment:
-			switch params.Action {
-			case api.ActionDeployCanary:
-				s.deleteConfigsForParamsChange(ctx, params, templateData.NameWithTrack, templateData.Namespace)
-				s.deleteSecretsForParamsChange(ctx, params, templateData.NameWithTrack, templateData.Namespace)
-				break
-			case api.ActionDeployStable:
-				s.deleteCanaryResources(ctx, templateData.Name, templateData.Namespace)
-				s.deleteResourcesForTypeSwitch(ctx, templateData.Name, templateData.Namespace)
-				s.deleteConfigsForParamsChange(ctx, params, templateData.NameWithTrack, templateData.Namespace)
-				s.deleteSecretsForParamsChange(ctx, params, templateData.NameWithTrack, templateData.Namespace)
-				s.deleteServiceAccountSecretForParamsChange(ctx, params, templateData.GoogleCloudCredentialsAppName, templateData.Namespace)
-				s.deleteIngressForVisibilityChange(ctx, templateData, templateData.Name, templateData.Namespace)
-				s.removeEstafetteCloudflareAnnotations(ctx, templateData, templateData.Name, templateData.Namespace)
-				s.removeBackendConfigAnnotation(ctx, templateData, templateData.Name, templateData.Namespace)
-				s.removeNegAnnotation(ctx, templateData, templateData.Name, templateData.Namespace)
-				s.deleteBackendConfigAndIAPOauthSecret(ctx, templateData, templateData.Name, templateData.Namespace)
-				s.deleteHorizontalPodAutoscaler(ctx, params, templateData.NameWithTrack, templateData.Namespace)
-				break
-			case api.ActionRollbackCanary:
-				s.deleteCanaryResources(ctx, templateData.Name, templateData.Namespace)
-				break
-			case api.ActionRestartCanary:
-				s.restartDeployment(ctx, fmt.Sprintf("%v-canary", templateData.Name), templateData.Namespace)
-				break
-			case api.ActionRestartStable:
-				s.restartDeployment(ctx, fmt.Sprintf("%v-stable", templateData.Name), templateData.Namespace)
-				break
-			case api.ActionRestartSimple:
-				s.restartDeployment(ctx, templateData.Name, templateData.Namespace)
-				break
-			case api.ActionDeploySimple:
-				s.deleteResourcesForTypeSwitch(ctx, fmt.Sprintf("%v-canary", templateData.Name), templateData.Namespace)
-				s.deleteResourcesForTypeSwitch(ctx, fmt.Sprintf("%v-stable", templateData.Name), templateData.Namespace)
-				s.deleteConfigsForParamsChange(ctx, params, templateData.Name, templateData.Namespace)
-				s.deleteSecretsForParamsChange(ctx, params, templateData.Name, templateData.Namespace)
-				s.deleteServiceAccountSecretForParamsChange(ctx, params, templateData.GoogleCloudCredentialsAppName, templateData.Namespace)
-				s.deleteIngressForVisibilityChange(ctx, templateData, templateData.Name, templateData.Namespace)
-				s.removeEstafetteCloudflareAnnotations(ctx, templateData, templateData.Name, templateData.Namespace)
-				s.removeBackendConfigAnnotation(ctx, templateData, templateData.Name, templateData.Namespace)
-				s.removeNegAnnotation(ctx, templateData, templateData.Name, templateData.Namespace)
-				s.deleteBackendConfigAndIAPOauthSecret(ctx, templateData, templateData.Name, templateData.Namespace)
-				s.deleteHorizontalPodAutoscaler(ctx, params, templateData.Name, templateData.Namespace)
-				break
-			}
-			break
-
-		case api.KindHeadlessDeployment:
-			switch params.Action {
-			case api.ActionDeployCanary:
-				s.deleteConfigsForParamsChange(ctx, params, templateData.NameWithTrack, templateData.Namespace)
-				s.deleteSecretsForParamsChange(ctx, params, templateData.NameWithTrack, templateData.Namespace)
-				break
-			case api.ActionDeployStable:
-				s.deleteCanaryResources(ctx, templateData.Name, templateData.Namespace)
-				s.deleteResourcesForTypeSwitch(ctx, templateData.Name, templateData.Namespace)
-				s.deleteConfigsForParamsChange(ctx, params, templateData.NameWithTrack, templateData.Namespace)
-				s.deleteSecretsForParamsChange(ctx, params, templateData.NameWithTrack, templateData.Namespace)
-				s.deleteServiceAccountSecretForParamsChange(ctx, params, templateData.GoogleCloudCredentialsAppName, templateData.Namespace)
-				s.deleteHorizontalPodAutoscaler(ctx, params, templateData.NameWithTrack, templateData.Namespace)
-				break
-			case api.ActionRollbackCanary:
-				s.deleteCanaryResources(ctx, templateData.Name, templateData.Namespace)
-				break
-			case api.ActionRestartCanary:
-				s.restartDeployment(ctx, fmt.Sprintf("%v-canary", templateData.Name), templateData.Namespace)
-				break
-			case api.ActionRestartStable:
-				s.restartDeployment(ctx, fmt.Sprintf("%v-stable", templateData.Name), templateData.Namespace)
-				break
-			case api.ActionRestartSimple:
-				s.restartDeployment(ctx, templateData.Name, templateData.Namespace)
-				break
-			case api.ActionDeploySimple:
-				s.deleteResourcesForTypeSwitch(ctx, fmt.Sprintf("%v-canary", templateData.Name), templateData.Namespace)
-				s.deleteResourcesForTypeSwitch(ctx, fmt.Sprintf("%v-stable", templateData.Name), templateData.Namespace)
-				s.deleteConfigsForParamsChange(ctx, params, templateData.Name, templateData.Namespace)
-				s.deleteSecretsForParamsChange(ctx, params, templateData.Name, templateData.Namespace)
-				s.deleteServiceAccountSecretForParamsChange(ctx, params, templateData.GoogleCloudCredentialsAppName, templateData.Namespace)
-				s.deleteHorizontalPodAutoscaler(ctx, params, templateData.Name, templateData.Namespace)
-				break
-			}
-			break
-		case api.KindStatefulset:
-			s.deleteConfigsForParamsChange(ctx, params, templateData.Name, templateData.Namespace)
-			s.deleteSecretsForParamsChange(ctx, params, templateData.Name, templateData.Namespace)
-			s.deleteServiceAccountSecretForParamsChange(ctx, params, templateData.GoogleCloudCredentialsAppName, templateData.Namespace)
-			s.deleteIngressForVisibilityChange(ctx, templateData, templateData.Name, templateData.Namespace)
-			s.removeEstafetteCloudflareAnnotations(ctx, templateData, templateData.Name, templateData.Namespace)
-			s.removeBackendConfigAnnotation(ctx, templateData, templateData.Name, templateData.Namespace)
-			s.deleteBackendConfigAndIAPOauthSecret(ctx, templateData, templateData.Name, templateData.Namespace)
-			break
-		}
-
-		s.assistTroubleshooting(ctx, templateData, releaseID, buildVersion, err)
+	deployment, err := s.kubernetesClient.GetDeployment(ctx, namespace, name)
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	return kubernetes.Revision(deployment), nil
 }
 
-func (s *service) assistTroubleshooting(ctx context.Context, templateData api.TemplateData, releaseID, buildVersion string, err error) {
-	if s.assistTroubleshootingOnError {
-		log.Info().Msgf("Showing current ingresses, services, configmaps, secrets, deployments, jobs, cronjobs, poddisruptionbudgets, horizontalpodautoscalers, pods, endpoints for app=%v...", s.paramsForTroubleshooting.App)
-		_ = foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"get", "ing,svc,cm,secret,deploy,job,cronjob,sts,pdb,hpa,po,ep", "-l", fmt.Sprintf("app=%v", s.paramsForTroubleshooting.App), "-n", s.paramsForTroubleshooting.Namespace})
+// keptRevisions returns the revisions a deployment or statefulset can still be rolled back to
+func (s *service) keptRevisions(ctx context.Context, kind api.Kind, name, namespace string) (revisions map[string]bool, err error) {
+	revisions = map[string]bool{}
 
+	if kind == api.KindStatefulset {
+		controllerRevisions, err := s.kubernetesClient.ListStatefulSetRevisions(ctx, namespace, name)
 		if err != nil {
-			log.Info().Msg("Rollout failed, trying to show logs...")
-			if releaseID != "" {
-				_ = foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"logs", "-l", fmt.Sprintf("app=%v,estafette.io/release-id=%v", templateData.AppLabelSelector, api.SanitizeLabel(releaseID)), "-n", templateData.Namespace, "--all-containers"})
-			} else if buildVersion != "" {
-				_ = foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"logs", "-l", fmt.Sprintf("app=%v,version=%v", templateData.AppLabelSelector, api.SanitizeLabel(buildVersion)), "-n", templateData.Namespace, "--all-containers"})
-			}
-		} else if s.paramsForTroubleshooting.Action == api.ActionDeployCanary {
-			log.Info().Msg("Showing logs for canary deployment...")
-			_ = foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"logs", "-l", fmt.Sprintf("app=%v,track=canary", s.paramsForTroubleshooting.App), "-n", s.paramsForTroubleshooting.Namespace, "-c", s.paramsForTroubleshooting.App, "--tail", "50"})
+			return nil, err
 		}
-
-		foundation.HandleError(err)
+		for _, revision := range controllerRevisions {
+			revisions[fmt.Sprint(revision.Revision)] = true
+		}
+		return revisions, nil
 	}
-}
 
-func (s *service) deleteCanaryResources(ctx context.Context, name, namespace string) {
-	log.Info().Msgf("Delete canary deployment, ingress, hpa and svc...")
-	foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "deploy,svc,ingress,hpa",
-		fmt.Sprintf("%v-canary", name),
-		fmt.Sprintf("%v-canary-internal", name),
-		fmt.Sprintf("%v-canary-apigee", name),
-		"-n", namespace, "--ignore-not-found=true"})
-}
-
-func (s *service) restartDeployment(ctx context.Context, name, namespace string) {
-	log.Info().Msgf("Restarting deployment rollout...")
-	foundation.RunCommandWithArgs(ctx, "kubectl", []string{"rollout", "restart", "deployment", name, "-n", namespace})
-	_ = foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"rollout", "status", "deployment", name, "-n", namespace})
-}
+	replicaSets, err := s.kubernetesClient.ListDeploymentReplicaSets(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	for i := range replicaSets {
+		revisions[fmt.Sprint(kubernetes.Revision(&replicaSets[i]))] = true
+	}
 
-func (s *service) deleteResourcesForTypeSwitch(ctx context.Context, name, namespace string) {
-	// clean up resources in case a switch from simple to canary releases or vice versa has been made
-	log.Info().Msg("Deleting simple type deployment, configmap, secret, hpa and pdb...")
-	foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "deploy", name, "-n", namespace, "--ignore-not-found=true"})
-	foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "configmap", fmt.Sprintf("%v-configs", name), "-n", namespace, "--ignore-not-found=true"})
-	foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "secret", fmt.Sprintf("%v-secrets", name), "-n", namespace, "--ignore-not-found=true"})
-	foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "hpa", name, "-n", namespace, "--ignore-not-found=true"})
-	foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "pdb", name, "-n", namespace, "--ignore-not-found=true"})
+	return revisions, nil
 }
 
-func (s *service) deleteConfigsForParamsChange(ctx context.Context, params api.Params, name, namespace string) {
-	if len(params.Configs.Files) == 0 && len(params.Configs.InlineFiles) == 0 {
-		log.Info().Msg("Deleting application configs if it exists, because no configs are specified...")
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "configmap", fmt.Sprintf("%v-configs", name), "-n", namespace, "--ignore-not-found=true"})
-	}
+func snapshotName(name string, revision int64) string {
+	return fmt.Sprintf("%v-rev%v", name, revision)
 }
 
-func (s *service) deleteSecretsForParamsChange(ctx context.Context, params api.Params, name, namespace string) {
-	if !params.HasSecrets() {
-		log.Info().Msg("Deleting application secrets if it exists, because no secrets are specified...")
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "secret", fmt.Sprintf("%v-secrets", name), "-n", namespace, "--ignore-not-found=true"})
+func snapshotObjectMeta(original metav1.ObjectMeta, revision int64) metav1.ObjectMeta {
+	labels := map[string]string{}
+	if app, ok := original.Labels["app"]; ok {
+		// keep the app label so the delete action cleans up snapshots as well
+		labels["app"] = app
 	}
-}
+	labels[snapshotOfLabel] = api.SanitizeLabel(strings.TrimSuffix(strings.TrimSuffix(original.Name, "-configs"), "-secrets"))
+	labels[snapshotRevisionLabel] = fmt.Sprint(revision)
 
-func (s *service) deleteServiceAccountSecretForParamsChange(ctx context.Context, params api.Params, name, namespace string) {
-	if !params.UseGoogleCloudCredentials && params.LegacyGoogleCloudServiceAccountKeyFile == "" {
-		log.Info().Msg("Deleting service account secret if it exists, because no use of service account is specified...")
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "secret", fmt.Sprintf("%v-gcp-service-account", name), "-n", namespace, "--ignore-not-found=true"})
+	return metav1.ObjectMeta{
+		Name:      snapshotName(original.Name, revision),
+		Namespace: original.Namespace,
+		Labels:    labels,
 	}
 }
 
-func (s *service) deleteIngressForVisibilityChange(ctx context.Context, templateData api.TemplateData, name, namespace string) {
-	if !templateData.UseNginxIngress && !templateData.UseGCEIngress {
-		// public uses service of type loadbalancer and doesn't need ingress
-		log.Info().Msg("Deleting ingress if it exists, which is used for visibility private, iap or public-whitelist...")
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "ingress", name, "-n", namespace, "--ignore-not-found=true"})
+func restoredObjectMeta(snapshot metav1.ObjectMeta, name string) metav1.ObjectMeta {
+	labels := map[string]string{}
+	for k, v := range snapshot.Labels {
+		if k != snapshotOfLabel && k != snapshotRevisionLabel {
+			labels[k] = v
+		}
 	}
-}
+	labels["type"] = "application"
 
-func (s *service) deleteBackendConfigAndIAPOauthSecret(ctx context.Context, templateData api.TemplateData, name, namespace string) {
-	if !templateData.Service.UseBackendConfigAnnotationOnService {
-		log.Info().Msg("Deleting iap oauth secret if it exists, because visibility is not set to iap...")
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "secret", fmt.Sprintf("%v--iap-oauth-credentials", name), "-n", namespace, "--ignore-not-found=true"})
-		log.Info().Msg("Deleting iap backend config if it exists, because visibility is not set to iap...")
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "backendconfig", name, "-n", namespace, "--ignore-not-found=true"})
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: snapshot.Namespace,
+		Labels:    labels,
 	}
 }
 
-func (s *service) removePoddisruptionBudgetIfRequired(ctx context.Context, params api.Params, name, namespace string) {
+func (s *service) removePoddisruptionBudgetIfRequired(ctx context.Context, params api.Params, name, namespace string) (err error) {
 	if (params.Kind == api.KindDeployment || params.Kind == api.KindHeadlessDeployment) && (params.Action == api.ActionDeploySimple || params.Action == api.ActionDeployStable) {
 		// if there's a pdb that doesn't use maxUnavailable: 1 remove it so a new one can be created with correct settings
 		deletePoddisruptionBudget := false
-		maxUnavailable, err := foundation.GetCommandWithArgsOutput(ctx, "kubectl", []string{"get", "pdb", name, "-n", namespace, "-o=jsonpath={.spec.maxUnavailable}"})
+		pdb, err := s.kubernetesClient.GetPodDisruptionBudget(ctx, namespace, name)
 		if err == nil {
-			maxUnavailableInt, err := strconv.Atoi(maxUnavailable)
-			if err == nil {
-				if maxUnavailableInt != 1 {
-					log.Info().Msgf("MaxUnavailable from pdb %v is %v instead of 1", name, maxUnavailableInt)
-					deletePoddisruptionBudget = true
-				}
-			} else {
-				log.Info().Msgf("Failed reading maxUnavailable from pdb %v: %v", name, err)
+			if pdb.Spec.MaxUnavailable == nil {
+				log.Info().Msgf("Pdb %v has no maxUnavailable", name)
+				deletePoddisruptionBudget = true
+			} else if pdb.Spec.MaxUnavailable.String() != "1" {
+				log.Info().Msgf("MaxUnavailable from pdb %v is %v instead of 1", name, pdb.Spec.MaxUnavailable.String())
 				deletePoddisruptionBudget = true
 			}
 		} else {
@@ -382,149 +1419,486 @@ func (s *service) removePoddisruptionBudgetIfRequired(ctx context.Context, param
 		}
 
 		if deletePoddisruptionBudget {
-			foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "pdb", name, "-n", namespace, "--ignore-not-found=true"})
-		} else {
-			log.Info().Msgf("Poddisruptionbudet %v is fine, not removing it", name)
+			return s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourcePodDisruptionBudgets, namespace, name)
 		}
+
+		log.Info().Msgf("Poddisruptionbudet %v is fine, not removing it", name)
 	}
+
+	return nil
 }
-func (s *service) removeIngressIfRequired(ctx context.Context, params api.Params, templateData api.TemplateData, name, namespace string) {
-	if params.Kind == api.KindDeployment && (params.Action == api.ActionDeploySimple || params.Action == api.ActionDeployCanary || params.Action == api.ActionDeployStable) {
+
+func (s *service) removeIngressIfRequired(ctx context.Context, params api.Params, templateData api.TemplateData, name, namespace string) (err error) {
+	if params.Kind == api.KindDeployment && (params.Action == api.ActionDeploySimple || params.Action == api.ActionDeployCanary || params.Action == api.ActionDeployStable || params.Action == api.ActionDeployProgressive) {
 		if templateData.UseNginxIngress {
 			// check if ingress exists and has kubernetes.io/ingress.class: gce, then delete it because of https://github.com/kubernetes/ingress-gce/issues/481
-			ingressClass, err := foundation.GetCommandWithArgsOutput(ctx, "kubectl", []string{"get", "ing", name, "-n", namespace, "-o=go-template={{index .metadata.annotations \"kubernetes.io/ingress.class\"}}"})
+			ingress, err := s.kubernetesClient.GetIngress(ctx, namespace, name)
 			if err == nil {
+				ingressClass := ingress.Annotations["kubernetes.io/ingress.class"]
 				if ingressClass == "gce" {
 					// delete the ingress so all related load balancers, etc get deleted
 					log.Info().Msg("Deleting ingress so the gce ingress controller removes the related load balancer...")
-					foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "ingress", name, "-n", namespace, "--ignore-not-found=true"})
-					foundation.RunCommandWithArgs(ctx, "kubectl", []string{"wait", "--for=delete", "ingress/" + name, "-n", namespace, "--timeout=3s"})
-				} else {
-					log.Info().Msgf("Ingress %v already has kubernetes.io/ingress.class: %v annotation, no need to delete the ingress", name, ingressClass)
+					return s.deleteIngressAndWait(ctx, name, namespace)
 				}
+				log.Info().Msgf("Ingress %v already has kubernetes.io/ingress.class: %v annotation, no need to delete the ingress", name, ingressClass)
 			} else {
 				log.Info().Msgf("Ingress %v or kubernetes.io/ingress.class annotation doesn't exist, no need to delete the ingress: %v", name, err)
 			}
 		} else if templateData.UseGCEIngress {
 			// check if ingress exists and has kubernetes.io/ingress.class: gce, then delete it to ensure there's no nginx ingress annotations lingering around
-			ingressClass, err := foundation.GetCommandWithArgsOutput(ctx, "kubectl", []string{"get", "ing", name, "-n", namespace, "-o=go-template={{ .spec.ingressClassName }}"})
+			ingress, err := s.kubernetesClient.GetIngress(ctx, namespace, name)
 			if err == nil {
+				ingressClass := ""
+				if ingress.Spec.IngressClassName != nil {
+					ingressClass = *ingress.Spec.IngressClassName
+				}
 				if ingressClass == "nginx-office" {
 					// delete the ingress so all related nginx ingress config gets deleted
 					log.Info().Msg("Deleting ingress so the nginx ingress controller removes related config...")
-					foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "ingress", name, "-n", namespace, "--ignore-not-found=true"})
-					foundation.RunCommandWithArgs(ctx, "kubectl", []string{"wait", "--for=delete", "ingress/" + name, "-n", namespace, "--timeout=3s"})
-				} else {
-					log.Info().Msgf("Ingress %v already has ingressClassName: %v already set, no need to delete the ingress", name, ingressClass)
+					return s.deleteIngressAndWait(ctx, name, namespace)
 				}
+				log.Info().Msgf("Ingress %v already has ingressClassName: %v already set, no need to delete the ingress", name, ingressClass)
 			} else {
 				log.Info().Msgf("Ingress %v or ingressClassName doesn't exist, no need to delete the ingress: %v", name, err)
 			}
 		}
 	}
+
+	return nil
+}
+
+func (s *service) deleteIngressAndWait(ctx context.Context, name, namespace string) (err error) {
+	err = s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceIngresses, namespace, name)
+	if err != nil {
+		return
+	}
+
+	return s.kubernetesClient.WaitForDeletion(ctx, kubernetes.ResourceIngresses, namespace, name, 3*time.Second)
 }
 
-func (s *service) deployGoogleEndpointsServiceIfRequired(ctx context.Context, params api.Params) {
-	if params.Kind == api.KindDeployment && (params.Visibility == api.VisibilityESP || params.Visibility == api.VisibilityESPv2) && (params.Action == api.ActionDeploySimple || params.Action == api.ActionDeployCanary) {
-		err := s.gcpClient.DeployGoogleCloudEndpoints(ctx, params)
+func (s *service) deployGoogleEndpointsServiceIfRequired(ctx context.Context, params api.Params) (err error) {
+	if params.Kind == api.KindDeployment && (params.Visibility == api.VisibilityESP || params.Visibility == api.VisibilityESPv2) && (params.Action == api.ActionDeploySimple || params.Action == api.ActionDeployCanary || params.Action == api.ActionDeployProgressive) {
+		err = s.gcpClient.DeployGoogleCloudEndpoints(ctx, params)
 		if err != nil {
-			log.Fatal().Err(err).Msgf("Failed deploying endpoints service in project %v", params.EspEndpointsProjectID)
+			return fmt.Errorf("Failed deploying endpoints service in project %v: %w", params.EspEndpointsProjectID, err)
 		}
 	}
+
+	return nil
 }
 
-func (s *service) failIfCreatingNewPublicService(ctx context.Context, params api.Params, templateData api.TemplateData, name, namespace string) {
+func (s *service) failIfCreatingNewPublicService(ctx context.Context, params api.Params, templateData api.TemplateData, name, namespace string) (err error) {
 	if params.Kind == api.KindDeployment && params.Visibility == api.VisibilityPublic {
-		serviceType, err := foundation.GetCommandWithArgsOutput(ctx, "kubectl", []string{"get", "service", name, "-n", namespace, "-o=jsonpath={.spec.type}"})
+		service, err := s.kubernetesClient.GetService(ctx, namespace, name)
 		// fail if creating new public service or updating to public
 		if err != nil {
-			log.Fatal().Err(err).Msgf("Creating new public service is no longer supported, please use visibility esp or apigee.")
-		} else if serviceType != "LoadBalancer" {
-			log.Fatal().Msgf("Changing service visibility to public is no longer supported, please use visibility esp or apigee.")
+			return fmt.Errorf("Creating new public service is no longer supported, please use visibility esp or apigee: %w", err)
+		} else if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			return fmt.Errorf("Changing service visibility to public is no longer supported, please use visibility esp or apigee.")
 		}
 	}
+
+	return nil
 }
 
-func (s *service) patchServiceIfRequired(ctx context.Context, params api.Params, templateData api.TemplateData, name, namespace string) {
+func (s *service) patchServiceIfRequired(ctx context.Context, params api.Params, templateData api.TemplateData, name, namespace string) (err error) {
 	if params.Kind == api.KindDeployment && templateData.Service.ServiceType == "ClusterIP" {
-		output, err := foundation.GetCommandWithArgsOutput(ctx, "kubectl", []string{"get", "service", templateData.Service.Name, "-n", namespace, "-o=jsonpath={.spec.type} {.spec.ports[*].nodePort}"})
+		service, err := s.kubernetesClient.GetService(ctx, namespace, templateData.Service.Name)
 		if err != nil {
 			log.Info().Msgf("Failed retrieving service details: %v", err)
+			return nil
 		}
-		outputFields := strings.Fields(output)
-		serviceType := outputFields[0]
-		if err == nil && (serviceType == "NodePort" || serviceType == "LoadBalancer") {
-			log.Info().Msgf("Service is of type %v, patching it...", serviceType)
 
-			// brute force patch the service
-			nodePortRemovePatchStr := ""
-			for i := 0; i < len(outputFields)-1; i++ {
-				nodePortRemovePatchStr += fmt.Sprintf("{\"op\": \"remove\", \"path\": \"/spec/ports/%d/nodePort\"}, ", i)
-			}
-			patchStr := "[{\"op\": \"remove\", \"path\": \"/spec/loadBalancerSourceRanges\"},{\"op\": \"remove\", \"path\": \"/spec/externalTrafficPolicy\"}, " + nodePortRemovePatchStr + "{\"op\": \"replace\", \"path\": \"/spec/type\", \"value\": \"ClusterIP\"}]"
-			err = foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"patch", "service", templateData.Service.Name, "-n", namespace, "--type", "json", "--patch", patchStr})
-			if err != nil {
-				patchStr := "[{\"op\": \"remove\", \"path\": \"/spec/externalTrafficPolicy\"}, " + nodePortRemovePatchStr + "{\"op\": \"replace\", \"path\": \"/spec/type\", \"value\": \"ClusterIP\"}]"
-				err = foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"patch", "service", templateData.Service.Name, "-n", namespace, "--type", "json", "--patch", patchStr})
-			}
-			if err != nil {
-				log.Fatal().Err(err).Msg(fmt.Sprintf("Failed patching service to change from %v to ClusterIP", serviceType))
-			}
-		} else {
+		serviceType := service.Spec.Type
+		if serviceType != corev1.ServiceTypeNodePort && serviceType != corev1.ServiceTypeLoadBalancer {
 			log.Info().Msgf("Service is of type %v, no need to patch it", serviceType)
+			return nil
+		}
+
+		log.Info().Msgf("Service is of type %v, patching it...", serviceType)
+
+		// brute force patch the service
+		patchOperations := []string{}
+		if len(service.Spec.LoadBalancerSourceRanges) > 0 {
+			patchOperations = append(patchOperations, `{"op": "remove", "path": "/spec/loadBalancerSourceRanges"}`)
+		}
+		if service.Spec.ExternalTrafficPolicy != "" {
+			patchOperations = append(patchOperations, `{"op": "remove", "path": "/spec/externalTrafficPolicy"}`)
+		}
+		for i, port := range service.Spec.Ports {
+			if port.NodePort != 0 {
+				patchOperations = append(patchOperations, fmt.Sprintf(`{"op": "remove", "path": "/spec/ports/%d/nodePort"}`, i))
+			}
+		}
+		patchOperations = append(patchOperations, `{"op": "replace", "path": "/spec/type", "value": "ClusterIP"}`)
+
+		err = s.kubernetesClient.PatchResource(ctx, kubernetes.ResourceServices, namespace, templateData.Service.Name, types.JSONPatchType, []byte("["+strings.Join(patchOperations, ", ")+"]"))
+		if err != nil {
+			return fmt.Errorf("Failed patching service to change from %v to ClusterIP: %w", serviceType, err)
 		}
 	}
+
+	return nil
 }
 
 func (s *service) cleanupJobIfRequired(ctx context.Context, params api.Params, templateData api.TemplateData, name, namespace string) {
 	if params.Kind == api.KindJob {
-		err := foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"delete", "job", name, "-n", namespace, "--ignore-not-found=true"})
+		err := s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceJobs, namespace, name)
 		if err != nil {
 			log.Info().Msgf("Deleting job %v failed: %v", name, err)
 		}
+		// the new job can only be created once the previous one is gone
+		err = s.kubernetesClient.WaitForDeletion(ctx, kubernetes.ResourceJobs, namespace, name, 2*time.Minute)
+		if err != nil {
+			log.Info().Msgf("Waiting for job %v to be deleted failed: %v", name, err)
+		}
 	}
 	if params.Kind == api.KindCronJob {
-		err := foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"delete", "cronjob", name, "-n", namespace, "--ignore-not-found=true"})
+		err := s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceCronJobs, namespace, name)
 		if err != nil {
 			log.Info().Msgf("Deleting cronjob %v failed: %v", name, err)
 		}
 	}
 }
 
+// runPreDeployHooks runs each pre-deploy hook as a job with the application container, env, secrets and configs, and fails if any of them fails so no pods get replaced
+func (s *service) runPreDeployHooks(ctx context.Context, params api.Params, templateData api.TemplateData, manifests []byte) (err error) {
+
+	// the hooks need the namespace, service account, configs and secrets of this release to exist
+	supportingManifests, err := kubernetes.FilterManifests(manifests, "Namespace", "ServiceAccount", "ConfigMap", "Secret")
+	if err != nil {
+		return fmt.Errorf("Failed selecting manifests required by pre-deploy hooks: %w", err)
+	}
+
+	// the running pods mount the same configs and secrets, so keep the current ones to put them back if a hook fails
+	previous, err := s.getConfigsAndSecrets(ctx, templateData.Namespace, supportingManifests)
+	if err != nil {
+		return fmt.Errorf("Failed retrieving configs and secrets before running pre-deploy hooks: %w", err)
+	}
+
+	log.Info().Msg("Applying the namespace, service account, configs and secrets required by the pre-deploy hooks...")
+	err = s.kubernetesClient.ApplyManifests(ctx, templateData.Namespace, supportingManifests, false)
+	if err != nil {
+		s.restoreConfigsAndSecretsAfterFailedHook(ctx, templateData.Namespace, previous)
+		return fmt.Errorf("Failed applying manifests required by pre-deploy hooks: %w", err)
+	}
+
+	hookTmpl, err := s.builderService.GetHookTemplate()
+	if err != nil {
+		s.restoreConfigsAndSecretsAfterFailedHook(ctx, templateData.Namespace, previous)
+		return fmt.Errorf("Failed building pre-deploy hook template: %w", err)
+	}
+
+	for _, hook := range params.Hooks.PreDeploy {
+		err = s.runHook(ctx, hookTmpl, hook, getHookTemplateData(templateData, hook))
+		if err != nil {
+			s.restoreConfigsAndSecretsAfterFailedHook(ctx, templateData.Namespace, previous)
+			return fmt.Errorf("Pre-deploy hook %v failed, aborting the release: %w", hook.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// configsAndSecrets holds the configmaps and secrets of a release by name; a nil value means it doesn't exist
+type configsAndSecrets struct {
+	configMaps map[string]*corev1.ConfigMap
+	secrets    map[string]*corev1.Secret
+}
+
+// getConfigsAndSecrets retrieves the live state of the configmaps and secrets in manifests
+func (s *service) getConfigsAndSecrets(ctx context.Context, namespace string, manifests []byte) (current configsAndSecrets, err error) {
+	objects, err := kubernetes.DecodeManifests(manifests)
+	if err != nil {
+		return
+	}
+
+	current = configsAndSecrets{configMaps: map[string]*corev1.ConfigMap{}, secrets: map[string]*corev1.Secret{}}
+	for _, obj := range objects {
+		switch obj.GetKind() {
+		case "ConfigMap":
+			configMap, err := s.kubernetesClient.GetConfigMap(ctx, namespace, obj.GetName())
+			if err != nil && !errors.Is(err, kubernetes.ErrResourceNotFound) {
+				return current, err
+			}
+			current.configMaps[obj.GetName()] = configMap
+		case "Secret":
+			secret, err := s.kubernetesClient.GetSecret(ctx, namespace, obj.GetName())
+			if err != nil && !errors.Is(err, kubernetes.ErrResourceNotFound) {
+				return current, err
+			}
+			current.secrets[obj.GetName()] = secret
+		}
+	}
+
+	return current, nil
+}
+
+// restoreConfigsAndSecretsAfterFailedHook puts back the configmaps and secrets as they were before the pre-deploy hooks, and removes the ones that didn't exist;
+// the release fails because of the hook, so failing to restore is only logged
+func (s *service) restoreConfigsAndSecretsAfterFailedHook(ctx context.Context, namespace string, previous configsAndSecrets) {
+	for name, configMap := range previous.configMaps {
+		var err error
+		if configMap == nil {
+			log.Info().Msgf("Removing configmap %v, it didn't exist before the pre-deploy hooks...", name)
+			err = s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceConfigMaps, namespace, name)
+		} else {
+			log.Info().Msgf("Restoring configmap %v as it was before the pre-deploy hooks...", name)
+			err = s.kubernetesClient.CreateOrUpdateConfigMap(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: configMap.Labels, Annotations: configMap.Annotations},
+				Data:       configMap.Data,
+				BinaryData: configMap.BinaryData,
+			})
+		}
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed restoring configmap %v after failed pre-deploy hook", name)
+		}
+	}
+
+	for name, secret := range previous.secrets {
+		var err error
+		if secret == nil {
+			log.Info().Msgf("Removing secret %v, it didn't exist before the pre-deploy hooks...", name)
+			err = s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceSecrets, namespace, name)
+		} else {
+			log.Info().Msgf("Restoring secret %v as it was before the pre-deploy hooks...", name)
+			err = s.kubernetesClient.CreateOrUpdateSecret(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: secret.Labels, Annotations: secret.Annotations},
+				Type:       secret.Type,
+				Data:       secret.Data,
+			})
+		}
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed restoring secret %v after failed pre-deploy hook", name)
+		}
+	}
+}
+
+func (s *service) runHook(ctx context.Context, hookTmpl *template.Template, hook api.HookParams, hookTemplateData api.TemplateData) (err error) {
+	renderedTemplate, err := s.builderService.RenderTemplate(hookTmpl, hookTemplateData, true)
+	if err != nil {
+		return fmt.Errorf("Failed rendering job: %w", err)
+	}
+
+	// jobs are immutable, so remove the job of the previous release before running it again
+	err = s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceJobs, hookTemplateData.Namespace, hookTemplateData.JobName)
+	if err != nil {
+		return
+	}
+	err = s.kubernetesClient.WaitForDeletion(ctx, kubernetes.ResourceJobs, hookTemplateData.Namespace, hookTemplateData.JobName, 2*time.Minute)
+	if err != nil {
+		return fmt.Errorf("Failed waiting for previous job %v to be deleted: %w", hookTemplateData.JobName, err)
+	}
+
+	log.Info().Msgf("Running pre-deploy hook %v as job %v...", hook.Name, hookTemplateData.JobName)
+	err = s.kubernetesClient.ApplyManifests(ctx, hookTemplateData.Namespace, renderedTemplate.Bytes(), false)
+	if err != nil {
+		return fmt.Errorf("Failed applying job: %w", err)
+	}
+
+	timeout, _ := time.ParseDuration(hook.Timeout)
+
+	return s.waitForJobFollowingLogs(ctx, hookTemplateData.Namespace, hookTemplateData.JobName, hookTemplateData.Name, timeout)
+}
+
+// waitForRollout waits for the deployment or statefulset of the release to finish within rollingupdate.timeout, and fails, undoes or pauses a rollout that doesn't according to rollingupdate.onTimeout
+func (s *service) waitForRollout(ctx context.Context, params api.Params, templateData api.TemplateData) (err error) {
+	timeout, err := time.ParseDuration(params.RollingUpdate.Timeout)
+	if err != nil {
+		return fmt.Errorf("Failed parsing rollingupdate timeout %v: %w", params.RollingUpdate.Timeout, err)
+	}
+
+	rolloutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	kind, name := "deployment", templateData.NameWithTrack
+	if params.Kind == api.KindStatefulset {
+		kind, name = "statefulset", templateData.Name
+		log.Info().Msgf("Waiting up to %v for the statefulset to finish...", timeout)
+		err = s.kubernetesClient.WaitForStatefulSetRollout(rolloutCtx, templateData.Namespace, name)
+	} else {
+		log.Info().Msgf("Waiting up to %v for the deployment to finish...", timeout)
+		err = s.kubernetesClient.WaitForDeploymentRollout(rolloutCtx, templateData.Namespace, name)
+	}
+	s.reportRollout(ctx, templateData, err)
+	if err == nil || !errors.Is(err, kubernetes.ErrRolloutTimedOut) {
+		return
+	}
+
+	switch params.RollingUpdate.OnTimeout {
+	case "undo":
+		log.Warn().Msgf("Rollout of %v %v didn't finish in time, undoing it...", kind, name)
+		undoCtx, cancelUndo := context.WithTimeout(ctx, timeout)
+		defer cancelUndo()
+		var undoErr error
+		switch {
+		case templateData.TrackLabel == "canary":
+			undoErr = s.deleteCanaryResources(undoCtx, templateData.Name, templateData.Namespace)
+		case params.Kind == api.KindStatefulset:
+			undoErr = s.rollbackStatefulSet(undoCtx, name, templateData.Namespace)
+		default:
+			undoErr = s.rollbackDeployment(undoCtx, name, templateData.Namespace)
+		}
+		if undoErr != nil {
+			s.setRolloutOutcome(api.RolloutOutcomeTimedOut)
+			return fmt.Errorf("Rollout of %v %v didn't finish within %v and undoing it failed: %v: %w", kind, name, timeout, undoErr, err)
+		}
+		s.setRolloutOutcome(api.RolloutOutcomeUndone)
+		return fmt.Errorf("Rollout of %v %v didn't finish within %v and has been undone: %w", kind, name, timeout, err)
+
+	case "pause":
+		if params.Kind == api.KindStatefulset {
+			// validation doesn't allow it, statefulsets have no paused field
+			s.setRolloutOutcome(api.RolloutOutcomeTimedOut)
+			return fmt.Errorf("Rollout of %v %v didn't finish within %v and statefulsets can't be paused: %w", kind, name, timeout, err)
+		}
+		log.Warn().Msgf("Rollout of %v %v didn't finish in time, pausing it...", kind, name)
+		pauseErr := s.kubernetesClient.PatchResource(ctx, kubernetes.ResourceDeployments, templateData.Namespace, name, types.MergePatchType, []byte(`{"spec":{"paused":true}}`))
+		if pauseErr != nil {
+			s.setRolloutOutcome(api.RolloutOutcomeTimedOut)
+			return fmt.Errorf("Rollout of %v %v didn't finish within %v and pausing it failed: %v: %w", kind, name, timeout, pauseErr, err)
+		}
+		s.setRolloutOutcome(api.RolloutOutcomePaused)
+		return fmt.Errorf("Rollout of %v %v didn't finish within %v and has been paused, the next release resumes it: %w", kind, name, timeout, err)
+	}
+
+	s.setRolloutOutcome(api.RolloutOutcomeTimedOut)
+	return fmt.Errorf("Rollout of %v %v didn't finish within %v: %w", kind, name, timeout, err)
+}
+
+// waitForJob waits for the job of a release to complete, and removes it afterwards unless it failed or has to be kept
+func (s *service) waitForJob(ctx context.Context, params api.Params, templateData api.TemplateData) (err error) {
+	timeout, err := time.ParseDuration(params.JobTimeout)
+	if err != nil {
+		return fmt.Errorf("Failed parsing job timeout %v: %w", params.JobTimeout, err)
+	}
+
+	log.Info().Msgf("Waiting for job %v to complete...", templateData.JobName)
+	err = s.waitForJobFollowingLogs(ctx, templateData.Namespace, templateData.JobName, templateData.Name, timeout)
+	if err != nil {
+		return
+	}
+
+	if !params.KeepFinishedJob {
+		log.Info().Msgf("Removing completed job %v, set keepFinishedJob to keep it...", templateData.JobName)
+		deleteErr := s.kubernetesClient.DeleteResource(ctx, kubernetes.ResourceJobs, templateData.Namespace, templateData.JobName)
+		if deleteErr != nil {
+			log.Warn().Err(deleteErr).Msgf("Failed removing completed job %v", templateData.JobName)
+		}
+	}
+
+	return nil
+}
+
+// waitForJobFollowingLogs shows the logs of the pods of a job until it completes, fails or the timeout expires; the logs of the pods that are still
+// being written once the job is done get the drain timeout to end, so the tail isn't lost
+func (s *service) waitForJobFollowingLogs(ctx context.Context, namespace, jobName, container string, timeout time.Duration) (err error) {
+	logsCtx, cancelLogs := context.WithCancel(ctx)
+	defer cancelLogs()
+
+	jobDone := make(chan struct{})
+	logsDone := make(chan struct{})
+	go func() {
+		defer close(logsDone)
+		err := s.kubernetesClient.FollowPodLogs(logsCtx, namespace, fmt.Sprintf("job-name=%v", jobName), container, jobDone)
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed following logs of job %v", jobName)
+		}
+	}()
+
+	err = s.kubernetesClient.WaitForJobCompletion(ctx, namespace, jobName, timeout)
+	close(jobDone)
+
+	select {
+	case <-logsDone:
+	case <-time.After(s.logsDrainTimeout):
+		log.Warn().Msgf("Logs of job %v didn't end within %v after it was done, no longer following them", jobName, s.logsDrainTimeout)
+		cancelLogs()
+		<-logsDone
+	}
+
+	return err
+}
+
+// getHookTemplateData derives the data for rendering a hook job from the data of the workload, so it runs with the same container, env, secrets and configs
+func getHookTemplateData(templateData api.TemplateData, hook api.HookParams) api.TemplateData {
+	hookTemplateData := templateData
+	hookTemplateData.JobName = fmt.Sprintf("%v-%v", templateData.NameWithTrack, hook.Name)
+	hookTemplateData.RestartPolicy = "Never"
+	hookTemplateData.Completions = 1
+	hookTemplateData.Parallelism = 1
+	hookTemplateData.BackoffLimit = *hook.BackoffLimit
+	hookTemplateData.HasCustomSidecars = false
+	hookTemplateData.Container.Command = hook.Command
+	hookTemplateData.Container.Args = hook.Args
+
+	hookTemplateData.Labels = map[string]string{}
+	for k, v := range templateData.Labels {
+		hookTemplateData.Labels[k] = v
+	}
+	hookTemplateData.Labels["estafette.io/hook"] = hook.Name
+
+	// keep the hook pods out of the selectors of the services, pod disruption budgets and deployments of the app
+	hookTemplateData.PodLabels = map[string]string{}
+	for k, v := range templateData.PodLabels {
+		if k == "app" || k == "track" || k == "estafette.io/atomic-id" {
+			continue
+		}
+		hookTemplateData.PodLabels[k] = v
+	}
+	hookTemplateData.PodLabels["estafette.io/hook"] = hook.Name
+
+	return hookTemplateData
+}
+
 func (s *service) getExistingNumberOfReplicas(ctx context.Context, params api.Params) int {
 	if params.Kind == api.KindDeployment || params.Kind == api.KindHeadlessDeployment {
 		if params.StrategyType == api.StrategyTypeAtomicUpdate {
-			replicas, err := foundation.GetCommandWithArgsOutput(ctx, "kubectl", []string{"get", "deploy", "-l", fmt.Sprintf("app in (%v),estafette.io/atomic-id,estafette.io/atomic-id notin (%v)", api.SanitizeLabel(params.App), params.AtomicID), "-n", params.Namespace, "--sort-by=.metadata.creationTimestamp", "-o=jsonpath={.items[-1:].spec.replicas}"})
+			deployments, err := s.kubernetesClient.ListDeployments(ctx, params.Namespace, fmt.Sprintf("app in (%v),estafette.io/atomic-id,estafette.io/atomic-id notin (%v)", api.SanitizeLabel(params.App), params.AtomicID))
 			if err != nil {
 				log.Info().Err(err).Msg("Failed retrieving replicas for previous atomic deployments. Ignoring setting replicas since there's no switch for deployment type...")
 				return -1
 			}
-			replicasInt, err := strconv.Atoi(replicas)
-			if err != nil {
-				log.Info().Err(err).Msgf("Failed converting replicas value %v for previous atomic deployments. Ignoring setting replicas since there's no switch for deployment type...", replicas)
+			if len(deployments) == 0 {
+				log.Info().Msg("Found no previous atomic deployments. Ignoring setting replicas since there's no switch for deployment type...")
+				return -1
+			}
+
+			// use the most recently created deployment
+			sort.Slice(deployments, func(i, j int) bool {
+				return deployments[i].CreationTimestamp.Before(&deployments[j].CreationTimestamp)
+			})
+			latest := deployments[len(deployments)-1]
+			if latest.Spec.Replicas == nil {
+				log.Info().Msgf("Previous atomic deployment %v has no replicas set. Ignoring setting replicas since there's no switch for deployment type...", latest.Name)
 				return -1
 			}
+
+			replicasInt := int(*latest.Spec.Replicas)
 			log.Info().Msgf("Retrieved number of replicas for previous atomic deployments is %v; using it to set correct number of replicas switching deployment type...", replicasInt)
 			return replicasInt
 		}
 
 		deploymentName := ""
-		if params.Action == api.ActionDeploySimple || params.Action == api.ActionDiffSimple {
+		if params.StrategyType == api.StrategyTypeBlueGreen && params.BlueGreenLiveColor != "" {
+			deploymentName = params.App + "-" + params.BlueGreenLiveColor
+		} else if params.Action == api.ActionDeploySimple || params.Action == api.ActionDiffSimple {
 			deploymentName = params.App + "-stable"
 		} else if params.Action == api.ActionDeployStable || params.Action == api.ActionDiffStable {
 			deploymentName = params.App
 		}
 		if deploymentName != "" {
-			replicas, err := foundation.GetCommandWithArgsOutput(ctx, "kubectl", []string{"get", "deploy", deploymentName, "-n", params.Namespace, "-o=jsonpath={.spec.replicas}"})
+			deployment, err := s.kubernetesClient.GetDeployment(ctx, params.Namespace, deploymentName)
 			if err != nil {
 				log.Info().Msgf("Failed retrieving replicas for %v: %v ignoring setting replicas since there's no switch for deployment type...", deploymentName, err)
 				return -1
 			}
-			replicasInt, err := strconv.Atoi(replicas)
-			if err != nil {
-				log.Info().Msgf("Failed converting replicas value %v for %v: %v ignoring setting replicas since there's no switch for deployment type...", replicas, deploymentName, err)
+			if deployment.Spec.Replicas == nil {
+				log.Info().Msgf("Deployment %v has no replicas set, ignoring setting replicas since there's no switch for deployment type...", deploymentName)
 				return -1
 			}
+
+			replicasInt := int(*deployment.Spec.Replicas)
 			log.Info().Msgf("Retrieved number of replicas for %v is %v; using it to set correct number of replicas switching deployment type...", deploymentName, replicasInt)
 			return replicasInt
 		}
@@ -533,103 +1907,114 @@ func (s *service) getExistingNumberOfReplicas(ctx context.Context, params api.Pa
 	return -1
 }
 
-func (s *service) patchDeploymentIfRequired(ctx context.Context, params api.Params, name, namespace string) {
+func (s *service) patchDeploymentIfRequired(ctx context.Context, params api.Params, name, namespace string) (err error) {
 	if (params.Kind == api.KindDeployment || params.Kind == api.KindHeadlessDeployment) && params.Action == api.ActionDeploySimple {
-		selectorLabels, err := foundation.GetCommandWithArgsOutput(ctx, "kubectl", []string{"get", "deploy", name, "-n", namespace, "-o=jsonpath={.spec.selector.matchLabels}"})
+		deployment, err := s.kubernetesClient.GetDeployment(ctx, namespace, name)
 		if err != nil {
 			log.Info().Msgf("Failed retrieving deployment selector labels: %v", err)
+			return nil
 		}
-		if err == nil && selectorLabels != fmt.Sprintf("map[app:%v]", name) {
-			log.Info().Msgf("Deployment selector labels %v not correct, patching it...", selectorLabels)
 
-			// patch the deployment
-			err = foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"patch", "deploy", name, "-n", namespace, "--type", "json", "--patch", fmt.Sprintf("[{\"op\": \"replace\", \"path\": \"/spec/selector/matchLabels\", \"value\": {\"app\":\"%v\"}}]", name)})
-			if err != nil {
-				log.Fatal().Err(err).Msg(fmt.Sprintf("Failed patching deployment to change selector labels from %v to app=%v", selectorLabels, name))
-			}
-		} else {
+		selectorLabels := map[string]string{}
+		if deployment.Spec.Selector != nil {
+			selectorLabels = deployment.Spec.Selector.MatchLabels
+		}
+		if len(selectorLabels) == 1 && selectorLabels["app"] == name {
 			log.Info().Msgf("Deployment selector labels %v are correct, not patching", selectorLabels)
+			return nil
+		}
+
+		log.Info().Msgf("Deployment selector labels %v not correct, patching it...", selectorLabels)
+
+		// patch the deployment
+		err = s.kubernetesClient.PatchResource(ctx, kubernetes.ResourceDeployments, namespace, name, types.JSONPatchType, []byte(fmt.Sprintf("[{\"op\": \"replace\", \"path\": \"/spec/selector/matchLabels\", \"value\": {\"app\":\"%v\"}}]", name)))
+		if err != nil {
+			return fmt.Errorf("Failed patching deployment to change selector labels from %v to app=%v: %w", selectorLabels, name, err)
 		}
 	}
+
+	return nil
 }
 
-func (s *service) removeEstafetteCloudflareAnnotations(ctx context.Context, templateData api.TemplateData, name, namespace string) {
+func (s *service) removeEstafetteCloudflareAnnotations(ctx context.Context, templateData api.TemplateData, name, namespace string) (err error) {
 	if !templateData.Service.UseDNSAnnotationsOnService {
 		// ingress is used and has the estafette.io/cloudflare annotations, so they should be removed from the service
 		log.Info().Msg("Removing estafette.io/cloudflare annotations on the service if they exists, since they're now set on the ingress instead...")
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"annotate", "svc", templateData.Service.Name, "-n", namespace, "estafette.io/cloudflare-dns-"})
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"annotate", "svc", templateData.Service.Name, "-n", namespace, "estafette.io/cloudflare-proxy-"})
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"annotate", "svc", templateData.Service.Name, "-n", namespace, "estafette.io/cloudflare-hostnames-"})
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"annotate", "svc", templateData.Service.Name, "-n", namespace, "estafette.io/cloudflare-state-"})
+		return s.kubernetesClient.RemoveAnnotations(ctx, kubernetes.ResourceServices, namespace, templateData.Service.Name, "estafette.io/cloudflare-dns", "estafette.io/cloudflare-proxy", "estafette.io/cloudflare-hostnames", "estafette.io/cloudflare-state")
 	}
+
+	return nil
 }
 
 func (s *service) removeExtensionCloudFlareExtensionStateAnnotation(ctx context.Context, params api.Params, name string, namespace string) {
 	if *params.DNS.UseExternalDNS && !*params.DNS.UseCloudflareEstafetteExtension {
 
 		log.Info().Msg("Removing CloudFlare extension DNS state ingress annotation ...")
-		_ = foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"annotate", "ing", name, "-n", namespace, "estafette.io/cloudflare-state-"})
+		ingressNames := []string{name}
 		if params.Visibility == api.VisibilityApigee {
-			_ = foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"annotate", "ing", name + "-apigee", "-n", namespace, "estafette.io/cloudflare-state-"})
+			ingressNames = append(ingressNames, name+"-apigee")
 		}
 		if len(params.InternalHosts) > 0 {
-			_ = foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"annotate", "ing", name + "-internal", "-n", namespace, "estafette.io/cloudflare-state-"})
+			ingressNames = append(ingressNames, name+"-internal")
+		}
+		for _, n := range ingressNames {
+			err := s.kubernetesClient.RemoveAnnotations(ctx, kubernetes.ResourceIngresses, namespace, n, "estafette.io/cloudflare-state")
+			if err != nil {
+				log.Warn().Err(err).Msgf("Failed removing CloudFlare extension DNS state annotation from ingress %v", n)
+			}
 		}
 
 		log.Info().Msg("Removing CloudFlare extension DNS state service annotation ...")
-		_ = foundation.RunCommandWithArgsExtended(ctx, "kubectl", []string{"annotate", "svc", name, "-n", namespace, "estafette.io/cloudflare-state-"})
+		err := s.kubernetesClient.RemoveAnnotations(ctx, kubernetes.ResourceServices, namespace, name, "estafette.io/cloudflare-state")
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed removing CloudFlare extension DNS state annotation from service %v", name)
+		}
 	}
 }
 
-func (s *service) removeBackendConfigAnnotation(ctx context.Context, templateData api.TemplateData, name, namespace string) {
+func (s *service) removeBackendConfigAnnotation(ctx context.Context, templateData api.TemplateData, name, namespace string) (err error) {
 	if !templateData.Service.UseBackendConfigAnnotationOnService {
 		// iap is not used, so the beta.cloud.google.com/backend-config annotations should be removed from the service
 		log.Info().Msg("Removing beta.cloud.google.com/backend-config annotations on the service if they exists, since visibility is not set to iap...")
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"annotate", "svc", templateData.Service.Name, "-n", namespace, "beta.cloud.google.com/backend-config-"})
+		return s.kubernetesClient.RemoveAnnotations(ctx, kubernetes.ResourceServices, namespace, templateData.Service.Name, "beta.cloud.google.com/backend-config")
 	}
+
+	return nil
 }
 
-func (s *service) removeNegAnnotation(ctx context.Context, templateData api.TemplateData, name, namespace string) {
+func (s *service) removeNegAnnotation(ctx context.Context, templateData api.TemplateData, name, namespace string) (err error) {
 	if !templateData.Service.UseNegAnnotationOnService {
 		// cloud native load balancing is not used, so the beta.cloud.google.com/backend-config annotations should be removed from the service
 		log.Info().Msg("Removing cloud.google.com/neg annotations on the service if they exists, since visibility is not set to iap or containerNativeLoadBalancing is set to fals...")
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"annotate", "svc", templateData.Service.Name, "-n", namespace, "cloud.google.com/neg-"})
+		return s.kubernetesClient.RemoveAnnotations(ctx, kubernetes.ResourceServices, namespace, templateData.Service.Name, "cloud.google.com/neg")
 	}
-}
 
-func (s *service) deleteHorizontalPodAutoscaler(ctx context.Context, params api.Params, name, namespace string) {
-	if (params.Kind == api.KindDeployment || params.Kind == api.KindHeadlessDeployment) && (params.Autoscale.Enabled == nil || !*params.Autoscale.Enabled) && (params.Action == api.ActionDeploySimple || params.Action == api.ActionDeployStable) {
-		log.Info().Msgf("Deleting HorizontalPodAutoscaler %v, since autoscaling is disabled...", name)
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "hpa", name, "-n", namespace, "--ignore-not-found=true"})
-	}
+	return nil
 }
 
-func (s *service) handleAtomicUpdate(ctx context.Context, params api.Params, templateData api.TemplateData) {
+func (s *service) handleAtomicUpdate(ctx context.Context, params api.Params, templateData api.TemplateData) (err error) {
 	if params.StrategyType != api.StrategyTypeAtomicUpdate {
-		return
+		return nil
 	}
 
 	// update service in order to point to new deployment
 	log.Info().Msgf("Updating service selector to use the latest atomic id...")
 	atomicServiceTmpl, err := s.builderService.GetAtomicUpdateServiceTemplate()
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed building service template")
+		return fmt.Errorf("Failed building service template: %w", err)
 	}
 
 	renderedTemplate, err := s.builderService.RenderTemplate(atomicServiceTmpl, templateData, true)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed rendering templates")
+		return fmt.Errorf("Failed rendering service template: %w", err)
 	}
 
-	log.Info().Msg("Storing rendered service manifest on disk...")
-	err = ioutil.WriteFile("/service.yaml", renderedTemplate.Bytes(), 0600)
+	log.Info().Msg("Applying the service manifest...")
+	err = s.kubernetesClient.ApplyManifests(ctx, templateData.Namespace, renderedTemplate.Bytes(), false)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed writing manifest")
+		return fmt.Errorf("Failed applying service manifest: %w", err)
 	}
 
-	log.Info().Msg("Applying the service manifest...")
-	foundation.RunCommandWithArgs(ctx, "kubectl", []string{"apply", "-f", "/service.yaml", "-n", templateData.Namespace})
-
 	// wait a bit to drain traffic to old deployment
 	sleepTime := 30
 	log.Info().Msgf("Waiting for %v seconds to drain traffic to previous deployment(s)...", sleepTime)
@@ -637,13 +2022,32 @@ func (s *service) handleAtomicUpdate(ctx context.Context, params api.Params, tem
 
 	// clean up old deployments, configmaps, secrets, hpa, pdb
 	log.Info().Msg("Cleaning up previous deployments, configmaps, secrets, hpas and pdbs...")
-	foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "deploy,hpa,pdb", "-l", fmt.Sprintf("app in (%v),estafette.io/atomic-id,estafette.io/atomic-id notin (%v)", api.SanitizeLabel(params.App), params.AtomicID), "-n", templateData.Namespace, "--ignore-not-found=true"})
-	foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "configmap,secret", "-l", fmt.Sprintf("app in (%v),type in (application),estafette.io/atomic-id,estafette.io/atomic-id notin (%v)", api.SanitizeLabel(params.App), params.AtomicID), "-n", templateData.Namespace, "--ignore-not-found=true"})
+	workloadResources := []schema.GroupVersionResource{kubernetes.ResourceDeployments, kubernetes.ResourceHorizontalPodAutoscalers, kubernetes.ResourcePodDisruptionBudgets}
+	configResources := []schema.GroupVersionResource{kubernetes.ResourceConfigMaps, kubernetes.ResourceSecrets}
+
+	app := api.SanitizeLabel(params.App)
+	workloadSelectors := []string{fmt.Sprintf("app in (%v),estafette.io/atomic-id,estafette.io/atomic-id notin (%v)", app, params.AtomicID)}
+	configSelectors := []string{fmt.Sprintf("app in (%v),type in (application),estafette.io/atomic-id,estafette.io/atomic-id notin (%v)", app, params.AtomicID)}
 	if templateData.IncludeTrackLabel {
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "deploy,hpa,pdb", "-l", fmt.Sprintf("app in (%v),!estafette.io/atomic-id,track in (%v)", api.SanitizeLabel(params.App), templateData.TrackLabel), "-n", templateData.Namespace, "--ignore-not-found=true"})
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "configmap,secret", "-l", fmt.Sprintf("app in (%v),type in (application),!estafette.io/atomic-id,track in (%v)", api.SanitizeLabel(params.App), templateData.TrackLabel), "-n", templateData.Namespace, "--ignore-not-found=true"})
+		workloadSelectors = append(workloadSelectors, fmt.Sprintf("app in (%v),!estafette.io/atomic-id,track in (%v)", app, templateData.TrackLabel))
+		configSelectors = append(configSelectors, fmt.Sprintf("app in (%v),type in (application),!estafette.io/atomic-id,track in (%v)", app, templateData.TrackLabel))
 	} else {
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "deploy,hpa,pdb", "-l", fmt.Sprintf("app in (%v),!estafette.io/atomic-id,!track", api.SanitizeLabel(params.App)), "-n", templateData.Namespace, "--ignore-not-found=true"})
-		foundation.RunCommandWithArgs(ctx, "kubectl", []string{"delete", "configmap,secret", "-l", fmt.Sprintf("app in (%v),type in (application),!estafette.io/atomic-id,!track", api.SanitizeLabel(params.App)), "-n", templateData.Namespace, "--ignore-not-found=true"})
+		workloadSelectors = append(workloadSelectors, fmt.Sprintf("app in (%v),!estafette.io/atomic-id,!track", app))
+		configSelectors = append(configSelectors, fmt.Sprintf("app in (%v),type in (application),!estafette.io/atomic-id,!track", app))
+	}
+
+	for _, selector := range workloadSelectors {
+		err = s.kubernetesClient.DeleteResources(ctx, workloadResources, templateData.Namespace, selector, false)
+		if err != nil {
+			return fmt.Errorf("Failed cleaning up previous atomic deployments: %w", err)
+		}
+	}
+	for _, selector := range configSelectors {
+		err = s.kubernetesClient.DeleteResources(ctx, configResources, templateData.Namespace, selector, false)
+		if err != nil {
+			return fmt.Errorf("Failed cleaning up previous atomic configs and secrets: %w", err)
+		}
 	}
+
+	return nil
 }
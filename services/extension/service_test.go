@@ -0,0 +1,1073 @@
+package extension
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/gcp"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/estafette/estafette-extension-gke/clients/parameters"
+	"github.com/estafette/estafette-extension-gke/services/analysis"
+	"github.com/estafette/estafette-extension-gke/services/builder"
+	"github.com/estafette/estafette-extension-gke/services/diagnostics"
+	"github.com/estafette/estafette-extension-gke/services/generator"
+	"github.com/estafette/estafette-extension-gke/services/policy"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestPatchServiceIfRequired(t *testing.T) {
+
+	t.Run("PatchesNodePortServiceToClusterIP", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+
+		params := api.Params{Kind: api.KindDeployment}
+		templateData := api.TemplateData{Service: api.ServiceData{Name: "myapp", ServiceType: "ClusterIP"}}
+
+		kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp").Return(&corev1.Service{
+			Spec: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeNodePort,
+				Ports: []corev1.ServicePort{{Name: "http", NodePort: 31234}},
+			},
+		}, nil)
+		kubernetesClient.EXPECT().PatchResource(gomock.Any(), kubernetes.ResourceServices, "mynamespace", "myapp", types.JSONPatchType, []byte(`[{"op": "remove", "path": "/spec/ports/0/nodePort"}, {"op": "replace", "path": "/spec/type", "value": "ClusterIP"}]`)).Return(nil)
+
+		// act
+		err := service.patchServiceIfRequired(context.Background(), params, templateData, "myapp", "mynamespace")
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("DoesNotPatchClusterIPService", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+
+		params := api.Params{Kind: api.KindDeployment}
+		templateData := api.TemplateData{Service: api.ServiceData{Name: "myapp", ServiceType: "ClusterIP"}}
+
+		kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp").Return(&corev1.Service{
+			Spec: corev1.ServiceSpec{
+				Type: corev1.ServiceTypeClusterIP,
+			},
+		}, nil)
+
+		// act
+		err := service.patchServiceIfRequired(context.Background(), params, templateData, "myapp", "mynamespace")
+
+		assert.Nil(t, err)
+	})
+}
+
+func TestFailIfCreatingNewPublicService(t *testing.T) {
+
+	t.Run("ReturnsErrorIfServiceDoesNotExist", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+
+		params := api.Params{Kind: api.KindDeployment, Visibility: api.VisibilityPublic}
+
+		kubernetesClient.EXPECT().GetService(gomock.Any(), "mynamespace", "myapp").Return(nil, kubernetes.ErrResourceNotFound)
+
+		// act
+		err := service.failIfCreatingNewPublicService(context.Background(), params, api.TemplateData{}, "myapp", "mynamespace")
+
+		assert.True(t, errors.Is(err, kubernetes.ErrResourceNotFound))
+	})
+}
+
+func TestRestartDeployment(t *testing.T) {
+
+	t.Run("ReturnsErrorIfRolloutFails", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+
+		gomock.InOrder(
+			kubernetesClient.EXPECT().RestartDeployment(gomock.Any(), "mynamespace", "myapp").Return(nil),
+			kubernetesClient.EXPECT().WaitForDeploymentRollout(gomock.Any(), "mynamespace", "myapp").Return(kubernetes.ErrRolloutFailed),
+		)
+
+		// act
+		err := service.restartDeployment(context.Background(), "myapp", "mynamespace")
+
+		assert.True(t, errors.Is(err, kubernetes.ErrRolloutFailed))
+	})
+}
+
+func TestRunProgressiveCanary(t *testing.T) {
+
+	t.Run("SetsWeightOfCanaryIngressForEveryStepIfAnalysisPasses", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		analysisService := analysis.NewMockService(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, analysisService: analysisService}
+
+		params := api.Params{
+			Action: api.ActionDeployProgressive,
+			Canary: api.CanaryParams{
+				Steps:        []int{5, 50, 100},
+				StepInterval: "0s",
+			},
+		}
+		templateData := api.TemplateData{Name: "myapp", Namespace: "mynamespace", AppLabelSelector: "myapp"}
+
+		canaryIngress := unstructured.Unstructured{}
+		canaryIngress.SetName("myapp-canary")
+		canaryIngress.SetAnnotations(map[string]string{"nginx.ingress.kubernetes.io/canary": "true", "nginx.ingress.kubernetes.io/canary-weight": "5"})
+		stableIngress := unstructured.Unstructured{}
+		stableIngress.SetName("myapp")
+
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), []schema.GroupVersionResource{kubernetes.ResourceIngresses}, "mynamespace", "app=myapp").Return([]unstructured.Unstructured{stableIngress, canaryIngress}, nil).Times(3)
+		kubernetesClient.EXPECT().PatchResource(gomock.Any(), kubernetes.ResourceIngresses, "mynamespace", "myapp-canary", types.MergePatchType, []byte(`{"metadata":{"annotations":{"nginx.ingress.kubernetes.io/canary-weight":"50"}}}`)).Return(nil)
+		kubernetesClient.EXPECT().PatchResource(gomock.Any(), kubernetes.ResourceIngresses, "mynamespace", "myapp-canary", types.MergePatchType, []byte(`{"metadata":{"annotations":{"nginx.ingress.kubernetes.io/canary-weight":"100"}}}`)).Return(nil)
+		analysisService.EXPECT().Analyze(gomock.Any(), params.Canary.Analysis).Return(analysis.Result{Passed: true}, nil).Times(3)
+
+		// act
+		err := service.runProgressiveCanary(context.Background(), params, templateData)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("DeletesCanaryResourcesIfAnalysisFails", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		analysisService := analysis.NewMockService(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, analysisService: analysisService}
+
+		params := api.Params{
+			Action: api.ActionDeployProgressive,
+			Canary: api.CanaryParams{
+				Steps:        []int{5, 50, 100},
+				StepInterval: "0s",
+			},
+		}
+		templateData := api.TemplateData{Name: "myapp", Namespace: "mynamespace", AppLabelSelector: "myapp"}
+
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), gomock.Any(), "mynamespace", "app=myapp").Return([]unstructured.Unstructured{}, nil).Times(2)
+		gomock.InOrder(
+			analysisService.EXPECT().Analyze(gomock.Any(), params.Canary.Analysis).Return(analysis.Result{Passed: true}, nil),
+			analysisService.EXPECT().Analyze(gomock.Any(), params.Canary.Analysis).Return(analysis.Result{Passed: false}, nil),
+		)
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceDeployments, "mynamespace", "myapp-canary").Return(nil)
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), gomock.Any(), "mynamespace", gomock.Any()).Return(nil).AnyTimes()
+
+		// act
+		err := service.runProgressiveCanary(context.Background(), params, templateData)
+
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "at weight 50")
+	})
+}
+
+func TestRollbackDeployment(t *testing.T) {
+
+	t.Run("RestoresConfigsOfPreviousRevisionAndRollsBackToIt", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "myapp-stable", Annotations: map[string]string{kubernetes.RevisionAnnotation: "3"}}}
+		replicaSets := []appsv1.ReplicaSet{
+			{ObjectMeta: metav1.ObjectMeta{Name: "myapp-stable-a", Annotations: map[string]string{kubernetes.RevisionAnnotation: "1"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "myapp-stable-b", Annotations: map[string]string{kubernetes.RevisionAnnotation: "2"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "myapp-stable-c", Annotations: map[string]string{kubernetes.RevisionAnnotation: "3"}}},
+		}
+		configMapSnapshot := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp-stable-configs-rev2", Namespace: "mynamespace", Labels: map[string]string{"app": "myapp", "estafette.io/snapshot-of": "myapp-stable", "estafette.io/snapshot-revision": "2"}},
+			Data:       map[string]string{"config.yaml": "a: b"},
+		}
+
+		kubernetesClient.EXPECT().GetDeployment(gomock.Any(), "mynamespace", "myapp-stable").Return(deployment, nil).AnyTimes()
+		kubernetesClient.EXPECT().ListDeploymentReplicaSets(gomock.Any(), "mynamespace", "myapp-stable").Return(replicaSets, nil).AnyTimes()
+		kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", "myapp-stable-configs-rev2").Return(configMapSnapshot, nil)
+		kubernetesClient.EXPECT().GetSecret(gomock.Any(), "mynamespace", "myapp-stable-secrets-rev2").Return(nil, kubernetes.ErrResourceNotFound)
+		gomock.InOrder(
+			kubernetesClient.EXPECT().CreateOrUpdateConfigMap(gomock.Any(), &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "myapp-stable-configs", Namespace: "mynamespace", Labels: map[string]string{"app": "myapp", "type": "application"}},
+				Data:       map[string]string{"config.yaml": "a: b"},
+			}).Return(nil),
+			kubernetesClient.EXPECT().RollbackDeployment(gomock.Any(), "mynamespace", "myapp-stable", replicaSets[1]).Return(nil),
+			kubernetesClient.EXPECT().WaitForDeploymentRollout(gomock.Any(), "mynamespace", "myapp-stable").Return(nil),
+		)
+
+		// snapshot of the revision created by the rollback
+		kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", "myapp-stable-configs").Return(nil, kubernetes.ErrResourceNotFound)
+		kubernetesClient.EXPECT().GetSecret(gomock.Any(), "mynamespace", "myapp-stable-secrets").Return(nil, kubernetes.ErrResourceNotFound)
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), gomock.Any(), "mynamespace", "estafette.io/snapshot-of=myapp-stable").Return([]unstructured.Unstructured{}, nil)
+
+		// act
+		err := service.rollbackDeployment(context.Background(), "myapp-stable", "mynamespace")
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrorIfThereIsNoPreviousRevision", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "myapp", Annotations: map[string]string{kubernetes.RevisionAnnotation: "1"}}}
+		replicaSets := []appsv1.ReplicaSet{
+			{ObjectMeta: metav1.ObjectMeta{Name: "myapp-a", Annotations: map[string]string{kubernetes.RevisionAnnotation: "1"}}},
+		}
+
+		kubernetesClient.EXPECT().GetDeployment(gomock.Any(), "mynamespace", "myapp").Return(deployment, nil)
+		kubernetesClient.EXPECT().ListDeploymentReplicaSets(gomock.Any(), "mynamespace", "myapp").Return(replicaSets, nil)
+
+		// act
+		err := service.rollbackDeployment(context.Background(), "myapp", "mynamespace")
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestSnapshotConfigsAndSecrets(t *testing.T) {
+
+	t.Run("StoresCopyOfConfigsForCurrentRevisionAndPrunesSnapshotsWithoutReplicaSet", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+
+		deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "myapp", Annotations: map[string]string{kubernetes.RevisionAnnotation: "12"}}}
+		replicaSets := []appsv1.ReplicaSet{
+			{ObjectMeta: metav1.ObjectMeta{Name: "myapp-a", Annotations: map[string]string{kubernetes.RevisionAnnotation: "11"}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "myapp-b", Annotations: map[string]string{kubernetes.RevisionAnnotation: "12"}}},
+		}
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp-configs", Namespace: "mynamespace", Labels: map[string]string{"app": "myapp", "version": "1.0.0", "type": "application"}},
+			Data:       map[string]string{"config.yaml": "a: b"},
+		}
+		oldSnapshot := unstructured.Unstructured{}
+		oldSnapshot.SetKind("ConfigMap")
+		oldSnapshot.SetName("myapp-configs-rev1")
+		oldSnapshot.SetLabels(map[string]string{"estafette.io/snapshot-revision": "1"})
+		keptSnapshot := unstructured.Unstructured{}
+		keptSnapshot.SetKind("ConfigMap")
+		keptSnapshot.SetName("myapp-configs-rev11")
+		keptSnapshot.SetLabels(map[string]string{"estafette.io/snapshot-revision": "11"})
+
+		kubernetesClient.EXPECT().GetDeployment(gomock.Any(), "mynamespace", "myapp").Return(deployment, nil)
+		kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", "myapp-configs").Return(configMap, nil)
+		kubernetesClient.EXPECT().CreateOrUpdateConfigMap(gomock.Any(), &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp-configs-rev12", Namespace: "mynamespace", Labels: map[string]string{"app": "myapp", "estafette.io/snapshot-of": "myapp", "estafette.io/snapshot-revision": "12"}},
+			Data:       map[string]string{"config.yaml": "a: b"},
+		}).Return(nil)
+		kubernetesClient.EXPECT().GetSecret(gomock.Any(), "mynamespace", "myapp-secrets").Return(nil, kubernetes.ErrResourceNotFound)
+		kubernetesClient.EXPECT().ListDeploymentReplicaSets(gomock.Any(), "mynamespace", "myapp").Return(replicaSets, nil)
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), []schema.GroupVersionResource{kubernetes.ResourceConfigMaps, kubernetes.ResourceSecrets}, "mynamespace", "estafette.io/snapshot-of=myapp").Return([]unstructured.Unstructured{oldSnapshot, keptSnapshot}, nil)
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceConfigMaps, "mynamespace", "myapp-configs-rev1").Return(nil)
+
+		// act
+		service.snapshotConfigsAndSecrets(context.Background(), api.KindDeployment, "myapp", "mynamespace")
+	})
+}
+
+func TestRun(t *testing.T) {
+
+	t.Run("DeletesSimpleDeploymentWithoutInventoryLabelAfterSwitchToStableRelease", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		gcpClient := gcp.NewMockClient(ctrl)
+		parametersClient := parameters.NewMockClient(ctrl)
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		builderService := builder.NewMockService(ctrl)
+		generatorService := generator.NewMockService(ctrl)
+		policyService := policy.NewMockService(ctrl)
+		service := &service{gcpClient: gcpClient, parametersClient: parametersClient, kubernetesClient: kubernetesClient, builderService: builderService, generatorService: generatorService, policyService: policyService}
+
+		falseValue := false
+		params := api.Params{Kind: api.KindDeployment, Action: api.ActionDeployStable, App: "myapp", Namespace: "mynamespace", Lock: api.LockParams{Timeout: "1m"}, RollingUpdate: api.RollingUpdateParams{Timeout: "1m"}, DNS: api.DNSParams{UseExternalDNS: &falseValue, UseCloudflareEstafetteExtension: &falseValue}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp-stable", Namespace: "mynamespace", AppLabelSelector: "myapp", IncludeTrackLabel: true, TrackLabel: "stable"}
+		tmpl := template.Must(template.New("kubernetes.yaml").Parse(""))
+		rendered := bytes.NewBufferString("apiVersion: v1\nkind: Service\nmetadata:\n  name: myapp\n---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: myapp-stable\n")
+
+		gcpClient.EXPECT().LoadGKEClusterKubeConfig(gomock.Any(), gomock.Any()).Return("mycontext", nil)
+		kubernetesClient.EXPECT().Init(gomock.Any(), "mycontext").Return(nil)
+		parametersClient.EXPECT().Init(gomock.Any(), "kind: deployment", gomock.Any(), true, true, "", "", "", "myapp", "1.0.0", "", "deploy-stable", "").Return(params, nil)
+		builderService.EXPECT().BuildTemplates(params, gomock.Any()).Return(tmpl, nil).Times(2)
+		builderService.EXPECT().RenderConfig(params).Return(map[string]string{})
+		generatorService.EXPECT().GenerateTemplateData(gomock.Any(), gomock.Any(), "", "", "", "", "", "", "", "", "").Return(templateData)
+		builderService.EXPECT().RenderTemplate(tmpl, templateData, gomock.Any()).Return(*rendered, nil).Times(2)
+		builderService.EXPECT().ValidateManifests(gomock.Any(), "").Return(nil)
+		policyService.EXPECT().Evaluate(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(true, []error{}, []string{}, nil)
+		kubernetesClient.EXPECT().AcquireLease(gomock.Any(), "mynamespace", "myapp-release-lock", gomock.Any(), gomock.Any()).Return(kubernetes.LeaseHolder{}, nil)
+		kubernetesClient.EXPECT().RenewLease(gomock.Any(), "mynamespace", "myapp-release-lock", gomock.Any()).Return(nil).AnyTimes()
+		kubernetesClient.EXPECT().ReleaseLease(gomock.Any(), "mynamespace", "myapp-release-lock", gomock.Any()).Return(nil)
+		kubernetesClient.EXPECT().GetDeployment(gomock.Any(), "mynamespace", gomock.Any()).Return(nil, kubernetes.ErrResourceNotFound).AnyTimes()
+		kubernetesClient.EXPECT().GetPodDisruptionBudget(gomock.Any(), "mynamespace", gomock.Any()).Return(nil, kubernetes.ErrResourceNotFound).AnyTimes()
+		kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", gomock.Any()).Return(nil, kubernetes.ErrResourceNotFound).AnyTimes()
+		kubernetesClient.EXPECT().GetSecret(gomock.Any(), "mynamespace", gomock.Any()).Return(nil, kubernetes.ErrResourceNotFound).AnyTimes()
+		kubernetesClient.EXPECT().CreateOrUpdateConfigMap(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), gomock.Any(), "mynamespace", gomock.Any()).Return([]unstructured.Unstructured{}, nil).AnyTimes()
+		kubernetesClient.EXPECT().ListDeploymentReplicaSets(gomock.Any(), "mynamespace", gomock.Any()).Return([]appsv1.ReplicaSet{}, nil).AnyTimes()
+		kubernetesClient.EXPECT().RemoveAnnotations(gomock.Any(), gomock.Any(), "mynamespace", gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		kubernetesClient.EXPECT().ValidateManifests(gomock.Any(), "mynamespace", gomock.Any()).Return(nil)
+		kubernetesClient.EXPECT().DiffManifests(gomock.Any(), "mynamespace", gomock.Any()).Return([]kubernetes.ResourceDiff{}, nil)
+		kubernetesClient.EXPECT().ApplyManifests(gomock.Any(), "mynamespace", gomock.Any(), false).Return(nil)
+		kubernetesClient.EXPECT().WaitForDeploymentRollout(gomock.Any(), "mynamespace", "myapp-stable").Return(nil)
+
+		// the deployment of the simple release was applied before resources got an inventory label, so only its name gives it away
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceDeployments, "mynamespace", "myapp").Return(nil)
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), gomock.Any(), "mynamespace", gomock.Any()).Return(nil).AnyTimes()
+
+		// act
+		err := service.Run(context.Background(), &api.GKECredentials{}, "", "kind: deployment", "", "", "", "myapp", "1.0.0", "deploy-stable", "", "", "", "", "", "")
+
+		assert.Nil(t, err)
+	})
+}
+
+func TestRender(t *testing.T) {
+
+	t.Run("WritesOneFilePerResourceWithoutAccessingTheCluster", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		parametersClient := parameters.NewMockClient(ctrl)
+		builderService := builder.NewMockService(ctrl)
+		generatorService := generator.NewMockService(ctrl)
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		policyService := policy.NewMockService(ctrl)
+		service := &service{parametersClient: parametersClient, builderService: builderService, generatorService: generatorService, kubernetesClient: kubernetesClient, policyService: policyService}
+
+		outputDir := filepath.Join(t.TempDir(), "manifests")
+		params := api.Params{Kind: api.KindDeployment, Action: api.ActionDeploySimple, Replicas: 3}
+		tmpl := template.Must(template.New("kubernetes.yaml").Parse(""))
+		rendered := "apiVersion: v1\nkind: Service\nmetadata:\n  name: myapp\n---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: myapp\nspec:\n  replicas: 3\n"
+		inventory, err := kubernetes.NewInventory([]byte(rendered))
+		assert.Nil(t, err)
+
+		parametersClient.EXPECT().Init(gomock.Any(), "kind: deployment", gomock.Any(), false, false, "", "", "", "myapp", "1.0.0", "", "deploy-simple", "").Return(params, nil)
+		builderService.EXPECT().BuildTemplates(params, true).Return(tmpl, nil)
+		builderService.EXPECT().RenderConfig(params).Return(map[string]string{})
+		generatorService.EXPECT().GenerateTemplateData(gomock.Any(), 3, "", "", "", "", "", "", "", "", "").Return(api.TemplateData{AppLabelSelector: "myapp"})
+		builderService.EXPECT().RenderTemplate(tmpl, api.TemplateData{AppLabelSelector: "myapp"}, false).Return(*bytes.NewBufferString(rendered), nil)
+		builderService.EXPECT().ValidateManifests(gomock.Any(), "").Return(nil)
+		policyService.EXPECT().Evaluate(gomock.Any(), &api.GKECredentials{}, gomock.Any(), gomock.Any()).Return(true, []error{}, []string{}, nil)
+
+		// act
+		err = service.Render(context.Background(), &api.GKECredentials{}, "", "kind: deployment", "", "", "", "myapp", "1.0.0", "deploy-simple", "", "", "", "", "", "", outputDir)
+
+		assert.Nil(t, err)
+		files, err := ioutil.ReadDir(outputDir)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(files))
+		deployment, err := ioutil.ReadFile(filepath.Join(outputDir, "deployment-myapp.yaml"))
+		assert.Nil(t, err)
+		assert.Equal(t, "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  labels:\n    app: myapp\n    estafette.io/inventory: "+inventory.Hash+"\n    estafette.io/inventory-track: simple\n  name: myapp\nspec:\n  replicas: 3\n", string(deployment))
+		assert.FileExists(t, filepath.Join(outputDir, "service-myapp.yaml"))
+	})
+
+	t.Run("RemovesManifestsOfEarlierRender", func(t *testing.T) {
+
+		service := &service{}
+		outputDir := t.TempDir()
+		err := ioutil.WriteFile(filepath.Join(outputDir, "ingress-myapp.yaml"), []byte("kind: Ingress"), 0644)
+		assert.Nil(t, err)
+
+		// act
+		err = service.writeManifests([]byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: myapp\n"), outputDir)
+
+		assert.Nil(t, err)
+		assert.NoFileExists(t, filepath.Join(outputDir, "ingress-myapp.yaml"))
+		assert.FileExists(t, filepath.Join(outputDir, "service-myapp.yaml"))
+	})
+
+	t.Run("ReturnsErrorIfManifestsViolatePolicyRules", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		parametersClient := parameters.NewMockClient(ctrl)
+		builderService := builder.NewMockService(ctrl)
+		generatorService := generator.NewMockService(ctrl)
+		policyService := policy.NewMockService(ctrl)
+		service := &service{parametersClient: parametersClient, builderService: builderService, generatorService: generatorService, policyService: policyService}
+
+		outputDir := filepath.Join(t.TempDir(), "manifests")
+		params := api.Params{Kind: api.KindDeployment, Action: api.ActionDeploySimple, Replicas: 30}
+		tmpl := template.Must(template.New("kubernetes.yaml").Parse(""))
+		rendered := bytes.NewBufferString("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: myapp\nspec:\n  replicas: 30\n")
+
+		parametersClient.EXPECT().Init(gomock.Any(), "kind: deployment", gomock.Any(), false, false, "", "", "", "myapp", "1.0.0", "", "deploy-simple", "").Return(params, nil)
+		builderService.EXPECT().BuildTemplates(params, true).Return(tmpl, nil)
+		builderService.EXPECT().RenderConfig(params).Return(map[string]string{})
+		generatorService.EXPECT().GenerateTemplateData(gomock.Any(), 30, "", "", "", "", "", "", "", "", "").Return(api.TemplateData{})
+		builderService.EXPECT().RenderTemplate(tmpl, api.TemplateData{}, false).Return(*rendered, nil)
+		builderService.EXPECT().ValidateManifests(gomock.Any(), "").Return(nil)
+		policyService.EXPECT().Evaluate(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, []error{errors.New("Policy max-replicas: Deployment myapp has 30 replicas, more than the maximum of 20")}, []string{}, nil)
+
+		// act
+		err := service.Render(context.Background(), &api.GKECredentials{}, "", "kind: deployment", "", "", "", "myapp", "1.0.0", "deploy-simple", "", "", "", "", "", "", outputDir)
+
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "Deployment myapp has 30 replicas")
+		}
+		files, _ := ioutil.ReadDir(outputDir)
+		assert.Equal(t, 0, len(files))
+	})
+
+	t.Run("ReturnsErrorIfResourceIsRenderedTwice", func(t *testing.T) {
+
+		service := &service{}
+		outputDir := t.TempDir()
+
+		// act
+		err := service.writeManifests([]byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: myapp\n---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: myapp\n"), outputDir)
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestCleanupOldResources(t *testing.T) {
+
+	t.Run("DeletesCanaryWithoutInventoryLabelAfterStableRelease", func(t *testing.T) {
+
+		for _, kind := range []api.Kind{api.KindDeployment, api.KindHeadlessDeployment} {
+
+			ctrl := gomock.NewController(t)
+
+			kubernetesClient := kubernetes.NewMockClient(ctrl)
+			service := &service{kubernetesClient: kubernetesClient}
+			params := api.Params{Kind: kind, Action: api.ActionDeployStable}
+			templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp-stable", Namespace: "mynamespace", AppLabelSelector: "myapp"}
+
+			for _, r := range []schema.GroupVersionResource{kubernetes.ResourceDeployments, kubernetes.ResourceServices, kubernetes.ResourceIngresses, kubernetes.ResourceHorizontalPodAutoscalers} {
+				for _, n := range []string{"myapp-canary", "myapp-canary-internal", "myapp-canary-apigee"} {
+					kubernetesClient.EXPECT().DeleteResource(gomock.Any(), r, "mynamespace", n).Return(nil)
+				}
+			}
+			kubernetesClient.EXPECT().DeleteResource(gomock.Any(), gomock.Any(), "mynamespace", gomock.Any()).Return(nil).AnyTimes()
+			kubernetesClient.EXPECT().RemoveAnnotations(gomock.Any(), kubernetes.ResourceServices, "mynamespace", gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			// act
+			err := service.cleanupOldResources(context.Background(), params, templateData, nil)
+
+			assert.Nil(t, err, kind)
+			ctrl.Finish()
+		}
+	})
+
+	t.Run("DeletesCanaryAndStableDeploymentsWithoutInventoryLabelAfterSimpleRelease", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{Kind: api.KindHeadlessDeployment, Action: api.ActionDeploySimple, Configs: api.ConfigsParams{InlineFiles: map[string]string{"config.yaml": "a: b"}}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp", Namespace: "mynamespace", AppLabelSelector: "myapp", GoogleCloudCredentialsAppName: "myapp"}
+
+		for _, n := range []string{"myapp-canary", "myapp-stable"} {
+			kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceDeployments, "mynamespace", n).Return(nil)
+			kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceConfigMaps, "mynamespace", n+"-configs").Return(nil)
+			kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceSecrets, "mynamespace", n+"-secrets").Return(nil)
+			kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceHorizontalPodAutoscalers, "mynamespace", n).Return(nil)
+			kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourcePodDisruptionBudgets, "mynamespace", n).Return(nil)
+		}
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceSecrets, "mynamespace", "myapp-secrets").Return(nil)
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceSecrets, "mynamespace", "myapp-gcp-service-account").Return(nil)
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceHorizontalPodAutoscalers, "mynamespace", "myapp").Return(nil)
+
+		// act
+		err := service.cleanupOldResources(context.Background(), params, templateData, nil)
+
+		assert.Nil(t, err)
+	})
+}
+
+func TestPruneInventory(t *testing.T) {
+
+	t.Run("DeletesResourcesOfPreviousReleasesThatAreNotRenderedAnymore", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp-stable", Namespace: "mynamespace", AppLabelSelector: "myapp", IncludeTrackLabel: true, TrackLabel: "stable"}
+		inventory := &kubernetes.Inventory{Hash: "0123456789abcdef", Resources: map[string]bool{"ingress/myapp": true}}
+		labelSelector := "app=myapp,estafette.io/inventory,estafette.io/inventory!=0123456789abcdef"
+
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), []schema.GroupVersionResource{kubernetes.ResourceIngresses}, "mynamespace", labelSelector).Return([]unstructured.Unstructured{
+			newUnstructured("networking.k8s.io/v1", "Ingress", "myapp"),
+			newUnstructured("networking.k8s.io/v1", "Ingress", "myapp-internal"),
+		}, nil)
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), []schema.GroupVersionResource{kubernetes.ResourceDeployments}, "mynamespace", labelSelector).Return([]unstructured.Unstructured{
+			newUnstructured("apps/v1", "Deployment", "myapp-canary"),
+		}, nil)
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), gomock.Any(), "mynamespace", labelSelector).Return(nil, nil).AnyTimes()
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceIngresses, "mynamespace", "myapp-internal").Return(nil)
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceDeployments, "mynamespace", "myapp-canary").Return(nil)
+
+		// act
+		err := service.pruneInventory(context.Background(), templateData, inventory)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("OnlyDeletesOwnResourcesForCanary", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp-canary", Namespace: "mynamespace", AppLabelSelector: "myapp", IncludeTrackLabel: true, TrackLabel: "canary"}
+		inventory := &kubernetes.Inventory{Hash: "0123456789abcdef", Resources: map[string]bool{"deployment/myapp-canary": true}}
+		labelSelector := "app=myapp,estafette.io/inventory,estafette.io/inventory!=0123456789abcdef,estafette.io/inventory-track=canary"
+
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), []schema.GroupVersionResource{kubernetes.ResourceSecrets}, "mynamespace", labelSelector).Return([]unstructured.Unstructured{
+			newUnstructured("v1", "Secret", "myapp-canary-secrets"),
+			newUnstructured("v1", "Secret", "myapp-certificate-secret"),
+		}, nil)
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), gomock.Any(), "mynamespace", labelSelector).Return(nil, nil).AnyTimes()
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceSecrets, "mynamespace", "myapp-canary-secrets").Return(nil)
+
+		// act
+		err := service.pruneInventory(context.Background(), templateData, inventory)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("DoesNotDeleteResourcesOfOtherBlueGreenColor", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp-green", Namespace: "mynamespace", AppLabelSelector: "myapp", UseBlueGreen: true, BlueGreenColor: "green", BlueGreenLiveColor: "blue"}
+		inventory := &kubernetes.Inventory{Hash: "0123456789abcdef", Resources: map[string]bool{"deployment/myapp-green": true}}
+		labelSelector := "app=myapp,estafette.io/inventory,estafette.io/inventory!=0123456789abcdef"
+
+		blue := newUnstructured("apps/v1", "Deployment", "myapp-blue")
+		blue.SetLabels(map[string]string{"estafette.io/color": "blue"})
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), []schema.GroupVersionResource{kubernetes.ResourceDeployments}, "mynamespace", labelSelector).Return([]unstructured.Unstructured{
+			blue,
+			newUnstructured("apps/v1", "Deployment", "myapp"),
+		}, nil)
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), gomock.Any(), "mynamespace", labelSelector).Return(nil, nil).AnyTimes()
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceDeployments, "mynamespace", "myapp").Return(nil)
+
+		// act
+		err := service.pruneInventory(context.Background(), templateData, inventory)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("DoesNothingWithoutInventory", func(t *testing.T) {
+
+		service := &service{}
+
+		// act
+		err := service.pruneInventory(context.Background(), api.TemplateData{}, nil)
+
+		assert.Nil(t, err)
+	})
+}
+
+func TestRunPreDeployHooks(t *testing.T) {
+
+	manifests := []byte(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: myapp-configs
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: myapp-secrets
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: myapp
+`)
+
+	t.Run("RunsEachHookAsJobAfterApplyingSecrets", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		builderService := builder.NewMockService(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, builderService: builderService}
+		backoffLimit := 0
+		params := api.Params{Hooks: api.HooksParams{PreDeploy: []api.HookParams{{Name: "migrate", Command: []string{"./migrate"}, BackoffLimit: &backoffLimit, Timeout: "5m"}}}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp", Namespace: "mynamespace"}
+		hookTmpl := template.New("job.yaml")
+
+		gomock.InOrder(
+			kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", "myapp-configs").Return(nil, kubernetes.ErrResourceNotFound),
+			kubernetesClient.EXPECT().GetSecret(gomock.Any(), "mynamespace", "myapp-secrets").Return(nil, kubernetes.ErrResourceNotFound),
+			kubernetesClient.EXPECT().ApplyManifests(gomock.Any(), "mynamespace", gomock.Any(), false).DoAndReturn(func(ctx context.Context, namespace string, manifests []byte, dryRun bool) error {
+				assert.Contains(t, string(manifests), "kind: Secret")
+				assert.NotContains(t, string(manifests), "kind: Deployment")
+				return nil
+			}),
+			builderService.EXPECT().GetHookTemplate().Return(hookTmpl, nil),
+			builderService.EXPECT().RenderTemplate(hookTmpl, gomock.Any(), true).Return(*bytes.NewBufferString("kind: Job"), nil),
+			kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceJobs, "mynamespace", "myapp-migrate").Return(nil),
+			kubernetesClient.EXPECT().WaitForDeletion(gomock.Any(), kubernetes.ResourceJobs, "mynamespace", "myapp-migrate", gomock.Any()).Return(nil),
+			kubernetesClient.EXPECT().ApplyManifests(gomock.Any(), "mynamespace", []byte("kind: Job"), false).Return(nil),
+			kubernetesClient.EXPECT().WaitForJobCompletion(gomock.Any(), "mynamespace", "myapp-migrate", 5*time.Minute).Return(nil),
+		)
+		kubernetesClient.EXPECT().FollowPodLogs(gomock.Any(), "mynamespace", "job-name=myapp-migrate", "myapp", gomock.Any()).Return(nil)
+
+		// act
+		err := service.runPreDeployHooks(context.Background(), params, templateData, manifests)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("AbortsOnFirstFailingHook", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		builderService := builder.NewMockService(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, builderService: builderService}
+		backoffLimit := 0
+		params := api.Params{Hooks: api.HooksParams{PreDeploy: []api.HookParams{
+			{Name: "migrate", Command: []string{"./migrate"}, BackoffLimit: &backoffLimit, Timeout: "5m"},
+			{Name: "seed", Command: []string{"./seed"}, BackoffLimit: &backoffLimit, Timeout: "5m"},
+		}}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp", Namespace: "mynamespace"}
+
+		kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", "myapp-configs").Return(nil, kubernetes.ErrResourceNotFound)
+		kubernetesClient.EXPECT().GetSecret(gomock.Any(), "mynamespace", "myapp-secrets").Return(nil, kubernetes.ErrResourceNotFound)
+		kubernetesClient.EXPECT().ApplyManifests(gomock.Any(), "mynamespace", gomock.Any(), false).Return(nil).Times(2)
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceConfigMaps, "mynamespace", "myapp-configs").Return(nil)
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceSecrets, "mynamespace", "myapp-secrets").Return(nil)
+		builderService.EXPECT().GetHookTemplate().Return(template.New("job.yaml"), nil)
+		builderService.EXPECT().RenderTemplate(gomock.Any(), gomock.Any(), true).Return(bytes.Buffer{}, nil)
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceJobs, "mynamespace", "myapp-migrate").Return(nil)
+		kubernetesClient.EXPECT().WaitForDeletion(gomock.Any(), kubernetes.ResourceJobs, "mynamespace", "myapp-migrate", gomock.Any()).Return(nil)
+		kubernetesClient.EXPECT().FollowPodLogs(gomock.Any(), "mynamespace", "job-name=myapp-migrate", "myapp", gomock.Any()).Return(nil)
+		kubernetesClient.EXPECT().WaitForJobCompletion(gomock.Any(), "mynamespace", "myapp-migrate", 5*time.Minute).Return(kubernetes.ErrJobFailed)
+
+		// act
+		err := service.runPreDeployHooks(context.Background(), params, templateData, manifests)
+
+		assert.True(t, errors.Is(err, kubernetes.ErrJobFailed))
+	})
+
+	t.Run("RestoresPreviousConfigsAndSecretsIfHookFails", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		builderService := builder.NewMockService(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, builderService: builderService}
+		backoffLimit := 0
+		params := api.Params{Hooks: api.HooksParams{PreDeploy: []api.HookParams{{Name: "migrate", Command: []string{"./migrate"}, BackoffLimit: &backoffLimit, Timeout: "5m"}}}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp", Namespace: "mynamespace"}
+
+		previousConfigMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "myapp-configs", Namespace: "mynamespace", Labels: map[string]string{"app": "myapp"}, ResourceVersion: "12"}, Data: map[string]string{"config.yaml": "version: 1"}}
+		previousSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "myapp-secrets", Namespace: "mynamespace", Labels: map[string]string{"app": "myapp"}, ResourceVersion: "13"}, Type: corev1.SecretTypeOpaque, Data: map[string][]byte{"secret.yaml": []byte("password: old")}}
+
+		kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", "myapp-configs").Return(previousConfigMap, nil)
+		kubernetesClient.EXPECT().GetSecret(gomock.Any(), "mynamespace", "myapp-secrets").Return(previousSecret, nil)
+		kubernetesClient.EXPECT().ApplyManifests(gomock.Any(), "mynamespace", gomock.Any(), false).Return(nil).Times(2)
+		builderService.EXPECT().GetHookTemplate().Return(template.New("job.yaml"), nil)
+		builderService.EXPECT().RenderTemplate(gomock.Any(), gomock.Any(), true).Return(bytes.Buffer{}, nil)
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceJobs, "mynamespace", "myapp-migrate").Return(nil)
+		kubernetesClient.EXPECT().WaitForDeletion(gomock.Any(), kubernetes.ResourceJobs, "mynamespace", "myapp-migrate", gomock.Any()).Return(nil)
+		kubernetesClient.EXPECT().FollowPodLogs(gomock.Any(), "mynamespace", "job-name=myapp-migrate", "myapp", gomock.Any()).Return(nil)
+		kubernetesClient.EXPECT().WaitForJobCompletion(gomock.Any(), "mynamespace", "myapp-migrate", 5*time.Minute).Return(kubernetes.ErrJobFailed)
+		kubernetesClient.EXPECT().CreateOrUpdateConfigMap(gomock.Any(), &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp-configs", Namespace: "mynamespace", Labels: map[string]string{"app": "myapp"}},
+			Data:       map[string]string{"config.yaml": "version: 1"},
+		}).Return(nil)
+		kubernetesClient.EXPECT().CreateOrUpdateSecret(gomock.Any(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "myapp-secrets", Namespace: "mynamespace", Labels: map[string]string{"app": "myapp"}},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{"secret.yaml": []byte("password: old")},
+		}).Return(nil)
+
+		// act
+		err := service.runPreDeployHooks(context.Background(), params, templateData, manifests)
+
+		assert.True(t, errors.Is(err, kubernetes.ErrJobFailed))
+	})
+}
+
+func TestWaitForRollout(t *testing.T) {
+
+	t.Run("ReturnsNilIfRolloutFinishesInTime", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{Kind: api.KindDeployment, RollingUpdate: api.RollingUpdateParams{Timeout: "5m", OnTimeout: "fail"}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp", Namespace: "mynamespace"}
+
+		kubernetesClient.EXPECT().WaitForDeploymentRollout(gomock.Any(), "mynamespace", "myapp").DoAndReturn(func(ctx context.Context, namespace, name string) error {
+			deadline, ok := ctx.Deadline()
+			assert.True(t, ok)
+			assert.True(t, time.Until(deadline) <= 5*time.Minute)
+			return nil
+		})
+		kubernetesClient.EXPECT().GetDeployment(gomock.Any(), "mynamespace", "myapp").Return(nil, kubernetes.ErrResourceNotFound).AnyTimes()
+
+		// act
+		err := service.waitForRollout(context.Background(), params, templateData)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("PausesDeploymentIfRolloutTimesOutAndOnTimeoutIsPause", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, report: &api.Report{}}
+		params := api.Params{Kind: api.KindDeployment, RollingUpdate: api.RollingUpdateParams{Timeout: "5m", OnTimeout: "pause"}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp", Namespace: "mynamespace"}
+
+		kubernetesClient.EXPECT().WaitForDeploymentRollout(gomock.Any(), "mynamespace", "myapp").Return(kubernetes.ErrRolloutTimedOut)
+		kubernetesClient.EXPECT().PatchResource(gomock.Any(), kubernetes.ResourceDeployments, "mynamespace", "myapp", types.MergePatchType, []byte(`{"spec":{"paused":true}}`)).Return(nil)
+
+		// act
+		err := service.waitForRollout(context.Background(), params, templateData)
+
+		assert.True(t, errors.Is(err, kubernetes.ErrRolloutTimedOut))
+		assert.Equal(t, api.RolloutOutcomePaused, service.report.Rollout)
+	})
+
+	t.Run("RemovesCanaryIfRolloutTimesOutAndOnTimeoutIsUndo", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, report: &api.Report{}}
+		params := api.Params{Kind: api.KindDeployment, RollingUpdate: api.RollingUpdateParams{Timeout: "5m", OnTimeout: "undo"}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp-canary", Namespace: "mynamespace", TrackLabel: "canary"}
+
+		kubernetesClient.EXPECT().WaitForDeploymentRollout(gomock.Any(), "mynamespace", "myapp-canary").Return(kubernetes.ErrRolloutTimedOut)
+		kubernetesClient.EXPECT().DeleteResource(gomock.Any(), gomock.Any(), "mynamespace", gomock.Any()).Return(nil).Times(12)
+
+		// act
+		err := service.waitForRollout(context.Background(), params, templateData)
+
+		assert.True(t, errors.Is(err, kubernetes.ErrRolloutTimedOut))
+		assert.Equal(t, api.RolloutOutcomeUndone, service.report.Rollout)
+	})
+
+	t.Run("RollsBackStatefulSetIfRolloutTimesOutAndOnTimeoutIsUndo", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, report: &api.Report{}}
+		params := api.Params{Kind: api.KindStatefulset, RollingUpdate: api.RollingUpdateParams{Timeout: "5m", OnTimeout: "undo"}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp", Namespace: "mynamespace"}
+		revisions := []appsv1.ControllerRevision{
+			{ObjectMeta: metav1.ObjectMeta{Name: "myapp-a"}, Revision: 3},
+			{ObjectMeta: metav1.ObjectMeta{Name: "myapp-b"}, Revision: 4},
+		}
+
+		gomock.InOrder(
+			kubernetesClient.EXPECT().WaitForStatefulSetRollout(gomock.Any(), "mynamespace", "myapp").Return(kubernetes.ErrRolloutTimedOut),
+			kubernetesClient.EXPECT().RollbackStatefulSet(gomock.Any(), "mynamespace", "myapp", revisions[0]).Return(nil),
+			kubernetesClient.EXPECT().WaitForStatefulSetRollout(gomock.Any(), "mynamespace", "myapp").Return(nil),
+		)
+		kubernetesClient.EXPECT().ListStatefulSetRevisions(gomock.Any(), "mynamespace", "myapp").Return(revisions, nil).AnyTimes()
+		kubernetesClient.EXPECT().GetConfigMap(gomock.Any(), "mynamespace", gomock.Any()).Return(nil, kubernetes.ErrResourceNotFound).AnyTimes()
+		kubernetesClient.EXPECT().GetSecret(gomock.Any(), "mynamespace", gomock.Any()).Return(nil, kubernetes.ErrResourceNotFound).AnyTimes()
+		kubernetesClient.EXPECT().ListResources(gomock.Any(), gomock.Any(), "mynamespace", gomock.Any()).Return(nil, nil).AnyTimes()
+
+		// act
+		err := service.waitForRollout(context.Background(), params, templateData)
+
+		assert.True(t, errors.Is(err, kubernetes.ErrRolloutTimedOut))
+		assert.Equal(t, api.RolloutOutcomeUndone, service.report.Rollout)
+	})
+
+	t.Run("DoesNotPauseStatefulSetIfRolloutTimesOutAndOnTimeoutIsPause", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, report: &api.Report{}}
+		params := api.Params{Kind: api.KindStatefulset, RollingUpdate: api.RollingUpdateParams{Timeout: "5m", OnTimeout: "pause"}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp", Namespace: "mynamespace"}
+
+		kubernetesClient.EXPECT().WaitForStatefulSetRollout(gomock.Any(), "mynamespace", "myapp").Return(kubernetes.ErrRolloutTimedOut)
+
+		// act
+		err := service.waitForRollout(context.Background(), params, templateData)
+
+		assert.True(t, errors.Is(err, kubernetes.ErrRolloutTimedOut))
+		assert.Equal(t, api.RolloutOutcomeTimedOut, service.report.Rollout)
+	})
+
+	t.Run("FailsWithoutUndoingIfRolloutFailsForAnotherReason", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, report: &api.Report{}}
+		params := api.Params{Kind: api.KindStatefulset, RollingUpdate: api.RollingUpdateParams{Timeout: "5m", OnTimeout: "undo"}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp", Namespace: "mynamespace"}
+
+		kubernetesClient.EXPECT().WaitForStatefulSetRollout(gomock.Any(), "mynamespace", "myapp").Return(kubernetes.ErrNotInitialized)
+
+		// act
+		err := service.waitForRollout(context.Background(), params, templateData)
+
+		assert.True(t, errors.Is(err, kubernetes.ErrNotInitialized))
+		assert.Equal(t, api.RolloutOutcomeFailed, service.report.Rollout)
+	})
+}
+
+func TestWaitForJob(t *testing.T) {
+
+	t.Run("RemovesJobAfterItCompleted", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{Kind: api.KindJob, JobTimeout: "30m"}
+		templateData := api.TemplateData{Name: "myjob", JobName: "myjob", Namespace: "mynamespace"}
+
+		gomock.InOrder(
+			kubernetesClient.EXPECT().WaitForJobCompletion(gomock.Any(), "mynamespace", "myjob", 30*time.Minute).Return(nil),
+			kubernetesClient.EXPECT().DeleteResource(gomock.Any(), kubernetes.ResourceJobs, "mynamespace", "myjob").Return(nil),
+		)
+		kubernetesClient.EXPECT().FollowPodLogs(gomock.Any(), "mynamespace", "job-name=myjob", "myjob", gomock.Any()).Return(nil)
+
+		// act
+		err := service.waitForJob(context.Background(), params, templateData)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("KeepsJobAfterItCompletedIfKeepFinishedJobIsTrue", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{Kind: api.KindJob, JobTimeout: "30m", KeepFinishedJob: true}
+		templateData := api.TemplateData{Name: "myjob", JobName: "myjob", Namespace: "mynamespace"}
+
+		kubernetesClient.EXPECT().WaitForJobCompletion(gomock.Any(), "mynamespace", "myjob", 30*time.Minute).Return(nil)
+		kubernetesClient.EXPECT().FollowPodLogs(gomock.Any(), "mynamespace", "job-name=myjob", "myjob", gomock.Any()).Return(nil)
+
+		// act
+		err := service.waitForJob(context.Background(), params, templateData)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrorAndKeepsJobIfItFailed", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{Kind: api.KindJob, JobTimeout: "30m"}
+		templateData := api.TemplateData{Name: "myjob", JobName: "myjob", Namespace: "mynamespace"}
+
+		kubernetesClient.EXPECT().WaitForJobCompletion(gomock.Any(), "mynamespace", "myjob", 30*time.Minute).Return(kubernetes.ErrJobFailed)
+		kubernetesClient.EXPECT().FollowPodLogs(gomock.Any(), "mynamespace", "job-name=myjob", "myjob", gomock.Any()).Return(nil)
+
+		// act
+		err := service.waitForJob(context.Background(), params, templateData)
+
+		assert.True(t, errors.Is(err, kubernetes.ErrJobFailed))
+	})
+}
+
+func TestWaitForJobFollowingLogs(t *testing.T) {
+
+	t.Run("LetsLogsDrainAfterJobCompleted", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, logsDrainTimeout: time.Minute}
+		drained := false
+
+		kubernetesClient.EXPECT().WaitForJobCompletion(gomock.Any(), "mynamespace", "myjob", 30*time.Minute).Return(nil)
+		kubernetesClient.EXPECT().FollowPodLogs(gomock.Any(), "mynamespace", "job-name=myjob", "myjob", gomock.Any()).DoAndReturn(func(ctx context.Context, namespace, labelSelector, container string, done <-chan struct{}) error {
+			<-done
+			// the last lines of the pods still come in after the job completed
+			time.Sleep(10 * time.Millisecond)
+			drained = ctx.Err() == nil
+			return nil
+		})
+
+		// act
+		err := service.waitForJobFollowingLogs(context.Background(), "mynamespace", "myjob", "myjob", 30*time.Minute)
+
+		assert.Nil(t, err)
+		assert.True(t, drained)
+	})
+
+	t.Run("StopsFollowingLogsThatDoNotEndWithinDrainTimeout", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient, logsDrainTimeout: 10 * time.Millisecond}
+
+		kubernetesClient.EXPECT().WaitForJobCompletion(gomock.Any(), "mynamespace", "myjob", 30*time.Minute).Return(kubernetes.ErrJobFailed)
+		kubernetesClient.EXPECT().FollowPodLogs(gomock.Any(), "mynamespace", "job-name=myjob", "myjob", gomock.Any()).DoAndReturn(func(ctx context.Context, namespace, labelSelector, container string, done <-chan struct{}) error {
+			<-ctx.Done()
+			return nil
+		})
+
+		// act
+		err := service.waitForJobFollowingLogs(context.Background(), "mynamespace", "myjob", "myjob", 30*time.Minute)
+
+		assert.True(t, errors.Is(err, kubernetes.ErrJobFailed))
+	})
+}
+
+func TestGetHookTemplateData(t *testing.T) {
+
+	t.Run("KeepsHookPodsOutOfTheWorkloadSelectors", func(t *testing.T) {
+
+		backoffLimit := 2
+		hook := api.HookParams{Name: "migrate", Command: []string{"./migrate"}, Args: []string{"up"}, BackoffLimit: &backoffLimit}
+		templateData := api.TemplateData{
+			Name:          "myapp",
+			NameWithTrack: "myapp-canary",
+			Labels:        map[string]string{"app": "myapp"},
+			PodLabels:     map[string]string{"app": "myapp", "track": "canary", "app.kubernetes.io/name": "myapp"},
+		}
+
+		// act
+		hookTemplateData := getHookTemplateData(templateData, hook)
+
+		assert.Equal(t, "myapp-canary-migrate", hookTemplateData.JobName)
+		assert.Equal(t, "Never", hookTemplateData.RestartPolicy)
+		assert.Equal(t, 2, hookTemplateData.BackoffLimit)
+		assert.Equal(t, []string{"./migrate"}, hookTemplateData.Container.Command)
+		assert.Equal(t, []string{"up"}, hookTemplateData.Container.Args)
+		assert.Equal(t, map[string]string{"app.kubernetes.io/name": "myapp", "estafette.io/hook": "migrate"}, hookTemplateData.PodLabels)
+		assert.Equal(t, map[string]string{"app": "myapp", "estafette.io/hook": "migrate"}, hookTemplateData.Labels)
+		assert.Equal(t, map[string]string{"app": "myapp", "track": "canary", "app.kubernetes.io/name": "myapp"}, templateData.PodLabels)
+	})
+}
+
+func TestDiagnoseRollout(t *testing.T) {
+
+	t.Run("AddsDiagnosisOfDeploymentToTroubleshooting", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		diagnosticsService := diagnostics.NewMockService(ctrl)
+		service := &service{diagnosticsService: diagnosticsService, paramsForTroubleshooting: api.Params{Kind: api.KindDeployment}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp-stable", Namespace: "mynamespace"}
+		troubleshooting := &api.ReportTroubleshooting{}
+
+		diagnosticsService.EXPECT().DiagnoseDeployment(gomock.Any(), "mynamespace", "myapp-stable").Return(diagnostics.Diagnosis{
+			Reason:   diagnostics.ReasonImagePull,
+			Findings: []diagnostics.Finding{{Reason: diagnostics.ReasonImagePull, Object: "pod/myapp-stable-abc", Container: "myapp", Message: "can't pull image"}},
+			Events:   []string{"Warning Failed pod/myapp-stable-abc: Failed to pull image"},
+		}, nil)
+
+		// act
+		service.diagnoseRollout(context.Background(), templateData, troubleshooting)
+
+		assert.Equal(t, "ImagePullBackOff", troubleshooting.RootCause)
+		assert.Equal(t, "Root cause: ImagePullBackOff\n- pod/myapp-stable-abc container myapp: can't pull image", troubleshooting.Summary)
+		assert.Equal(t, []string{"Warning Failed pod/myapp-stable-abc: Failed to pull image"}, troubleshooting.Events)
+	})
+	t.Run("DiagnosesPodsOfJob", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		diagnosticsService := diagnostics.NewMockService(ctrl)
+		service := &service{diagnosticsService: diagnosticsService, paramsForTroubleshooting: api.Params{Kind: api.KindJob}}
+		templateData := api.TemplateData{Name: "myjob", JobName: "myjob", Namespace: "mynamespace"}
+		troubleshooting := &api.ReportTroubleshooting{}
+
+		diagnosticsService.EXPECT().DiagnosePods(gomock.Any(), "mynamespace", "job-name=myjob").Return(diagnostics.Diagnosis{
+			Reason:   diagnostics.ReasonOOMKilled,
+			Findings: []diagnostics.Finding{{Reason: diagnostics.ReasonOOMKilled, Object: "pod/myjob-abc", Container: "myjob", Message: "killed for exceeding its memory limit, restarted 0 times"}},
+		}, nil)
+
+		// act
+		service.diagnoseRollout(context.Background(), templateData, troubleshooting)
+
+		assert.Equal(t, "OOMKilled", troubleshooting.RootCause)
+	})
+}
+
+func newUnstructured(apiVersion, kind, name string) unstructured.Unstructured {
+	obj := unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetName(name)
+	return obj
+}
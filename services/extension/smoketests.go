@@ -0,0 +1,173 @@
+package extension
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// runSmoketests sends the smoke test requests to the service of the release and each of its hosts from a pod inside the cluster
+func (s *service) runSmoketests(ctx context.Context, params api.Params, templateData api.TemplateData) (err error) {
+	timeout, err := time.ParseDuration(params.Smoketests.Timeout)
+	if err != nil {
+		return fmt.Errorf("Failed parsing smoke tests timeout %v: %w", params.Smoketests.Timeout, err)
+	}
+
+	pod := getSmoketestsPod(params, templateData)
+
+	log.Info().Msgf("Running %v smoke test(s) against %v...", len(params.Smoketests.Checks), strings.Join(getSmoketestsBaseURLs(params, templateData), ", "))
+	logs, err := s.kubernetesClient.RunPod(ctx, pod, timeout)
+	for _, line := range strings.Split(strings.TrimSpace(logs), "\n") {
+		if line != "" {
+			log.Info().Msg(line)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("Smoke tests failed: %w", err)
+	}
+
+	return nil
+}
+
+// rollbackAfterFailedSmoketests removes a canary or rolls a deployment back to its previous revision
+func (s *service) rollbackAfterFailedSmoketests(ctx context.Context, params api.Params, templateData api.TemplateData) {
+	if templateData.TrackLabel == "canary" {
+		log.Warn().Msg("Smoke tests failed, rolling back the canary...")
+		err := s.deleteCanaryResources(ctx, templateData.Name, templateData.Namespace)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed rolling back the canary after failed smoke tests")
+		}
+		return
+	}
+
+	if templateData.UseBlueGreen && templateData.BlueGreenColor != templateData.BlueGreenLiveColor {
+		log.Warn().Msgf("Smoke tests failed, colour %v doesn't receive traffic so it doesn't need to be rolled back; don't switch to it", templateData.BlueGreenColor)
+		return
+	}
+
+	if params.Kind == api.KindDeployment || params.Kind == api.KindHeadlessDeployment {
+		log.Warn().Msgf("Smoke tests failed, rolling back deployment %v...", templateData.NameWithTrack)
+		err := s.rollbackDeployment(ctx, templateData.NameWithTrack, templateData.Namespace)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed rolling back the deployment after failed smoke tests")
+		}
+	}
+}
+
+func getSmoketestsPod(params api.Params, templateData api.TemplateData) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%v-smoketests", templateData.NameWithTrack),
+			Namespace: templateData.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       templateData.Name,
+				"app.kubernetes.io/managed-by": "estafette",
+				"estafette.io/smoketests":      "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:    "smoketests",
+					Image:   params.Smoketests.Image,
+					Command: []string{"/bin/sh", "-c", getSmoketestsScript(params, templateData)},
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("10m"),
+							corev1.ResourceMemory: resource.MustParse("32Mi"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceMemory: resource.MustParse("32Mi"),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// getSmoketestsBaseURLs returns the service of the release, unless there's none or it's only updated after the rollout, and each of its hosts; an idle bluegreen colour is only reachable through its preview service
+func getSmoketestsBaseURLs(params api.Params, templateData api.TemplateData) (baseURLs []string) {
+	idleBlueGreenColor := templateData.UseBlueGreen && templateData.BlueGreenColor != templateData.BlueGreenLiveColor
+
+	if params.Kind != api.KindHeadlessDeployment && params.StrategyType != api.StrategyTypeAtomicUpdate {
+		serviceName := templateData.Service.Name
+		if templateData.TrackLabel != "" {
+			serviceName += "-" + templateData.TrackLabel
+		}
+		if idleBlueGreenColor {
+			serviceName += "-preview"
+		}
+		serviceHost := fmt.Sprintf("%v.%v.svc.cluster.local", serviceName, templateData.Namespace)
+
+		switch {
+		case templateData.HasOpenrestySidecar && templateData.DisableHTTPPort:
+			baseURLs = append(baseURLs, fmt.Sprintf("https://%v", serviceHost))
+		case templateData.HasOpenrestySidecar:
+			baseURLs = append(baseURLs, fmt.Sprintf("http://%v", serviceHost))
+		default:
+			baseURLs = append(baseURLs, fmt.Sprintf("http://%v:%v", serviceHost, templateData.Container.Port))
+		}
+	}
+
+	if idleBlueGreenColor {
+		return
+	}
+
+	for _, host := range templateData.Hosts {
+		baseURLs = append(baseURLs, fmt.Sprintf("https://%v", host))
+	}
+
+	return
+}
+
+// getSmoketestsScript generates a shell script running curl for every check against every base url, exiting non-zero if any of them responds with an unexpected status
+func getSmoketestsScript(params api.Params, templateData api.TemplateData) string {
+	var sb strings.Builder
+	sb.WriteString("failed=0\n")
+
+	for _, baseURL := range getSmoketestsBaseURLs(params, templateData) {
+		for _, check := range params.Smoketests.Checks {
+			url := baseURL + check.Path
+
+			curlArgs := []string{"curl", "-sk", "-o", "/dev/null", "-w", "'%{http_code}'", "--max-time", "10", "--retry", "5", "--retry-connrefused", "--retry-delay", "2"}
+
+			headerNames := make([]string, 0, len(check.Headers))
+			for name := range check.Headers {
+				headerNames = append(headerNames, name)
+			}
+			sort.Strings(headerNames)
+			for _, name := range headerNames {
+				curlArgs = append(curlArgs, "-H", shellQuote(fmt.Sprintf("%v: %v", name, check.Headers[name])))
+			}
+			if check.CanaryHeader && templateData.TrackLabel == "canary" {
+				curlArgs = append(curlArgs, "-H", shellQuote(fmt.Sprintf("%v: %v", templateData.Canary.Header, templateData.Canary.HeaderValue)))
+			}
+			curlArgs = append(curlArgs, shellQuote(url))
+
+			sb.WriteString(fmt.Sprintf("status=$(%v)\n", strings.Join(curlArgs, " ")))
+			sb.WriteString(fmt.Sprintf("if [ \"$status\" = \"%v\" ]; then echo %v; else echo %v; failed=1; fi\n",
+				check.ExpectedStatus,
+				shellQuote(fmt.Sprintf("PASS %v responded with %v", url, check.ExpectedStatus)),
+				shellQuote(fmt.Sprintf("FAIL %v responded with ", url))+`"$status"`+shellQuote(fmt.Sprintf(", expected %v", check.ExpectedStatus)),
+			))
+		}
+	}
+
+	sb.WriteString("exit $failed\n")
+
+	return sb.String()
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,132 @@
+package extension
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestRunSmoketests(t *testing.T) {
+
+	t.Run("RunsChecksInPodWithinTimeout", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{Kind: api.KindDeployment, Smoketests: api.SmoketestsParams{Image: "curlimages/curl:8.6.0", Timeout: "2m", Checks: []api.SmoketestCheckParams{{Path: "/liveness", ExpectedStatus: 200}}}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp", Namespace: "mynamespace", Service: api.ServiceData{Name: "myapp"}, Container: api.ContainerData{Port: 5000}}
+
+		kubernetesClient.EXPECT().RunPod(gomock.Any(), gomock.Any(), 2*time.Minute).DoAndReturn(func(ctx context.Context, pod *corev1.Pod, timeout time.Duration) (string, error) {
+			assert.Equal(t, "myapp-smoketests", pod.Name)
+			assert.Equal(t, "mynamespace", pod.Namespace)
+			assert.Equal(t, "curlimages/curl:8.6.0", pod.Spec.Containers[0].Image)
+			return "PASS http://myapp.mynamespace.svc.cluster.local:5000/liveness responded with 200\n", nil
+		})
+
+		// act
+		err := service.runSmoketests(context.Background(), params, templateData)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("ReturnsErrorIfAnyCheckFails", func(t *testing.T) {
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		kubernetesClient := kubernetes.NewMockClient(ctrl)
+		service := &service{kubernetesClient: kubernetesClient}
+		params := api.Params{Kind: api.KindDeployment, Smoketests: api.SmoketestsParams{Timeout: "2m", Checks: []api.SmoketestCheckParams{{Path: "/", ExpectedStatus: 200}}}}
+		templateData := api.TemplateData{Name: "myapp", NameWithTrack: "myapp", Namespace: "mynamespace"}
+
+		kubernetesClient.EXPECT().RunPod(gomock.Any(), gomock.Any(), gomock.Any()).Return("FAIL http://myapp.mynamespace.svc.cluster.local:5000/ responded with 503, expected 200\n", kubernetes.ErrPodFailed)
+
+		// act
+		err := service.runSmoketests(context.Background(), params, templateData)
+
+		assert.True(t, errors.Is(err, kubernetes.ErrPodFailed))
+	})
+}
+
+func TestGetSmoketestsBaseURLs(t *testing.T) {
+
+	t.Run("ReturnsServiceWithContainerPortAndHosts", func(t *testing.T) {
+
+		params := api.Params{Kind: api.KindDeployment}
+		templateData := api.TemplateData{Namespace: "mynamespace", Service: api.ServiceData{Name: "myapp"}, Container: api.ContainerData{Port: 5000}, Hosts: []string{"myapp.example.com"}}
+
+		// act
+		baseURLs := getSmoketestsBaseURLs(params, templateData)
+
+		assert.Equal(t, []string{"http://myapp.mynamespace.svc.cluster.local:5000", "https://myapp.example.com"}, baseURLs)
+	})
+
+	t.Run("ReturnsServiceOfTrackForCanary", func(t *testing.T) {
+
+		params := api.Params{Kind: api.KindDeployment}
+		templateData := api.TemplateData{Namespace: "mynamespace", Service: api.ServiceData{Name: "myapp"}, TrackLabel: "canary", HasOpenrestySidecar: true}
+
+		// act
+		baseURLs := getSmoketestsBaseURLs(params, templateData)
+
+		assert.Equal(t, []string{"http://myapp-canary.mynamespace.svc.cluster.local"}, baseURLs)
+	})
+
+	t.Run("ReturnsOnlyPreviewServiceForIdleBlueGreenColor", func(t *testing.T) {
+
+		params := api.Params{Kind: api.KindDeployment, StrategyType: api.StrategyTypeBlueGreen}
+		templateData := api.TemplateData{Namespace: "mynamespace", Service: api.ServiceData{Name: "myapp"}, Container: api.ContainerData{Port: 5000}, Hosts: []string{"myapp.example.com"}, UseBlueGreen: true, BlueGreenColor: "green", BlueGreenLiveColor: "blue"}
+
+		// act
+		baseURLs := getSmoketestsBaseURLs(params, templateData)
+
+		assert.Equal(t, []string{"http://myapp-preview.mynamespace.svc.cluster.local:5000"}, baseURLs)
+	})
+
+	t.Run("ReturnsOnlyHostsForHeadlessDeployment", func(t *testing.T) {
+
+		params := api.Params{Kind: api.KindHeadlessDeployment}
+		templateData := api.TemplateData{Namespace: "mynamespace", Hosts: []string{"myapp.example.com"}}
+
+		// act
+		baseURLs := getSmoketestsBaseURLs(params, templateData)
+
+		assert.Equal(t, []string{"https://myapp.example.com"}, baseURLs)
+	})
+}
+
+func TestGetSmoketestsScript(t *testing.T) {
+
+	t.Run("AddsHeadersAndCanaryHeaderForCanary", func(t *testing.T) {
+
+		params := api.Params{Kind: api.KindHeadlessDeployment, Smoketests: api.SmoketestsParams{Checks: []api.SmoketestCheckParams{{Path: "/api", ExpectedStatus: 204, Headers: map[string]string{"Authorization": "Bearer it's"}, CanaryHeader: true}}}}
+		templateData := api.TemplateData{Hosts: []string{"myapp.example.com"}, TrackLabel: "canary", Canary: api.CanaryParams{Header: "track", HeaderValue: "canary"}}
+
+		// act
+		script := getSmoketestsScript(params, templateData)
+
+		assert.Contains(t, script, `-H 'Authorization: Bearer it'\''s' -H 'track: canary' 'https://myapp.example.com/api'`)
+		assert.Contains(t, script, `if [ "$status" = "204" ]`)
+		assert.Contains(t, script, "exit $failed")
+	})
+
+	t.Run("OmitsCanaryHeaderForStable", func(t *testing.T) {
+
+		params := api.Params{Kind: api.KindHeadlessDeployment, Smoketests: api.SmoketestsParams{Checks: []api.SmoketestCheckParams{{Path: "/", ExpectedStatus: 200, CanaryHeader: true}}}}
+		templateData := api.TemplateData{Hosts: []string{"myapp.example.com"}, TrackLabel: "stable", Canary: api.CanaryParams{Header: "track", HeaderValue: "canary"}}
+
+		// act
+		script := getSmoketestsScript(params, templateData)
+
+		assert.NotContains(t, script, "track: canary")
+	})
+}
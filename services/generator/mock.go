@@ -63,17 +63,17 @@ func (mr *MockServiceMockRecorder) BuildSidecar(sidecar, params interface{}) *go
 }
 
 // GenerateTemplateData mocks base method.
-func (m *MockService) GenerateTemplateData(params api.Params, currentReplicas int, gitSource, gitOwner, gitName, gitBranch, gitRevision, releaseID, triggeredBy string) api.TemplateData {
+func (m *MockService) GenerateTemplateData(params api.Params, currentReplicas int, gitSource, gitOwner, gitName, gitBranch, gitRevision, releaseID, builderImageSHA, builderImageDate, triggeredBy string) api.TemplateData {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateTemplateData", params, currentReplicas, gitSource, gitOwner, gitName, gitBranch, gitRevision, releaseID, triggeredBy)
+	ret := m.ctrl.Call(m, "GenerateTemplateData", params, currentReplicas, gitSource, gitOwner, gitName, gitBranch, gitRevision, releaseID, builderImageSHA, builderImageDate, triggeredBy)
 	ret0, _ := ret[0].(api.TemplateData)
 	return ret0
 }
 
 // GenerateTemplateData indicates an expected call of GenerateTemplateData.
-func (mr *MockServiceMockRecorder) GenerateTemplateData(params, currentReplicas, gitSource, gitOwner, gitName, gitBranch, gitRevision, releaseID, triggeredBy interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) GenerateTemplateData(params, currentReplicas, gitSource, gitOwner, gitName, gitBranch, gitRevision, releaseID, builderImageSHA, builderImageDate, triggeredBy interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateTemplateData", reflect.TypeOf((*MockService)(nil).GenerateTemplateData), params, currentReplicas, gitSource, gitOwner, gitName, gitBranch, gitRevision, releaseID, triggeredBy)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateTemplateData", reflect.TypeOf((*MockService)(nil).GenerateTemplateData), params, currentReplicas, gitSource, gitOwner, gitName, gitBranch, gitRevision, releaseID, builderImageSHA, builderImageDate, triggeredBy)
 }
 
 // IsSimpleEnvvarValue mocks base method.
@@ -38,8 +38,11 @@ func (s *service) GenerateTemplateData(params api.Params, currentReplicas int, g
 	data := api.TemplateData{
 		Name:                    params.App,
 		NameWithTrack:           params.App,
+		JobName:                 params.App,
 		Namespace:               params.Namespace,
 		Schedule:                params.Schedule,
+		TimeZone:                params.TimeZone,
+		Suspend:                 params.Suspend,
 		ConcurrencyPolicy:       params.ConcurrencyPolicy,
 		RestartPolicy:           params.RestartPolicy,
 		Completions:             params.Completions,
@@ -97,6 +100,7 @@ func (s *service) GenerateTemplateData(params api.Params, currentReplicas int, g
 
 		PreferPreemptibles:            params.ChaosProof,
 		UseWindowsNodes:               params.OperatingSystem == api.OperatingSystemWindows,
+		UseArm64Nodes:                 params.Architecture == api.ArchitectureARM64,
 		MountServiceAccountSecret:     params.UseGoogleCloudCredentials || params.LegacyGoogleCloudServiceAccountKeyFile != "",
 		UseLegacyServiceAccountKey:    params.LegacyGoogleCloudServiceAccountKeyFile != "",
 		GoogleCloudCredentialsAppName: params.GoogleCloudCredentialsApp,
@@ -111,6 +115,7 @@ func (s *service) GenerateTemplateData(params api.Params, currentReplicas int, g
 			Repository:      params.Container.ImageRepository,
 			Name:            params.Container.ImageName,
 			Tag:             params.Container.ImageTag,
+			Digest:          params.Container.ImageDigest,
 			ImagePullPolicy: params.Container.ImagePullPolicy,
 			Port:            params.Container.Port,
 			PortGrpc:        params.Container.PortGrpc,
@@ -198,7 +203,7 @@ func (s *service) GenerateTemplateData(params api.Params, currentReplicas int, g
 	// set tracing service name
 	data.Container.EnvironmentVariables = s.AddEnvironmentVariableIfNotSet(data.Container.EnvironmentVariables, "JAEGER_SERVICE_NAME", params.App)
 
-	if params.Action == api.ActionDeployCanary || params.Action == api.ActionDiffCanary {
+	if params.Action == api.ActionDeployCanary || params.Action == api.ActionDiffCanary || params.Action == api.ActionDeployProgressive {
 		data.Container.EnvironmentVariables = s.AddEnvironmentVariableIfNotSet(data.Container.EnvironmentVariables, "JAEGER_SAMPLER_TYPE", "probabilistic")
 		data.Container.EnvironmentVariables = s.AddEnvironmentVariableIfNotSet(data.Container.EnvironmentVariables, "JAEGER_SAMPLER_PARAM", "0.1")
 		data.Container.EnvironmentVariables = s.AddEnvironmentVariableIfNotSet(data.Container.EnvironmentVariables, "JAEGER_TAGS", "track=canary")
@@ -245,6 +250,15 @@ func (s *service) GenerateTemplateData(params api.Params, currentReplicas int, g
 			"effect":   "NoSchedule",
 		})
 	}
+	if data.UseArm64Nodes {
+		data.HasTolerations = true
+		data.Tolerations = append(data.Tolerations, &map[string]interface{}{
+			"key":      "kubernetes.io/arch",
+			"operator": "Equal",
+			"value":    "arm64",
+			"effect":   "NoSchedule",
+		})
+	}
 
 	if params.Tolerations != nil {
 		data.HasTolerations = true
@@ -354,7 +368,8 @@ func (s *service) GenerateTemplateData(params api.Params, currentReplicas int, g
 		api.ActionDiffSimple:
 		data.IncludeTrackLabel = false
 	case api.ActionDeployCanary,
-		api.ActionDiffCanary:
+		api.ActionDiffCanary,
+		api.ActionDeployProgressive:
 		data.NameWithTrack += "-canary"
 		data.IncludeTrackLabel = true
 		data.TrackLabel = "canary"
@@ -365,12 +380,17 @@ func (s *service) GenerateTemplateData(params api.Params, currentReplicas int, g
 		data.TrackLabel = "stable"
 	}
 
+	// a progressive canary starts at the first step and gets its weight increased by the extension
+	if params.Action == api.ActionDeployProgressive && len(params.Canary.Steps) > 0 {
+		data.Canary.Weight = strconv.Itoa(params.Canary.Steps[0])
+	}
+
 	switch params.StrategyType {
 	case api.StrategyTypeRollingUpdate:
 		data.StrategyType = string(params.StrategyType)
 	case api.StrategyTypeRecreate:
 		data.StrategyType = string(params.StrategyType)
-	case api.StrategyTypeAtomicUpdate:
+	case api.StrategyTypeAtomicUpdate, api.StrategyTypeBlueGreen:
 		data.StrategyType = string(api.StrategyTypeRollingUpdate)
 	}
 
@@ -383,6 +403,19 @@ func (s *service) GenerateTemplateData(params api.Params, currentReplicas int, g
 		data.PodLabels["estafette.io/atomic-id"] = params.AtomicID
 	}
 
+	if params.StrategyType == api.StrategyTypeBlueGreen && (params.Action == api.ActionDeploySimple || params.Action == api.ActionDiffSimple) && params.BlueGreenColor != "" {
+		data.NameWithTrack += "-" + params.BlueGreenColor
+		data.UseBlueGreen = true
+		data.BlueGreenColor = params.BlueGreenColor
+		data.BlueGreenLiveColor = params.BlueGreenLiveColor
+
+		// the idle colour might have been scaled down after a switch, so it always needs its replicas set
+		data.IncludeReplicas = data.Replicas > 0
+
+		data.Labels["estafette.io/color"] = params.BlueGreenColor
+		data.PodLabels["estafette.io/color"] = params.BlueGreenColor
+	}
+
 	// set some additional labels similar to helm charts in order to unify alerting and dashboards
 	data.PodLabels["app.kubernetes.io/name"] = data.Name
 	data.PodLabels["app.kubernetes.io/instance"] = data.NameWithTrack
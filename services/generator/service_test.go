@@ -1755,6 +1755,47 @@ func TestGenerateTemplateData(t *testing.T) {
 		}, templateData.Tolerations[0])
 	})
 
+	t.Run("SetsUseArm64NodesAndAddsArchTolerationIfArchitectureParamIsArm64", func(t *testing.T) {
+
+		ctx := context.Background()
+		service, err := NewService(ctx)
+		assert.Nil(t, err)
+
+		params := api.Params{
+			Architecture: api.ArchitectureARM64,
+		}
+
+		// act
+		templateData := service.GenerateTemplateData(params, -1, "github.com", "estafette", "estafette-extension-gke", "master", "02770946ad015b34da9e9980007bf81308c41aec", "", "", "", "")
+
+		assert.True(t, templateData.UseArm64Nodes)
+		assert.True(t, templateData.HasTolerations)
+		assert.Equal(t, 1, len(templateData.Tolerations))
+		assert.Equal(t, &map[string]interface{}{
+			"key":      "kubernetes.io/arch",
+			"operator": "Equal",
+			"value":    "arm64",
+			"effect":   "NoSchedule",
+		}, templateData.Tolerations[0])
+	})
+
+	t.Run("SetsUseArm64NodesToFalseIfArchitectureParamIsAmd64", func(t *testing.T) {
+
+		ctx := context.Background()
+		service, err := NewService(ctx)
+		assert.Nil(t, err)
+
+		params := api.Params{
+			Architecture: api.ArchitectureAMD64,
+		}
+
+		// act
+		templateData := service.GenerateTemplateData(params, -1, "github.com", "estafette", "estafette-extension-gke", "master", "02770946ad015b34da9e9980007bf81308c41aec", "", "", "", "")
+
+		assert.False(t, templateData.UseArm64Nodes)
+		assert.False(t, templateData.HasTolerations)
+	})
+
 	t.Run("AddsPreemptibleTolerationAndOtherTolerationsIfChaosProofParamIsTrueAndTolerationsAreSet", func(t *testing.T) {
 
 		ctx := context.Background()
@@ -2028,6 +2069,45 @@ func TestGenerateTemplateData(t *testing.T) {
 		assert.Equal(t, "myapp-canary", templateData.NameWithTrack)
 	})
 
+	t.Run("AppendsCanaryToNameWithTrackIfActionIsDeployProgressive", func(t *testing.T) {
+
+		ctx := context.Background()
+		service, err := NewService(ctx)
+		assert.Nil(t, err)
+
+		params := api.Params{
+			App:    "myapp",
+			Action: api.ActionDeployProgressive,
+		}
+
+		// act
+		templateData := service.GenerateTemplateData(params, -1, "github.com", "estafette", "estafette-extension-gke", "master", "02770946ad015b34da9e9980007bf81308c41aec", "", "", "", "")
+
+		assert.Equal(t, "myapp-canary", templateData.NameWithTrack)
+		assert.Equal(t, "canary", templateData.TrackLabel)
+	})
+
+	t.Run("SetsCanaryWeightToFirstStepIfActionIsDeployProgressive", func(t *testing.T) {
+
+		ctx := context.Background()
+		service, err := NewService(ctx)
+		assert.Nil(t, err)
+
+		params := api.Params{
+			App:    "myapp",
+			Action: api.ActionDeployProgressive,
+			Canary: api.CanaryParams{
+				Weight: "5",
+				Steps:  []int{10, 50, 100},
+			},
+		}
+
+		// act
+		templateData := service.GenerateTemplateData(params, -1, "github.com", "estafette", "estafette-extension-gke", "master", "02770946ad015b34da9e9980007bf81308c41aec", "", "", "", "")
+
+		assert.Equal(t, "10", templateData.Canary.Weight)
+	})
+
 	t.Run("AppendsStableToNameWithTrackIfParamsTypeIsRollforward", func(t *testing.T) {
 
 		ctx := context.Background()
@@ -2062,6 +2142,58 @@ func TestGenerateTemplateData(t *testing.T) {
 		assert.Equal(t, "myapp", templateData.NameWithTrack)
 	})
 
+	t.Run("AppendsColorToNameWithTrackIfStrategyTypeIsBlueGreen", func(t *testing.T) {
+
+		ctx := context.Background()
+		service, err := NewService(ctx)
+		assert.Nil(t, err)
+
+		params := api.Params{
+			App:                "myapp",
+			Action:             api.ActionDeploySimple,
+			StrategyType:       api.StrategyTypeBlueGreen,
+			BlueGreenColor:     "green",
+			BlueGreenLiveColor: "blue",
+			Replicas:           3,
+		}
+
+		// act
+		templateData := service.GenerateTemplateData(params, -1, "github.com", "estafette", "estafette-extension-gke", "master", "02770946ad015b34da9e9980007bf81308c41aec", "", "", "", "")
+
+		assert.Equal(t, "myapp-green", templateData.NameWithTrack)
+		assert.True(t, templateData.UseBlueGreen)
+		assert.Equal(t, "green", templateData.BlueGreenColor)
+		assert.Equal(t, "blue", templateData.BlueGreenLiveColor)
+		assert.Equal(t, "green", templateData.Labels["estafette.io/color"])
+		assert.Equal(t, "green", templateData.PodLabels["estafette.io/color"])
+		assert.Equal(t, "RollingUpdate", templateData.StrategyType)
+	})
+
+	t.Run("IncludesReplicasIfStrategyTypeIsBlueGreenAndAutoscaleIsEnabled", func(t *testing.T) {
+
+		ctx := context.Background()
+		service, err := NewService(ctx)
+		assert.Nil(t, err)
+
+		autoscaleEnabled := true
+		params := api.Params{
+			App:            "myapp",
+			Action:         api.ActionDeploySimple,
+			StrategyType:   api.StrategyTypeBlueGreen,
+			BlueGreenColor: "blue",
+			Autoscale: api.AutoscaleParams{
+				Enabled:     &autoscaleEnabled,
+				MinReplicas: 3,
+			},
+		}
+
+		// act
+		templateData := service.GenerateTemplateData(params, -1, "github.com", "estafette", "estafette-extension-gke", "master", "02770946ad015b34da9e9980007bf81308c41aec", "", "", "", "")
+
+		assert.True(t, templateData.IncludeReplicas)
+		assert.Equal(t, 3, templateData.Replicas)
+	})
+
 	t.Run("AddsBuildImageShaAndBuildImageDateIfNotEmpty", func(t *testing.T) {
 
 		ctx := context.Background()
@@ -0,0 +1,53 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: service.go
+
+// Package policy is a generated GoMock package.
+package policy
+
+import (
+	context "context"
+	reflect "reflect"
+
+	api "github.com/estafette/estafette-extension-gke/api"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockService is a mock of Service interface.
+type MockService struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceMockRecorder
+}
+
+// MockServiceMockRecorder is the mock recorder for MockService.
+type MockServiceMockRecorder struct {
+	mock *MockService
+}
+
+// NewMockService creates a new mock instance.
+func NewMockService(ctrl *gomock.Controller) *MockService {
+	mock := &MockService{ctrl: ctrl}
+	mock.recorder = &MockServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockService) EXPECT() *MockServiceMockRecorder {
+	return m.recorder
+}
+
+// Evaluate mocks base method.
+func (m *MockService) Evaluate(ctx context.Context, credential *api.GKECredentials, params api.Params, manifests []byte) (bool, []error, []string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Evaluate", ctx, credential, params, manifests)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].([]error)
+	ret2, _ := ret[2].([]string)
+	ret3, _ := ret[3].(error)
+	return ret0, ret1, ret2, ret3
+}
+
+// Evaluate indicates an expected call of Evaluate.
+func (mr *MockServiceMockRecorder) Evaluate(ctx, credential, params, manifests interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Evaluate", reflect.TypeOf((*MockService)(nil).Evaluate), ctx, credential, params, manifests)
+}
@@ -0,0 +1,246 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/estafette/estafette-extension-gke/clients/kubernetes"
+	foundation "github.com/estafette/estafette-foundation"
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+//go:generate mockgen -package=policy -destination ./mock.go -source=service.go
+type Service interface {
+	Evaluate(ctx context.Context, credential *api.GKECredentials, params api.Params, manifests []byte) (valid bool, errors []error, warnings []string, err error)
+}
+
+// NewService returns a new policy.Service; rules in the file at rulesPath apply on top of the ones in the credential, if the file exists
+func NewService(ctx context.Context, rulesPath string) (Service, error) {
+	return &service{
+		rulesPath: rulesPath,
+	}, nil
+}
+
+type service struct {
+	rulesPath string
+}
+
+// podSpecPaths holds the path to the pod spec of every kind that creates pods
+var podSpecPaths = map[string][]string{
+	"Pod":         {"spec"},
+	"Deployment":  {"spec", "template", "spec"},
+	"StatefulSet": {"spec", "template", "spec"},
+	"DaemonSet":   {"spec", "template", "spec"},
+	"ReplicaSet":  {"spec", "template", "spec"},
+	"Job":         {"spec", "template", "spec"},
+	"CronJob":     {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// Evaluate checks the rendered manifests against the policy rules; violations of rules with severity error make it invalid, those of rules with
+// severity warning are returned as warnings
+func (s *service) Evaluate(ctx context.Context, credential *api.GKECredentials, params api.Params, manifests []byte) (valid bool, errors []error, warnings []string, err error) {
+	rules, err := s.getRules(credential)
+	if err != nil {
+		return false, nil, nil, err
+	}
+
+	errors = []error{}
+	warnings = []string{}
+	if len(rules) == 0 {
+		return true, errors, warnings, nil
+	}
+
+	objects, err := kubernetes.DecodeManifests(manifests)
+	if err != nil {
+		return false, nil, nil, fmt.Errorf("Failed splitting rendered manifests: %w", err)
+	}
+
+	for _, rule := range rules {
+		for _, violation := range evaluateRule(rule, params, objects) {
+			if rule.Severity == api.PolicySeverityWarning {
+				warnings = append(warnings, fmt.Sprintf("Policy %v: %v", rule.Name, violation))
+			} else {
+				errors = append(errors, fmt.Errorf("Policy %v: %v", rule.Name, violation))
+			}
+		}
+	}
+
+	return len(errors) == 0, errors, warnings, nil
+}
+
+// getRules returns the rules of the credential followed by those of the rules file
+func (s *service) getRules(credential *api.GKECredentials) (rules []api.PolicyRule, err error) {
+	rules = []api.PolicyRule{}
+	if credential != nil {
+		rules = append(rules, credential.AdditionalProperties.Policies...)
+	}
+
+	if s.rulesPath != "" && foundation.FileExists(s.rulesPath) {
+		log.Info().Msgf("Reading policy rules from file at path %v...", s.rulesPath)
+		data, err := ioutil.ReadFile(s.rulesPath)
+		if err != nil {
+			return nil, fmt.Errorf("Failed reading policy rules file %v: %w", s.rulesPath, err)
+		}
+		var policyRules api.PolicyRules
+		err = yaml.Unmarshal(data, &policyRules)
+		if err != nil {
+			return nil, fmt.Errorf("Failed unmarshalling policy rules file %v: %w", s.rulesPath, err)
+		}
+		rules = append(rules, policyRules.Rules...)
+	}
+
+	for i := range rules {
+		rules[i].SetDefaults()
+		err = rules[i].Validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rules, nil
+}
+
+func evaluateRule(rule api.PolicyRule, params api.Params, objects []*unstructured.Unstructured) (violations []string) {
+	if rule.Rule == api.PolicyRuleAllowedVisibilities {
+		// the visibility is a property of the release rather than of any single object
+		if !rule.AppliesToNamespace(params.Namespace) {
+			return nil
+		}
+		for _, visibility := range rule.Visibilities {
+			if visibility == params.Visibility {
+				return nil
+			}
+		}
+		allowed := []string{}
+		for _, visibility := range rule.Visibilities {
+			allowed = append(allowed, string(visibility))
+		}
+		return []string{fmt.Sprintf("visibility %v isn't allowed in namespace %v; use %v", params.Visibility, params.Namespace, strings.Join(allowed, ", "))}
+	}
+
+	for _, obj := range objects {
+		namespace := obj.GetNamespace()
+		if namespace == "" {
+			namespace = params.Namespace
+		}
+		if !rule.AppliesToNamespace(namespace) {
+			continue
+		}
+
+		switch rule.Rule {
+		case api.PolicyRuleMaxReplicas:
+			violations = append(violations, checkMaxReplicas(rule, obj)...)
+		case api.PolicyRuleRequireResourceLimits:
+			forEachContainer(obj, func(container map[string]interface{}, podSpec map[string]interface{}) {
+				violations = append(violations, checkResourceLimits(rule, obj, container)...)
+			})
+		case api.PolicyRuleDisallowLatestTag:
+			forEachContainer(obj, func(container map[string]interface{}, podSpec map[string]interface{}) {
+				violations = append(violations, checkLatestTag(obj, container)...)
+			})
+		case api.PolicyRuleRequireSecurityContext:
+			forEachContainer(obj, func(container map[string]interface{}, podSpec map[string]interface{}) {
+				violations = append(violations, checkSecurityContext(obj, container, podSpec)...)
+			})
+		}
+	}
+
+	return violations
+}
+
+func checkMaxReplicas(rule api.PolicyRule, obj *unstructured.Unstructured) (violations []string) {
+	if _, hasPodSpec := podSpecPaths[obj.GetKind()]; hasPodSpec {
+		if replicas, found := nestedNumber(obj.Object, "spec", "replicas"); found && replicas > int64(rule.MaxReplicas) {
+			violations = append(violations, fmt.Sprintf("%v %v has %v replicas, more than the maximum of %v", obj.GetKind(), obj.GetName(), replicas, rule.MaxReplicas))
+		}
+	}
+	if obj.GetKind() == "HorizontalPodAutoscaler" {
+		if maxReplicas, found := nestedNumber(obj.Object, "spec", "maxReplicas"); found && maxReplicas > int64(rule.MaxReplicas) {
+			violations = append(violations, fmt.Sprintf("%v %v scales up to %v replicas, more than the maximum of %v", obj.GetKind(), obj.GetName(), maxReplicas, rule.MaxReplicas))
+		}
+	}
+
+	return violations
+}
+
+func checkResourceLimits(rule api.PolicyRule, obj *unstructured.Unstructured, container map[string]interface{}) (violations []string) {
+	limits, _, _ := unstructured.NestedMap(container, "resources", "limits")
+	for _, resource := range rule.Resources {
+		if _, found := limits[resource]; !found {
+			violations = append(violations, fmt.Sprintf("container %v of %v %v has no %v limit", container["name"], obj.GetKind(), obj.GetName(), resource))
+		}
+	}
+
+	return violations
+}
+
+func checkLatestTag(obj *unstructured.Unstructured, container map[string]interface{}) (violations []string) {
+	image, _ := container["image"].(string)
+	if strings.Contains(image, "@") {
+		// pinned by digest
+		return nil
+	}
+
+	tag := ""
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		tag = image[i+1:]
+	}
+	if tag == "" {
+		return []string{fmt.Sprintf("container %v of %v %v uses image %v without tag, which resolves to latest; pin a version instead", container["name"], obj.GetKind(), obj.GetName(), image)}
+	}
+	if tag == "latest" {
+		return []string{fmt.Sprintf("container %v of %v %v uses image %v; pin a version instead of latest", container["name"], obj.GetKind(), obj.GetName(), image)}
+	}
+
+	return nil
+}
+
+func checkSecurityContext(obj *unstructured.Unstructured, container map[string]interface{}, podSpec map[string]interface{}) (violations []string) {
+	if securityContext, _, _ := unstructured.NestedMap(container, "securityContext"); len(securityContext) > 0 {
+		return nil
+	}
+	if securityContext, _, _ := unstructured.NestedMap(podSpec, "securityContext"); len(securityContext) > 0 {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("container %v of %v %v has no securityContext and neither has its pod", container["name"], obj.GetKind(), obj.GetName())}
+}
+
+// nestedNumber returns a number field of a decoded manifest, which holds numbers as float64 rather than int64
+func nestedNumber(obj map[string]interface{}, fields ...string) (number int64, found bool) {
+	value, found, _ := unstructured.NestedFieldNoCopy(obj, fields...)
+	switch v := value.(type) {
+	case int64:
+		return v, found
+	case float64:
+		return int64(v), found
+	}
+
+	return 0, false
+}
+
+// forEachContainer calls fn for every container and init container of the pod spec of an object, if its kind has one
+func forEachContainer(obj *unstructured.Unstructured, fn func(container map[string]interface{}, podSpec map[string]interface{})) {
+	specPath, ok := podSpecPaths[obj.GetKind()]
+	if !ok {
+		return
+	}
+	podSpec, found, _ := unstructured.NestedMap(obj.Object, specPath...)
+	if !found {
+		return
+	}
+
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _, _ := unstructured.NestedSlice(podSpec, field)
+		for _, c := range containers {
+			if container, ok := c.(map[string]interface{}); ok {
+				fn(container, podSpec)
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package policy
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/estafette/estafette-extension-gke/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluate(t *testing.T) {
+
+	deployment := []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: myapp
+  namespace: mynamespace
+spec:
+  replicas: 30
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox:1.36
+        resources:
+          limits:
+            cpu: 100m
+            memory: 64Mi
+      containers:
+      - name: myapp
+        image: estafette/myapp:latest
+        resources:
+          limits:
+            memory: 256Mi
+        securityContext:
+          runAsNonRoot: true
+      - name: openresty
+        image: estafette/openresty-sidecar
+        resources:
+          limits:
+            cpu: 100m
+            memory: 64Mi
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: myapp
+  namespace: mynamespace
+spec:
+  maxReplicas: 50
+`)
+	params := api.Params{Namespace: "mynamespace", Visibility: api.VisibilityPublic}
+
+	t.Run("ReturnsValidWithoutRules", func(t *testing.T) {
+
+		service := &service{}
+
+		// act
+		valid, errors, warnings, err := service.Evaluate(context.Background(), &api.GKECredentials{}, params, deployment)
+
+		assert.Nil(t, err)
+		assert.True(t, valid)
+		assert.Equal(t, 0, len(errors))
+		assert.Equal(t, 0, len(warnings))
+	})
+
+	t.Run("ReturnsErrorsForReplicasAboveMaximum", func(t *testing.T) {
+
+		service := &service{}
+		credential := &api.GKECredentials{AdditionalProperties: api.GKECredentialAdditionalProperties{Policies: []api.PolicyRule{{Rule: api.PolicyRuleMaxReplicas, MaxReplicas: 20}}}}
+
+		// act
+		valid, errors, _, err := service.Evaluate(context.Background(), credential, params, deployment)
+
+		assert.Nil(t, err)
+		assert.False(t, valid)
+		if assert.Equal(t, 2, len(errors)) {
+			assert.Equal(t, "Policy max-replicas: Deployment myapp has 30 replicas, more than the maximum of 20", errors[0].Error())
+			assert.Equal(t, "Policy max-replicas: HorizontalPodAutoscaler myapp scales up to 50 replicas, more than the maximum of 20", errors[1].Error())
+		}
+	})
+
+	t.Run("ReturnsErrorsForContainersWithoutResourceLimits", func(t *testing.T) {
+
+		service := &service{}
+		credential := &api.GKECredentials{AdditionalProperties: api.GKECredentialAdditionalProperties{Policies: []api.PolicyRule{{Rule: api.PolicyRuleRequireResourceLimits}}}}
+
+		// act
+		valid, errors, _, err := service.Evaluate(context.Background(), credential, params, deployment)
+
+		assert.Nil(t, err)
+		assert.False(t, valid)
+		if assert.Equal(t, 1, len(errors)) {
+			assert.Equal(t, "Policy require-resource-limits: container myapp of Deployment myapp has no cpu limit", errors[0].Error())
+		}
+	})
+
+	t.Run("ReturnsErrorsForImagesWithLatestOrWithoutTag", func(t *testing.T) {
+
+		service := &service{}
+		credential := &api.GKECredentials{AdditionalProperties: api.GKECredentialAdditionalProperties{Policies: []api.PolicyRule{{Rule: api.PolicyRuleDisallowLatestTag}}}}
+
+		// act
+		valid, errors, _, err := service.Evaluate(context.Background(), credential, params, deployment)
+
+		assert.Nil(t, err)
+		assert.False(t, valid)
+		if assert.Equal(t, 2, len(errors)) {
+			assert.Equal(t, "Policy disallow-latest-tag: container myapp of Deployment myapp uses image estafette/myapp:latest; pin a version instead of latest", errors[0].Error())
+			assert.Equal(t, "Policy disallow-latest-tag: container openresty of Deployment myapp uses image estafette/openresty-sidecar without tag, which resolves to latest; pin a version instead", errors[1].Error())
+		}
+	})
+
+	t.Run("AllowsImagesPinnedByDigestOrOnRegistryWithPort", func(t *testing.T) {
+
+		service := &service{}
+		credential := &api.GKECredentials{AdditionalProperties: api.GKECredentialAdditionalProperties{Policies: []api.PolicyRule{{Rule: api.PolicyRuleDisallowLatestTag}}}}
+		manifests := []byte("apiVersion: batch/v1\nkind: CronJob\nmetadata:\n  name: myjob\nspec:\n  jobTemplate:\n    spec:\n      template:\n        spec:\n          containers:\n          - name: myjob\n            image: registry:5000/myjob@sha256:0123456789abcdef\n          - name: other\n            image: registry:5000/other:1.2.3\n")
+
+		// act
+		valid, errors, _, err := service.Evaluate(context.Background(), credential, params, manifests)
+
+		assert.Nil(t, err)
+		assert.True(t, valid)
+		assert.Equal(t, 0, len(errors))
+	})
+
+	t.Run("ReturnsErrorForVisibilityThatIsNotAllowedInNamespace", func(t *testing.T) {
+
+		service := &service{}
+		credential := &api.GKECredentials{AdditionalProperties: api.GKECredentialAdditionalProperties{Policies: []api.PolicyRule{{Name: "internal-only", Rule: api.PolicyRuleAllowedVisibilities, Visibilities: []api.Visibility{api.VisibilityPrivate, api.VisibilityIAP}, Namespaces: []string{"my*"}}}}}
+
+		// act
+		valid, errors, _, err := service.Evaluate(context.Background(), credential, params, deployment)
+
+		assert.Nil(t, err)
+		assert.False(t, valid)
+		if assert.Equal(t, 1, len(errors)) {
+			assert.Equal(t, "Policy internal-only: visibility public isn't allowed in namespace mynamespace; use private, iap", errors[0].Error())
+		}
+	})
+
+	t.Run("SkipsRulesForOtherNamespaces", func(t *testing.T) {
+
+		service := &service{}
+		credential := &api.GKECredentials{AdditionalProperties: api.GKECredentialAdditionalProperties{Policies: []api.PolicyRule{
+			{Rule: api.PolicyRuleAllowedVisibilities, Visibilities: []api.Visibility{api.VisibilityPrivate}, Namespaces: []string{"production"}},
+			{Rule: api.PolicyRuleMaxReplicas, MaxReplicas: 20, Namespaces: []string{"production"}},
+		}}}
+
+		// act
+		valid, errors, _, err := service.Evaluate(context.Background(), credential, params, deployment)
+
+		assert.Nil(t, err)
+		assert.True(t, valid)
+		assert.Equal(t, 0, len(errors))
+	})
+
+	t.Run("ReturnsWarningsForRulesWithSeverityWarning", func(t *testing.T) {
+
+		service := &service{}
+		credential := &api.GKECredentials{AdditionalProperties: api.GKECredentialAdditionalProperties{Policies: []api.PolicyRule{{Rule: api.PolicyRuleRequireSecurityContext, Severity: api.PolicySeverityWarning}}}}
+
+		// act
+		valid, errors, warnings, err := service.Evaluate(context.Background(), credential, params, deployment)
+
+		assert.Nil(t, err)
+		assert.True(t, valid)
+		assert.Equal(t, 0, len(errors))
+		assert.Equal(t, []string{
+			"Policy require-security-context: container init of Deployment myapp has no securityContext and neither has its pod",
+			"Policy require-security-context: container openresty of Deployment myapp has no securityContext and neither has its pod",
+		}, warnings)
+	})
+
+	t.Run("AppliesRulesFromRulesFileOnTopOfCredential", func(t *testing.T) {
+
+		rulesPath := filepath.Join(t.TempDir(), "gke.yaml")
+		err := ioutil.WriteFile(rulesPath, []byte("rules:\n- rule: max-replicas\n  maxReplicas: 40\n  severity: warning\n"), 0644)
+		assert.Nil(t, err)
+		service := &service{rulesPath: rulesPath}
+		credential := &api.GKECredentials{AdditionalProperties: api.GKECredentialAdditionalProperties{Policies: []api.PolicyRule{{Rule: api.PolicyRuleMaxReplicas, MaxReplicas: 20}}}}
+
+		// act
+		valid, errors, warnings, err := service.Evaluate(context.Background(), credential, params, deployment)
+
+		assert.Nil(t, err)
+		assert.False(t, valid)
+		assert.Equal(t, 2, len(errors))
+		assert.Equal(t, []string{"Policy max-replicas: HorizontalPodAutoscaler myapp scales up to 50 replicas, more than the maximum of 40"}, warnings)
+	})
+
+	t.Run("ReturnsErrorForInvalidRule", func(t *testing.T) {
+
+		service := &service{}
+		credential := &api.GKECredentials{AdditionalProperties: api.GKECredentialAdditionalProperties{Policies: []api.PolicyRule{{Rule: "no-root"}}}}
+
+		// act
+		_, _, _, err := service.Evaluate(context.Background(), credential, params, deployment)
+
+		assert.NotNil(t, err)
+	})
+}